@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/signer"
+)
+
+// signerCmd represents the signer command
+var signerCmd = &cobra.Command{
+	Use:   "signer",
+	Short: "Run the signer of the validator key for a remote Theta node.",
+	Run:   runSigner,
+}
+
+func init() {
+	RootCmd.AddCommand(signerCmd)
+}
+
+func runSigner(cmd *cobra.Command, args []string) {
+	privKey, err := loadOrCreateKey()
+	if err != nil {
+		log.Fatalf("Failed to load or create key: %v", err)
+	}
+
+	guard, err := loadSignGuard()
+	if err != nil {
+		log.Fatalf("Failed to load the sign state: %v", err)
+	}
+
+	listenAddr := viper.GetString(common.CfgSignerListenAddress)
+	if len(listenAddr) == 0 {
+		listenAddr = "unix://" + path.Join(cfgPath, "signer", "signer.sock")
+	}
+	server, err := signer.NewServer(signer.NewLocalSigner(privKey, guard), listenAddr,
+		viper.GetString(common.CfgSignerSecret))
+	if err != nil {
+		log.Fatalf("Failed to start the signer at %v: %v", listenAddr, err)
+	}
+	if err := server.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start the signer: %v", err)
+	}
+	log.WithFields(log.Fields{
+		"listen":  listenAddr,
+		"address": privKey.PublicKey().Address().Hex(),
+	}).Info("Signer started")
+
+	server.Wait()
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"path"
 	"strings"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/node"
 	"github.com/thetatoken/theta/p2p/messenger"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/snapshot"
 	"github.com/thetatoken/theta/store/database/backend"
 	ks "github.com/thetatoken/theta/wallet/softwallet/keystore"
@@ -44,6 +46,11 @@ func runStart(cmd *cobra.Command, args []string) {
 		log.Fatalf("Failed to load or create key: %v", err)
 	}
 
+	validatorSigner, err := newSigner(privKey)
+	if err != nil {
+		log.Fatalf("Failed to create the signer: %v", err)
+	}
+
 	network := newMessenger(privKey, peerSeeds, port)
 	mainDBPath := path.Join(cfgPath, "db", "main")
 	refDBPath := path.Join(cfgPath, "db", "ref")
@@ -64,12 +71,18 @@ func runStart(cmd *cobra.Command, args []string) {
 
 	params := &node.Params{
 		ChainID:      root.ChainID,
-		PrivateKey:   privKey,
+		Signer:       validatorSigner,
 		Root:         root,
 		Network:      network,
 		DB:           db,
 		SnapshotPath: snapshotPath,
 	}
+	if viper.GetBool(common.CfgMempoolJournalEnabled) {
+		params.MempoolJournalPath = path.Join(cfgPath, "mempool", "journal")
+	}
+	if viper.GetBool(common.CfgConsensusWALEnabled) {
+		params.ConsensusWALPath = path.Join(cfgPath, "consensus", "wal")
+	}
 	n := node.NewNode(params)
 	n.Start(context.Background())
 
@@ -156,6 +169,37 @@ func loadOrCreateKey() (*crypto.PrivateKey, error) {
 	return nodePrivKey, nil
 }
 
+// newSigner returns the signer of the validator key. The node key is used unless a remote signer is configured.
+func newSigner(privKey *crypto.PrivateKey) (core.Signer, error) {
+	remoteAddr := viper.GetString(common.CfgSignerRemoteAddress)
+	if len(remoteAddr) == 0 {
+		guard, err := loadSignGuard()
+		if err != nil {
+			return nil, err
+		}
+		return signer.NewLocalSigner(privKey, guard), nil
+	}
+
+	remoteSigner, err := signer.NewRemoteSigner(remoteAddr, viper.GetString(common.CfgSignerSecret))
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"signer":  remoteAddr,
+		"address": remoteSigner.Address().Hex(),
+	}).Info("Using remote signer for the validator key")
+	return remoteSigner, nil
+}
+
+// loadSignGuard loads the record of the blocks and votes signed with the local key
+func loadSignGuard() (*signer.SignGuard, error) {
+	signerDir := path.Join(cfgPath, "signer")
+	if err := os.MkdirAll(signerDir, 0700); err != nil {
+		return nil, err
+	}
+	return signer.NewSignGuard(path.Join(signerDir, "sign_state.json"))
+}
+
 func newMessenger(privKey *crypto.PrivateKey, seedPeerNetAddresses []string, port int) *messenger.Messenger {
 	log.WithFields(log.Fields{
 		"pubKey":  fmt.Sprintf("%v", privKey.PublicKey().ToBytes()),
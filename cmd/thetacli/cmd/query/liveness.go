@@ -0,0 +1,44 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// livenessCmd represents the liveness command.
+// Example:
+//		thetacli query liveness --address=2E833968E5bB786Ae419c4d13189fB081Cc43bab
+var livenessCmd = &cobra.Command{
+	Use:     "liveness",
+	Short:   "Get the uptime of the validators",
+	Example: `thetacli query liveness --address=2E833968E5bB786Ae419c4d13189fB081Cc43bab`,
+	Run:     doLivenessCmd,
+}
+
+func doLivenessCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetValidatorLiveness", rpc.GetValidatorLivenessArgs{Address: addressFlag})
+	if err != nil {
+		utils.Error("Failed to get validator liveness: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get validator liveness: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	livenessCmd.Flags().StringVar(&addressFlag, "address", "", "Address of the validator, all the stake holders if empty")
+}
@@ -14,4 +14,7 @@ func init() {
 	QueryCmd.AddCommand(accountCmd)
 	QueryCmd.AddCommand(splitRuleCmd)
 	QueryCmd.AddCommand(vcpCmd)
+	QueryCmd.AddCommand(livenessCmd)
+	QueryCmd.AddCommand(stakeRewardsCmd)
+	QueryCmd.AddCommand(pendingValidatorsCmd)
 }
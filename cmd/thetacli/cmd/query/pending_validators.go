@@ -0,0 +1,40 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// pendingValidatorsCmd represents the pending validators command.
+// Example:
+//		thetacli query pending_validators
+var pendingValidatorsCmd = &cobra.Command{
+	Use:     "pending_validators",
+	Short:   "Get the active validator set and the validator set it hands over to",
+	Example: `thetacli query pending_validators`,
+	Run:     doPendingValidatorsCmd,
+}
+
+func doPendingValidatorsCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetPendingValidatorSet", rpc.GetPendingValidatorSetArgs{})
+	if err != nil {
+		utils.Error("Failed to get pending validator set: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get pending validator set: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
@@ -0,0 +1,51 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+var (
+	sourceFlag string
+	holderFlag string
+)
+
+// stakeRewardsCmd represents the stake rewards command.
+// Example:
+//		thetacli query stake_rewards --source=2E833968E5bB786Ae419c4d13189fB081Cc43bab
+var stakeRewardsCmd = &cobra.Command{
+	Use:     "stake_rewards",
+	Short:   "Get the staking rewards earned by a stake source",
+	Example: `thetacli query stake_rewards --source=2E833968E5bB786Ae419c4d13189fB081Cc43bab`,
+	Run:     doStakeRewardsCmd,
+}
+
+func doStakeRewardsCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.GetStakeRewards", rpc.GetStakeRewardsArgs{Source: sourceFlag, Holder: holderFlag})
+	if err != nil {
+		utils.Error("Failed to get stake rewards: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get stake rewards: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	stakeRewardsCmd.Flags().StringVar(&sourceFlag, "source", "", "Source address of the stakes")
+	stakeRewardsCmd.Flags().StringVar(&holderFlag, "holder", "", "Address of the stake holder, all the stake holders if empty")
+	stakeRewardsCmd.MarkFlagRequired("source")
+}
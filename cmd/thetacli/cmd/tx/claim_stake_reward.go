@@ -0,0 +1,90 @@
+package tx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// claimStakeRewardCmd represents the claim stake reward command
+// Example:
+//		thetacli tx claim --chain="privatenet" --source=2E833968E5bB786Ae419c4d13189fB081Cc43bab --holder=2E833968E5bB786Ae419c4d13189fB081Cc43bab --seq=8
+var claimStakeRewardCmd = &cobra.Command{
+	Use:     "claim",
+	Short:   "claim the staking rewards earned by the stake deposited to a validator",
+	Example: `thetacli tx claim --chain="privatenet" --source=2E833968E5bB786Ae419c4d13189fB081Cc43bab --holder=2E833968E5bB786Ae419c4d13189fB081Cc43bab --seq=8`,
+	Run:     doClaimStakeRewardCmd,
+}
+
+func doClaimStakeRewardCmd(cmd *cobra.Command, args []string) {
+	wallet, sourceAddress, err := walletUnlock(cmd, sourceFlag)
+	if err != nil {
+		return
+	}
+	defer wallet.Lock(sourceAddress)
+
+	fee, ok := types.ParseCoinAmount(feeFlag)
+	if !ok {
+		utils.Error("Failed to parse fee")
+	}
+
+	claimStakeRewardTx := &types.ClaimStakeRewardTx{
+		Fee: types.Coins{
+			ThetaWei: new(big.Int).SetUint64(0),
+			TFuelWei: fee,
+		},
+		Source: types.TxInput{
+			Address:  sourceAddress,
+			Sequence: uint64(seqFlag),
+		},
+		Holder: types.TxOutput{
+			Address: common.HexToAddress(holderFlag),
+		},
+	}
+
+	sig, err := wallet.Sign(sourceAddress, claimStakeRewardTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	claimStakeRewardTx.SetSignature(sourceAddress, sig)
+
+	raw, err := types.TxToBytes(claimStakeRewardTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	if err != nil {
+		utils.Error("Failed to broadcast transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Server returned error: %v\n", res.Error)
+	}
+	fmt.Printf("Successfully broadcasted transaction.\n")
+}
+
+func init() {
+	claimStakeRewardCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	claimStakeRewardCmd.Flags().StringVar(&sourceFlag, "source", "", "Source of the stake")
+	claimStakeRewardCmd.Flags().StringVar(&holderFlag, "holder", "", "Holder of the stake")
+	claimStakeRewardCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei), "Fee")
+	claimStakeRewardCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	claimStakeRewardCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+
+	claimStakeRewardCmd.MarkFlagRequired("chain")
+	claimStakeRewardCmd.MarkFlagRequired("source")
+	claimStakeRewardCmd.MarkFlagRequired("holder")
+	claimStakeRewardCmd.MarkFlagRequired("seq")
+}
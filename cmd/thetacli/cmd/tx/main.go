@@ -31,6 +31,11 @@ var (
 	purposeFlag                  uint8
 	sourceFlag                   string
 	holderFlag                   string
+	expiryHeightFlag             uint64
+	commissionFlag               uint
+	monikerFlag                  string
+	websiteFlag                  string
+	consensusPubKeyFlag          string
 )
 
 // TxCmd represents the Tx command
@@ -48,4 +53,7 @@ func init() {
 	TxCmd.AddCommand(smartContractCmd)
 	TxCmd.AddCommand(depositStakeCmd)
 	TxCmd.AddCommand(withdrawStakeCmd)
+	TxCmd.AddCommand(unjailCmd)
+	TxCmd.AddCommand(claimStakeRewardCmd)
+	TxCmd.AddCommand(registerValidatorCmd)
 }
@@ -0,0 +1,95 @@
+package tx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// registerValidatorCmd represents the register validator command
+// Example:
+//		thetacli tx register --chain="privatenet" --holder=2E833968E5bB786Ae419c4d13189fB081Cc43bab --commission=10 --moniker="My Validator" --website="https://example.org" --pubkey=04a1b2... --seq=8
+var registerValidatorCmd = &cobra.Command{
+	Use:     "register",
+	Short:   "register the commission and the metadata of a validator candidate",
+	Example: `thetacli tx register --chain="privatenet" --holder=2E833968E5bB786Ae419c4d13189fB081Cc43bab --commission=10 --moniker="My Validator" --website="https://example.org" --pubkey=04a1b2... --seq=8`,
+	Run:     doRegisterValidatorCmd,
+}
+
+func doRegisterValidatorCmd(cmd *cobra.Command, args []string) {
+	wallet, holderAddress, err := walletUnlock(cmd, holderFlag)
+	if err != nil {
+		return
+	}
+	defer wallet.Lock(holderAddress)
+
+	fee, ok := types.ParseCoinAmount(feeFlag)
+	if !ok {
+		utils.Error("Failed to parse fee")
+	}
+
+	registerValidatorTx := &types.RegisterValidatorTx{
+		Fee: types.Coins{
+			ThetaWei: new(big.Int).SetUint64(0),
+			TFuelWei: fee,
+		},
+		Holder: types.TxInput{
+			Address:  holderAddress,
+			Sequence: uint64(seqFlag),
+		},
+		CommissionPercent: commissionFlag,
+		Moniker:           monikerFlag,
+		Website:           websiteFlag,
+		ConsensusPubKey:   common.FromHex(consensusPubKeyFlag),
+	}
+
+	sig, err := wallet.Sign(holderAddress, registerValidatorTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	registerValidatorTx.SetSignature(holderAddress, sig)
+
+	raw, err := types.TxToBytes(registerValidatorTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	if err != nil {
+		utils.Error("Failed to broadcast transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Server returned error: %v\n", res.Error)
+	}
+	fmt.Printf("Successfully broadcasted transaction.\n")
+}
+
+func init() {
+	registerValidatorCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	registerValidatorCmd.Flags().StringVar(&holderFlag, "holder", "", "Stake holder of the validator candidate")
+	registerValidatorCmd.Flags().UintVar(&commissionFlag, "commission", 0, "Percentage of the staking rewards kept by the holder")
+	registerValidatorCmd.Flags().StringVar(&monikerFlag, "moniker", "", "Human-readable name of the validator")
+	registerValidatorCmd.Flags().StringVar(&websiteFlag, "website", "", "Website of the validator")
+	registerValidatorCmd.Flags().StringVar(&consensusPubKeyFlag, "pubkey", "", "Consensus public key in hex")
+	registerValidatorCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei), "Fee")
+	registerValidatorCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	registerValidatorCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+
+	registerValidatorCmd.MarkFlagRequired("chain")
+	registerValidatorCmd.MarkFlagRequired("holder")
+	registerValidatorCmd.MarkFlagRequired("moniker")
+	registerValidatorCmd.MarkFlagRequired("pubkey")
+	registerValidatorCmd.MarkFlagRequired("seq")
+}
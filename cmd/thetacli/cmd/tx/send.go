@@ -51,7 +51,8 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 			TFuelWei: new(big.Int).Add(tfuel, fee),
 			ThetaWei: theta,
 		},
-		Sequence: uint64(seqFlag),
+		Sequence:     uint64(seqFlag),
+		ExpiryHeight: expiryHeightFlag,
 	}}
 	outputs := []types.TxOutput{{
 		Address: common.HexToAddress(toFlag),
@@ -111,6 +112,7 @@ func init() {
 	sendCmd.Flags().StringVar(&tfuelAmountFlag, "tfuel", "0", "TFuel amount")
 	sendCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei), "Fee")
 	sendCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+	sendCmd.Flags().Uint64Var(&expiryHeightFlag, "expiry", 0, "Block height after which the transaction becomes invalid, 0 means no expiry")
 
 	sendCmd.MarkFlagRequired("chain")
 	sendCmd.MarkFlagRequired("from")
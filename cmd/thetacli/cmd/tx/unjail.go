@@ -0,0 +1,84 @@
+package tx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/cmd/thetacli/cmd/utils"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// unjailCmd represents the unjail command
+// Example:
+//		thetacli tx unjail --chain="privatenet" --holder=2E833968E5bB786Ae419c4d13189fB081Cc43bab --seq=8
+var unjailCmd = &cobra.Command{
+	Use:     "unjail",
+	Short:   "unjail a validator jailed for inactivity",
+	Example: `thetacli tx unjail --chain="privatenet" --holder=2E833968E5bB786Ae419c4d13189fB081Cc43bab --seq=8`,
+	Run:     doUnjailCmd,
+}
+
+func doUnjailCmd(cmd *cobra.Command, args []string) {
+	wallet, holderAddress, err := walletUnlock(cmd, holderFlag)
+	if err != nil {
+		return
+	}
+	defer wallet.Lock(holderAddress)
+
+	fee, ok := types.ParseCoinAmount(feeFlag)
+	if !ok {
+		utils.Error("Failed to parse fee")
+	}
+
+	unjailTx := &types.UnjailTx{
+		Fee: types.Coins{
+			ThetaWei: new(big.Int).SetUint64(0),
+			TFuelWei: fee,
+		},
+		Holder: types.TxInput{
+			Address:  holderAddress,
+			Sequence: uint64(seqFlag),
+		},
+	}
+
+	sig, err := wallet.Sign(holderAddress, unjailTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	unjailTx.SetSignature(holderAddress, sig)
+
+	raw, err := types.TxToBytes(unjailTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("theta.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	if err != nil {
+		utils.Error("Failed to broadcast transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Server returned error: %v\n", res.Error)
+	}
+	fmt.Printf("Successfully broadcasted transaction.\n")
+}
+
+func init() {
+	unjailCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	unjailCmd.Flags().StringVar(&holderFlag, "holder", "", "Jailed stake holder")
+	unjailCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeTFuelWei), "Fee")
+	unjailCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	unjailCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano)")
+
+	unjailCmd.MarkFlagRequired("chain")
+	unjailCmd.MarkFlagRequired("holder")
+	unjailCmd.MarkFlagRequired("seq")
+}
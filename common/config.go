@@ -12,13 +12,39 @@ const (
 	CfgConsensusMaxEpochLength = "consensus.maxEpochLength"
 	// CfgConsensusMinProposalWait defines the minimal interval between proposals.
 	CfgConsensusMinProposalWait = "consensus.minProposalWait"
+	// CfgConsensusAdaptiveTimeouts decides whether to adapt the epoch length to the network conditions,
+	// starting from the max epoch length.
+	CfgConsensusAdaptiveTimeouts = "consensus.adaptiveTimeouts"
+	// CfgConsensusMinEpochTimeout defines the lower bound in seconds of the adaptive epoch length.
+	CfgConsensusMinEpochTimeout = "consensus.minEpochTimeout"
+	// CfgConsensusMaxEpochTimeout defines the upper bound in seconds of the adaptive epoch length.
+	CfgConsensusMaxEpochTimeout = "consensus.maxEpochTimeout"
 	// CfgConsensusMessageQueueSize defines the capacity of consensus message queue.
 	CfgConsensusMessageQueueSize = "consensus.messageQueueSize"
 	// CfgConsensusMaxNumValidators defines the max number validators allowed
 	CfgConsensusMaxNumValidators = "consensus.maxNumValidators"
+	// CfgConsensusWALEnabled decides whether to record the consensus messages and decisions in a write-ahead log,
+	// which is replayed after the node restarts.
+	CfgConsensusWALEnabled = "consensus.walEnabled"
+	// CfgConsensusWALSyncPolicy defines when the write-ahead log is flushed to disk: "always", "local" for the
+	// local votes, proposals and epoch changes only, or "none" to leave it to the OS.
+	CfgConsensusWALSyncPolicy = "consensus.walSyncPolicy"
+
+	// CfgMempoolReplacementPriceBump defines the minimal gas price increase (in percent) required
+	// for a transaction to replace a pending transaction with the same sequence.
+	CfgMempoolReplacementPriceBump = "mempool.replacementPriceBump"
+	// CfgMempoolJournalEnabled decides whether to journal the pending transactions to disk, so they survive restarts.
+	CfgMempoolJournalEnabled = "mempool.journalEnabled"
+	// CfgMempoolTxTTLBlocks defines the number of blocks a transaction can stay in the mempool, 0 means no limit.
+	CfgMempoolTxTTLBlocks = "mempool.txTTLBlocks"
+	// CfgMempoolTxTTLSeconds defines the number of seconds a transaction can stay in the mempool, 0 means no limit.
+	CfgMempoolTxTTLSeconds = "mempool.txTTLSeconds"
 
 	// CfgSyncMessageQueueSize defines the capacity of Sync Manager message queue.
 	CfgSyncMessageQueueSize = "sync.messageQueueSize"
+	// CfgSyncBehindHeightThreshold defines how many blocks the node can fall behind the highest peer
+	// before it is considered syncing.
+	CfgSyncBehindHeightThreshold = "sync.behindHeightThreshold"
 
 	// CfgP2PName sets the ID of local node in P2P network.
 	CfgP2PName = "p2p.name"
@@ -30,6 +56,22 @@ const (
 	CfgP2PMessageQueueSize = "p2p.messageQueueSize"
 	// CfgP2PSeedPeerOnlyOutbound decides whether only the seed peers can be outbound peers.
 	CfgP2PSeedPeerOnlyOutbound = "p2p.seedPeerOnlyOutbound"
+	// CfgP2PBanDuration sets the number of seconds a misbehaving peer is banned for.
+	CfgP2PBanDuration = "p2p.banDuration"
+	// CfgP2PNodeMode sets the role of the node in the sentry topology: "normal", "validator" or "sentry".
+	CfgP2PNodeMode = "p2p.nodeMode"
+	// CfgP2PSentryNodes sets the sentry nodes a node in the validator mode exclusively connects to.
+	CfgP2PSentryNodes = "p2p.sentryNodes"
+	// CfgP2PPrivateValidators sets the validators a node in the sentry mode protects and hides from discovery.
+	CfgP2PPrivateValidators = "p2p.privateValidators"
+	// CfgP2PChannelSchedules overrides the priority and the minimum bandwidth share of the channels, keyed by channel name.
+	CfgP2PChannelSchedules = "p2p.channelSchedules"
+	// CfgP2PDiscoveryMode selects the peer discovery mechanism: "addrbook" or "dht".
+	CfgP2PDiscoveryMode = "p2p.discovery"
+	// CfgP2PCompressionEnabled sets whether to offer message compression to the peers during handshake.
+	CfgP2PCompressionEnabled = "p2p.compression.enabled"
+	// CfgP2PCompressionThreshold sets the minimum size in bytes of the messages to be compressed.
+	CfgP2PCompressionThreshold = "p2p.compression.threshold"
 
 	// CfgRPCEnabled sets whether to run RPC service.
 	CfgRPCEnabled = "rpc.enabled"
@@ -37,6 +79,27 @@ const (
 	CfgRPCPort = "rpc.port"
 	// CfgRPCMaxConnections limits concurrent connections accepted by RPC server.
 	CfgRPCMaxConnections = "rpc.maxConnections"
+	// CfgRPCRefuseTxsWhileSyncing decides whether to refuse transaction broadcasts while the node is syncing.
+	CfgRPCRefuseTxsWhileSyncing = "rpc.refuseTxsWhileSyncing"
+	// CfgRPCAdminEnabled sets whether to run the admin RPC service.
+	CfgRPCAdminEnabled = "rpc.admin.enabled"
+	// CfgRPCAdminAddress sets the listen address of the admin RPC service.
+	CfgRPCAdminAddress = "rpc.admin.address"
+	// CfgRPCAdminToken sets the bearer token required by the admin RPC service, empty for no authentication.
+	CfgRPCAdminToken = "rpc.admin.token"
+
+	// CfgSignerRemoteAddress sets the address of the remote signer holding the validator key, e.g.
+	// "unix:///var/theta/signer.sock" or "tcp://127.0.0.1:16890". Empty to sign with the local key.
+	CfgSignerRemoteAddress = "signer.remoteAddress"
+	// CfgSignerListenAddress sets the address the signer process listens on, empty for a unix
+	// socket under the config directory.
+	CfgSignerListenAddress = "signer.listenAddress"
+	// CfgSignerSecret sets the secret shared by the signer and the node to authenticate the
+	// connections, required on tcp.
+	CfgSignerSecret = "signer.secret"
+	// CfgSignerGuardCheckDepth sets how many recent blocks are checked for our signatures when the
+	// record of the signed blocks and votes is missing at startup, 0 to skip the check.
+	CfgSignerGuardCheckDepth = "signer.guardCheckDepth"
 
 	// CfgLogLevels sets the log level.
 	CfgLogLevels = "log.levels"
@@ -55,10 +118,21 @@ p2p:
 func init() {
 	viper.SetDefault(CfgConsensusMaxEpochLength, 10)
 	viper.SetDefault(CfgConsensusMinProposalWait, 6)
+	viper.SetDefault(CfgConsensusAdaptiveTimeouts, false)
+	viper.SetDefault(CfgConsensusMinEpochTimeout, 4)
+	viper.SetDefault(CfgConsensusMaxEpochTimeout, 120)
 	viper.SetDefault(CfgConsensusMessageQueueSize, 512)
 	viper.SetDefault(CfgConsensusMaxNumValidators, 7)
+	viper.SetDefault(CfgConsensusWALEnabled, true)
+	viper.SetDefault(CfgConsensusWALSyncPolicy, "local")
+
+	viper.SetDefault(CfgMempoolReplacementPriceBump, 10)
+	viper.SetDefault(CfgMempoolJournalEnabled, true)
+	viper.SetDefault(CfgMempoolTxTTLBlocks, 1200)
+	viper.SetDefault(CfgMempoolTxTTLSeconds, 10800)
 
 	viper.SetDefault(CfgSyncMessageQueueSize, 512)
+	viper.SetDefault(CfgSyncBehindHeightThreshold, 10)
 
 	viper.SetDefault(CfgRPCEnabled, false)
 	viper.SetDefault(CfgP2PMessageQueueSize, 512)
@@ -66,9 +140,26 @@ func init() {
 	viper.SetDefault(CfgP2PPort, 50001)
 	viper.SetDefault(CfgP2PSeeds, "")
 	viper.SetDefault(CfgP2PSeedPeerOnlyOutbound, false)
+	viper.SetDefault(CfgP2PBanDuration, 86400)
+	viper.SetDefault(CfgP2PNodeMode, "normal")
+	viper.SetDefault(CfgP2PSentryNodes, "")
+	viper.SetDefault(CfgP2PPrivateValidators, "")
+	viper.SetDefault(CfgP2PChannelSchedules, map[string]interface{}{})
+	viper.SetDefault(CfgP2PDiscoveryMode, "addrbook")
+	viper.SetDefault(CfgP2PCompressionEnabled, false)
+	viper.SetDefault(CfgP2PCompressionThreshold, 1024)
 
 	viper.SetDefault(CfgRPCPort, "16888")
 	viper.SetDefault(CfgRPCMaxConnections, 200)
+	viper.SetDefault(CfgRPCRefuseTxsWhileSyncing, false)
+	viper.SetDefault(CfgRPCAdminEnabled, false)
+	viper.SetDefault(CfgRPCAdminAddress, "127.0.0.1:16889")
+	viper.SetDefault(CfgRPCAdminToken, "")
+
+	viper.SetDefault(CfgSignerRemoteAddress, "")
+	viper.SetDefault(CfgSignerListenAddress, "")
+	viper.SetDefault(CfgSignerSecret, "")
+	viper.SetDefault(CfgSignerGuardCheckDepth, 100)
 
 	viper.SetDefault(CfgLogLevels, "*:debug")
 	viper.SetDefault(CfgLogPrintSelfID, false)
@@ -0,0 +1,54 @@
+package common
+
+//
+// Block heights at which the protocol upgrades take effect. All the nodes of a
+// network need to agree on them.
+//
+var (
+	// HeightEnableTxExpiry specifies the minimal block height from which the tx inputs can carry
+	// an expiry height. Before it, the inputs with an expiry height are rejected, since the nodes
+	// not upgraded yet cannot decode them.
+	HeightEnableTxExpiry uint64 = 5000000
+
+	// HeightEnableRandomBeacon specifies the minimal block height from which the blocks
+	// carry the random beacon, and the proposers are selected with it
+	HeightEnableRandomBeacon uint64 = 5000000
+
+	// HeightEnableValidatorLiveness specifies the minimal block height from which the ledger tracks
+	// the votes and proposals missed by the validators, and jails the inactive validators
+	HeightEnableValidatorLiveness uint64 = 6000000
+
+	// HeightEnableValidatorRewards specifies the minimal block height from which the validators and
+	// their stakers receive the block rewards and the transaction fees
+	HeightEnableValidatorRewards uint64 = 6000000
+
+	// HeightEnableValidatorRegistration specifies the minimal block height from which the validator
+	// candidates need to register and to keep the minimal self-stake to be selected as validators
+	HeightEnableValidatorRegistration uint64 = 6000000
+
+	// HeightEnableValidatorSetDelay specifies the minimal block height from which the changes of the
+	// top stake holders take effect on the validator set with a delay, and limited per transition. It
+	// must not precede HeightEnableValidatorRegistration.
+	HeightEnableValidatorSetDelay uint64 = 6000000
+
+	// ValidatorSetDelayBlocks and ValidatorSetDelayEpochs specify the number of blocks and epochs before
+	// a change of the top stake holders takes effect on the validator set, from HeightEnableValidatorSetDelay
+	ValidatorSetDelayBlocks uint64 = 100
+	ValidatorSetDelayEpochs uint64 = 0
+
+	// MaxValidatorSetChangePercent specifies the max percentage of the voting power that can change in a
+	// validator set transition. Larger changes are spread over multiple transitions.
+	MaxValidatorSetChangePercent uint = 30
+
+	// MaxNumValidatorsWithDelay specifies the max number of validators selected from the top stake holders
+	// from HeightEnableValidatorSetDelay
+	MaxNumValidatorsWithDelay int = 7
+
+	// ProposerFeePercent specifies the percentage of the collected transaction fees kept by the block proposer
+	// from HeightEnableValidatorRewards. The rest goes to the validators voting in the HCC of the block.
+	ProposerFeePercent uint = 20
+
+	// JailSlashPercent specifies the percentage of the active stakes of a validator slashed (burned) when
+	// the validator is jailed for inactivity, from HeightEnableValidatorRewards
+	JailSlashPercent uint = 1
+)
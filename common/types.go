@@ -388,4 +388,7 @@ const (
 
 	// ChannelIDPing indicates the channel for Ping/Pong messages between peers
 	ChannelIDPing
+
+	// ChannelIDDHT indicates the channel for the DHT based peer discovery
+	ChannelIDDHT
 )
@@ -16,7 +16,6 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/util"
 	"github.com/thetatoken/theta/core"
-	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/dispatcher"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store"
@@ -26,11 +25,15 @@ var logger *log.Entry = log.WithFields(log.Fields{"prefix": "consensus"})
 
 var _ core.ConsensusEngine = (*ConsensusEngine)(nil)
 
+// maxSignStateCheckEpochLag is the max number of epochs the tip can fall behind the current epoch
+// for the local chain to be considered to have the recent blocks for the sign state check.
+const maxSignStateCheckEpochLag = 100
+
 // ConsensusEngine is the default implementation of the Engine interface.
 type ConsensusEngine struct {
 	logger *log.Entry
 
-	privateKey *crypto.PrivateKey
+	signer core.Signer
 
 	chain            *blockchain.Chain
 	dispatcher       *dispatcher.Dispatcher
@@ -40,6 +43,8 @@ type ConsensusEngine struct {
 	incoming        chan interface{}
 	finalizedBlocks chan *core.Block
 
+	invalidBlockHandler func(common.Hash) // notified when a received block fails the validation
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	ctx     context.Context
@@ -49,27 +54,32 @@ type ConsensusEngine struct {
 	mu            *sync.Mutex
 	epochTimer    *time.Timer
 	proposalTimer *time.Timer
+	timeouts      *adaptiveTimeouts
 
 	state *State
+	wal   *writeAheadLog // records the messages and decisions before they are processed, nil if disabled
+
+	signStateChecked bool // whether the recent blocks have been checked for signatures the signer has no record of
 
 	rand *rand.Rand
 }
 
 // NewConsensusEngine creates a instance of ConsensusEngine.
-func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *blockchain.Chain, dispatcher *dispatcher.Dispatcher, validatorManager core.ValidatorManager) *ConsensusEngine {
+func NewConsensusEngine(signer core.Signer, db store.Store, chain *blockchain.Chain, dispatcher *dispatcher.Dispatcher, validatorManager core.ValidatorManager) *ConsensusEngine {
 	e := &ConsensusEngine{
 		chain:      chain,
 		dispatcher: dispatcher,
 
-		privateKey: privateKey,
+		signer: signer,
 
 		incoming:        make(chan interface{}, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 		finalizedBlocks: make(chan *core.Block, viper.GetInt(common.CfgConsensusMessageQueueSize)),
 
 		wg: &sync.WaitGroup{},
 
-		mu:    &sync.Mutex{},
-		state: NewState(db, chain),
+		mu:       &sync.Mutex{},
+		state:    NewState(db, chain),
+		timeouts: newAdaptiveTimeouts(),
 
 		validatorManager: validatorManager,
 	}
@@ -88,6 +98,18 @@ func (e *ConsensusEngine) SetLedger(ledger core.Ledger) {
 	e.ledger = ledger
 }
 
+// SetInvalidBlockHandler sets the callback invoked with the hash of each block that fails
+// the validation, so that the peer which relayed the block can be penalized.
+func (e *ConsensusEngine) SetInvalidBlockHandler(handler func(common.Hash)) {
+	e.invalidBlockHandler = handler
+}
+
+// SetWALFilePath enables the write-ahead log, which records the received messages and the local
+// decisions before they are processed, and is replayed when the engine starts.
+func (e *ConsensusEngine) SetWALFilePath(filePath string) {
+	e.wal = newWriteAheadLog(filePath, viper.GetString(common.CfgConsensusWALSyncPolicy))
+}
+
 // GetLedger returns the ledger instance attached to the consensus engine
 func (e *ConsensusEngine) GetLedger() core.Ledger {
 	return e.ledger
@@ -95,12 +117,12 @@ func (e *ConsensusEngine) GetLedger() core.Ledger {
 
 // ID returns the identifier of current node.
 func (e *ConsensusEngine) ID() string {
-	return e.privateKey.PublicKey().Address().Hex()
+	return e.signer.Address().Hex()
 }
 
-// PrivateKey returns the private key
-func (e *ConsensusEngine) PrivateKey() *crypto.PrivateKey {
-	return e.privateKey
+// Signer returns the signer of the proposals and votes
+func (e *ConsensusEngine) Signer() core.Signer {
+	return e.signer
 }
 
 // Chain return a pointer to the underlying chain store.
@@ -131,15 +153,149 @@ func (e *ConsensusEngine) Start(ctx context.Context) {
 			"CfgConsensusMinProposalWait": viper.GetInt(common.CfgConsensusMinProposalWait),
 		}).Fatal("Invalid configuration: max epoch length must be larger than minimal proposal wait")
 	}
+	if viper.GetBool(common.CfgConsensusAdaptiveTimeouts) && viper.GetInt(common.CfgConsensusMinEpochTimeout) <= 0 {
+		log.WithFields(log.Fields{
+			"CfgConsensusMinEpochTimeout": viper.GetInt(common.CfgConsensusMinEpochTimeout),
+		}).Fatal("Invalid configuration: min epoch timeout must be positive")
+	}
+	if e.wal != nil && !isValidWALSyncPolicy(e.wal.syncPolicy) {
+		log.WithFields(log.Fields{
+			"CfgConsensusWALSyncPolicy": e.wal.syncPolicy,
+		}).Fatal("Invalid configuration: WAL sync policy must be one of always, local and none")
+	}
+
+	// Signing again with a lost sign state could conflict with our recent signatures.
+	if err := e.checkSignState(); err != nil {
+		e.logger.WithFields(log.Fields{"error": err}).Warn("Postponed the check for recent signatures")
+	}
 
 	// Set ledger state pointer to intial state.
 	lastCC := e.state.GetHighestCCBlock()
 	e.ledger.ResetState(lastCC.Height, lastCC.StateHash)
 
+	// Recover the messages and decisions not processed before the last shutdown.
+	e.replayWAL()
+
 	e.wg.Add(1)
 	go e.mainLoop()
 }
 
+// signStateKeeper is implemented by the signers that keep a record of the signed blocks and votes.
+type signStateKeeper interface {
+	IsSignStateFresh() (bool, error)
+	SeedSignState(vote *core.Vote, header *core.BlockHeader) error
+}
+
+// seedSignState seeds a fresh sign state with the last vote and proposal kept in the consensus
+// state, e.g. when the node is upgraded from a version without the sign state.
+func (e *ConsensusEngine) seedSignState() error {
+	keeper, ok := e.signer.(signStateKeeper)
+	if !ok {
+		return nil
+	}
+	fresh, err := keeper.IsSignStateFresh()
+	if err != nil || !fresh {
+		return err
+	}
+
+	id := e.signer.Address()
+	var vote *core.Vote
+	if lastVote := e.state.GetLastVote(); lastVote.Height != 0 && lastVote.ID == id {
+		vote = &lastVote
+	}
+	var header *core.BlockHeader
+	if lastProposal := e.state.GetLastProposal(); lastProposal.Block != nil && lastProposal.Block.Proposer == id {
+		header = lastProposal.Block.BlockHeader
+	}
+	if vote == nil && header == nil {
+		return nil
+	}
+	if err := keeper.SeedSignState(vote, header); err != nil {
+		return errors.Wrap(err, "Failed to seed the sign state with the last vote and proposal")
+	}
+	e.logger.WithFields(log.Fields{
+		"vote":     vote,
+		"proposal": header,
+	}).Info("Seeded the sign state with the last vote and proposal")
+	return nil
+}
+
+// checkSignState seeds the sign state if needed, and makes sure the validator key has not signed
+// the recent blocks if the signer has lost the record of its signatures. The check is postponed
+// while the signer is unreachable or the local chain is missing the recent blocks, e.g. when the
+// node starts from a snapshot, and signing is refused until then.
+func (e *ConsensusEngine) checkSignState() error {
+	if e.signStateChecked {
+		return nil
+	}
+	if err := e.seedSignState(); err != nil {
+		return err
+	}
+	height, found, err := e.findRecentSignature()
+	if err != nil {
+		return err
+	}
+	if found {
+		log.WithFields(log.Fields{
+			"height": height,
+			"signer": e.signer.Address().Hex(),
+		}).Fatalf("The record of the signed blocks and votes is missing, but the chain shows recent signatures from the validator key. Restore the sign state before restarting the node, or set %v=0 to skip the check if it is certain that the key signs for no other node.", common.CfgSignerGuardCheckDepth)
+	}
+	e.signStateChecked = true
+	return nil
+}
+
+// findRecentSignature returns the height of a recent block or vote signed by our key, if the
+// signer has no record of the previous signatures. The error is returned if the local chain does
+// not have the recent blocks to check yet.
+func (e *ConsensusEngine) findRecentSignature() (uint64, bool, error) {
+	keeper, ok := e.signer.(signStateKeeper)
+	if !ok {
+		return 0, false, nil
+	}
+	fresh, err := keeper.IsSignStateFresh()
+	if err != nil {
+		return 0, false, errors.Wrap(err, "Failed to query the sign state")
+	}
+	if !fresh {
+		return 0, false, nil
+	}
+	depth := viper.GetInt64(common.CfgSignerGuardCheckDepth)
+	if depth <= 0 {
+		return 0, false, nil
+	}
+
+	tip := e.GetTip(true)
+	root := e.chain.Root()
+	if root.Height > 0 && tip.Height < root.Height+uint64(depth) {
+		return 0, false, fmt.Errorf("%v blocks are synced after the snapshot at height %v, %v are needed to check for recent signatures",
+			tip.Height-root.Height, root.Height, depth)
+	}
+	if epoch := e.GetEpoch(); tip.Epoch+maxSignStateCheckEpochLag < epoch {
+		return 0, false, fmt.Errorf("The tip of epoch %v is behind the current epoch %v, the recent blocks are needed to check for recent signatures",
+			tip.Epoch, epoch)
+	}
+
+	id := e.signer.Address()
+	for height := tip.Height; height > e.chain.Root().Height && height+uint64(depth) > tip.Height; height-- {
+		for _, block := range e.chain.FindBlocksByHeight(height) {
+			if block.Proposer == id {
+				return height, true, nil
+			}
+			votes := e.chain.FindVotesByHash(block.Hash())
+			if block.HCC.Votes != nil {
+				votes = votes.Merge(block.HCC.Votes)
+			}
+			for _, vote := range votes.Votes() {
+				if vote.ID == id {
+					return vote.Height, true, nil
+				}
+			}
+		}
+	}
+	return 0, false, nil
+}
+
 // Stop notifies all goroutines to stop without blocking.
 func (e *ConsensusEngine) Stop() {
 	e.cancel()
@@ -155,19 +311,27 @@ func (e *ConsensusEngine) mainLoop() {
 
 	for {
 		e.enterEpoch()
+		epochStart := time.Now()
+		lastFinalized := e.state.GetLastFinalizedBlock().Hash()
 	Epoch:
 		for {
 			select {
 			case <-e.ctx.Done():
 				e.stopped = true
+				if e.wal != nil {
+					e.wal.close()
+				}
 				return
 			case msg := <-e.incoming:
 				endEpoch := e.processMessage(msg)
 				if endEpoch {
+					finalized := e.state.GetLastFinalizedBlock().Hash() != lastFinalized
+					e.timeouts.onProgress(time.Since(epochStart), finalized)
 					break Epoch
 				}
 			case <-e.epochTimer.C:
 				e.logger.WithFields(log.Fields{"e.epoch": e.GetEpoch()}).Debug("Epoch timeout. Repeating epoch")
+				e.timeouts.onTimeout()
 				e.vote()
 				break Epoch
 			case <-e.proposalTimer.C:
@@ -178,17 +342,19 @@ func (e *ConsensusEngine) mainLoop() {
 }
 
 func (e *ConsensusEngine) enterEpoch() {
+	timeouts := e.timeouts.timeouts()
+
 	// Reset timers.
 	if e.epochTimer != nil {
 		e.epochTimer.Stop()
 	}
-	e.epochTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second)
+	e.epochTimer = time.NewTimer(timeouts.EpochTimeout)
 
 	if e.proposalTimer != nil {
 		e.proposalTimer.Stop()
 	}
 	if e.shouldPropose(e.GetEpoch()) {
-		e.proposalTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinProposalWait)) * time.Second)
+		e.proposalTimer = time.NewTimer(timeouts.ProposalWait)
 	} else {
 		e.proposalTimer = time.NewTimer(math.MaxInt64)
 		e.proposalTimer.Stop()
@@ -205,6 +371,16 @@ func (e *ConsensusEngine) GetChannelIDs() []common.ChannelIDEnum {
 }
 
 func (e *ConsensusEngine) AddMessage(msg interface{}) {
+	switch m := msg.(type) {
+	case core.Vote:
+		if e.shouldWriteReceivedVoteWAL(m) {
+			e.writeWAL(WALEntryReceivedVote, m.Height, m)
+		}
+	case *core.Block:
+		if e.shouldWriteReceivedBlockWAL(m) {
+			e.writeWAL(WALEntryReceivedBlock, m.Height, m)
+		}
+	}
 	e.incoming <- msg
 }
 
@@ -320,6 +496,9 @@ func (e *ConsensusEngine) validateBlock(block *core.Block, parent *core.Extended
 		}).Warn("Block is invalid")
 		return false
 	}
+	if !e.validateRandomness(block, parent) {
+		return false
+	}
 	if !e.shouldProposeByID(block.Epoch, block.Proposer.Hex()) {
 		e.logger.WithFields(log.Fields{
 			"block.Epoch":    block.Epoch,
@@ -330,6 +509,38 @@ func (e *ConsensusEngine) validateBlock(block *core.Block, parent *core.Extended
 	return true
 }
 
+// validateRandomness checks the random beacon carried by the block, which is required
+// from HeightEnableRandomBeacon on. The signature of the block needs to be valid.
+func (e *ConsensusEngine) validateRandomness(block *core.Block, parent *core.ExtendedBlock) bool {
+	if block.Height < common.HeightEnableRandomBeacon {
+		if len(block.Randomness) != 0 {
+			e.logger.WithFields(log.Fields{
+				"block":        block.Hash().Hex(),
+				"block.Height": block.Height,
+			}).Warn("Block carries random beacon before it is enabled")
+			return false
+		}
+		return true
+	}
+
+	pubKey, err := block.Signature.RecoverSignerPublicKey(block.SignBytes())
+	if err != nil {
+		e.logger.WithFields(log.Fields{
+			"block": block.Hash().Hex(),
+			"error": err,
+		}).Warn("Failed to recover the public key of the proposer")
+		return false
+	}
+	if !pubKey.VRFVerify(core.BeaconInput(parent.BlockHeader, block.Epoch), block.Randomness) {
+		e.logger.WithFields(log.Fields{
+			"block":          block.Hash().Hex(),
+			"block.proposer": block.Proposer.Hex(),
+		}).Warn("Invalid random beacon")
+		return false
+	}
+	return true
+}
+
 func (e *ConsensusEngine) handleBlock(block *core.Block) {
 	parent, err := e.chain.FindBlock(block.Parent)
 	if err != nil {
@@ -347,12 +558,17 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 		e.logger.WithFields(log.Fields{
 			"block.Hash": block.Hash().Hex(),
 		}).Warn("Block is invalid")
+		if e.invalidBlockHandler != nil {
+			e.invalidBlockHandler(block.Hash())
+		}
 		return
 	}
 
 	for _, vote := range block.HCC.Votes.Votes() {
 		e.handleVoteInBlock(vote)
 	}
+	// The HCC votes certify the HCC block, even if some of the standalone votes were lost.
+	e.checkCC(block.HCC.BlockHash)
 
 	result := e.ledger.ResetState(parent.Height, parent.StateHash)
 	if result.IsError() {
@@ -362,7 +578,7 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 		}).Error("Failed to reset state to parent.StateHash")
 		return
 	}
-	result = e.ledger.ApplyBlockTxs(block.Txs, block.StateHash)
+	result = e.ledger.ApplyBlockTxs(block)
 	if result.IsError() {
 		e.logger.WithFields(log.Fields{
 			"error":           result.String(),
@@ -401,7 +617,7 @@ func (e *ConsensusEngine) handleBlock(block *core.Block) {
 }
 
 func (e *ConsensusEngine) shouldVote(block common.Hash) bool {
-	return e.shouldVoteByID(e.privateKey.PublicKey().Address(), block)
+	return e.shouldVoteByID(e.signer.Address(), block)
 }
 
 func (e *ConsensusEngine) shouldVoteByID(id common.Address, block common.Hash) bool {
@@ -442,11 +658,21 @@ func (e *ConsensusEngine) vote() {
 			log.Panic(err)
 		}
 		// Recreating vote so that it has updated epoch and signature.
-		vote = e.createVote(block.Block)
+		vote, err = e.createVote(block.Block)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"error": err}).Warn("Failed to sign vote")
+			return
+		}
 	} else {
-		vote = e.createVote(tip.Block)
+		var err error
+		vote, err = e.createVote(tip.Block)
+		if err != nil {
+			e.logger.WithFields(log.Fields{"error": err}).Warn("Failed to sign vote")
+			return
+		}
 		e.state.SetLastVote(vote)
 	}
+	e.writeWAL(WALEntryLocalVote, vote.Height, vote)
 	e.logger.WithFields(log.Fields{
 		"vote": vote,
 	}).Debug("Sending vote")
@@ -467,19 +693,22 @@ func (e *ConsensusEngine) broadcastVote(vote core.Vote) {
 	e.dispatcher.SendData([]string{}, voteMsg)
 }
 
-func (e *ConsensusEngine) createVote(block *core.Block) core.Vote {
+func (e *ConsensusEngine) createVote(block *core.Block) (core.Vote, error) {
+	if err := e.checkSignState(); err != nil {
+		return core.Vote{}, err
+	}
 	vote := core.Vote{
 		Block:  block.Hash(),
 		Height: block.Height,
-		ID:     e.privateKey.PublicKey().Address(),
+		ID:     e.signer.Address(),
 		Epoch:  e.GetEpoch(),
 	}
-	sig, err := e.privateKey.Sign(vote.SignBytes())
+	sig, err := e.signer.SignVote(vote)
 	if err != nil {
-		e.logger.WithFields(log.Fields{"error": err}).Panic("Failed to sign vote")
+		return core.Vote{}, err
 	}
 	vote.SetSignature(sig)
-	return vote
+	return vote, nil
 }
 
 func (e *ConsensusEngine) validateVote(vote core.Vote) bool {
@@ -544,6 +773,7 @@ func (e *ConsensusEngine) handleVote(vote core.Vote) (endEpoch bool) {
 				"nextEpoch":    nextEpoch,
 				"epochVoteSet": currentEpochVotes,
 			}).Debug("Majority votes for current epoch. Moving to new epoch")
+			e.writeWALEpoch(nextEpoch)
 			e.state.SetEpoch(nextEpoch)
 		}
 	}
@@ -565,7 +795,8 @@ func (e *ConsensusEngine) checkCC(hash common.Hash) {
 		return
 	}
 
-	votes := e.chain.FindVotesByHash(hash)
+	// The votes repeated by a validator in later epochs count only once.
+	votes := e.chain.FindVotesByHash(hash).UniqueVoter()
 	validators := e.validatorManager.GetValidatorSet(hash)
 	if validators.HasMajority(votes) {
 		e.processCCBlock(block)
@@ -621,7 +852,9 @@ func (e *ConsensusEngine) GetTip(includePendingBlockingLeaf bool) *core.Extended
 
 // GetSummary returns a summary of consensus state.
 func (e *ConsensusEngine) GetSummary() *StateStub {
-	return e.state.GetSummary()
+	stub := e.state.GetSummary()
+	stub.Timeouts = e.timeouts.timeouts()
+	return stub
 }
 
 // FinalizedBlocks returns a channel that will be published with finalized blocks by the engine.
@@ -629,6 +862,11 @@ func (e *ConsensusEngine) FinalizedBlocks() chan *core.Block {
 	return e.finalizedBlocks
 }
 
+// FindBlock returns the block with the given hash in the chain.
+func (e *ConsensusEngine) FindBlock(blockHash common.Hash) (*core.ExtendedBlock, error) {
+	return e.chain.FindBlock(blockHash)
+}
+
 // GetLastFinalizedBlock returns the last finalized block.
 func (e *ConsensusEngine) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return e.state.GetLastFinalizedBlock()
@@ -667,6 +905,7 @@ func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) {
 
 	e.state.SetLastFinalizedBlock(block)
 	e.ledger.FinalizeState(block.Height, block.StateHash)
+	e.rotateWAL(block.Height)
 
 	// Mark block and its ancestors as finalized.
 	e.chain.FinalizePreviousBlocks(block.Hash())
@@ -681,6 +920,141 @@ func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) {
 	}
 }
 
+// writeWAL records the message in the write-ahead log before it is processed.
+func (e *ConsensusEngine) writeWAL(entryType WALEntryType, height uint64, msg interface{}) {
+	if e.wal == nil {
+		return
+	}
+	entry, err := NewWALEntry(entryType, e.GetEpoch(), height, msg)
+	if err != nil {
+		e.logger.WithFields(log.Fields{"error": err, "type": entryType}).Warn("Failed to encode WAL entry")
+		return
+	}
+	e.writeWALEntry(entry)
+}
+
+// writeWALEpoch records entering the given epoch in the write-ahead log.
+func (e *ConsensusEngine) writeWALEpoch(epoch uint64) {
+	if e.wal == nil {
+		return
+	}
+	entry, _ := NewWALEntry(WALEntryEpoch, epoch, 0, nil)
+	e.writeWALEntry(entry)
+}
+
+func (e *ConsensusEngine) writeWALEntry(entry *WALEntry) {
+	if err := e.wal.write(entry); err != nil {
+		e.logger.WithFields(log.Fields{"error": err, "entry": entry}).Warn("Failed to write WAL entry")
+	}
+}
+
+// shouldWriteReceivedVoteWAL returns whether the received vote needs to be recorded in the write-ahead
+// log. The votes on the finalized blocks are not needed for the recovery, and neither are the votes
+// already persisted in the vote index of the chain.
+func (e *ConsensusEngine) shouldWriteReceivedVoteWAL(vote core.Vote) bool {
+	if e.wal == nil || vote.Height <= e.wal.getFinalizedHeight() {
+		return false
+	}
+	if vote.Block.IsEmpty() {
+		return true
+	}
+	for _, v := range e.chain.FindVotesByHash(vote.Block).Votes() {
+		if v.ID == vote.ID && v.Epoch == vote.Epoch {
+			return false
+		}
+	}
+	return true
+}
+
+// shouldWriteReceivedBlockWAL returns whether the received block needs to be recorded in the write-ahead
+// log. The blocks are added to the chain before they are passed to the engine, so only the blocks not yet
+// processed need to be recorded.
+func (e *ConsensusEngine) shouldWriteReceivedBlockWAL(block *core.Block) bool {
+	if e.wal == nil || block.Height <= e.wal.getFinalizedHeight() {
+		return false
+	}
+	eb, err := e.chain.FindBlock(block.Hash())
+	return err != nil || eb.Status.IsPending()
+}
+
+// rotateWAL deletes the WAL segments with the entries on the finalized blocks only.
+func (e *ConsensusEngine) rotateWAL(finalizedHeight uint64) {
+	if e.wal == nil {
+		return
+	}
+	if err := e.wal.rotate(finalizedHeight); err != nil {
+		e.logger.WithFields(log.Fields{"error": err}).Warn("Failed to rotate WAL")
+	}
+}
+
+// replayWAL processes the entries of the write-ahead log on the blocks above the last finalized
+// block, which recovers the votes and blocks received but possibly not processed before the last
+// shutdown, as well as the local votes and proposal.
+func (e *ConsensusEngine) replayWAL() {
+	if e.wal == nil {
+		return
+	}
+	entries, err := e.wal.load()
+	if err != nil {
+		e.logger.WithFields(log.Fields{"error": err}).Warn("Failed to load WAL, the tail of the WAL could be corrupted")
+	}
+	lastFinalized := e.state.GetLastFinalizedBlock().Height
+	e.rotateWAL(lastFinalized)
+
+	numReplayed := 0
+	for _, entry := range entries {
+		if entry.Type != WALEntryEpoch && entry.Height <= lastFinalized {
+			continue
+		}
+		if e.replayWALEntry(entry) {
+			numReplayed++
+		}
+	}
+	e.logger.WithFields(log.Fields{
+		"numEntries":  len(entries),
+		"numReplayed": numReplayed,
+	}).Info("Replayed WAL")
+}
+
+func (e *ConsensusEngine) replayWALEntry(entry *WALEntry) bool {
+	msg, err := entry.DecodeMessage()
+	if err != nil {
+		e.logger.WithFields(log.Fields{"error": err, "entry": entry}).Warn("Failed to decode WAL entry")
+		return false
+	}
+
+	switch entry.Type {
+	case WALEntryReceivedVote:
+		e.handleStandaloneVote(msg.(core.Vote))
+	case WALEntryReceivedBlock:
+		// Only the blocks added to the chain but not processed yet need to be handled.
+		block := msg.(*core.Block)
+		eb, err := e.chain.FindBlock(block.Hash())
+		if err != nil || !eb.Status.IsPending() {
+			return false
+		}
+		if _, err := e.chain.FindBlock(block.Parent); err != nil {
+			return false
+		}
+		e.handleBlock(block)
+	case WALEntryLocalVote:
+		e.handleVote(msg.(core.Vote))
+	case WALEntryLocalProposal:
+		// Restore the proposal so that it is repeated rather than re-created within the same epoch.
+		proposal := msg.(core.Proposal)
+		if proposal.Block == nil || proposal.Block.Epoch != e.GetEpoch() {
+			return false
+		}
+		e.state.SetLastProposal(proposal)
+	case WALEntryEpoch:
+		if entry.Epoch <= e.GetEpoch() {
+			return false
+		}
+		e.state.SetEpoch(entry.Epoch)
+	}
+	return true
+}
+
 func (e *ConsensusEngine) randHex() []byte {
 	bytes := make([]byte, 10)
 	e.rand.Read(bytes)
@@ -726,6 +1100,9 @@ func (e *ConsensusEngine) shouldProposeByID(epoch uint64, id string) bool {
 }
 
 func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
+	if err := e.checkSignState(); err != nil {
+		return core.Proposal{}, err
+	}
 	tip := e.GetTipToExtend()
 	result := e.ledger.ResetState(tip.Height, tip.StateHash)
 	if result.IsError() {
@@ -742,13 +1119,20 @@ func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
 	block.Epoch = e.GetEpoch()
 	block.Parent = tip.Hash()
 	block.Height = tip.Height + 1
-	block.Proposer = e.privateKey.PublicKey().Address()
+	block.Proposer = e.signer.Address()
 	block.Timestamp = big.NewInt(time.Now().Unix())
+	if block.Height >= common.HeightEnableRandomBeacon {
+		randomness, err := e.signer.VRFProve(core.BeaconInput(tip.BlockHeader, block.Epoch))
+		if err != nil {
+			return core.Proposal{}, errors.Wrap(err, "Failed to evaluate the random beacon")
+		}
+		block.Randomness = randomness
+	}
 	block.HCC.BlockHash = e.state.GetHighestCCBlock().Hash()
 	block.HCC.Votes = e.chain.FindVotesByHash(block.HCC.BlockHash).UniqueVoter()
 
 	// Add Txs.
-	newRoot, txs, result := e.ledger.ProposeBlockTxs()
+	newRoot, txs, result := e.ledger.ProposeBlockTxs(block)
 	if result.IsError() {
 		err := fmt.Errorf("Failed to collect Txs for block proposal: %v", result.String())
 		return core.Proposal{}, err
@@ -757,9 +1141,9 @@ func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
 	block.StateHash = newRoot
 
 	// Sign block.
-	sig, err := e.privateKey.Sign(block.SignBytes())
+	sig, err := e.signer.SignBlock(block.BlockHeader)
 	if err != nil {
-		e.logger.WithFields(log.Fields{"error": err}).Panic("Failed to sign vote")
+		return core.Proposal{}, errors.Wrap(err, "Failed to sign block")
 	}
 	block.SetSignature(sig)
 
@@ -779,7 +1163,10 @@ func (e *ConsensusEngine) createProposal() (core.Proposal, error) {
 		}
 	}
 	proposal.Votes = lastCCVotes.Merge(epochVotes).UniqueVoterAndBlock()
-	selfVote := e.createVote(block)
+	selfVote, err := e.createVote(block)
+	if err != nil {
+		return core.Proposal{}, errors.Wrap(err, "Failed to sign vote for proposed block")
+	}
 	proposal.Votes.AddVote(selfVote)
 
 	_, err = e.chain.AddBlock(block)
@@ -805,6 +1192,7 @@ func (e *ConsensusEngine) propose() {
 			e.logger.WithFields(log.Fields{"error": err}).Error("Failed to create proposal")
 			return
 		}
+		e.writeWAL(WALEntryLocalProposal, proposal.Block.Height, proposal)
 		e.state.LastProposal = proposal
 
 		e.logger.WithFields(log.Fields{"proposal": proposal}).Info("Making proposal")
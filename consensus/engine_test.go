@@ -1,10 +1,12 @@
 package consensus
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/thetatoken/theta/blockchain"
@@ -12,6 +14,7 @@ import (
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/store/database/backend"
 	"github.com/thetatoken/theta/store/kvstore"
 )
@@ -160,6 +163,192 @@ func TestSingleBlockValidation(t *testing.T) {
 	require.False(ce.validateBlock(invalidBlock, chain.Root()), "Missing timestamp")
 }
 
+func TestRandomBeaconValidation(t *testing.T) {
+	require := require.New(t)
+
+	enableHeight := common.HeightEnableRandomBeacon
+	common.HeightEnableRandomBeacon = 1
+	defer func() {
+		common.HeightEnableRandomBeacon = enableHeight
+	}()
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("a0", "")
+	root.ChainID = "testchain"
+	root.Epoch = 0
+	chain := blockchain.NewChain("testchain", store, root)
+
+	ce := NewConsensusEngine(nil, store, chain, nil, validatorManager)
+
+	newBlock := func(epoch uint64, signer *crypto.PrivateKey, beaconEpoch uint64, beaconSigner *crypto.PrivateKey) *core.Block {
+		b := core.NewBlock()
+		b.ChainID = chain.ChainID
+		b.Height = chain.Root().Height + 1
+		b.Epoch = epoch
+		b.Parent = chain.Root().Hash()
+		b.HCC.BlockHash = b.Parent
+		b.Proposer = signer.PublicKey().Address()
+		b.Timestamp = big.NewInt(time.Now().Unix())
+		if beaconSigner != nil {
+			b.Randomness, _ = beaconSigner.VRFProve(core.BeaconInput(chain.Root().BlockHeader, beaconEpoch))
+		}
+		b.Signature, _ = signer.Sign(b.SignBytes())
+		_, err := chain.AddBlock(b)
+		require.Nil(err)
+		return b
+	}
+
+	b1 := newBlock(1, privKey, 1, privKey)
+	require.True(ce.validateBlock(b1, chain.Root()))
+	require.False(b1.Beacon().IsEmpty())
+
+	privKey2, _, _ := crypto.GenerateKeyPair()
+	require.False(ce.validateBlock(newBlock(2, privKey, 0, nil), chain.Root()), "Missing random beacon")
+	require.False(ce.validateBlock(newBlock(3, privKey, 2, privKey), chain.Root()), "Random beacon of another epoch")
+	require.False(ce.validateBlock(newBlock(4, privKey, 4, privKey2), chain.Root()), "Random beacon of another key")
+
+	common.HeightEnableRandomBeacon = 2
+	require.False(ce.validateBlock(b1, chain.Root()), "Random beacon before it is enabled")
+}
+
+func TestFindRecentSignature(t *testing.T) {
+	require := require.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	privKey2, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("a0", "")
+	root.ChainID = "testchain"
+	root.Epoch = 0
+	chain := blockchain.NewChain("testchain", store, root)
+
+	newEngine := func(guard *signer.SignGuard) *ConsensusEngine {
+		return NewConsensusEngine(signer.NewLocalSigner(privKey, guard), store, chain, nil, validatorManager)
+	}
+	newGuard := func() *signer.SignGuard {
+		guard, err := signer.NewSignGuard("")
+		require.Nil(err)
+		return guard
+	}
+
+	_, found, err := newEngine(newGuard()).findRecentSignature()
+	require.Nil(err)
+	require.False(found, "Nothing signed yet")
+
+	parent := chain.Root().Block
+	for i, proposer := range []*crypto.PrivateKey{privKey, privKey2, privKey2} {
+		b := core.NewBlock()
+		b.ChainID = chain.ChainID
+		b.Height = parent.Height + 1
+		b.Epoch = uint64(i + 1)
+		b.Parent = parent.Hash()
+		b.HCC.BlockHash = b.Parent
+		b.Proposer = proposer.PublicKey().Address()
+		b.Timestamp = big.NewInt(time.Now().Unix())
+		b.Signature, _ = proposer.Sign(b.SignBytes())
+		_, err = chain.AddBlock(b)
+		require.Nil(err)
+		chain.MarkBlockValid(b.Hash())
+		parent = b
+	}
+
+	height, found, err := newEngine(newGuard()).findRecentSignature()
+	require.Nil(err)
+	require.True(found)
+	require.Equal(chain.Root().Height+1, height)
+
+	guard := newGuard()
+	require.Nil(guard.CheckAndRecordVote(core.Vote{Block: parent.Hash(), Height: parent.Height, Epoch: 3}))
+	_, found, _ = newEngine(guard).findRecentSignature()
+	require.False(found, "The sign state is present")
+
+	_, found, _ = newEngine(nil).findRecentSignature()
+	require.False(found, "The signer keeps no sign state")
+
+	viper.Set(common.CfgSignerGuardCheckDepth, 2)
+	defer viper.Set(common.CfgSignerGuardCheckDepth, 100)
+	_, found, err = newEngine(newGuard()).findRecentSignature()
+	require.Nil(err)
+	require.False(found, "The signed block is deeper than the check depth")
+
+	// The epoch lag is bounded independently of the check depth
+	ce := newEngine(newGuard())
+	require.Nil(ce.state.SetEpoch(parent.Epoch + maxSignStateCheckEpochLag))
+	_, found, err = ce.findRecentSignature()
+	require.Nil(err)
+	require.False(found)
+	require.Nil(ce.state.SetEpoch(parent.Epoch + maxSignStateCheckEpochLag + 1))
+	_, _, err = ce.findRecentSignature()
+	require.NotNil(err, "The local chain is behind the current epoch")
+	require.Nil(ce.state.SetEpoch(parent.Epoch))
+
+	// The check is retried while the sign state is unknown
+	unreachable := &unreachableSigner{LocalSigner: signer.NewLocalSigner(privKey, newGuard()), unreachable: true}
+	ce = NewConsensusEngine(unreachable, store, chain, nil, validatorManager)
+	require.NotNil(ce.checkSignState())
+	require.False(ce.signStateChecked)
+	unreachable.unreachable = false
+	require.Nil(ce.checkSignState())
+	require.True(ce.signStateChecked)
+
+	// The check is postponed until the blocks after a snapshot are synced
+	viper.Set(common.CfgSignerGuardCheckDepth, 100)
+	snapshotRoot := core.CreateTestBlock("snapshot", "")
+	snapshotRoot.Height = 1000
+	snapshotChain := blockchain.NewChain("testchain", kvstore.NewKVStore(backend.NewMemDatabase()), snapshotRoot)
+	ce = NewConsensusEngine(signer.NewLocalSigner(privKey, newGuard()), store, snapshotChain, nil, validatorManager)
+	_, _, err = ce.findRecentSignature()
+	require.NotNil(err)
+	require.NotNil(ce.checkSignState())
+	_, err = ce.createVote(parent)
+	require.NotNil(err, "Signing is refused until the check passes")
+}
+
+// unreachableSigner fails to report its sign state while it is unreachable
+type unreachableSigner struct {
+	*signer.LocalSigner
+	unreachable bool
+}
+
+func (s *unreachableSigner) IsSignStateFresh() (bool, error) {
+	if s.unreachable {
+		return false, errors.New("signer unreachable")
+	}
+	return s.LocalSigner.IsSignStateFresh()
+}
+
+func TestSeedSignState(t *testing.T) {
+	require := require.New(t)
+
+	privKey, _, _ := crypto.GenerateKeyPair()
+	validatorManager := MockValidatorManager{PrivKey: privKey}
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("a0", "")
+	chain := blockchain.NewChain("testchain", store, root)
+
+	// The consensus state of a node upgraded from a version without the sign state
+	lastVote := core.Vote{Block: common.BytesToHash([]byte("b1")), Height: 5, Epoch: 7, ID: privKey.PublicKey().Address()}
+	state := NewState(store, chain)
+	require.Nil(state.SetLastVote(lastVote))
+
+	guard, err := signer.NewSignGuard("")
+	require.Nil(err)
+	ce := NewConsensusEngine(signer.NewLocalSigner(privKey, guard), store, chain, nil, validatorManager)
+	require.Nil(ce.seedSignState())
+	require.False(guard.IsFresh())
+	require.Equal(uint64(5), guard.State().VoteHeight)
+	require.Nil(ce.checkSignState())
+
+	_, err = ce.signer.SignVote(core.Vote{Block: common.BytesToHash([]byte("b2")), Height: 5, Epoch: 8, ID: lastVote.ID})
+	require.NotNil(err, "Conflicts with the seeded vote")
+}
+
 func TestValidParent(t *testing.T) {
 	require := require.New(t)
 
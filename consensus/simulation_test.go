@@ -0,0 +1,270 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/netsync"
+	"github.com/thetatoken/theta/p2p/simulation"
+	"github.com/thetatoken/theta/signer"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+// simValidatorManager rotates the proposer among a fixed validator set by epoch
+type simValidatorManager struct {
+	validators *core.ValidatorSet
+}
+
+func (m simValidatorManager) SetConsensusEngine(consensus core.ConsensusEngine) {}
+
+func (m simValidatorManager) GetProposer(_ common.Hash, epoch uint64) core.Validator {
+	validators := m.validators.Validators()
+	return validators[epoch%uint64(len(validators))]
+}
+
+func (m simValidatorManager) GetNextProposer(blockHash common.Hash, epoch uint64) core.Validator {
+	return m.GetProposer(blockHash, epoch)
+}
+
+func (m simValidatorManager) GetValidatorSet(_ common.Hash) *core.ValidatorSet {
+	return m.validators
+}
+
+func (m simValidatorManager) GetNextValidatorSet(_ common.Hash) *core.ValidatorSet {
+	return m.validators
+}
+
+// simLedger accepts every block without transactions
+type simLedger struct{}
+
+func (l simLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.OK
+}
+
+func (l simLedger) ScreenTxReplacement(rawTx common.Bytes, precedingRawTxs []common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.OK
+}
+
+func (l simLedger) GetTxInfo(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.OK
+}
+
+func (l simLedger) ProposeBlockTxs(block *core.Block) (common.Hash, []common.Bytes, result.Result) {
+	return common.Hash{}, []common.Bytes{}, result.OK
+}
+
+func (l simLedger) ApplyBlockTxs(block *core.Block) result.Result {
+	return result.OK
+}
+
+func (l simLedger) ResetState(height uint64, rootHash common.Hash) result.Result {
+	return result.OK
+}
+
+func (l simLedger) FinalizeState(height uint64, rootHash common.Hash) result.Result {
+	return result.OK
+}
+
+func (l simLedger) GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*core.ValidatorCandidatePool, error) {
+	return nil, fmt.Errorf("Not supported")
+}
+
+// simNode is a validator node running over the Simnet
+type simNode struct {
+	id        string
+	consensus *ConsensusEngine
+	syncMgr   *netsync.SyncManager
+}
+
+// finalizedChain returns the hashes of the finalized blocks from the root up
+func (n *simNode) finalizedChain() []string {
+	chain := n.consensus.Chain()
+	hashes := []string{}
+	for block := n.consensus.GetLastFinalizedBlock(); ; {
+		hashes = append([]string{block.Hash().Hex()}, hashes...)
+		if block.Height <= chain.Root().Height {
+			break
+		}
+		parent, err := chain.FindBlock(block.Parent)
+		if err != nil {
+			break
+		}
+		block = parent
+	}
+	return hashes
+}
+
+func (n *simNode) finalizedHeight() uint64 {
+	return n.consensus.GetLastFinalizedBlock().Height
+}
+
+func assertNodesNotConflicting(assert *assert.Assertions, nodes []*simNode) {
+	for i := 0; i < len(nodes); i++ {
+		for j := i + 1; j < len(nodes); j++ {
+			AssertFinalizedBlocksNotConflicting(assert, nodes[i].finalizedChain(), nodes[j].finalizedChain(),
+				fmt.Sprintf("%v and %v finalized conflicting blocks", nodes[i].id, nodes[j].id))
+		}
+	}
+}
+
+// createSimNodes creates the validator nodes over the Simnet, which share the given root block
+func createSimNodes(simnet *simulation.Simnet, root *core.Block, numNodes int) []*simNode {
+	privKeys := []*crypto.PrivateKey{}
+	validators := core.NewValidatorSet()
+	for i := 0; i < numNodes; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		privKeys = append(privKeys, privKey)
+		validators.AddValidator(core.NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(1)))
+	}
+	validatorManager := simValidatorManager{validators: validators}
+
+	nodes := []*simNode{}
+	for i, privKey := range privKeys {
+		id := fmt.Sprintf("node%d", i+1)
+		endpoint := simnet.AddEndpoint(id)
+		store := kvstore.NewKVStore(backend.NewMemDatabase())
+		chain := blockchain.NewChain("testchain", store, root)
+		disp := dispatcher.NewDispatcher(endpoint)
+
+		ce := NewConsensusEngine(signer.NewLocalSigner(privKey, nil), store, chain, disp, validatorManager)
+		ce.SetLedger(simLedger{})
+		syncMgr := netsync.NewSyncManager(chain, ce, endpoint, disp, ce)
+		syncMgr.SetValidatorManager(validatorManager)
+		ce.SetInvalidBlockHandler(syncMgr.HandleInvalidBlock)
+
+		nodes = append(nodes, &simNode{id: id, consensus: ce, syncMgr: syncMgr})
+	}
+	return nodes
+}
+
+// startSimNodes starts the Simnet and the nodes, and returns a function to stop them
+func startSimNodes(ctx context.Context, simnet *simulation.Simnet, nodes []*simNode) func() {
+	simnet.Start(ctx)
+	for _, node := range nodes {
+		node.consensus.Start(ctx)
+		node.syncMgr.Start(ctx)
+	}
+	return func() {
+		for _, node := range nodes {
+			node.consensus.Stop()
+			node.syncMgr.Stop()
+		}
+		simnet.Stop()
+		for _, node := range nodes {
+			node.consensus.Wait()
+		}
+	}
+}
+
+func minFinalizedHeight(nodes []*simNode) uint64 {
+	height := nodes[0].finalizedHeight()
+	for _, node := range nodes[1:] {
+		if h := node.finalizedHeight(); h < height {
+			height = h
+		}
+	}
+	return height
+}
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestConsensusPartitionAndHeal(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping the consensus simulation in short mode")
+	}
+	assert := assert.New(t)
+	require := require.New(t)
+
+	for key, value := range map[string]interface{}{
+		common.CfgConsensusMaxEpochLength:   2,
+		common.CfgConsensusMinProposalWait:  1,
+		common.CfgConsensusAdaptiveTimeouts: false,
+	} {
+		original := viper.Get(key)
+		defer viper.Set(key, original)
+		viper.Set(key, value)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	simnet := simulation.NewSimnet()
+	simnet.SetSeed(1)
+	simnet.SetDefaultLinkConfig(simulation.LinkConfig{
+		Latency:     10 * time.Millisecond,
+		Jitter:      20 * time.Millisecond,
+		DropRate:    0.02,
+		ReorderRate: 0.1,
+	})
+
+	root := core.CreateTestBlock("sim0", "")
+	root.ChainID = "testchain"
+	root.Epoch = 0
+
+	nodes := createSimNodes(simnet, root, 4)
+	stop := startSimNodes(ctx, simnet, nodes)
+	defer stop()
+
+	// All the validators are connected
+	require.True(waitFor(30*time.Second, func() bool { return minFinalizedHeight(nodes) >= root.Height+2 }),
+		"Validators failed to finalize blocks, seed: %v", simnet.Seed())
+	assertNodesNotConflicting(assert, nodes)
+
+	// The majority keeps finalizing blocks, while the isolated validator cannot
+	majority, isolated := nodes[:3], nodes[3]
+	simnet.Partition([]string{"node1", "node2", "node3"}, []string{"node4"})
+	partitionHeight := minFinalizedHeight(majority)
+	require.True(waitFor(30*time.Second, func() bool { return minFinalizedHeight(majority) >= partitionHeight+3 }),
+		"The majority failed to finalize blocks in the partition, seed: %v", simnet.Seed())
+	assert.True(isolated.finalizedHeight() < minFinalizedHeight(majority))
+	assertNodesNotConflicting(assert, nodes)
+
+	// The isolated validator catches up after the partition heals, and all of them move on
+	simnet.Heal()
+	healHeight := minFinalizedHeight(majority)
+	require.True(waitFor(60*time.Second, func() bool { return minFinalizedHeight(nodes) >= healHeight+2 }),
+		"Validators failed to finalize blocks after the partition healed, seed: %v", simnet.Seed())
+	assertNodesNotConflicting(assert, nodes)
+
+	// A partition without a majority halts the finalization, but not the safety
+	simnet.Partition([]string{"node1", "node2"}, []string{"node3", "node4"})
+	time.Sleep(3 * time.Second) // let the messages in flight settle
+	splitHeights := []uint64{}
+	for _, node := range nodes {
+		splitHeights = append(splitHeights, node.finalizedHeight())
+	}
+	time.Sleep(5 * time.Second)
+	for i, node := range nodes {
+		assert.Equal(splitHeights[i], node.finalizedHeight(), "%v finalized blocks without a majority", node.id)
+	}
+	assertNodesNotConflicting(assert, nodes)
+	splitHeight := minFinalizedHeight(nodes)
+
+	simnet.Heal()
+	require.True(waitFor(60*time.Second, func() bool { return minFinalizedHeight(nodes) >= splitHeight+2 }),
+		"Validators failed to finalize blocks after the split healed, seed: %v", simnet.Seed())
+	assertNodesNotConflicting(assert, nodes)
+}
@@ -18,6 +18,7 @@ type StateStub struct {
 	LastProposal       core.Proposal
 	LastVote           core.Vote
 	Epoch              uint64
+	Timeouts           EpochTimeouts `rlp:"-"` // not persisted, filled in by the engine
 }
 
 const (
@@ -0,0 +1,112 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/thetatoken/theta/common"
+)
+
+// The epoch timeout doubles after each failed epoch, and shrinks to 3/4 after each epoch
+// that finalizes a block in less than half of the timeout.
+const (
+	epochTimeoutBackoffFactor = 2
+	epochTimeoutShrinkNum     = 3
+	epochTimeoutShrinkDenom   = 4
+)
+
+//
+// EpochTimeouts summarizes the timeouts chosen for the current epoch
+//
+type EpochTimeouts struct {
+	EpochTimeout    time.Duration
+	ProposalWait    time.Duration
+	NumFailedEpochs uint64 // consecutive epochs ended by the timeout
+}
+
+//
+// adaptiveTimeouts adapts the epoch timeout to the network conditions. The timeout backs off
+// exponentially after consecutive failed epochs to stay live during outages, and shrinks
+// when blocks are finalized quickly to reduce the block time on healthy networks. The
+// proposal wait keeps its ratio to the epoch timeout.
+//
+type adaptiveTimeouts struct {
+	mu *sync.Mutex
+
+	adaptive     bool
+	base         time.Duration
+	min          time.Duration
+	max          time.Duration
+	proposalWait time.Duration // proposal wait for the base timeout
+
+	current         time.Duration
+	numFailedEpochs uint64
+}
+
+func newAdaptiveTimeouts() *adaptiveTimeouts {
+	base := time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second
+	min := time.Duration(viper.GetInt(common.CfgConsensusMinEpochTimeout)) * time.Second
+	max := time.Duration(viper.GetInt(common.CfgConsensusMaxEpochTimeout)) * time.Second
+	if min > base {
+		min = base
+	}
+	if max < base {
+		max = base
+	}
+	return &adaptiveTimeouts{
+		mu:           &sync.Mutex{},
+		adaptive:     viper.GetBool(common.CfgConsensusAdaptiveTimeouts),
+		base:         base,
+		min:          min,
+		max:          max,
+		proposalWait: time.Duration(viper.GetInt(common.CfgConsensusMinProposalWait)) * time.Second,
+		current:      base,
+	}
+}
+
+// timeouts returns the timeouts for the next epoch
+func (at *adaptiveTimeouts) timeouts() EpochTimeouts {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	if !at.adaptive {
+		return EpochTimeouts{
+			EpochTimeout:    at.base,
+			ProposalWait:    at.proposalWait,
+			NumFailedEpochs: at.numFailedEpochs,
+		}
+	}
+	return EpochTimeouts{
+		EpochTimeout:    at.current,
+		ProposalWait:    time.Duration(float64(at.proposalWait) * float64(at.current) / float64(at.base)),
+		NumFailedEpochs: at.numFailedEpochs,
+	}
+}
+
+// onTimeout is called when an epoch ends by the timeout
+func (at *adaptiveTimeouts) onTimeout() {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	at.numFailedEpochs++
+	at.current *= epochTimeoutBackoffFactor
+	if at.current > at.max {
+		at.current = at.max
+	}
+}
+
+// onProgress is called when an epoch ends early with the votes of the validators
+func (at *adaptiveTimeouts) onProgress(elapsed time.Duration, finalized bool) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+
+	at.numFailedEpochs = 0
+	if !finalized || elapsed*2 >= at.current {
+		return
+	}
+	at.current = at.current * epochTimeoutShrinkNum / epochTimeoutShrinkDenom
+	if at.current < at.min {
+		at.current = at.min
+	}
+}
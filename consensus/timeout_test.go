@@ -0,0 +1,151 @@
+package consensus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/p2p/simulation"
+)
+
+func TestAdaptiveTimeouts(t *testing.T) {
+	assert := assert.New(t)
+
+	viper.Set(common.CfgConsensusMaxEpochLength, 10)
+	viper.Set(common.CfgConsensusMinProposalWait, 6)
+	viper.Set(common.CfgConsensusAdaptiveTimeouts, true)
+	viper.Set(common.CfgConsensusMinEpochTimeout, 4)
+	viper.Set(common.CfgConsensusMaxEpochTimeout, 60)
+	defer func() {
+		viper.Set(common.CfgConsensusAdaptiveTimeouts, false)
+		viper.Set(common.CfgConsensusMinEpochTimeout, 4)
+		viper.Set(common.CfgConsensusMaxEpochTimeout, 120)
+	}()
+
+	at := newAdaptiveTimeouts()
+	timeouts := at.timeouts()
+	assert.Equal(10*time.Second, timeouts.EpochTimeout)
+	assert.Equal(6*time.Second, timeouts.ProposalWait)
+
+	// Exponential backoff after consecutive failed epochs, up to the upper bound
+	at.onTimeout()
+	assert.Equal(20*time.Second, at.timeouts().EpochTimeout)
+	assert.Equal(12*time.Second, at.timeouts().ProposalWait)
+	at.onTimeout()
+	at.onTimeout()
+	timeouts = at.timeouts()
+	assert.Equal(60*time.Second, timeouts.EpochTimeout)
+	assert.Equal(uint64(3), timeouts.NumFailedEpochs)
+
+	// Progress without a quick finalization resets the failures only
+	at.onProgress(40*time.Second, true)
+	at.onProgress(time.Second, false)
+	timeouts = at.timeouts()
+	assert.Equal(60*time.Second, timeouts.EpochTimeout)
+	assert.Equal(uint64(0), timeouts.NumFailedEpochs)
+
+	// Shrinks on quick finalizations, down to the lower bound
+	at.onProgress(time.Second, true)
+	assert.Equal(45*time.Second, at.timeouts().EpochTimeout)
+	for i := 0; i < 20; i++ {
+		at.onProgress(time.Second, true)
+	}
+	timeouts = at.timeouts()
+	assert.Equal(4*time.Second, timeouts.EpochTimeout)
+	assert.Equal(2400*time.Millisecond, timeouts.ProposalWait)
+
+	// Fixed timeouts when disabled
+	viper.Set(common.CfgConsensusAdaptiveTimeouts, false)
+	at = newAdaptiveTimeouts()
+	at.onTimeout()
+	timeouts = at.timeouts()
+	assert.Equal(10*time.Second, timeouts.EpochTimeout)
+	assert.Equal(6*time.Second, timeouts.ProposalWait)
+	assert.Equal(uint64(1), timeouts.NumFailedEpochs)
+}
+
+// timeoutScale speeds up the timeouts of the consensus engines in TestAdaptiveTimeoutsMainLoop
+const timeoutScale = 100
+
+func scaleTimeouts(at *adaptiveTimeouts) {
+	at.base /= timeoutScale
+	at.min /= timeoutScale
+	at.max /= timeoutScale
+	at.proposalWait /= timeoutScale
+	at.current /= timeoutScale
+}
+
+func TestAdaptiveTimeoutsMainLoop(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping the consensus simulation in short mode")
+	}
+	require := require.New(t)
+
+	for key, value := range map[string]interface{}{
+		common.CfgConsensusMaxEpochLength:   10,
+		common.CfgConsensusMinProposalWait:  6,
+		common.CfgConsensusAdaptiveTimeouts: true,
+		common.CfgConsensusMinEpochTimeout:  4,
+		common.CfgConsensusMaxEpochTimeout:  120,
+	} {
+		original := viper.Get(key)
+		defer viper.Set(key, original)
+		viper.Set(key, value)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	simnet := simulation.NewSimnet()
+	simnet.SetSeed(1)
+	simnet.SetDefaultLinkConfig(simulation.LinkConfig{
+		Latency: 2 * time.Millisecond,
+		Jitter:  2 * time.Millisecond,
+	})
+
+	root := core.CreateTestBlock("timeout0", "")
+	root.ChainID = "testchain"
+	root.Epoch = 0
+
+	// The engines run the configured 4s to 120s timeouts 100 times faster
+	nodes := createSimNodes(simnet, root, 4)
+	for _, node := range nodes {
+		scaleTimeouts(node.consensus.timeouts)
+	}
+	minTimeout := 4 * time.Second / timeoutScale
+	maxTimeout := 120 * time.Second / timeoutScale
+	checkBounds := func() {
+		for _, node := range nodes {
+			timeout := node.consensus.timeouts.timeouts().EpochTimeout
+			require.True(timeout >= minTimeout && timeout <= maxTimeout,
+				"Epoch timeout of %v out of bounds: %v", node.id, timeout)
+		}
+	}
+
+	isolated := nodes[0]
+	simnet.Partition([]string{isolated.id}, []string{"node2", "node3", "node4"})
+	stop := startSimNodes(ctx, simnet, nodes)
+	defer stop()
+
+	// The isolated validator backs off up to the upper bound
+	require.True(waitFor(30*time.Second, func() bool {
+		checkBounds()
+		timeouts := isolated.consensus.timeouts.timeouts()
+		return timeouts.EpochTimeout == maxTimeout && timeouts.NumFailedEpochs > 5
+	}), "The isolated validator failed to back off to the upper bound")
+
+	// It shrinks the timeout after the partition heals and the blocks are finalized quickly again
+	healHeight := isolated.finalizedHeight()
+	simnet.Heal()
+	require.True(waitFor(30*time.Second, func() bool {
+		checkBounds()
+		timeouts := isolated.consensus.timeouts.timeouts()
+		return timeouts.EpochTimeout < maxTimeout && isolated.finalizedHeight() > healHeight
+	}), "The validator failed to shrink the timeout after the partition healed, seed: %v", simnet.Seed())
+	checkBounds()
+}
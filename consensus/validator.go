@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -8,6 +9,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
 )
 
 //
@@ -69,7 +71,8 @@ func (m *FixedValidatorManager) GetNextValidatorSet(blockHash common.Hash) *core
 var _ core.ValidatorManager = &RotatingValidatorManager{}
 
 // RotatingValidatorManager is an implementation of ValidatorManager interface that selects a random validator as
-// the proposer using validator's stake as weight.
+// the proposer using validator's stake as weight. The randomness comes from the random beacon carried by the
+// given block, so that the proposers of the future epochs cannot be computed in advance.
 type RotatingValidatorManager struct {
 	consensus core.ConsensusEngine
 }
@@ -87,15 +90,27 @@ func (m *RotatingValidatorManager) SetConsensusEngine(consensus core.ConsensusEn
 
 // GetProposer implements ValidatorManager interface.
 func (m *RotatingValidatorManager) GetProposer(blockHash common.Hash, epoch uint64) core.Validator {
-	return m.getProposerFromValidators(m.GetValidatorSet(blockHash), epoch)
+	return m.getProposerFromValidators(m.GetValidatorSet(blockHash), m.getBeacon(blockHash), epoch)
 }
 
 // GetNextProposer implements ValidatorManager interface.
 func (m *RotatingValidatorManager) GetNextProposer(blockHash common.Hash, epoch uint64) core.Validator {
-	return m.getProposerFromValidators(m.GetNextValidatorSet(blockHash), epoch)
+	return m.getProposerFromValidators(m.GetNextValidatorSet(blockHash), m.getBeacon(blockHash), epoch)
 }
 
-func (m *RotatingValidatorManager) getProposerFromValidators(valSet *core.ValidatorSet, epoch uint64) core.Validator {
+// getBeacon returns the random beacon of the given block, empty if the block does not carry one
+func (m *RotatingValidatorManager) getBeacon(blockHash common.Hash) common.Hash {
+	if m.consensus == nil {
+		return common.Hash{}
+	}
+	block, err := m.consensus.FindBlock(blockHash)
+	if err != nil || block.Block == nil {
+		return common.Hash{}
+	}
+	return block.Beacon()
+}
+
+func (m *RotatingValidatorManager) getProposerFromValidators(valSet *core.ValidatorSet, beacon common.Hash, epoch uint64) core.Validator {
 	if valSet.Size() == 0 {
 		panic("No validators have been added")
 	}
@@ -105,9 +120,15 @@ func (m *RotatingValidatorManager) getProposerFromValidators(valSet *core.Valida
 	scalingFactor = new(big.Int).Add(scalingFactor, common.Big1)
 	scaledTotalStake := scaleDown(totalStake, scalingFactor)
 
-	// TODO: replace with more secure randomness.
-	rnd := rand.New(rand.NewSource(int64(epoch)))
-	r := randUint64(rnd, scaledTotalStake)
+	var r uint64
+	if beacon.IsEmpty() {
+		// Deterministic fallback for the blocks without the random beacon, i.e. the blocks
+		// before HeightEnableRandomBeacon and the blocks in tests. The schedule is public.
+		rnd := rand.New(rand.NewSource(int64(epoch)))
+		r = randUint64(rnd, scaledTotalStake)
+	} else {
+		r = beaconUint64(beacon, epoch, scaledTotalStake)
+	}
 	curr := uint64(0)
 	validators := valSet.Validators()
 	for _, v := range validators {
@@ -137,22 +158,15 @@ func (m *RotatingValidatorManager) GetNextValidatorSet(blockHash common.Hash) *c
 // -------------------------------- Utilities ----------------------------------
 //
 
+// SelectTopStakeHoldersAsValidators returns the validator set in effect for the given validator candidate
+// pool. Once the delayed validator set hand-over is enabled, it is the active validator set recorded in
+// the pool, which follows the top stake holders with a delay.
 func SelectTopStakeHoldersAsValidators(vcp *core.ValidatorCandidatePool) *core.ValidatorSet {
-	maxNumValidators := viper.GetInt(common.CfgConsensusMaxNumValidators)
-	topStakeHolders := vcp.GetTopStakeHolders(maxNumValidators)
-
-	valSet := core.NewValidatorSet()
-	for _, stakeHolder := range topStakeHolders {
-		valAddr := stakeHolder.Holder.Hex()
-		valStake := stakeHolder.TotalStake()
-		if valStake.Cmp(core.Zero) == 0 {
-			continue
-		}
-		validator := core.NewValidator(valAddr, valStake)
-		valSet.AddValidator(validator)
+	if vcp.HasActiveValidators() {
+		return vcp.GetActiveValidatorSet()
 	}
-
-	return valSet
+	maxNumValidators := viper.GetInt(common.CfgConsensusMaxNumValidators)
+	return vcp.SelectTopValidators(maxNumValidators)
 }
 
 func selectTopStakeHoldersAsValidatorsForBlock(consensus core.ConsensusEngine, blockHash common.Hash, isNext bool) *core.ValidatorSet {
@@ -181,6 +195,14 @@ func randUint64(rnd *rand.Rand, max uint64) uint64 {
 	}
 }
 
+// Derive a random uint64 in [0, max) for the epoch from the random beacon
+func beaconUint64(beacon common.Hash, epoch uint64, max uint64) uint64 {
+	epochBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(epochBytes, epoch)
+	seed := new(big.Int).SetBytes(crypto.Keccak256(beacon[:], epochBytes))
+	return new(big.Int).Mod(seed, new(big.Int).SetUint64(max)).Uint64()
+}
+
 func scaleDown(x *big.Int, scalingFactor *big.Int) uint64 {
 	if scalingFactor.Cmp(common.Big0) == 0 {
 		panic("scalingFactor is zero")
@@ -0,0 +1,45 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+)
+
+func TestRotatingProposerSelection(t *testing.T) {
+	assert := assert.New(t)
+
+	valSet := core.NewValidatorSet()
+	for i, stake := range []int64{1000, 2000, 3000, 4000} {
+		address := common.BigToAddress(big.NewInt(int64(i + 1)))
+		valSet.AddValidator(core.NewValidator(address.Hex(), big.NewInt(stake)))
+	}
+	m := NewRotatingValidatorManager()
+
+	// Without the random beacon, the schedule only depends on the epoch
+	for epoch := uint64(1); epoch < 10; epoch++ {
+		assert.Equal(m.getProposerFromValidators(valSet, common.Hash{}, epoch).ID(), m.getProposerFromValidators(valSet, common.Hash{}, epoch).ID())
+	}
+
+	// With the random beacon, the schedule changes with the beacon, and the proposers
+	// are selected by stake
+	beacon1 := common.BytesToHash([]byte("beacon1"))
+	beacon2 := common.BytesToHash([]byte("beacon2"))
+	differs := false
+	counts := make(map[common.Address]int)
+	for epoch := uint64(1); epoch <= 2000; epoch++ {
+		p1 := m.getProposerFromValidators(valSet, beacon1, epoch)
+		p2 := m.getProposerFromValidators(valSet, beacon2, epoch)
+		assert.Equal(p1.ID(), m.getProposerFromValidators(valSet, beacon1, epoch).ID())
+		if p1.ID() != p2.ID() {
+			differs = true
+		}
+		counts[p1.ID()]++
+	}
+	assert.True(differs)
+	assert.True(counts[common.BigToAddress(big.NewInt(1))] < counts[common.BigToAddress(big.NewInt(4))])
+	assert.True(counts[common.BigToAddress(big.NewInt(1))] > 100)
+}
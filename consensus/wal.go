@@ -0,0 +1,377 @@
+package consensus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/rlp"
+)
+
+// WALEntryType is the type of a write-ahead log entry
+type WALEntryType byte
+
+const (
+	// WALEntryReceivedVote records a standalone vote received from a peer
+	WALEntryReceivedVote WALEntryType = WALEntryType(iota)
+	// WALEntryReceivedBlock records a block received from a peer
+	WALEntryReceivedBlock
+	// WALEntryLocalVote records a vote signed by the node, before it is broadcasted
+	WALEntryLocalVote
+	// WALEntryLocalProposal records a proposal made by the node, before it is broadcasted
+	WALEntryLocalProposal
+	// WALEntryEpoch records the node entering a new epoch
+	WALEntryEpoch
+)
+
+func (t WALEntryType) String() string {
+	switch t {
+	case WALEntryReceivedVote:
+		return "ReceivedVote"
+	case WALEntryReceivedBlock:
+		return "ReceivedBlock"
+	case WALEntryLocalVote:
+		return "LocalVote"
+	case WALEntryLocalProposal:
+		return "LocalProposal"
+	case WALEntryEpoch:
+		return "Epoch"
+	default:
+		return fmt.Sprintf("Unknown(%d)", byte(t))
+	}
+}
+
+// The sync policies of the write-ahead log, see common.CfgConsensusWALSyncPolicy
+const (
+	WALSyncAlways = "always" // flush every entry to disk
+	WALSyncLocal  = "local"  // flush the local decisions only, the received messages can be fetched again
+	WALSyncNone   = "none"   // leave the flushing to the OS
+)
+
+//
+// WALEntry is an entry of the consensus write-ahead log
+//
+type WALEntry struct {
+	Type      WALEntryType
+	Epoch     uint64       // epoch of the node when the entry is written, or the new epoch for WALEntryEpoch
+	Height    uint64       // height of the block the message refers to, 0 for WALEntryEpoch
+	Timestamp uint64       // unix time in milliseconds when the entry is written
+	Data      common.Bytes // RLP encoded vote, block or proposal
+}
+
+// NewWALEntry creates a write-ahead log entry for the given message
+func NewWALEntry(entryType WALEntryType, epoch uint64, height uint64, msg interface{}) (*WALEntry, error) {
+	entry := &WALEntry{
+		Type:      entryType,
+		Epoch:     epoch,
+		Height:    height,
+		Timestamp: uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+	}
+	if msg != nil {
+		data, err := rlp.EncodeToBytes(msg)
+		if err != nil {
+			return nil, err
+		}
+		entry.Data = data
+	}
+	return entry, nil
+}
+
+// DecodeMessage decodes the message recorded in the entry: a core.Vote, a *core.Block, a
+// core.Proposal, or the new epoch as uint64.
+func (entry *WALEntry) DecodeMessage() (interface{}, error) {
+	switch entry.Type {
+	case WALEntryReceivedVote, WALEntryLocalVote:
+		vote := core.Vote{}
+		err := rlp.DecodeBytes(entry.Data, &vote)
+		return vote, err
+	case WALEntryReceivedBlock:
+		block := &core.Block{}
+		err := rlp.DecodeBytes(entry.Data, block)
+		return block, err
+	case WALEntryLocalProposal:
+		proposal := core.Proposal{}
+		err := rlp.DecodeBytes(entry.Data, &proposal)
+		return proposal, err
+	case WALEntryEpoch:
+		return entry.Epoch, nil
+	default:
+		return nil, fmt.Errorf("Unknown WAL entry type: %v", entry.Type)
+	}
+}
+
+func (entry *WALEntry) String() string {
+	return fmt.Sprintf("WALEntry{Type: %v, Epoch: %v, Height: %v, Timestamp: %v}",
+		entry.Type, entry.Epoch, entry.Height, entry.Timestamp)
+}
+
+// ReadWAL reads the entries from the segment files of the write-ahead log at the given path, and
+// passes them to the handler one by one. A missing log has no entries. The error is returned when the
+// tail of a segment is corrupted, which could happen if the node crashed while writing.
+func ReadWAL(filePath string, handler func(entry *WALEntry)) error {
+	indices, err := listWALSegments(filePath)
+	if err != nil {
+		return err
+	}
+	for _, index := range indices {
+		if err := readWALSegment(walSegmentPath(filePath, index), handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readWALSegment(segmentPath string, handler func(entry *WALEntry)) error {
+	input, err := os.Open(segmentPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	stream := rlp.NewStream(input, 0)
+	for {
+		entry := &WALEntry{}
+		if err = stream.Decode(entry); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		handler(entry)
+	}
+}
+
+// walSegmentPath returns the path of the segment file with the given index
+func walSegmentPath(filePath string, index uint64) string {
+	return fmt.Sprintf("%s.%06d", filePath, index)
+}
+
+// listWALSegments returns the indices of the segment files of the write-ahead log in ascending order
+func listWALSegments(filePath string) ([]uint64, error) {
+	matches, err := filepath.Glob(filePath + ".*")
+	if err != nil {
+		return nil, err
+	}
+	indices := []uint64{}
+	for _, match := range matches {
+		index, err := strconv.ParseUint(strings.TrimPrefix(match, filePath+"."), 10, 64)
+		if err != nil {
+			continue // not a segment file
+		}
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	return indices, nil
+}
+
+// walSegmentSize is the size of a segment file of the write-ahead log, beyond which the log continues
+// in a new segment once a block is finalized
+const walSegmentSize int64 = 4 * 1024 * 1024
+
+//
+// walSegment is a segment file of the write-ahead log
+//
+type walSegment struct {
+	index     uint64
+	maxHeight uint64 // max height of the blocks the entries refer to
+}
+
+//
+// writeAheadLog records the consensus messages received and the decisions made by the node before
+// they are processed, so that the node can recover its consensus progress after a crash. The log is
+// split into segment files, and the segments with the entries on the finalized blocks only are deleted.
+//
+type writeAheadLog struct {
+	mu              *sync.Mutex
+	filePath        string // the segment files are named <filePath>.<index>
+	syncPolicy      string
+	segmentSize     int64
+	segments        []*walSegment // the last segment is the one being written
+	writer          *os.File
+	writtenSize     int64     // size of the segment being written
+	lastEpochEntry  *WALEntry // copied to the beginning of each segment, so that the older segments can be deleted
+	finalizedHeight uint64
+}
+
+func newWriteAheadLog(filePath string, syncPolicy string) *writeAheadLog {
+	return &writeAheadLog{
+		mu:          &sync.Mutex{},
+		filePath:    filePath,
+		syncPolicy:  syncPolicy,
+		segmentSize: walSegmentSize,
+	}
+}
+
+// isValidWALSyncPolicy returns whether the given sync policy is supported
+func isValidWALSyncPolicy(syncPolicy string) bool {
+	return syncPolicy == WALSyncAlways || syncPolicy == WALSyncLocal || syncPolicy == WALSyncNone
+}
+
+// load reads the entries from the segment files. The entries read before a corrupted tail are returned
+// along with the error. The log continues in a new segment after the next rotation.
+func (w *writeAheadLog) load() ([]*WALEntry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	indices, err := listWALSegments(w.filePath)
+	if err != nil {
+		return []*WALEntry{}, err
+	}
+	entries := []*WALEntry{}
+	w.segments = []*walSegment{}
+	for _, index := range indices {
+		segment := &walSegment{index: index}
+		w.segments = append(w.segments, segment)
+		err = readWALSegment(walSegmentPath(w.filePath, index), func(entry *WALEntry) {
+			entries = append(entries, entry)
+			w.track(segment, entry)
+		})
+		if err != nil {
+			break
+		}
+	}
+	return entries, err
+}
+
+// track updates the height range of the segment and the last epoch entry with the given entry.
+func (w *writeAheadLog) track(segment *walSegment, entry *WALEntry) {
+	if entry.Type == WALEntryEpoch {
+		w.lastEpochEntry = entry
+	} else if entry.Height > segment.maxHeight {
+		segment.maxHeight = entry.Height
+	}
+}
+
+// write appends the entry to the segment being written, and flushes it to disk as required by the sync policy.
+func (w *writeAheadLog) write(entry *WALEntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writer == nil {
+		return nil
+	}
+	return w.writeUnsafe(entry)
+}
+
+func (w *writeAheadLog) writeUnsafe(entry *WALEntry) error {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	n, err := w.writer.Write(data)
+	w.writtenSize += int64(n)
+	if err != nil {
+		return err
+	}
+	w.track(w.segments[len(w.segments)-1], entry)
+	if w.shouldSync(entry) {
+		return w.writer.Sync()
+	}
+	return nil
+}
+
+func (w *writeAheadLog) shouldSync(entry *WALEntry) bool {
+	switch w.syncPolicy {
+	case WALSyncAlways:
+		return true
+	case WALSyncLocal:
+		return entry.Type == WALEntryLocalVote || entry.Type == WALEntryLocalProposal || entry.Type == WALEntryEpoch
+	default:
+		return false
+	}
+}
+
+// getFinalizedHeight returns the height of the last finalized block the log has been rotated for.
+func (w *writeAheadLog) getFinalizedHeight() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.finalizedHeight
+}
+
+// rotate is called once the block at the given height is finalized. It continues the log in a new segment
+// if the current one is full, and deletes the older segments with the entries on the finalized blocks only.
+func (w *writeAheadLog) rotate(finalizedHeight uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.finalizedHeight = finalizedHeight
+	if w.writer == nil || w.writtenSize >= w.segmentSize {
+		if err := w.startSegmentUnsafe(); err != nil {
+			return err
+		}
+	}
+
+	current := w.segments[len(w.segments)-1]
+	segments := []*walSegment{}
+	for _, segment := range w.segments {
+		if segment != current && segment.maxHeight <= finalizedHeight {
+			if err := os.Remove(walSegmentPath(w.filePath, segment.index)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	w.segments = segments
+	return nil
+}
+
+// startSegmentUnsafe closes the segment being written, and starts a new one with the last epoch entry.
+func (w *writeAheadLog) startSegmentUnsafe() error {
+	if err := w.closeUnsafe(); err != nil {
+		return err
+	}
+
+	index := uint64(1)
+	if len(w.segments) > 0 {
+		index = w.segments[len(w.segments)-1].index + 1
+	}
+	if err := os.MkdirAll(filepath.Dir(w.filePath), 0700); err != nil {
+		return err
+	}
+	writer, err := os.OpenFile(walSegmentPath(w.filePath, index), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	w.segments = append(w.segments, &walSegment{index: index})
+	w.writer = writer
+	w.writtenSize = 0
+	if w.lastEpochEntry != nil {
+		if err := w.writeUnsafe(w.lastEpochEntry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close flushes the segment being written to disk and closes it.
+func (w *writeAheadLog) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.closeUnsafe()
+}
+
+func (w *writeAheadLog) closeUnsafe() error {
+	if w.writer == nil {
+		return nil
+	}
+	err := w.writer.Sync()
+	if closeErr := w.writer.Close(); err == nil {
+		err = closeErr
+	}
+	w.writer = nil
+	return err
+}
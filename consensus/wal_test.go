@@ -0,0 +1,227 @@
+package consensus
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/signer"
+	"github.com/thetatoken/theta/store/database/backend"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+func TestWriteAheadLog(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "theta-consensus-wal-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	walPath := path.Join(dir, "consensus", "wal")
+	w := newWriteAheadLog(walPath, WALSyncLocal)
+
+	// Non-existing WAL file
+	entries, err := w.load()
+	assert.Nil(err)
+	assert.Equal(0, len(entries))
+
+	block := core.CreateTestBlock("b1", "a0")
+	block.Height = 2
+	vote1 := core.Vote{Block: block.Hash(), Height: 2, Epoch: 3, ID: common.HexToAddress("A1")}
+	vote2 := core.Vote{Block: common.BytesToHash([]byte("b2")), Height: 3, Epoch: 4, ID: common.HexToAddress("A2")}
+
+	write := func(entryType WALEntryType, epoch uint64, height uint64, msg interface{}) {
+		entry, err := NewWALEntry(entryType, epoch, height, msg)
+		assert.Nil(err)
+		assert.Nil(w.write(entry))
+	}
+
+	assert.Nil(w.rotate(0))
+	write(WALEntryReceivedBlock, 3, 2, block)
+	write(WALEntryReceivedVote, 3, 2, vote1)
+	write(WALEntryEpoch, 4, 0, nil)
+	write(WALEntryLocalVote, 4, 3, vote2)
+	write(WALEntryEpoch, 5, 0, nil)
+	assert.Nil(w.close())
+
+	w = newWriteAheadLog(walPath, WALSyncLocal)
+	entries, err = w.load()
+	assert.Nil(err)
+	assert.Equal(5, len(entries))
+	assert.Equal(WALEntryReceivedBlock, entries[0].Type)
+	msg, err := entries[0].DecodeMessage()
+	assert.Nil(err)
+	assert.Equal(block.Hash(), msg.(*core.Block).Hash())
+	msg, err = entries[1].DecodeMessage()
+	assert.Nil(err)
+	assert.Equal(vote1.Block, msg.(core.Vote).Block)
+	assert.Equal(vote1.ID, msg.(core.Vote).ID)
+	msg, err = entries[2].DecodeMessage()
+	assert.Nil(err)
+	assert.Equal(uint64(4), msg.(uint64))
+	assert.Equal(WALEntryLocalVote, entries[3].Type)
+
+	// A full segment is continued in a new one with the last epoch entry, and the segments
+	// with the entries on the finalized blocks only are deleted
+	w.segmentSize = 1
+	assert.Nil(w.rotate(1))
+	assert.Equal(2, len(w.segments))
+	write(WALEntryLocalVote, 5, 4, vote2)
+	assert.Nil(w.rotate(3))
+	assert.Nil(w.close())
+	_, err = os.Stat(walSegmentPath(walPath, 1))
+	assert.True(os.IsNotExist(err))
+
+	w = newWriteAheadLog(walPath, WALSyncLocal)
+	entries, err = w.load()
+	assert.Nil(err)
+	assert.Equal(3, len(entries))
+	assert.Equal(WALEntryEpoch, entries[0].Type)
+	assert.Equal(uint64(5), entries[0].Epoch)
+	assert.Equal(WALEntryLocalVote, entries[1].Type)
+	assert.Equal(uint64(4), entries[1].Height)
+	assert.Equal(WALEntryEpoch, entries[2].Type)
+	assert.Equal(uint64(5), entries[2].Epoch)
+
+	// The entries before a corrupted tail should be readable
+	lastSegmentPath := walSegmentPath(walPath, 3)
+	file, err := os.OpenFile(lastSegmentPath, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.Nil(err)
+	_, err = file.Write([]byte{0xf8, 0xff, 0x01})
+	assert.Nil(err)
+	assert.Nil(file.Close())
+
+	numRead := 0
+	err = ReadWAL(walPath, func(entry *WALEntry) {
+		numRead++
+	})
+	assert.NotNil(err)
+	assert.Equal(3, numRead)
+
+	// The log continues in a new segment after the corrupted one
+	w = newWriteAheadLog(walPath, WALSyncLocal)
+	entries, err = w.load()
+	assert.NotNil(err)
+	assert.Equal(3, len(entries))
+	assert.Nil(w.rotate(3))
+	assert.Nil(w.close())
+	_, err = os.Stat(walSegmentPath(walPath, 4))
+	assert.Nil(err)
+}
+
+func TestWriteAheadLogSyncPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	received := &WALEntry{Type: WALEntryReceivedVote}
+	local := &WALEntry{Type: WALEntryLocalVote}
+
+	assert.True(newWriteAheadLog("", WALSyncAlways).shouldSync(received))
+	assert.True(newWriteAheadLog("", WALSyncAlways).shouldSync(local))
+	assert.False(newWriteAheadLog("", WALSyncLocal).shouldSync(received))
+	assert.True(newWriteAheadLog("", WALSyncLocal).shouldSync(local))
+	assert.False(newWriteAheadLog("", WALSyncNone).shouldSync(local))
+
+	assert.True(isValidWALSyncPolicy(WALSyncLocal))
+	assert.False(isValidWALSyncPolicy("sometimes"))
+}
+
+func TestReplayWAL(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "theta-consensus-wal-replay-test")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	walPath := path.Join(dir, "consensus", "wal")
+
+	privKeys := []*crypto.PrivateKey{}
+	validators := core.NewValidatorSet()
+	for i := 0; i < 4; i++ {
+		privKey, _, _ := crypto.GenerateKeyPair()
+		privKeys = append(privKeys, privKey)
+		validators.AddValidator(core.NewValidator(privKey.PublicKey().Address().Hex(), big.NewInt(1)))
+	}
+	validatorManager := simValidatorManager{validators: validators}
+
+	store := kvstore.NewKVStore(backend.NewMemDatabase())
+	root := core.CreateTestBlock("wal0", "")
+	root.Epoch = 0
+	chain := blockchain.NewChain("testchain", store, root)
+	block := core.CreateTestBlock("wal1", "wal0")
+	_, err = chain.AddBlock(block)
+	require.Nil(err)
+	chain.MarkBlockValid(block.Hash())
+
+	newEngine := func() *ConsensusEngine {
+		ce := NewConsensusEngine(signer.NewLocalSigner(privKeys[0], nil), store, chain, nil, validatorManager)
+		ce.SetLedger(simLedger{})
+		ce.SetWALFilePath(walPath)
+		return ce
+	}
+	newVote := func(privKey *crypto.PrivateKey, block *core.Block, epoch uint64) core.Vote {
+		vote := core.Vote{Block: block.Hash(), Height: block.Height, Epoch: epoch, ID: privKey.PublicKey().Address()}
+		sig, err := privKey.Sign(vote.SignBytes())
+		require.Nil(err)
+		vote.SetSignature(sig)
+		return vote
+	}
+
+	// The engine crashes after writing the entries, before processing them
+	ce := newEngine()
+	ce.replayWAL()
+	ce.writeWALEpoch(5)
+	ce.writeWAL(WALEntryReceivedVote, block.Height, newVote(privKeys[1], block, 5))
+	ce.writeWAL(WALEntryReceivedVote, root.Height, newVote(privKeys[2], root, 4)) // on a finalized block
+	proposal := core.Proposal{Block: core.CreateTestBlock("wal2", "wal1"), ProposerID: privKeys[0].PublicKey().Address()}
+	proposal.Block.Epoch = 5
+	ce.writeWAL(WALEntryLocalProposal, proposal.Block.Height, proposal)
+	ce.writeWAL(WALEntryLocalVote, block.Height, newVote(privKeys[0], block, 5))
+	require.Nil(ce.wal.close())
+
+	// The restarted engine recovers the epoch, the votes and the proposal
+	ce = newEngine()
+	assert.Equal(uint64(0), ce.GetEpoch())
+	ce.replayWAL()
+	defer ce.wal.close()
+	assert.Equal(uint64(5), ce.GetEpoch())
+
+	voters := map[common.Address]bool{}
+	for _, vote := range ce.chain.FindVotesByHash(block.Hash()).Votes() {
+		voters[vote.ID] = true
+	}
+	assert.Equal(2, len(voters))
+	assert.True(voters[privKeys[0].PublicKey().Address()])
+	assert.True(voters[privKeys[1].PublicKey().Address()])
+	assert.Equal(0, len(ce.chain.FindVotesByHash(root.Hash()).Votes()), "The votes on the finalized blocks are not replayed")
+	epochVotes, err := ce.state.GetEpochVotes()
+	require.Nil(err)
+	assert.Equal(2, len(epochVotes.Votes()))
+
+	lastProposal := ce.state.GetLastProposal()
+	require.NotNil(lastProposal.Block)
+	assert.Equal(proposal.Block.Hash(), lastProposal.Block.Hash())
+
+	// The replayed entries are kept in the WAL until the blocks are finalized, and the log
+	// continues in a new segment with the last epoch entry
+	entries, err := newWriteAheadLog(walPath, WALSyncLocal).load()
+	require.Nil(err)
+	assert.Equal(6, len(entries))
+	assert.Equal(WALEntryEpoch, entries[5].Type)
+	assert.Equal(uint64(5), entries[5].Epoch)
+
+	// Only the received votes and blocks above the finalized height and not yet in the chain are recorded
+	assert.False(ce.shouldWriteReceivedVoteWAL(newVote(privKeys[2], root, 5)))
+	assert.False(ce.shouldWriteReceivedVoteWAL(newVote(privKeys[1], block, 5)))
+	assert.True(ce.shouldWriteReceivedVoteWAL(newVote(privKeys[3], block, 5)))
+	assert.False(ce.shouldWriteReceivedBlockWAL(root))
+	assert.False(ce.shouldWriteReceivedBlockWAL(block))
+	assert.True(ce.shouldWriteReceivedBlockWAL(proposal.Block))
+}
@@ -47,6 +47,17 @@ func (b *Block) AddTxs(txs []common.Bytes) {
 	b.updateTxHash()
 }
 
+// ValidateTxs checks the transactions match the transaction root hash in the header.
+func (b *Block) ValidateTxs() result.Result {
+	if len(b.Txs) == 0 && b.TxHash.IsEmpty() {
+		return result.OK
+	}
+	if calculateRootHash(b.Txs) != b.TxHash {
+		return result.Error("Transactions do not match the TxHash")
+	}
+	return result.OK
+}
+
 // updateTxHash calculate transaction root hash.
 func (b *Block) updateTxHash() {
 	b.TxHash = calculateRootHash(b.Txs)
@@ -78,6 +89,7 @@ type BlockHeader struct {
 	Timestamp   *big.Int
 	Proposer    common.Address
 	Signature   *crypto.Signature
+	Randomness  common.Bytes `rlp:"optional"` // VRF proof of the random beacon, see BeaconInput()
 
 	hash common.Hash // Cache of calculated hash.
 }
@@ -130,11 +142,37 @@ func (h *BlockHeader) SignBytes() common.Bytes {
 		StateHash:   h.StateHash,
 		Timestamp:   h.Timestamp,
 		Proposer:    h.Proposer,
+		Randomness:  h.Randomness,
 	}
 	raw, _ := rlp.EncodeToBytes(r)
 	return raw
 }
 
+// Beacon returns the output of the random beacon carried by the block, or an empty
+// hash if the block does not carry one. The proof needs to be verified separately.
+func (h *BlockHeader) Beacon() common.Hash {
+	if len(h.Randomness) == 0 {
+		return common.Hash{}
+	}
+	beacon, err := crypto.VRFProofToHash(h.Randomness)
+	if err != nil {
+		return common.Hash{}
+	}
+	return beacon
+}
+
+// BeaconInput returns the input of the VRF evaluated by the proposer of a child
+// block of the parent at the given epoch. The beacon chains from the parent, or
+// starts from the parent hash if the parent does not carry a beacon.
+func BeaconInput(parent *BlockHeader, epoch uint64) common.Bytes {
+	seed := parent.Beacon()
+	if seed.IsEmpty() {
+		seed = parent.Hash()
+	}
+	raw, _ := rlp.EncodeToBytes([]interface{}{seed, epoch})
+	return raw
+}
+
 // SetSignature sets given signature in header.
 func (h *BlockHeader) SetSignature(sig *crypto.Signature) {
 	h.Signature = sig
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
 )
 
 func TestBlockHash(t *testing.T) {
@@ -28,6 +29,28 @@ func TestBlockHash(t *testing.T) {
 	assert.Equal("0x87a331c1e807476de260f2dc2e4d531dc42500764587605c7574179bc4cbd5bc", eb.Hash().Hex())
 }
 
+func TestBlockHashEmptyRandomness(t *testing.T) {
+	assert := assert.New(t)
+
+	nilRandomness := &BlockHeader{Epoch: 1}
+	emptyRandomness := &BlockHeader{Epoch: 1, Randomness: common.Bytes{}}
+	assert.Equal(nilRandomness.Hash(), emptyRandomness.Hash())
+
+	raw, err := rlp.EncodeToBytes(emptyRandomness)
+	assert.Nil(err)
+	decoded := &BlockHeader{}
+	assert.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Equal(nilRandomness.Hash(), decoded.Hash())
+
+	withRandomness := &BlockHeader{Epoch: 1, Randomness: common.Bytes{1}}
+	assert.NotEqual(nilRandomness.Hash(), withRandomness.Hash())
+	raw, err = rlp.EncodeToBytes(withRandomness)
+	assert.Nil(err)
+	decoded = &BlockHeader{}
+	assert.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Equal(withRandomness.Hash(), decoded.Hash())
+}
+
 func TestCreateTestBlock(t *testing.T) {
 	assert := assert.New(t)
 
@@ -8,13 +8,14 @@ import (
 // ConsensusEngine is the interface of a consensus engine.
 type ConsensusEngine interface {
 	ID() string
-	PrivateKey() *crypto.PrivateKey
+	Signer() Signer
 	GetTip(includePendingBlockingLeaf bool) *ExtendedBlock
 	GetEpoch() uint64
 	GetLedger() Ledger
 	AddMessage(msg interface{})
 	FinalizedBlocks() chan *Block
 	GetLastFinalizedBlock() *ExtendedBlock
+	FindBlock(blockHash common.Hash) (*ExtendedBlock, error)
 }
 
 // ValidatorManager is the component for managing validator related logic for consensus engine.
@@ -25,3 +26,15 @@ type ValidatorManager interface {
 	GetValidatorSet(blockHash common.Hash) *ValidatorSet
 	GetNextValidatorSet(blockHash common.Hash) *ValidatorSet
 }
+
+// Signer signs the proposals, votes and special transactions on behalf of the validator,
+// so that the validator key does not need to be held by the node. The implementations
+// refuse to sign conflicting blocks or votes.
+type Signer interface {
+	Address() common.Address
+	PublicKey() *crypto.PublicKey
+	SignBlock(header *BlockHeader) (*crypto.Signature, error)
+	SignVote(vote Vote) (*crypto.Signature, error)
+	SignTx(chainID string, rawTx common.Bytes) (*crypto.Signature, error) // only the coinbase and slash transactions
+	VRFProve(alpha common.Bytes) (common.Bytes, error)
+}
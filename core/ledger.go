@@ -29,8 +29,10 @@ type TxInfo struct {
 //
 type Ledger interface {
 	ScreenTx(rawTx common.Bytes) (priority *TxInfo, res result.Result)
-	ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
-	ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoot common.Hash) result.Result
+	ScreenTxReplacement(rawTx common.Bytes, precedingRawTxs []common.Bytes) (priority *TxInfo, res result.Result)
+	GetTxInfo(rawTx common.Bytes) (*TxInfo, result.Result)
+	ProposeBlockTxs(block *Block) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result)
+	ApplyBlockTxs(block *Block) result.Result
 	ResetState(height uint64, rootHash common.Hash) result.Result
 	FinalizeState(height uint64, rootHash common.Hash) result.Result
 	GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*ValidatorCandidatePool, error)
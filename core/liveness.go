@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	// LivenessWindowSize is the number of commit certificates over which the votes of a validator are tracked
+	LivenessWindowSize uint64 = 1000
+
+	// LivenessMinUptimePercent is the minimal percentage of the commit certificates in the window a
+	// validator needs to vote in. Validators below it are jailed once their window is full.
+	LivenessMinUptimePercent uint64 = 50
+)
+
+//
+// ValidatorLiveness records the activity of a validator over a sliding window of commit certificates
+//
+type ValidatorLiveness struct {
+	Address            common.Address
+	NumTracked         uint64       // number of commit certificates tracked since the record was created
+	MissedVotes        common.Bytes // bitmap of the missed votes, indexed by the tracking slot modulo the window size
+	NumMissedVotes     uint64       // number of votes missed within the window
+	NumProposedBlocks  uint64       // number of blocks proposed since the record was created
+	NumMissedProposals uint64       // number of skipped epochs in which the validator was the expected proposer
+}
+
+// NewValidatorLiveness creates an empty liveness record for the given validator
+func NewValidatorLiveness(address common.Address) *ValidatorLiveness {
+	return &ValidatorLiveness{
+		Address:     address,
+		MissedVotes: make(common.Bytes, (LivenessWindowSize+7)/8),
+	}
+}
+
+// RecordVote records whether the validator voted in the next tracked commit certificate. The
+// oldest certificate falls out of the window once the window is full.
+func (vl *ValidatorLiveness) RecordVote(voted bool) {
+	slot := vl.NumTracked % LivenessWindowSize
+	idx, mask := slot/8, byte(1)<<(slot%8)
+	if vl.MissedVotes[idx]&mask != 0 {
+		vl.NumMissedVotes--
+	}
+	if voted {
+		vl.MissedVotes[idx] &^= mask
+	} else {
+		vl.MissedVotes[idx] |= mask
+		vl.NumMissedVotes++
+	}
+	vl.NumTracked++
+}
+
+// WindowSize returns the number of commit certificates currently in the window
+func (vl *ValidatorLiveness) WindowSize() uint64 {
+	if vl.NumTracked < LivenessWindowSize {
+		return vl.NumTracked
+	}
+	return LivenessWindowSize
+}
+
+// Uptime returns the percentage of the commit certificates in the window the validator voted in
+func (vl *ValidatorLiveness) Uptime() float64 {
+	size := vl.WindowSize()
+	if size == 0 {
+		return 100
+	}
+	return float64(size-vl.NumMissedVotes) * 100 / float64(size)
+}
+
+// ShouldBeJailed returns true if the window is full and the validator voted in less than
+// LivenessMinUptimePercent of it
+func (vl *ValidatorLiveness) ShouldBeJailed() bool {
+	if vl.NumTracked < LivenessWindowSize {
+		return false
+	}
+	return (LivenessWindowSize-vl.NumMissedVotes)*100 < LivenessMinUptimePercent*LivenessWindowSize
+}
+
+func (vl *ValidatorLiveness) String() string {
+	return fmt.Sprintf("{Address: %v, NumTracked: %v, NumMissedVotes: %v, NumProposedBlocks: %v, NumMissedProposals: %v}",
+		vl.Address, vl.NumTracked, vl.NumMissedVotes, vl.NumProposedBlocks, vl.NumMissedProposals)
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func TestValidatorLiveness(t *testing.T) {
+	assert := assert.New(t)
+
+	vl := NewValidatorLiveness(common.HexToAddress("0x1"))
+	assert.Equal(float64(100), vl.Uptime())
+
+	// Miss the first half of the window
+	for i := uint64(0); i < LivenessWindowSize; i++ {
+		vl.RecordVote(i >= LivenessWindowSize/2)
+	}
+	assert.Equal(LivenessWindowSize, vl.WindowSize())
+	assert.Equal(LivenessWindowSize/2, vl.NumMissedVotes)
+	assert.Equal(float64(50), vl.Uptime())
+	assert.False(vl.ShouldBeJailed())
+
+	// The missed votes fall out of the window
+	for i := uint64(0); i < LivenessWindowSize/2; i++ {
+		vl.RecordVote(true)
+	}
+	assert.Equal(uint64(0), vl.NumMissedVotes)
+	assert.Equal(float64(100), vl.Uptime())
+
+	// Miss more than the threshold allows
+	for i := uint64(0); i <= LivenessWindowSize/2; i++ {
+		vl.RecordVote(false)
+	}
+	assert.Equal(LivenessWindowSize/2+1, vl.NumMissedVotes)
+	assert.True(vl.ShouldBeJailed())
+}
+
+func TestJailStakeHolder(t *testing.T) {
+	assert := assert.New(t)
+
+	vcp := &ValidatorCandidatePool{}
+	addr1 := common.HexToAddress("0x1")
+	addr2 := common.HexToAddress("0x2")
+	assert.Nil(vcp.DepositStake(addr1, addr1, MinValidatorStakeDeposit))
+	assert.Nil(vcp.DepositStake(addr2, addr2, MinValidatorStakeDeposit))
+
+	assert.Nil(vcp.JailStakeHolder(addr1))
+	assert.NotNil(vcp.JailStakeHolder(addr1))
+	assert.NotNil(vcp.JailStakeHolder(common.HexToAddress("0x3")))
+	top := vcp.GetTopStakeHolders(2)
+	assert.Equal(1, len(top))
+	assert.Equal(addr2, top[0].Holder)
+
+	assert.Nil(vcp.UnjailStakeHolder(addr1))
+	assert.NotNil(vcp.UnjailStakeHolder(addr1))
+	assert.Equal(2, len(vcp.GetTopStakeHolders(2)))
+}
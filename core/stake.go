@@ -53,8 +53,10 @@ func (s *Stake) String() string {
 //
 
 type StakeHolder struct {
-	Holder common.Address
-	Stakes []*Stake
+	Holder       common.Address
+	Stakes       []*Stake
+	Jailed       bool                   `rlp:"optional"` // jailed holders are not selected as validators until they are unjailed
+	Registration *ValidatorRegistration `rlp:"optional"` // nil if the holder has not registered as a validator candidate
 }
 
 func newStakeHolder(holder common.Address, stakes []*Stake) *StakeHolder {
@@ -74,6 +76,17 @@ func (sh *StakeHolder) TotalStake() *big.Int {
 	return totalAmount
 }
 
+// SelfStake returns the active stake deposited by the holder itself
+func (sh *StakeHolder) SelfStake() *big.Int {
+	totalAmount := new(big.Int).SetUint64(0)
+	for _, stake := range sh.Stakes {
+		if !stake.Withdrawn && stake.Source == sh.Holder {
+			totalAmount = new(big.Int).Add(totalAmount, stake.Amount)
+		}
+	}
+	return totalAmount
+}
+
 func (sh *StakeHolder) depositStake(source common.Address, amount *big.Int) error {
 	if amount.Cmp(Zero) < 0 {
 		return fmt.Errorf("Invalid stake: %v", amount)
@@ -129,6 +142,58 @@ func (sh *StakeHolder) returnStake(source common.Address, currentHeight uint64)
 	return nil, fmt.Errorf("Cannot return, no matched stake source address found: %v", source)
 }
 
+// slashStakes reduces each active stake by slashPercent, and returns the amount slashed from each source
+func (sh *StakeHolder) slashStakes(slashPercent uint) map[common.Address]*big.Int {
+	slashed := make(map[common.Address]*big.Int)
+	for _, stake := range sh.Stakes {
+		if stake.Withdrawn {
+			continue
+		}
+		amount := new(big.Int).Mul(stake.Amount, new(big.Int).SetUint64(uint64(slashPercent)))
+		amount.Div(amount, big.NewInt(100))
+		stake.Amount = new(big.Int).Sub(stake.Amount, amount)
+		slashed[stake.Source] = amount
+	}
+	return slashed
+}
+
 func (sh *StakeHolder) String() string {
-	return fmt.Sprintf("{holder: %v, stakes :%v}", sh.Holder, sh.Stakes)
+	return fmt.Sprintf("{holder: %v, stakes :%v, jailed: %v, registration: %v}", sh.Holder, sh.Stakes, sh.Jailed, sh.Registration)
+}
+
+//
+// ------- ValidatorRegistration ------- //
+//
+
+const (
+	MaxValidatorCommissionPercent uint = 100
+	MaxValidatorMonikerLength     int  = 64
+	MaxValidatorWebsiteLength     int  = 128
+)
+
+// ValidatorRegistration carries the commission and the metadata of a validator candidate
+type ValidatorRegistration struct {
+	CommissionPercent uint         // share of the staking rewards kept by the holder before the rest is split among the stake sources
+	Moniker           string       // human-readable name
+	Website           string       // optional
+	ConsensusPubKey   common.Bytes // public key the validator signs the consensus messages with
+}
+
+// Validate checks the commission and the lengths of the metadata
+func (vr *ValidatorRegistration) Validate() error {
+	if vr.CommissionPercent > MaxValidatorCommissionPercent {
+		return fmt.Errorf("Invalid commission: %v%%", vr.CommissionPercent)
+	}
+	if len(vr.Moniker) == 0 || len(vr.Moniker) > MaxValidatorMonikerLength {
+		return fmt.Errorf("Moniker needs to be between 1 and %v bytes", MaxValidatorMonikerLength)
+	}
+	if len(vr.Website) > MaxValidatorWebsiteLength {
+		return fmt.Errorf("Website needs to be at most %v bytes", MaxValidatorWebsiteLength)
+	}
+	return nil
+}
+
+func (vr *ValidatorRegistration) String() string {
+	return fmt.Sprintf("{CommissionPercent: %v, Moniker: %v, Website: %v, ConsensusPubKey: %v}",
+		vr.CommissionPercent, vr.Moniker, vr.Website, vr.ConsensusPubKey)
 }
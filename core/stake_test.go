@@ -146,3 +146,29 @@ func TestStakeReturn(t *testing.T) {
 	assert.Nil(returnedStake) // sourceAddr3 never deposited any stake, so cannot return
 	assert.NotNil(err)
 }
+
+func TestStakeSlash(t *testing.T) {
+	assert := assert.New(t)
+
+	sourceAddr1 := common.HexToAddress("0x111")
+	sourceAddr2 := common.HexToAddress("0x222")
+	holderAddr := common.HexToAddress("0xabc")
+	vcp := &ValidatorCandidatePool{}
+	assert.Nil(vcp.DepositStake(sourceAddr1, holderAddr, new(big.Int).Mul(big.NewInt(1000), MinValidatorStakeDeposit)))
+	assert.Nil(vcp.DepositStake(sourceAddr2, holderAddr, new(big.Int).Mul(big.NewInt(3000), MinValidatorStakeDeposit)))
+	assert.Nil(vcp.WithdrawStake(sourceAddr2, holderAddr, 100))
+
+	_, err := vcp.SlashStakeHolder(holderAddr, 101)
+	assert.NotNil(err)
+	_, err = vcp.SlashStakeHolder(common.HexToAddress("0xdef"), 10)
+	assert.NotNil(err)
+
+	// Only the active stakes are slashed
+	slashed, err := vcp.SlashStakeHolder(holderAddr, 10)
+	assert.Nil(err)
+	assert.Equal(1, len(slashed))
+	assert.Equal(new(big.Int).Mul(big.NewInt(100), MinValidatorStakeDeposit), slashed[sourceAddr1])
+	stakeHolder := vcp.GetStakeHolder(holderAddr)
+	assert.Equal(new(big.Int).Mul(big.NewInt(900), MinValidatorStakeDeposit), stakeHolder.TotalStake())
+	assert.Equal(new(big.Int).Mul(big.NewInt(3000), MinValidatorStakeDeposit), stakeHolder.Stakes[1].Amount)
+}
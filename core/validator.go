@@ -169,23 +169,191 @@ func (s *ValidatorSet) Validators() []Validator {
 
 var (
 	MinValidatorStakeDeposit *big.Int
+	MinValidatorSelfStake    *big.Int
 )
 
 func init() {
 	// Each stake deposit needs to be at least 5,000,000 Theta
 	MinValidatorStakeDeposit = new(big.Int).Mul(new(big.Int).SetUint64(5000000), new(big.Int).SetUint64(1000000000000000000))
+
+	// A validator candidate needs a self-stake of at least 5,000,000 Theta to be selected, applied to
+	// the validator candidate pool at HeightEnableValidatorRegistration
+	MinValidatorSelfStake = new(big.Int).Mul(new(big.Int).SetUint64(5000000), new(big.Int).SetUint64(1000000000000000000))
 }
 
 type ValidatorCandidatePool struct {
 	SortedCandidates []*StakeHolder
+	MinSelfStake     *big.Int `rlp:"optional"` // nil until the validator registration is activated
+
+	// The validator set in effect once the delayed validator set hand-over is enabled, and the
+	// validator set it hands over to from the given block height and epoch
+	ActiveValidators        []Validator `rlp:"optional"`
+	PendingValidators       []Validator `rlp:"optional"`
+	PendingActivationHeight uint64      `rlp:"optional"`
+	PendingActivationEpoch  uint64      `rlp:"optional"`
+}
+
+// SelectTopValidators returns the validator set made of the eligible stake holders with the most stake
+func (vcp *ValidatorCandidatePool) SelectTopValidators(maxNumValidators int) *ValidatorSet {
+	valSet := NewValidatorSet()
+	for _, stakeHolder := range vcp.GetTopStakeHolders(maxNumValidators) {
+		valStake := stakeHolder.TotalStake()
+		if valStake.Cmp(Zero) == 0 {
+			continue
+		}
+		valSet.AddValidator(Validator{Address: stakeHolder.Holder, Stake: valStake})
+	}
+	return valSet
+}
+
+// HasActiveValidators returns true if the validator set is managed by the delayed hand-over
+func (vcp *ValidatorCandidatePool) HasActiveValidators() bool {
+	return len(vcp.ActiveValidators) > 0
+}
+
+// GetActiveValidatorSet returns the validator set in effect under the delayed hand-over
+func (vcp *ValidatorCandidatePool) GetActiveValidatorSet() *ValidatorSet {
+	valSet := NewValidatorSet()
+	valSet.SetValidators(copyValidators(vcp.ActiveValidators))
+	return valSet
+}
+
+// GetPendingValidatorSet returns the validator set the active validators hand over to, nil if none
+func (vcp *ValidatorCandidatePool) GetPendingValidatorSet() *ValidatorSet {
+	if len(vcp.PendingValidators) == 0 {
+		return nil
+	}
+	valSet := NewValidatorSet()
+	valSet.SetValidators(copyValidators(vcp.PendingValidators))
+	return valSet
 }
 
+// SetActiveValidatorSet sets the validator set in effect
+func (vcp *ValidatorCandidatePool) SetActiveValidatorSet(valSet *ValidatorSet) {
+	vcp.ActiveValidators = copyValidators(valSet.Validators())
+}
+
+// SetPendingValidatorSet schedules the hand-over to the given validator set, or cancels it if the set is nil
+func (vcp *ValidatorCandidatePool) SetPendingValidatorSet(valSet *ValidatorSet, activationHeight uint64, activationEpoch uint64) {
+	if valSet == nil {
+		vcp.PendingValidators = nil
+		vcp.PendingActivationHeight = 0
+		vcp.PendingActivationEpoch = 0
+		return
+	}
+	vcp.PendingValidators = copyValidators(valSet.Validators())
+	vcp.PendingActivationHeight = activationHeight
+	vcp.PendingActivationEpoch = activationEpoch
+}
+
+// GetTopStakeHolders returns the eligible stake holders with the most stake
 func (vcp *ValidatorCandidatePool) GetTopStakeHolders(maxNumStakeHolders int) []*StakeHolder {
-	n := len(vcp.SortedCandidates)
-	if n > maxNumStakeHolders {
-		n = maxNumStakeHolders
+	topStakeHolders := []*StakeHolder{}
+	for _, candidate := range vcp.SortedCandidates {
+		if len(topStakeHolders) >= maxNumStakeHolders {
+			break
+		}
+		if !vcp.IsEligible(candidate) {
+			continue
+		}
+		topStakeHolders = append(topStakeHolders, candidate)
+	}
+	return topStakeHolders
+}
+
+// IsEligible returns true if the stake holder can be selected as a validator. Jailed holders are not
+// eligible. Once the validator registration is activated, the holder also needs to be registered and
+// to have at least the minimal self-stake.
+func (vcp *ValidatorCandidatePool) IsEligible(candidate *StakeHolder) bool {
+	if candidate.Jailed {
+		return false
+	}
+	if vcp.MinSelfStake == nil {
+		return true
+	}
+	return candidate.Registration != nil && candidate.SelfStake().Cmp(vcp.MinSelfStake) >= 0
+}
+
+// ActivateRegistration requires the validator candidates to register and to keep the given minimal
+// self-stake from now on. The existing stake holders are registered without commission and metadata.
+func (vcp *ValidatorCandidatePool) ActivateRegistration(minSelfStake *big.Int) {
+	vcp.MinSelfStake = new(big.Int).Set(minSelfStake)
+	for _, candidate := range vcp.SortedCandidates {
+		if candidate.Registration == nil {
+			candidate.Registration = &ValidatorRegistration{}
+		}
+	}
+}
+
+// RegisterStakeHolder sets the commission and the metadata of the given stake holder
+func (vcp *ValidatorCandidatePool) RegisterStakeHolder(holder common.Address, registration *ValidatorRegistration) error {
+	candidate := vcp.GetStakeHolder(holder)
+	if candidate == nil {
+		return fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	if err := registration.Validate(); err != nil {
+		return err
+	}
+	if vcp.MinSelfStake != nil && candidate.SelfStake().Cmp(vcp.MinSelfStake) < 0 {
+		return fmt.Errorf("Insufficient self-stake: %v, at least %v is required", candidate.SelfStake(), vcp.MinSelfStake)
+	}
+	candidate.Registration = registration
+	return nil
+}
+
+// GetStakeHolder returns the stake holder with the given address, or nil if not found
+func (vcp *ValidatorCandidatePool) GetStakeHolder(holder common.Address) *StakeHolder {
+	for _, candidate := range vcp.SortedCandidates {
+		if candidate.Holder == holder {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// JailStakeHolder excludes the given stake holder from the validator selection
+func (vcp *ValidatorCandidatePool) JailStakeHolder(holder common.Address) error {
+	candidate := vcp.GetStakeHolder(holder)
+	if candidate == nil {
+		return fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	if candidate.Jailed {
+		return fmt.Errorf("Stake holder already jailed: %v", holder)
+	}
+	candidate.Jailed = true
+	return nil
+}
+
+// UnjailStakeHolder makes the given jailed stake holder eligible for the validator selection again
+func (vcp *ValidatorCandidatePool) UnjailStakeHolder(holder common.Address) error {
+	candidate := vcp.GetStakeHolder(holder)
+	if candidate == nil {
+		return fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	if !candidate.Jailed {
+		return fmt.Errorf("Stake holder not jailed: %v", holder)
+	}
+	candidate.Jailed = false
+	return nil
+}
+
+// SlashStakeHolder reduces the active stakes of the given stake holder by slashPercent, and returns the
+// amount slashed from each stake source. The withdrawn stakes waiting to be returned are not slashed.
+func (vcp *ValidatorCandidatePool) SlashStakeHolder(holder common.Address, slashPercent uint) (map[common.Address]*big.Int, error) {
+	if slashPercent > 100 {
+		return nil, fmt.Errorf("Invalid slash percentage: %v", slashPercent)
+	}
+	candidate := vcp.GetStakeHolder(holder)
+	if candidate == nil {
+		return nil, fmt.Errorf("No matched stake holder address found: %v", holder)
 	}
-	return vcp.SortedCandidates[:n]
+	slashed := candidate.slashStakes(slashPercent)
+
+	sort.Slice(vcp.SortedCandidates[:], func(i, j int) bool { // descending order
+		return vcp.SortedCandidates[i].TotalStake().Cmp(vcp.SortedCandidates[j].TotalStake()) >= 0
+	})
+
+	return slashed, nil
 }
 
 func (vcp *ValidatorCandidatePool) DepositStake(source common.Address, holder common.Address, amount *big.Int) (err error) {
@@ -274,3 +442,102 @@ func (vcp *ValidatorCandidatePool) ReturnStakes(currentHeight uint64) []*Stake {
 
 	return returnedStakes
 }
+
+// HandOverValidators returns the validator set one transition from the active set towards the target set.
+// The voting power gained or lost in the transition is capped at maxChangePercent of the voting power of the
+// active set. When the full change exceeds the cap, the voting power of each validator moves the same fraction
+// of the way, so the leaving validators fade out and the joining validators fade in over several transitions.
+// A maxChangePercent of 0 or at least 100 disables the cap.
+// The validators fading in and out add up, so the joining validators with the least stake in the target set
+// are held back until the leaving validators fade out, if needed to keep the number of validators within
+// maxNumValidators. The active set is never grown beyond maxNumValidators, but it can take a few transitions to
+// shrink an active set larger than that. A maxNumValidators of 0 disables the limit.
+func HandOverValidators(active *ValidatorSet, target *ValidatorSet, maxChangePercent uint, maxNumValidators int) *ValidatorSet {
+	next := handOverValidators(active, target, maxChangePercent)
+	if maxNumValidators <= 0 || next.Size() <= maxNumValidators {
+		return next
+	}
+	return handOverValidators(active, holdBackJoiningValidators(active, target, maxNumValidators-active.Size()), maxChangePercent)
+}
+
+// holdBackJoiningValidators returns the target set with at most numSlots of the validators not in the active set,
+// keeping the ones with the most stake
+func holdBackJoiningValidators(active *ValidatorSet, target *ValidatorSet, numSlots int) *ValidatorSet {
+	joining := []Validator{}
+	valSet := NewValidatorSet()
+	for _, v := range target.Validators() {
+		if _, err := active.GetValidator(v.Address); err == nil {
+			valSet.AddValidator(v)
+		} else {
+			joining = append(joining, v)
+		}
+	}
+	sort.SliceStable(joining, func(i, j int) bool { // descending order of stake, the ties in address order
+		return joining[i].Stake.Cmp(joining[j].Stake) > 0
+	})
+	for i := 0; i < numSlots && i < len(joining); i++ {
+		valSet.AddValidator(joining[i])
+	}
+	return valSet
+}
+
+func handOverValidators(active *ValidatorSet, target *ValidatorSet, maxChangePercent uint) *ValidatorSet {
+	activeTotal := active.TotalStake()
+	if maxChangePercent == 0 || maxChangePercent >= 100 || activeTotal.Sign() == 0 {
+		return target.Copy()
+	}
+
+	stakes := make(map[common.Address][2]*big.Int)
+	addrs := []common.Address{}
+	for _, v := range active.Validators() {
+		stakes[v.Address] = [2]*big.Int{v.Stake, Zero}
+		addrs = append(addrs, v.Address)
+	}
+	for _, v := range target.Validators() {
+		if s, ok := stakes[v.Address]; ok {
+			stakes[v.Address] = [2]*big.Int{s[0], v.Stake}
+			continue
+		}
+		stakes[v.Address] = [2]*big.Int{Zero, v.Stake}
+		addrs = append(addrs, v.Address)
+	}
+
+	gained, lost := new(big.Int), new(big.Int)
+	for _, addr := range addrs {
+		diff := new(big.Int).Sub(stakes[addr][1], stakes[addr][0])
+		if diff.Sign() > 0 {
+			gained.Add(gained, diff)
+		} else {
+			lost.Sub(lost, diff)
+		}
+	}
+	change := gained
+	if lost.Cmp(gained) > 0 {
+		change = lost
+	}
+	budget := new(big.Int).Mul(activeTotal, new(big.Int).SetUint64(uint64(maxChangePercent)))
+	budget.Quo(budget, big.NewInt(100))
+	if change.Cmp(budget) <= 0 {
+		return target.Copy()
+	}
+
+	valSet := NewValidatorSet()
+	for _, addr := range addrs {
+		diff := new(big.Int).Sub(stakes[addr][1], stakes[addr][0])
+		diff.Mul(diff, budget)
+		diff.Quo(diff, change)
+		stake := new(big.Int).Add(stakes[addr][0], diff)
+		if stake.Sign() > 0 {
+			valSet.AddValidator(Validator{Address: addr, Stake: stake})
+		}
+	}
+	return valSet
+}
+
+func copyValidators(validators []Validator) []Validator {
+	ret := make([]Validator, len(validators))
+	for i, v := range validators {
+		ret[i] = Validator{Address: v.Address, Stake: new(big.Int).Set(v.Stake)}
+	}
+	return ret
+}
@@ -301,6 +301,123 @@ func TestValidatorCandidatePool(t *testing.T) {
 	checkAndPrintTopCandidates(t, assert, vcp, 3)
 }
 
+func TestValidatorRegistration(t *testing.T) {
+	assert := assert.New(t)
+
+	holderA := common.HexToAddress("0x111")
+	holderB := common.HexToAddress("0x222")
+	holderC := common.HexToAddress("0x333")
+	delegator := common.HexToAddress("0x444")
+	minDeposit := MinValidatorStakeDeposit
+	minSelfStake := new(big.Int).Mul(minDeposit, big.NewInt(2))
+
+	vcp := &ValidatorCandidatePool{}
+	assert.Nil(vcp.DepositStake(holderA, holderA, minSelfStake))
+	assert.Nil(vcp.DepositStake(holderB, holderB, minDeposit))
+	assert.Nil(vcp.DepositStake(delegator, holderB, minSelfStake))
+	assert.Equal(2, len(vcp.GetTopStakeHolders(10)))
+
+	// The existing holders are registered on activation, B lacks the minimal self-stake
+	vcp.ActivateRegistration(minSelfStake)
+	top := vcp.GetTopStakeHolders(10)
+	assert.Equal(1, len(top))
+	assert.Equal(holderA, top[0].Holder)
+	assert.NotNil(vcp.GetStakeHolder(holderB).Registration)
+
+	// New holders need to register
+	assert.Nil(vcp.DepositStake(holderC, holderC, minSelfStake))
+	assert.Equal(1, len(vcp.GetTopStakeHolders(10)))
+	registration := &ValidatorRegistration{
+		CommissionPercent: 10,
+		Moniker:           "validator C",
+		Website:           "https://example.org",
+	}
+	assert.Nil(vcp.RegisterStakeHolder(holderC, registration))
+	assert.Equal(2, len(vcp.GetTopStakeHolders(10)))
+	assert.Equal("validator C", vcp.GetStakeHolder(holderC).Registration.Moniker)
+
+	// Invalid registrations
+	assert.NotNil(vcp.RegisterStakeHolder(holderB, registration))   // insufficient self-stake
+	assert.NotNil(vcp.RegisterStakeHolder(delegator, registration)) // not a stake holder
+	assert.NotNil(vcp.RegisterStakeHolder(holderA, &ValidatorRegistration{CommissionPercent: 101, Moniker: "A"}))
+	assert.NotNil(vcp.RegisterStakeHolder(holderA, &ValidatorRegistration{}))
+
+	// Withdrawing the self-stake makes the holder ineligible
+	assert.Nil(vcp.WithdrawStake(holderC, holderC, 100))
+	assert.Equal(1, len(vcp.GetTopStakeHolders(10)))
+}
+
+func TestHandOverValidators(t *testing.T) {
+	assert := assert.New(t)
+
+	active := NewValidatorSet()
+	active.AddValidator(NewValidator("0x1", big.NewInt(100)))
+	active.AddValidator(NewValidator("0x2", big.NewInt(100)))
+	active.AddValidator(NewValidator("0x3", big.NewInt(100)))
+	active.AddValidator(NewValidator("0x4", big.NewInt(100)))
+
+	// Changes within the cap take effect at once
+	target := active.Copy()
+	target.validators[0].Stake = big.NewInt(150)
+	assert.True(HandOverValidators(active, target, 25, 0).Equals(target))
+
+	// Replacing a validator moves 100 out of 400 in each direction, capped at 10% per transition
+	target = NewValidatorSet()
+	target.AddValidator(NewValidator("0x1", big.NewInt(100)))
+	target.AddValidator(NewValidator("0x2", big.NewInt(100)))
+	target.AddValidator(NewValidator("0x3", big.NewInt(100)))
+	target.AddValidator(NewValidator("0x5", big.NewInt(100)))
+	next := HandOverValidators(active, target, 10, 0)
+	assert.Equal(5, next.Size())
+	v, err := next.GetValidator(common.HexToAddress("0x4"))
+	assert.Nil(err)
+	assert.Equal(int64(60), v.Stake.Int64())
+	v, err = next.GetValidator(common.HexToAddress("0x5"))
+	assert.Nil(err)
+	assert.Equal(int64(40), v.Stake.Int64())
+
+	// The hand-over completes after a few transitions, and the cap can be disabled
+	for i := 0; i < 3; i++ {
+		next = HandOverValidators(next, target, 10, 0)
+	}
+	assert.True(next.Equals(target))
+	assert.True(HandOverValidators(active, target, 0, 0).Equals(target))
+
+	// With at most 5 validators, only one of the joining validators fades in while 0x3 and 0x4 fade out
+	target = NewValidatorSet()
+	target.AddValidator(NewValidator("0x1", big.NewInt(100)))
+	target.AddValidator(NewValidator("0x2", big.NewInt(100)))
+	target.AddValidator(NewValidator("0x5", big.NewInt(100)))
+	target.AddValidator(NewValidator("0x6", big.NewInt(150)))
+	assert.Equal(6, HandOverValidators(active, target, 10, 0).Size())
+	next = HandOverValidators(active, target, 10, 5)
+	assert.Equal(5, next.Size())
+	_, err = next.GetValidator(common.HexToAddress("0x5"))
+	assert.NotNil(err)
+	v, err = next.GetValidator(common.HexToAddress("0x6"))
+	assert.Nil(err)
+	assert.True(v.Stake.Sign() > 0)
+
+	// The held back validator joins once the leaving validators are gone, the set never exceeds the limit
+	for i := 0; i < 20 && !next.Equals(target); i++ {
+		next = HandOverValidators(next, target, 10, 5)
+		assert.True(next.Size() <= 5)
+	}
+	assert.True(next.Equals(target))
+
+	// An active set above the limit does not grow, and shrinks as the leaving validators fade out
+	next = HandOverValidators(active, target, 10, 3)
+	assert.Equal(4, next.Size())
+	for i := 0; i < 20; i++ {
+		size := next.Size()
+		next = HandOverValidators(next, target, 10, 3)
+		assert.True(next.Size() <= size || next.Size() <= 3)
+	}
+	assert.Equal(3, next.Size()) // the target set exceeds the limit by itself, 0x5 with the least stake is held back
+	_, err = next.GetValidator(common.HexToAddress("0x5"))
+	assert.NotNil(err)
+}
+
 // ------------------------- Utilities -------------------------
 
 func checkAndPrintAllSortedCandidates(t *testing.T, assert *assert.Assertions, vcp *ValidatorCandidatePool) {
@@ -225,19 +225,24 @@ func (sig *Signature) IsEmpty() bool {
 
 // RecoverSignerAddress recovers the address of the signer for the given message
 func (sig *Signature) RecoverSignerAddress(msg common.Bytes) (common.Address, error) {
-	msgHash := keccak256(msg)
-	recoveredUncompressedPubKey, err := ecrecover(msgHash, sig.ToBytes())
+	pk, err := sig.RecoverSignerPublicKey(msg)
 	if err != nil {
 		return common.Address{}, err
 	}
 
-	pk, err := PublicKeyFromBytes(recoveredUncompressedPubKey)
+	address := pk.Address()
+	return address, nil
+}
+
+// RecoverSignerPublicKey recovers the public key of the signer for the given message
+func (sig *Signature) RecoverSignerPublicKey(msg common.Bytes) (*PublicKey, error) {
+	msgHash := keccak256(msg)
+	recoveredUncompressedPubKey, err := ecrecover(msgHash, sig.ToBytes())
 	if err != nil {
-		return common.Address{}, err
+		return nil, err
 	}
 
-	address := pk.Address()
-	return address, nil
+	return PublicKeyFromBytes(recoveredUncompressedPubKey)
 }
 
 // Verify verifies the signature with given raw message and address.
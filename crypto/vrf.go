@@ -0,0 +1,170 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/math"
+)
+
+//
+// ----------------------- Verifiable Random Function ----------------------- //
+//
+// The VRF follows the construction of ECVRF on the secp256k1 curve, with
+// try-and-increment hashing to the curve and Keccak256 as the hash function.
+// For a given key and input, exactly one output can be proven, so the output
+// is unpredictable without the private key, and cannot be grinded by the
+// prover.
+//
+
+const (
+	// VRFProofLength is the length of a VRF proof: Gamma (compressed point) || c || s
+	VRFProofLength = 33 + 32 + 32
+
+	vrfSuite = byte(0xFE)
+)
+
+var errInvalidVRFProof = errors.New("Invalid VRF proof")
+
+// VRFProve returns the VRF proof of the input with the private key
+func (sk *PrivateKey) VRFProve(alpha common.Bytes) (common.Bytes, error) {
+	curve := s256()
+	n := curve.Params().N
+	pk := sk.PublicKey()
+
+	hx, hy, err := vrfHashToCurve(pk, alpha)
+	if err != nil {
+		return nil, err
+	}
+	gx, gy := curve.ScalarMult(hx, hy, scalarBytes(sk.D()))
+
+	seckey := math.PaddedBigBytes(sk.D(), 32)
+	defer zeroBytes(seckey)
+	k := new(big.Int).SetBytes(keccak256(seckey, compressPoint(hx, hy)))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		return nil, errors.New("Invalid VRF nonce")
+	}
+	ux, uy := curve.ScalarBaseMult(scalarBytes(k))
+	vx, vy := curve.ScalarMult(hx, hy, scalarBytes(k))
+
+	c := vrfHashPoints(hx, hy, gx, gy, ux, uy, vx, vy)
+	s := new(big.Int).Mul(c, sk.D())
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	proof := make([]byte, 0, VRFProofLength)
+	proof = append(proof, compressPoint(gx, gy)...)
+	proof = append(proof, scalarBytes(c)...)
+	proof = append(proof, scalarBytes(s)...)
+	return proof, nil
+}
+
+// VRFVerify checks the VRF proof of the input against the public key
+func (pk *PublicKey) VRFVerify(alpha common.Bytes, proof common.Bytes) bool {
+	if pk == nil || pk.IsEmpty() {
+		return false
+	}
+	gamma, c, s, err := decodeVRFProof(proof)
+	if err != nil {
+		return false
+	}
+
+	curve := s256()
+	hx, hy, err := vrfHashToCurve(pk, alpha)
+	if err != nil {
+		return false
+	}
+
+	// U = s*G - c*PK, V = s*H - c*Gamma
+	sgx, sgy := curve.ScalarBaseMult(scalarBytes(s))
+	cpx, cpy := curve.ScalarMult(pk.pubKey.X, pk.pubKey.Y, scalarBytes(c))
+	shx, shy := curve.ScalarMult(hx, hy, scalarBytes(s))
+	cgx, cgy := curve.ScalarMult(gamma.X, gamma.Y, scalarBytes(c))
+	if sgx == nil || cpx == nil || shx == nil || cgx == nil {
+		return false
+	}
+	// The points with the same x coordinate would sum up to infinity or need doubling,
+	// which never happens for a valid proof
+	if sgx.Cmp(cpx) == 0 || shx.Cmp(cgx) == 0 {
+		return false
+	}
+	ux, uy := curve.Add(sgx, sgy, cpx, negateY(cpy))
+	vx, vy := curve.Add(shx, shy, cgx, negateY(cgy))
+
+	return vrfHashPoints(hx, hy, gamma.X, gamma.Y, ux, uy, vx, vy).Cmp(c) == 0
+}
+
+// VRFProofToHash returns the VRF output of the proof. The proof needs to be
+// verified separately.
+func VRFProofToHash(proof common.Bytes) (common.Hash, error) {
+	if _, _, _, err := decodeVRFProof(proof); err != nil {
+		return common.Hash{}, err
+	}
+	return keccak256Hash([]byte{vrfSuite, 0x03}, proof[:33]), nil
+}
+
+func decodeVRFProof(proof common.Bytes) (gamma *curvePoint, c, s *big.Int, err error) {
+	if len(proof) != VRFProofLength {
+		return nil, nil, nil, errInvalidVRFProof
+	}
+	pub, err := decompressPubkey(proof[:33])
+	if err != nil {
+		return nil, nil, nil, errInvalidVRFProof
+	}
+	n := s256().Params().N
+	c = new(big.Int).SetBytes(proof[33:65])
+	s = new(big.Int).SetBytes(proof[65:])
+	if c.Sign() == 0 || s.Sign() == 0 || c.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return nil, nil, nil, errInvalidVRFProof
+	}
+	return &curvePoint{X: pub.X, Y: pub.Y}, c, s, nil
+}
+
+type curvePoint struct {
+	X, Y *big.Int
+}
+
+// vrfHashToCurve hashes the public key and the input to a curve point by try-and-increment
+func vrfHashToCurve(pk *PublicKey, alpha common.Bytes) (*big.Int, *big.Int, error) {
+	pkBytes := compressPubkey(pk.pubKey)
+	p := s256().Params().P
+	for ctr := 0; ctr < 256; ctr++ {
+		h := keccak256([]byte{vrfSuite, 0x01}, pkBytes, alpha, []byte{byte(ctr)})
+		if new(big.Int).SetBytes(h).Cmp(p) >= 0 {
+			continue
+		}
+		point, err := decompressPubkey(append([]byte{0x02}, h...))
+		if err == nil {
+			return point.X, point.Y, nil
+		}
+	}
+	return nil, nil, errors.New("Failed to hash the VRF input to the curve")
+}
+
+func vrfHashPoints(points ...*big.Int) *big.Int {
+	buf := bytes.NewBuffer([]byte{vrfSuite, 0x02})
+	for i := 0; i+1 < len(points); i += 2 {
+		buf.Write(compressPoint(points[i], points[i+1]))
+	}
+	c := new(big.Int).SetBytes(keccak256(buf.Bytes()))
+	return c.Mod(c, s256().Params().N)
+}
+
+func compressPoint(x, y *big.Int) []byte {
+	b := make([]byte, 33)
+	b[0] = 0x02 | byte(y.Bit(0))
+	copy(b[1:], math.PaddedBigBytes(x, 32))
+	return b
+}
+
+func negateY(y *big.Int) *big.Int {
+	p := s256().Params().P
+	return new(big.Int).Sub(p, y)
+}
+
+func scalarBytes(k *big.Int) []byte {
+	return math.PaddedBigBytes(k, 32)
+}
@@ -0,0 +1,71 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func TestVRF(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, pubKey, err := GenerateKeyPair()
+	assert.Nil(err)
+	alpha := common.Bytes("epoch 100")
+
+	proof, err := privKey.VRFProve(alpha)
+	assert.Nil(err)
+	assert.Equal(VRFProofLength, len(proof))
+	assert.True(pubKey.VRFVerify(alpha, proof))
+
+	// The output is unique for the key and the input
+	proof2, err := privKey.VRFProve(alpha)
+	assert.Nil(err)
+	output1, err := VRFProofToHash(proof)
+	assert.Nil(err)
+	output2, err := VRFProofToHash(proof2)
+	assert.Nil(err)
+	assert.Equal(output1, output2)
+
+	proof3, err := privKey.VRFProve(common.Bytes("epoch 101"))
+	assert.Nil(err)
+	output3, err := VRFProofToHash(proof3)
+	assert.Nil(err)
+	assert.NotEqual(output1, output3)
+
+	// Wrong input or key
+	assert.False(pubKey.VRFVerify(common.Bytes("epoch 101"), proof))
+	_, pubKey2, _ := GenerateKeyPair()
+	assert.False(pubKey2.VRFVerify(alpha, proof))
+
+	// Tampered proofs
+	for _, i := range []int{0, 10, 40, 80} {
+		tampered := append(common.Bytes{}, proof...)
+		tampered[i] ^= 0x01
+		assert.False(pubKey.VRFVerify(alpha, tampered))
+	}
+	assert.False(pubKey.VRFVerify(alpha, proof[:VRFProofLength-1]))
+	assert.False(pubKey.VRFVerify(alpha, make(common.Bytes, VRFProofLength)))
+	_, err = VRFProofToHash(common.Bytes{})
+	assert.NotNil(err)
+}
+
+func TestVRFProofWithDegeneratePoints(t *testing.T) {
+	assert := assert.New(t)
+
+	privKey, pubKey, err := GenerateKeyPair()
+	assert.Nil(err)
+	alpha := common.Bytes("epoch 100")
+	proof, err := privKey.VRFProve(alpha)
+	assert.Nil(err)
+
+	// The prover knows the private key, and can pick s = c*x so that U is the point at infinity
+	c := new(big.Int).SetBytes(proof[33:65])
+	s := new(big.Int).Mul(c, privKey.D())
+	s.Mod(s, s256().Params().N)
+	crafted := append(common.Bytes{}, proof[:65]...)
+	crafted = append(crafted, scalarBytes(s)...)
+	assert.False(pubKey.VRFVerify(alpha, crafted))
+}
@@ -52,6 +52,16 @@ func (dp *Dispatcher) Wait() {
 	dp.wg.Wait()
 }
 
+// Peers returns the IDs of the connected peers
+func (dp *Dispatcher) Peers() []string {
+	return dp.p2pnet.Peers()
+}
+
+// ReportPeer reports a misbehavior of the peer to the p2p network
+func (dp *Dispatcher) ReportPeer(peerID string, misbehavior p2ptypes.Misbehavior) {
+	dp.p2pnet.ReportPeer(peerID, misbehavior)
+}
+
 // GetInventory sends out the InventoryRequest
 func (dp *Dispatcher) GetInventory(peerIDs []string, invreq InventoryRequest) {
 	dp.send(peerIDs, invreq.ChannelID, invreq)
@@ -18,6 +18,7 @@ import (
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/node"
 	p2psim "github.com/thetatoken/theta/p2p/simulation"
+	"github.com/thetatoken/theta/signer"
 )
 
 func TestConsensusBaseCase(t *testing.T) {
@@ -53,7 +54,7 @@ func TestConsensusBaseCase(t *testing.T) {
 		root.Epoch = 0
 
 		params := &node.Params{
-			PrivateKey: privateKey,
+			Signer:     signer.NewLocalSigner(privateKey, nil),
 			DB:         db,
 			ChainID:    chainID,
 			Root:       root,
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/thetatoken/theta/consensus"
+)
+
+func handleError(err error) {
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Usage: inspect_wal -config=<path_to_config_home> [-file=<path_to_wal>] [-verbose]")
+}
+
+func main() {
+	configPathPtr := flag.String("config", "", "path to theta config home")
+	filePathPtr := flag.String("file", "", "path to the consensus WAL, i.e. the segment files without the index suffix, overrides the WAL under the config home")
+	verbosePtr := flag.Bool("verbose", false, "print the decoded votes, blocks and proposals")
+	flag.Parse()
+
+	walPath := *filePathPtr
+	if len(walPath) == 0 {
+		if len(*configPathPtr) == 0 {
+			printUsage()
+			os.Exit(1)
+		}
+		walPath = path.Join(*configPathPtr, "consensus", "wal")
+	}
+	if matches, _ := filepath.Glob(walPath + ".*"); len(matches) == 0 {
+		handleError(fmt.Errorf("no WAL segment found at %v", walPath))
+	}
+
+	numEntries := 0
+	err := consensus.ReadWAL(walPath, func(entry *consensus.WALEntry) {
+		timestamp := time.Unix(0, int64(entry.Timestamp)*int64(time.Millisecond)).UTC()
+		fmt.Printf("#%d %v %-13v epoch: %v, height: %v\n", numEntries, timestamp.Format(time.RFC3339Nano),
+			entry.Type, entry.Epoch, entry.Height)
+		if *verbosePtr {
+			msg, err := entry.DecodeMessage()
+			if err != nil {
+				fmt.Printf("    failed to decode: %v\n", err)
+			} else {
+				fmt.Printf("    %v\n", msg)
+			}
+		}
+		numEntries++
+	})
+	if err != nil {
+		fmt.Printf("The WAL is corrupted after entry #%d: %v\n", numEntries, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d entries\n", numEntries)
+
+	os.Exit(0)
+}
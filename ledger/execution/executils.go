@@ -192,6 +192,61 @@ func validateInputAdvanced(acc *types.Account, signBytes []byte, in types.TxInpu
 	return result.OK
 }
 
+// getTxInputs returns the inputs of the given tx, i.e. the accounts that signed the tx
+func getTxInputs(tx types.Tx) []types.TxInput {
+	switch tx := tx.(type) {
+	case *types.CoinbaseTx:
+		return []types.TxInput{tx.Proposer}
+	case *types.SlashTx:
+		return []types.TxInput{tx.Proposer}
+	case *types.SendTx:
+		return tx.Inputs
+	case *types.ReserveFundTx:
+		return []types.TxInput{tx.Source}
+	case *types.ReleaseFundTx:
+		return []types.TxInput{tx.Source}
+	case *types.ServicePaymentTx:
+		return []types.TxInput{tx.Source, tx.Target}
+	case *types.SplitRuleTx:
+		return []types.TxInput{tx.Initiator}
+	case *types.SmartContractTx:
+		return []types.TxInput{tx.From}
+	case *types.DepositStakeTx:
+		return []types.TxInput{tx.Source}
+	case *types.WithdrawStakeTx:
+		return []types.TxInput{tx.Source}
+	case *types.UnjailTx:
+		return []types.TxInput{tx.Holder}
+	case *types.ClaimStakeRewardTx:
+		return []types.TxInput{tx.Source}
+	case *types.RegisterValidatorTx:
+		return []types.TxInput{tx.Holder}
+	default:
+		return nil
+	}
+}
+
+// checkTxExpiry rejects the tx if any of its inputs has an expiry height lower than
+// the height of the view. An expiry height of 0 means the input never expires. Inputs
+// with an expiry height are not allowed before HeightEnableTxExpiry
+func checkTxExpiry(view *state.StoreView, tx types.Tx) result.Result {
+	height := view.Height()
+	for _, in := range getTxInputs(tx) {
+		if in.ExpiryHeight == 0 {
+			continue
+		}
+		if height < common.HeightEnableTxExpiry {
+			return result.Error("Tx expiry height not supported until block height %v",
+				common.HeightEnableTxExpiry)
+		}
+		if height > in.ExpiryHeight {
+			return result.Error("Tx expired at height %v, current height: %v",
+				in.ExpiryHeight, height).WithErrorCode(result.CodeTxExpired)
+		}
+	}
+	return result.OK
+}
+
 func validateOutputsBasic(outs []types.TxOutput) result.Result {
 	for _, out := range outs {
 		// Check TxOutput basic
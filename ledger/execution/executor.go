@@ -37,8 +37,11 @@ type Executor struct {
 	servicePaymentTxExec *ServicePaymentTxExecutor
 	splitRuleTxExec      *SplitRuleTxExecutor
 	//smartContractTxExec  *SmartContractTxExecutor
-	depositStakeTxExec  *DepositStakeExecutor
-	withdrawStakeTxExec *WithdrawStakeExecutor
+	depositStakeTxExec      *DepositStakeExecutor
+	withdrawStakeTxExec     *WithdrawStakeExecutor
+	unjailTxExec            *UnjailExecutor
+	claimStakeRewardTxExec  *ClaimStakeRewardExecutor
+	registerValidatorTxExec *RegisterValidatorExecutor
 
 	skipSanityCheck bool
 }
@@ -57,9 +60,12 @@ func NewExecutor(state *st.LedgerState, consensus core.ConsensusEngine, valMgr c
 		servicePaymentTxExec: NewServicePaymentTxExecutor(state),
 		splitRuleTxExec:      NewSplitRuleTxExecutor(state),
 		//smartContractTxExec:  NewSmartContractTxExecutor(state),
-		depositStakeTxExec:  NewDepositStakeExecutor(),
-		withdrawStakeTxExec: NewWithdrawStakeExecutor(state),
-		skipSanityCheck:     false,
+		depositStakeTxExec:      NewDepositStakeExecutor(),
+		withdrawStakeTxExec:     NewWithdrawStakeExecutor(state),
+		unjailTxExec:            NewUnjailExecutor(),
+		claimStakeRewardTxExec:  NewClaimStakeRewardExecutor(),
+		registerValidatorTxExec: NewRegisterValidatorExecutor(),
+		skipSanityCheck:         false,
 	}
 
 	return executor
@@ -73,17 +79,61 @@ func (exec *Executor) SetSkipSanityCheck(skip bool) {
 
 // ExecuteTx executes the given transaction
 func (exec *Executor) ExecuteTx(tx types.Tx) (common.Hash, result.Result) {
-	return exec.processTx(tx, core.DeliveredView)
+	return exec.processTx(tx, nil, core.DeliveredView)
 }
 
 // CheckTx checks the validity of the given transaction
 func (exec *Executor) CheckTx(tx types.Tx) (common.Hash, result.Result) {
-	return exec.processTx(tx, core.CheckedView)
+	return exec.processTx(tx, nil, core.CheckedView)
 }
 
 // ScreenTx checks the validity of the given transaction
 func (exec *Executor) ScreenTx(tx types.Tx) (common.Hash, result.Result) {
-	return exec.processTx(tx, core.ScreenedView)
+	return exec.processTx(tx, nil, core.ScreenedView)
+}
+
+// ExecuteBlockTx executes the given transaction of the block. The coinbase transaction of the block
+// distributes the fees to the voters of the HCC carried by the block.
+func (exec *Executor) ExecuteBlockTx(tx types.Tx, block *core.Block) (common.Hash, result.Result) {
+	return exec.processTx(tx, block, core.DeliveredView)
+}
+
+// CheckBlockTx checks the validity of the given transaction of the block being proposed
+func (exec *Executor) CheckBlockTx(tx types.Tx, block *core.Block) (common.Hash, result.Result) {
+	return exec.processTx(tx, block, core.CheckedView)
+}
+
+// ScreenTxReplacement checks the validity of the given transaction, which is meant to replace a
+// pending transaction with the same sequence number. The screened view already reflects the pending
+// transaction, so the check is performed against the account state right before the pending
+// transaction instead, i.e. a copy of the delivered view with the preceding pending transactions
+// of the same account (in sequence order) applied.
+func (exec *Executor) ScreenTxReplacement(tx types.Tx, precedingTxs []types.Tx) (*core.TxInfo, result.Result) {
+	txInfo, res := exec.GetTxInfo(tx)
+	if res.IsError() {
+		return nil, res
+	}
+
+	view, err := exec.state.Delivered().Copy()
+	if err != nil {
+		return nil, result.Error("Failed to copy the delivered view: %v", err)
+	}
+
+	chainID := exec.state.GetChainID()
+	for _, precedingTx := range precedingTxs {
+		if res := exec.sanityCheck(chainID, view, precedingTx, nil); res.IsError() {
+			return nil, result.Error("Failed to replay the preceding pending transaction: %v", res.Message)
+		}
+		if _, res := exec.process(chainID, view, precedingTx); res.IsError() {
+			return nil, result.Error("Failed to replay the preceding pending transaction: %v", res.Message)
+		}
+	}
+
+	res = exec.sanityCheck(chainID, view, tx, nil)
+	if res.IsError() {
+		return nil, res
+	}
+	return txInfo, result.OK
 }
 
 // GetTxInfo extracts tx information used by mempool to sort Txs.
@@ -98,7 +148,7 @@ func (exec *Executor) GetTxInfo(tx types.Tx) (*core.TxInfo, result.Result) {
 }
 
 // processTx contains the main logic to process the transaction. If the tx is invalid, a TMSP error will be returned.
-func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.Hash, result.Result) {
+func (exec *Executor) processTx(tx types.Tx, block *core.Block, viewSel core.ViewSelector) (common.Hash, result.Result) {
 	chainID := exec.state.GetChainID()
 	var view *st.StoreView
 	switch viewSel {
@@ -110,7 +160,7 @@ func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.
 		view = exec.state.Screened()
 	}
 
-	sanityCheckResult := exec.sanityCheck(chainID, view, tx)
+	sanityCheckResult := exec.sanityCheck(chainID, view, tx, block)
 	if sanityCheckResult.IsError() {
 		return common.Hash{}, sanityCheckResult
 	}
@@ -119,11 +169,19 @@ func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.
 	return txHash, processResult
 }
 
-func (exec *Executor) sanityCheck(chainID string, view *st.StoreView, tx types.Tx) result.Result {
+func (exec *Executor) sanityCheck(chainID string, view *st.StoreView, tx types.Tx, block *core.Block) result.Result {
 	if exec.skipSanityCheck { // Skip checks, e.g. while replaying commmitted blocks.
 		return result.OK
 	}
 
+	if res := checkTxExpiry(view, tx); res.IsError() {
+		return res
+	}
+
+	if coinbaseTx, ok := tx.(*types.CoinbaseTx); ok {
+		return exec.coinbaseTxExec.sanityCheckForBlock(chainID, view, coinbaseTx, block)
+	}
+
 	var sanityCheckResult result.Result
 	txExecutor := exec.getTxExecutor(tx)
 	if txExecutor != nil {
@@ -171,6 +229,12 @@ func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 		txExecutor = exec.depositStakeTxExec
 	case *types.WithdrawStakeTx:
 		txExecutor = exec.withdrawStakeTxExec
+	case *types.UnjailTx:
+		txExecutor = exec.unjailTxExec
+	case *types.ClaimStakeRewardTx:
+		txExecutor = exec.claimStakeRewardTxExec
+	case *types.RegisterValidatorTx:
+		txExecutor = exec.registerValidatorTxExec
 	default:
 		txExecutor = nil
 	}
@@ -6,8 +6,12 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database/backend"
 )
 
 func TestGetInputs(t *testing.T) {
@@ -279,6 +283,109 @@ func TestSendTx(t *testing.T) {
 		"ExecTx/good DeliverTx: unexpected change in output balance, got: %v, expected: %v", balOut, balOutExp)
 }
 
+func TestSendTxExpiry(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	et.acc2State(et.accIn)
+	et.acc2State(et.accOut)
+	height := et.state().Height()
+
+	enableHeight := common.HeightEnableTxExpiry
+	defer func() {
+		common.HeightEnableTxExpiry = enableHeight
+	}()
+
+	//Expiry height not supported before the fork
+	common.HeightEnableTxExpiry = height + 1
+	tx := types.MakeSendTx(1, et.accOut, et.accIn)
+	tx.Inputs[0].ExpiryHeight = height + 10
+	et.signSendTx(tx, et.accIn)
+	res, _, _, _, _ := et.execSendTx(tx, true)
+	assert.True(res.IsError(), "ExecTx/Expiry before fork CheckTx: Expected error")
+	assert.NotEqual(result.CodeTxExpired, res.Code)
+
+	common.HeightEnableTxExpiry = 0
+
+	//Expired tx
+	tx = types.MakeSendTx(1, et.accOut, et.accIn)
+	tx.Inputs[0].ExpiryHeight = height - 1
+	et.signSendTx(tx, et.accIn)
+	res, _, _, _, _ = et.execSendTx(tx, true)
+	assert.Equal(result.CodeTxExpired, res.Code, "ExecTx/Expired CheckTx: Expected tx expired error, returned: %v", res)
+	res, _, _, _, _ = et.execSendTx(tx, false)
+	assert.Equal(result.CodeTxExpired, res.Code, "ExecTx/Expired DeliverTx: Expected tx expired error, returned: %v", res)
+
+	//Tx valid up to the expiry height
+	tx = types.MakeSendTx(1, et.accOut, et.accIn)
+	tx.Inputs[0].ExpiryHeight = height
+	et.signSendTx(tx, et.accIn)
+	res, _, _, _, _ = et.execSendTx(tx, false)
+	assert.True(res.IsOK(), "ExecTx/Unexpired DeliverTx: Expected OK return from ExecTx, Error: %v", res)
+}
+
+func TestScreenTxReplacement(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	// accIn can afford only one of the transactions
+	minFee := getMinimumTxFee()
+	et.accIn.Balance = types.NewCoins(6, 3*minFee)
+	et.acc2State(et.accIn)
+	et.acc2State(et.accOut)
+
+	tx1 := types.MakeSendTx(1, et.accOut, et.accIn)
+	et.signSendTx(tx1, et.accIn)
+	_, res := et.executor.ScreenTx(tx1)
+	assert.True(res.IsOK(), "ScreenTx: Expected OK, Error: %v", res)
+
+	// Fee bump of the pending tx
+	tx1b := types.MakeSendTx(1, et.accOut, et.accIn)
+	tx1b.Fee = types.NewCoins(0, 2*minFee)
+	tx1b.Inputs[0].Coins = types.NewCoins(4, 2*minFee)
+	et.signSendTx(tx1b, et.accIn)
+	_, res = et.executor.ScreenTx(tx1b)
+	assert.Equal(result.CodeInvalidSequence, res.Code)
+	txInfo, res := et.executor.ScreenTxReplacement(tx1b, []types.Tx{})
+	assert.True(res.IsOK(), "ScreenTxReplacement: Expected OK, Error: %v", res)
+	assert.Equal(uint64(1), txInfo.Sequence)
+
+	// The account cannot afford the replacement on top of the preceding pending tx
+	tx2 := types.MakeSendTx(2, et.accOut, et.accIn)
+	et.signSendTx(tx2, et.accIn)
+	_, res = et.executor.ScreenTxReplacement(tx2, []types.Tx{tx1})
+	assert.True(res.IsError(), "ScreenTxReplacement: Expected insufficient fund error")
+
+	// Nothing to replace without the preceding pending tx
+	_, res = et.executor.ScreenTxReplacement(tx2, []types.Tx{})
+	assert.Equal(result.CodeInvalidSequence, res.Code)
+}
+
+func TestGetTxInputs(t *testing.T) {
+	assert := assert.New(t)
+
+	// The expiry height of the inputs is only checked for the tx types covered by getTxInputs()
+	txs := []types.Tx{
+		&types.CoinbaseTx{},
+		&types.SlashTx{},
+		&types.SendTx{Inputs: []types.TxInput{{}}},
+		&types.ReserveFundTx{},
+		&types.ReleaseFundTx{},
+		&types.ServicePaymentTx{},
+		&types.SplitRuleTx{},
+		&types.SmartContractTx{},
+		&types.DepositStakeTx{},
+		&types.WithdrawStakeTx{},
+		&types.UnjailTx{},
+		&types.ClaimStakeRewardTx{},
+		&types.RegisterValidatorTx{},
+	}
+	assert.Equal(int(types.TxRegisterValidator)+1, len(txs))
+	for _, tx := range txs {
+		assert.NotEmpty(getTxInputs(tx), "getTxInputs: no inputs for %T", tx)
+	}
+}
+
 // func TestCalculateThetaReward(t *testing.T) {
 // 	assert := assert.New(t)
 
@@ -451,6 +558,231 @@ func TestCoinbaseTx(t *testing.T) {
 	// assert.Equal(int64(0), user1balance.TFuelWei.Int64())
 }
 
+func TestCalculateReward(t *testing.T) {
+	assert := assert.New(t)
+
+	holderA := common.HexToAddress("0x1")
+	holderB := common.HexToAddress("0x2")
+	delegator := common.HexToAddress("0x3")
+	minDeposit := core.MinValidatorStakeDeposit
+	vcp := &core.ValidatorCandidatePool{}
+	assert.Nil(vcp.DepositStake(holderA, holderA, minDeposit))
+	assert.Nil(vcp.DepositStake(delegator, holderA, minDeposit))
+	assert.Nil(vcp.DepositStake(holderB, holderB, new(big.Int).Mul(minDeposit, big.NewInt(2))))
+
+	height := common.HeightEnableValidatorRewards
+	view := st.NewStoreView(height, common.Hash{}, backend.NewMemDatabase())
+	view.UpdateValidatorCandidatePool(vcp)
+	validators := []common.Address{holderA, holderB}
+
+	collectFee(view, types.NewCoins(0, 600))
+	collectFee(view, types.NewCoins(0, 400))
+	assert.Equal(types.NewCoins(0, 1000), view.GetFeePool())
+
+	// Block rewards pro rata to the stakes, 800 of the fees shared by the voting validators weighted
+	// by stake, and 200 for the proposer
+	assert.Equal(uint(20), common.ProposerFeePercent)
+	doubleStake := new(big.Int).Mul(minDeposit, big.NewInt(2))
+	doubleThetaReward := mulDiv(doubleStake, types.ValidatorThetaGenerationRateNumerator, types.ValidatorThetaGenerationRateDenominator)
+	doubleBlockReward := mulDiv(doubleStake, types.ValidatorTFuelGenerationRateNumerator, types.ValidatorTFuelGenerationRateDenominator)
+	rewards := CalculateReward(view, holderA, validators, validators)
+	assert.Equal(2, len(rewards))
+	expected := map[common.Address]*big.Int{
+		holderA: new(big.Int).Add(doubleBlockReward, big.NewInt(600)),
+		holderB: new(big.Int).Add(doubleBlockReward, big.NewInt(400)),
+	}
+	for addr, tfuel := range expected {
+		reward, ok := rewards[string(addr[:])]
+		assert.True(ok)
+		assert.True(reward.IsEqual(types.Coins{ThetaWei: doubleThetaReward, TFuelWei: tfuel}), "%v: %v", addr.Hex(), reward)
+	}
+
+	// The fee share of holder B goes to the proposer if B did not vote in the HCC
+	block := core.NewBlock()
+	block.HCC.Votes = core.NewVoteSet()
+	block.HCC.Votes.AddVote(core.Vote{ID: holderA, Epoch: 1})
+	block.HCC.Votes.AddVote(core.Vote{ID: holderA, Epoch: 2})
+	assert.Equal([]common.Address{holderA}, HCCVoters(block))
+	noVoteRewards := CalculateReward(view, holderA, validators, HCCVoters(block))
+	assert.Equal(new(big.Int).Add(doubleBlockReward, big.NewInt(1000)), noVoteRewards[string(holderA[:])].TFuelWei)
+	assert.Equal(doubleBlockReward, noVoteRewards[string(holderB[:])].TFuelWei)
+	assert.Equal(0, len(HCCVoters(nil)))
+
+	// The rewards of holder A accrue to its stake sources pro rata
+	rewardA := rewards[string(holderA[:])]
+	assert.True(accrueStakeReward(view, vcp.GetStakeHolder(holderA), rewardA))
+	halfRewardA := new(big.Int).Div(rewardA.TFuelWei, big.NewInt(2))
+	for _, stake := range vcp.GetStakeHolder(holderA).Stakes {
+		pending, claimed, _ := PendingStakeReward(view, holderA, stake)
+		assert.Equal(halfRewardA, pending.TFuelWei)
+		assert.True(claimed.IsZero())
+	}
+
+	// A deposit settles the rewards earned so far, the new stake only earns the later rewards
+	settleStakeReward(view, holderA, delegator, minDeposit)
+	assert.Nil(vcp.DepositStake(delegator, holderA, minDeposit))
+	assert.True(accrueStakeReward(view, vcp.GetStakeHolder(holderA), types.NewCoins(0, 3000)))
+	paid := payStakeReward(view, holderA, delegator, activeStakeAmount(vcp, holderA, delegator))
+	assert.Equal(new(big.Int).Add(halfRewardA, big.NewInt(2000)), paid.TFuelWei)
+	paid = payStakeReward(view, holderA, holderA, activeStakeAmount(vcp, holderA, holderA))
+	assert.Equal(new(big.Int).Add(halfRewardA, big.NewInt(1000)), paid.TFuelWei)
+
+	// Nothing left to claim after the payout
+	for _, stake := range vcp.GetStakeHolder(holderA).Stakes {
+		pending, claimed, _ := PendingStakeReward(view, holderA, stake)
+		assert.True(pending.IsZero())
+		assert.False(claimed.IsZero())
+	}
+
+	// The commission is taken from the whole reward, so that only the delegated stakes pay it
+	stakeHolderA := vcp.GetStakeHolder(holderA)
+	assert.True(calculateCommission(stakeHolderA, types.NewCoins(0, 1000)).IsZero())
+	stakeHolderA.Registration = &core.ValidatorRegistration{CommissionPercent: 10, Moniker: "A"}
+	assert.Equal(big.NewInt(100), calculateCommission(stakeHolderA, types.NewCoins(0, 1000)).TFuelWei)
+
+	// No rewards before the fork height
+	view = st.NewStoreView(height-1, common.Hash{}, backend.NewMemDatabase())
+	view.UpdateValidatorCandidatePool(vcp)
+	collectFee(view, types.NewCoins(0, 1000))
+	assert.True(view.GetFeePool().IsZero())
+	rewards = CalculateReward(view, holderA, validators, validators)
+	assert.Equal(2, len(rewards))
+	for _, reward := range rewards {
+		assert.True(reward.IsZero())
+	}
+}
+
+func TestStakeRewardLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	minDeposit := core.MinValidatorStakeDeposit
+	txFee := getMinimumTxFee()
+	initBalance := types.Coins{ThetaWei: new(big.Int).Mul(minDeposit, big.NewInt(2)), TFuelWei: big.NewInt(10 * txFee)}
+	holder := et.accProposer
+	holder.Balance = initBalance
+	delegator := types.MakeAccWithInitBalance("delegator", initBalance)
+	et.acc2State(holder, delegator)
+	et.state().Delivered().UpdateValidatorCandidatePool(&core.ValidatorCandidatePool{})
+	et.fastforwardTo(common.HeightEnableValidatorRewards)
+
+	balance := func(acc types.PrivAccount) types.Coins {
+		return et.state().Delivered().GetAccount(acc.Address).Balance
+	}
+	stakeReward := func() (pending, claimed types.Coins, slashed *big.Int) {
+		view := et.state().Delivered()
+		stakeHolder := view.GetValidatorCandidatePool().GetStakeHolder(holder.Address)
+		for _, stake := range stakeHolder.Stakes {
+			if stake.Source == delegator.Address {
+				return PendingStakeReward(view, holder.Address, stake)
+			}
+		}
+		assert.Fail("Stake of the delegator not found")
+		return
+	}
+	executeCoinbaseTx := func() {
+		view := et.state().Delivered()
+		validators := getValidatorAddresses(et.executor.consensus, et.executor.valMgr)
+		block := core.NewBlock()
+		block.HCC.Votes = core.NewVoteSet()
+		for _, validator := range validators {
+			block.HCC.Votes.AddVote(core.Vote{ID: validator, Epoch: 1})
+		}
+		tx := &types.CoinbaseTx{
+			Proposer:    types.TxInput{Address: holder.Address},
+			BlockHeight: et.state().Height(),
+		}
+		for addr, reward := range CalculateReward(view, holder.Address, validators, HCCVoters(block)) {
+			tx.Outputs = append(tx.Outputs, types.TxOutput{Address: common.BytesToAddress([]byte(addr)), Coins: reward})
+		}
+		tx.Proposer.Signature = holder.Sign(tx.SignBytes(et.chainID))
+		_, res := et.executor.ExecuteBlockTx(tx, block)
+		assert.True(res.IsOK(), res.String())
+	}
+
+	// Deposit: the holder and the delegator stake the same amount
+	for _, acc := range []types.PrivAccount{holder, delegator} {
+		tx := &types.DepositStakeTx{
+			Fee:     types.NewCoins(0, txFee),
+			Source:  types.TxInput{Address: acc.Address, Coins: types.Coins{ThetaWei: minDeposit, TFuelWei: big.NewInt(0)}, Sequence: 1},
+			Holder:  types.TxOutput{Address: holder.Address},
+			Purpose: core.StakeForValidator,
+		}
+		tx.Source.Signature = acc.Sign(tx.SignBytes(et.chainID))
+		_, res := et.executor.ExecuteTx(tx)
+		assert.True(res.IsOK(), res.String())
+	}
+	expectedBalance := types.Coins{ThetaWei: minDeposit, TFuelWei: big.NewInt(9 * txFee)}
+	assert.True(expectedBalance.IsEqual(balance(delegator)), balance(delegator).String())
+	assert.Equal(types.NewCoins(0, 2*txFee), et.state().Delivered().GetFeePool())
+
+	// Coinbase accrual: the fees of the deposits go to the stake of the proposer, shared by its sources
+	// pro rata, without touching their accounts
+	executeCoinbaseTx()
+	assert.True(et.state().Delivered().GetFeePool().IsZero())
+	assert.True(expectedBalance.IsEqual(balance(delegator)), balance(delegator).String())
+	pending, claimed, slashed := stakeReward()
+	assert.Equal(types.NewCoins(0, txFee), pending)
+	assert.True(claimed.IsZero())
+	assert.Equal(0, slashed.Sign())
+
+	// Claim: the pending rewards are credited to the delegator
+	et.fastforwardBy(1)
+	claimTx := &types.ClaimStakeRewardTx{
+		Fee:    types.NewCoins(0, txFee),
+		Source: types.TxInput{Address: delegator.Address, Sequence: 2},
+		Holder: types.TxOutput{Address: holder.Address},
+	}
+	claimTx.Source.Signature = delegator.Sign(claimTx.SignBytes(et.chainID))
+	_, res := et.executor.ExecuteTx(claimTx)
+	assert.True(res.IsOK(), res.String())
+	assert.True(expectedBalance.IsEqual(balance(delegator)), balance(delegator).String()) // fee paid by the reward
+	pending, claimed, _ = stakeReward()
+	assert.True(pending.IsZero())
+	assert.Equal(types.NewCoins(0, txFee), claimed)
+
+	// Slashing: the rewards earned before are settled, and the later rewards accrue to the reduced stakes
+	view := et.state().Delivered()
+	vcp := view.GetValidatorCandidatePool()
+	total, err := SlashStake(view, vcp, holder.Address, 10)
+	assert.Nil(err)
+	view.UpdateValidatorCandidatePool(vcp)
+	slashedAmount := new(big.Int).Div(minDeposit, big.NewInt(10))
+	assert.Equal(new(big.Int).Mul(slashedAmount, big.NewInt(2)), total)
+	executeCoinbaseTx()
+	stakeAmount := new(big.Int).Sub(minDeposit, slashedAmount)
+	index := scaleCoins(types.NewCoins(0, txFee), types.StakeRewardPrecision, new(big.Int).Mul(stakeAmount, big.NewInt(2)))
+	expectedReward := scaleCoins(index, stakeAmount, types.StakeRewardPrecision)
+	pending, claimed, slashed = stakeReward()
+	assert.Equal(expectedReward, pending)
+	assert.Equal(types.NewCoins(0, txFee), claimed)
+	assert.Equal(slashedAmount, slashed)
+
+	// Withdraw: the pending rewards are paid out, the slashed stake is returned after the locking period
+	et.fastforwardBy(1)
+	withdrawTx := &types.WithdrawStakeTx{
+		Fee:     types.NewCoins(0, txFee),
+		Source:  types.TxInput{Address: delegator.Address, Sequence: 3},
+		Holder:  types.TxOutput{Address: holder.Address},
+		Purpose: core.StakeForValidator,
+	}
+	withdrawTx.Source.Signature = delegator.Sign(withdrawTx.SignBytes(et.chainID))
+	_, res = et.executor.ExecuteTx(withdrawTx)
+	assert.True(res.IsOK(), res.String())
+	expectedBalance = expectedBalance.Plus(expectedReward).Minus(types.NewCoins(0, txFee))
+	assert.True(expectedBalance.IsEqual(balance(delegator)), balance(delegator).String())
+	pending, claimed, slashed = stakeReward()
+	assert.True(pending.IsZero())
+	assert.Equal(types.NewCoins(0, txFee).Plus(expectedReward), claimed)
+	assert.Equal(slashedAmount, slashed)
+
+	vcp = et.state().Delivered().GetValidatorCandidatePool()
+	returnedStakes := vcp.ReturnStakes(et.state().Height() + core.ReturnLockingPeriod)
+	assert.Equal(1, len(returnedStakes))
+	assert.Equal(delegator.Address, returnedStakes[0].Source)
+	assert.Equal(stakeAmount, returnedStakes[0].Amount)
+}
+
 func TestReserveFundTx(t *testing.T) {
 	assert := assert.New(t)
 	et := NewExecTest()
@@ -0,0 +1,241 @@
+package execution
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// CalculateReward calculates the block reward for each account. Starting from HeightEnableValidatorRewards,
+// each validator receives the block reward for its stake, and the validators among the voters of the
+// HCC carried by the block share the collected fees not kept by the proposer. The share of the validators
+// not voting goes to the proposer. The rewards of a validator with stake accrue to the sources of its
+// stakes, see accrueStakeReward().
+func CalculateReward(view *st.StoreView, proposer common.Address, validatorAddresses []common.Address,
+	voters []common.Address) map[string]types.Coins {
+	accountReward := map[string]types.Coins{}
+	for _, validatorAddress := range validatorAddresses {
+		zeroReward := types.Coins{}.NoNil()
+		accountReward[string(validatorAddress[:])] = zeroReward
+	}
+
+	if view.Height() < common.HeightEnableValidatorRewards {
+		// Initial Mainnet release should not reward the validators until the guardians ready to deploy
+		return accountReward
+	}
+
+	voted := make(map[common.Address]bool)
+	for _, voter := range voters {
+		voted[voter] = true
+	}
+	stakeHolders := []*core.StakeHolder{}
+	totalStake := new(big.Int)
+	if vcp := view.GetValidatorCandidatePool(); vcp != nil {
+		for _, validatorAddress := range validatorAddresses {
+			stakeHolder := vcp.GetStakeHolder(validatorAddress)
+			if stakeHolder == nil {
+				continue
+			}
+			stakeHolders = append(stakeHolders, stakeHolder)
+			totalStake.Add(totalStake, stakeHolder.TotalStake())
+		}
+	}
+
+	// Block rewards for the stakes
+	for _, stakeHolder := range stakeHolders {
+		stake := stakeHolder.TotalStake()
+		reward := types.Coins{
+			ThetaWei: mulDiv(stake, types.ValidatorThetaGenerationRateNumerator, types.ValidatorThetaGenerationRateDenominator),
+			TFuelWei: mulDiv(stake, types.ValidatorTFuelGenerationRateNumerator, types.ValidatorTFuelGenerationRateDenominator),
+		}
+		addReward(accountReward, stakeHolder.Holder, reward)
+	}
+
+	// Collected fees, the shares of the validators not voting and the rounding remainder go to the proposer
+	fees := view.GetFeePool()
+	if fees.IsPositive() {
+		proposerFees := fees.CalculatePercentage(common.ProposerFeePercent)
+		validatorFees := fees.Minus(proposerFees)
+		distributed := types.NewCoins(0, 0)
+		if totalStake.Sign() > 0 {
+			for _, stakeHolder := range stakeHolders {
+				if !voted[stakeHolder.Holder] {
+					continue
+				}
+				share := scaleCoins(validatorFees, stakeHolder.TotalStake(), totalStake)
+				addReward(accountReward, stakeHolder.Holder, share)
+				distributed = distributed.Plus(share)
+			}
+		}
+		proposerFees = proposerFees.Plus(validatorFees.Minus(distributed))
+		addReward(accountReward, proposer, proposerFees)
+	}
+
+	return accountReward
+}
+
+// HCCVoters returns the voters of the HCC carried by the block, who share the collected fees
+func HCCVoters(block *core.Block) []common.Address {
+	voters := []common.Address{}
+	if block == nil || block.HCC.Votes == nil {
+		return voters
+	}
+	for _, vote := range block.HCC.Votes.UniqueVoter().Votes() {
+		voters = append(voters, vote.ID)
+	}
+	return voters
+}
+
+// collectFee adds the fee of a successfully processed transaction to the fee pool, to be distributed
+// by the coinbase transaction of the next block
+func collectFee(view *st.StoreView, fee types.Coins) {
+	if view.Height() < common.HeightEnableValidatorRewards {
+		return // burned
+	}
+	view.SetFeePool(view.GetFeePool().Plus(fee))
+}
+
+// accrueStakeReward adds the reward to the reward index of the stake holder, so that the sources of
+// its stakes earn the reward pro rata without touching their accounts. The rounding remainder is burned.
+// It returns false if the holder has no active stake to accrue to.
+func accrueStakeReward(view *st.StoreView, stakeHolder *core.StakeHolder, reward types.Coins) bool {
+	totalStake := stakeHolder.TotalStake()
+	if totalStake.Sign() <= 0 {
+		return false
+	}
+	delta := scaleCoins(reward, types.StakeRewardPrecision, totalStake)
+	index := view.GetStakeRewardIndex(stakeHolder.Holder)
+	view.SetStakeRewardIndex(stakeHolder.Holder, index.Plus(delta))
+	return true
+}
+
+// calculateCommission returns the share of the reward kept by the stake holder. Taking it from the whole
+// reward before the rest accrues to all the stakes pro rata charges the commission on the delegated stakes only.
+func calculateCommission(stakeHolder *core.StakeHolder, reward types.Coins) types.Coins {
+	if stakeHolder.Registration == nil {
+		return types.NewCoins(0, 0)
+	}
+	return reward.CalculatePercentage(stakeHolder.Registration.CommissionPercent)
+}
+
+// settleStakeReward settles the reward account of the stake deposited by the source to the holder. The
+// amount is the active stake of the source since the last settlement, and must be settled before it changes.
+func settleStakeReward(view *st.StoreView, holder common.Address, source common.Address, amount *big.Int) *types.StakeRewardAccount {
+	index := view.GetStakeRewardIndex(holder)
+	sra := getStakeRewardAccount(view, holder, source)
+	sra.Settle(index, amount)
+	view.SetStakeRewardAccount(holder, source, sra)
+	return sra
+}
+
+// getStakeRewardAccount returns the reward account of the stake. The reward indices start from zero at
+// HeightEnableValidatorRewards and the accounts are created when the stakes are deposited, so a missing
+// account belongs to a stake deposited before that height, which earns from index zero.
+func getStakeRewardAccount(view *st.StoreView, holder common.Address, source common.Address) *types.StakeRewardAccount {
+	sra := view.GetStakeRewardAccount(holder, source)
+	if sra == nil {
+		sra = types.NewStakeRewardAccount(types.NewCoins(0, 0))
+	}
+	return sra
+}
+
+// payStakeReward settles the reward account of the stake and marks the accrued rewards as paid out. The
+// caller needs to credit the returned rewards to the source account.
+func payStakeReward(view *st.StoreView, holder common.Address, source common.Address, amount *big.Int) types.Coins {
+	sra := settleStakeReward(view, holder, source, amount)
+	paid := sra.PayOut()
+	view.SetStakeRewardAccount(holder, source, sra)
+	return paid
+}
+
+// activeStakeAmount returns the active stake deposited by the source to the holder
+func activeStakeAmount(vcp *core.ValidatorCandidatePool, holder common.Address, source common.Address) *big.Int {
+	stakeHolder := vcp.GetStakeHolder(holder)
+	if stakeHolder == nil {
+		return new(big.Int)
+	}
+	for _, stake := range stakeHolder.Stakes {
+		if stake.Source == source && !stake.Withdrawn {
+			return new(big.Int).Set(stake.Amount)
+		}
+	}
+	return new(big.Int)
+}
+
+// SlashStake slashes the active stakes of the holder by slashPercent. The reward accounts of the stake
+// sources are settled before the stakes shrink, and record the amounts slashed. The slashed ThetaWei is
+// burned. The caller needs to update the validator candidate pool of the view.
+func SlashStake(view *st.StoreView, vcp *core.ValidatorCandidatePool, holder common.Address, slashPercent uint) (*big.Int, error) {
+	stakeHolder := vcp.GetStakeHolder(holder)
+	if stakeHolder == nil {
+		return nil, fmt.Errorf("No matched stake holder address found: %v", holder)
+	}
+	for _, stake := range stakeHolder.Stakes {
+		if !stake.Withdrawn {
+			settleStakeReward(view, holder, stake.Source, stake.Amount)
+		}
+	}
+
+	slashed, err := vcp.SlashStakeHolder(holder, slashPercent)
+	if err != nil {
+		return nil, err
+	}
+	total := new(big.Int)
+	for source, amount := range slashed {
+		sra := getStakeRewardAccount(view, holder, source)
+		sra.Slash(amount)
+		view.SetStakeRewardAccount(holder, source, sra)
+		total.Add(total, amount)
+	}
+	return total, nil
+}
+
+// PendingStakeReward returns the rewards earned by the stake deposited by the source to the holder
+// but not paid out yet, the rewards paid out so far, and the ThetaWei slashed from the stake so far
+func PendingStakeReward(view *st.StoreView, holder common.Address, stake *core.Stake) (pending types.Coins, claimed types.Coins, slashed *big.Int) {
+	sra := getStakeRewardAccount(view, holder, stake.Source)
+	amount := new(big.Int)
+	if !stake.Withdrawn {
+		amount = stake.Amount
+	}
+	slashed = new(big.Int)
+	if sra.Slashed != nil {
+		slashed.Set(sra.Slashed)
+	}
+	return sra.Pending(view.GetStakeRewardIndex(holder), amount), sra.Claimed.NoNil(), slashed
+}
+
+func addReward(accountReward map[string]types.Coins, addr common.Address, reward types.Coins) {
+	if !reward.IsPositive() {
+		return
+	}
+	key := string(addr[:])
+	if existing, ok := accountReward[key]; ok {
+		reward = existing.Plus(reward)
+	}
+	accountReward[key] = reward
+}
+
+// scaleCoins returns coins * num / denom
+func scaleCoins(coins types.Coins, num, denom *big.Int) types.Coins {
+	c := coins.NoNil()
+	theta := new(big.Int).Mul(c.ThetaWei, num)
+	tfuel := new(big.Int).Mul(c.TFuelWei, num)
+	return types.Coins{
+		ThetaWei: theta.Div(theta, denom),
+		TFuelWei: tfuel.Div(tfuel, denom),
+	}
+}
+
+// mulDiv returns amount * num / denom, or zero if denom is not positive
+func mulDiv(amount *big.Int, num, denom int64) *big.Int {
+	if denom <= 0 {
+		return new(big.Int)
+	}
+	ret := new(big.Int).Mul(amount, big.NewInt(num))
+	return ret.Div(ret, big.NewInt(denom))
+}
@@ -17,6 +17,7 @@ import (
 	st "github.com/thetatoken/theta/ledger/state"
 
 	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/store/database/backend"
 )
 
@@ -27,7 +28,7 @@ type TestConsensusEngine struct {
 }
 
 func (tce *TestConsensusEngine) ID() string                        { return tce.privKey.PublicKey().Address().Hex() }
-func (tce *TestConsensusEngine) PrivateKey() *crypto.PrivateKey    { return tce.privKey }
+func (tce *TestConsensusEngine) Signer() core.Signer               { return signer.NewLocalSigner(tce.privKey, nil) }
 func (tce *TestConsensusEngine) GetTip(bool) *core.ExtendedBlock   { return nil }
 func (tce *TestConsensusEngine) GetEpoch() uint64                  { return 100 }
 func (tce *TestConsensusEngine) AddMessage(msg interface{})        {}
@@ -36,6 +37,9 @@ func (tce *TestConsensusEngine) GetLedger() core.Ledger            { return nil
 func (tce *TestConsensusEngine) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return &core.ExtendedBlock{}
 }
+func (tce *TestConsensusEngine) FindBlock(common.Hash) (*core.ExtendedBlock, error) {
+	return &core.ExtendedBlock{}, nil
+}
 
 func NewTestConsensusEngine(seed string) *TestConsensusEngine {
 	privKey, _, _ := crypto.TEST_GenerateKeyPairWithSeed(seed)
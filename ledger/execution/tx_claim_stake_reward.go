@@ -0,0 +1,123 @@
+package execution
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*ClaimStakeRewardExecutor)(nil)
+
+// ------------------------------- ClaimStakeReward Transaction -----------------------------------
+
+// ClaimStakeRewardExecutor implements the TxExecutor interface
+type ClaimStakeRewardExecutor struct {
+}
+
+// NewClaimStakeRewardExecutor creates a new instance of ClaimStakeRewardExecutor
+func NewClaimStakeRewardExecutor() *ClaimStakeRewardExecutor {
+	return &ClaimStakeRewardExecutor{}
+}
+
+func (exec *ClaimStakeRewardExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.ClaimStakeRewardTx)
+
+	if view.Height() < common.HeightEnableValidatorRewards {
+		return result.Error("ClaimStakeReward transaction not supported until block height %v",
+			common.HeightEnableValidatorRewards)
+	}
+
+	res := tx.Source.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	sourceAccount, success := getInput(view, tx.Source)
+	if success.IsError() {
+		return result.Error("Failed to get the source account: %v", tx.Source.Address)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(sourceAccount, signBytes, tx.Source)
+	if res.IsError() {
+		logger.Infof(fmt.Sprintf("validateSourceAdvanced failed on %v: %v", tx.Source.Address.Hex(), res))
+		return res
+	}
+
+	if !sanityCheckForFee(tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return result.Error("Validator candidate pool not found")
+	}
+	if view.GetStakeRewardAccount(tx.Holder.Address, tx.Source.Address) == nil &&
+		activeStakeAmount(vcp, tx.Holder.Address, tx.Source.Address).Sign() == 0 {
+		return result.Error("No stake rewards for source %v and holder %v", tx.Source.Address, tx.Holder.Address)
+	}
+
+	minimalBalance := tx.Fee
+	if !sourceAccount.Balance.IsGTE(minimalBalance) {
+		logger.Infof(fmt.Sprintf("ClaimStakeReward: Source did not have enough balance %v", tx.Source.Address.Hex()))
+		return result.Error("ClaimStakeReward: Source balance is %v, but required minimal balance is %v",
+			sourceAccount.Balance, minimalBalance)
+	}
+
+	return result.OK
+}
+
+func (exec *ClaimStakeRewardExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.ClaimStakeRewardTx)
+
+	sourceAccount, success := getInput(view, tx.Source)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the source account")
+	}
+
+	if !chargeFee(sourceAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	sourceAddress := tx.Source.Address
+	holderAddress := tx.Holder.Address
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}, result.Error("Validator candidate pool not found")
+	}
+
+	amount := activeStakeAmount(vcp, holderAddress, sourceAddress)
+	paid := payStakeReward(view, holderAddress, sourceAddress, amount)
+	sourceAccount.Balance = sourceAccount.Balance.Plus(paid)
+
+	sourceAccount.Sequence++
+	view.SetAccount(sourceAddress, sourceAccount)
+
+	collectFee(view, tx.Fee)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *ClaimStakeRewardExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.ClaimStakeRewardTx)
+	return &core.TxInfo{
+		Address:           tx.Source.Address,
+		Sequence:          tx.Source.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+	}
+}
+
+func (exec *ClaimStakeRewardExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.ClaimStakeRewardTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasClaimStakeRewardTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
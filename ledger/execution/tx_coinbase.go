@@ -31,7 +31,12 @@ func NewCoinbaseTxExecutor(state *st.LedgerState, consensus core.ConsensusEngine
 }
 
 func (exec *CoinbaseTxExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
-	tx := transaction.(*types.CoinbaseTx)
+	return exec.sanityCheckForBlock(chainID, view, transaction.(*types.CoinbaseTx), nil)
+}
+
+// sanityCheckForBlock checks the coinbase transaction of the given block, the collected fees are
+// distributed to the voters of the HCC carried by the block
+func (exec *CoinbaseTxExecutor) sanityCheckForBlock(chainID string, view *st.StoreView, tx *types.CoinbaseTx, block *core.Block) result.Result {
 	validatorAddresses := getValidatorAddresses(exec.consensus, exec.valMgr)
 
 	// Validate proposer, basic
@@ -74,7 +79,7 @@ func (exec *CoinbaseTxExecutor) sanityCheck(chainID string, view *st.StoreView,
 	}
 
 	// check the reward amount
-	expectedRewards := CalculateReward(view, validatorAddresses)
+	expectedRewards := CalculateReward(view, tx.Proposer.Address, validatorAddresses, HCCVoters(block))
 	if len(expectedRewards) != len(tx.Outputs) {
 		return result.Error("Number of rewarded account is incorrect")
 	}
@@ -101,33 +106,46 @@ func (exec *CoinbaseTxExecutor) process(chainID string, view *st.StoreView, tran
 		return common.Hash{}, res
 	}
 
+	rewardsEnabled := view.Height() >= common.HeightEnableValidatorRewards
+	var vcp *core.ValidatorCandidatePool
+	if rewardsEnabled {
+		vcp = view.GetValidatorCandidatePool()
+	}
+
 	for _, output := range tx.Outputs {
+		coins := output.Coins
+
+		// The rewards of a stake holder accrue to the sources of its stakes, except for the commission
+		if vcp != nil && coins.IsPositive() {
+			stakeHolder := vcp.GetStakeHolder(output.Address)
+			if stakeHolder != nil {
+				commission := calculateCommission(stakeHolder, coins)
+				if accrueStakeReward(view, stakeHolder, coins.Minus(commission)) {
+					if !commission.IsPositive() {
+						continue
+					}
+					coins = commission
+				}
+			}
+		}
+
 		addr := string(output.Address[:])
 		if account, exists := accounts[addr]; exists {
-			account.Balance = account.Balance.Plus(output.Coins)
+			account.Balance = account.Balance.Plus(coins)
 			view.SetAccount(output.Address, account)
 		}
 	}
 
+	if rewardsEnabled {
+		view.SetFeePool(types.NewCoins(0, 0)) // distributed by the outputs
+	}
+
 	view.SetCoinbaseTransactionProcessed(true)
 
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
 
-// CalculateReward calculates the block reward for each account
-func CalculateReward(view *st.StoreView, validatorAddresses []common.Address) map[string]types.Coins {
-	accountReward := map[string]types.Coins{}
-
-	for _, validatorAddress := range validatorAddresses {
-		// Initial Mainnet release should not reward the validators until the guardians ready to deploy
-		zeroReward := types.Coins{}.NoNil()
-		accountReward[string(validatorAddress[:])] = zeroReward
-	}
-
-	return accountReward
-}
-
 func (exec *CoinbaseTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
 	return &core.TxInfo{
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
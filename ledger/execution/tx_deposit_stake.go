@@ -105,11 +105,15 @@ func (exec *DepositStakeExecutor) process(chainID string, view *st.StoreView, tr
 		sourceAccount.Balance = sourceAccount.Balance.Minus(stake)
 		stakeAmount := stake.ThetaWei
 		vcp := view.GetValidatorCandidatePool()
+		prevAmount := activeStakeAmount(vcp, holderAddress, sourceAddress)
 		err := vcp.DepositStake(sourceAddress, holderAddress, stakeAmount)
 		if err != nil {
 			return common.Hash{}, result.Error("Failed to deposit stake, err: %v", err)
 		}
 		view.UpdateValidatorCandidatePool(vcp)
+		if view.Height() >= common.HeightEnableValidatorRewards {
+			settleStakeReward(view, holderAddress, sourceAddress, prevAmount) // the stake amount changes
+		}
 	} else if tx.Purpose == core.StakeForGuardian {
 		return common.Hash{}, result.Error("Staking for guardian not supported yet")
 	} else {
@@ -126,6 +130,8 @@ func (exec *DepositStakeExecutor) process(chainID string, view *st.StoreView, tr
 	sourceAccount.Sequence++
 	view.SetAccount(sourceAddress, sourceAccount)
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
@@ -0,0 +1,150 @@
+package execution
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*RegisterValidatorExecutor)(nil)
+
+// ------------------------------- RegisterValidator Transaction -----------------------------------
+
+// RegisterValidatorExecutor implements the TxExecutor interface
+type RegisterValidatorExecutor struct {
+}
+
+// NewRegisterValidatorExecutor creates a new instance of RegisterValidatorExecutor
+func NewRegisterValidatorExecutor() *RegisterValidatorExecutor {
+	return &RegisterValidatorExecutor{}
+}
+
+func (exec *RegisterValidatorExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.RegisterValidatorTx)
+
+	if view.Height() < common.HeightEnableValidatorRegistration {
+		return result.Error("RegisterValidator transaction not supported until block height %v",
+			common.HeightEnableValidatorRegistration)
+	}
+
+	res := tx.Holder.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	holderAccount, success := getInput(view, tx.Holder)
+	if success.IsError() {
+		return result.Error("Failed to get the holder account: %v", tx.Holder.Address)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(holderAccount, signBytes, tx.Holder)
+	if res.IsError() {
+		logger.Infof(fmt.Sprintf("validateSourceAdvanced failed on %v: %v", tx.Holder.Address.Hex(), res))
+		return res
+	}
+
+	if !sanityCheckForFee(tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if err := newValidatorRegistration(tx).Validate(); err != nil {
+		return result.Error("Invalid validator registration: %v", err)
+	}
+	if _, err := crypto.PublicKeyFromBytes(tx.ConsensusPubKey); err != nil {
+		return result.Error("Invalid consensus public key: %v", err)
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return result.Error("Validator candidate pool not found")
+	}
+	holder := vcp.GetStakeHolder(tx.Holder.Address)
+	if holder == nil {
+		return result.Error("Stake holder %v not found, the self-stake needs to be deposited first", tx.Holder.Address)
+	}
+	if vcp.MinSelfStake != nil && holder.SelfStake().Cmp(vcp.MinSelfStake) < 0 {
+		return result.Error("Insufficient self-stake: %v, at least %v ThetaWei is required", holder.SelfStake(), vcp.MinSelfStake)
+	}
+
+	minimalBalance := tx.Fee
+	if !holderAccount.Balance.IsGTE(minimalBalance) {
+		logger.Infof(fmt.Sprintf("RegisterValidator: Holder did not have enough balance %v", tx.Holder.Address.Hex()))
+		return result.Error("RegisterValidator: Holder balance is %v, but required minimal balance is %v",
+			holderAccount.Balance, minimalBalance)
+	}
+
+	return result.OK
+}
+
+func (exec *RegisterValidatorExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.RegisterValidatorTx)
+
+	holderAccount, success := getInput(view, tx.Holder)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the holder account")
+	}
+
+	if !chargeFee(holderAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	holderAddress := tx.Holder.Address
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}, result.Error("Validator candidate pool not found")
+	}
+	err := vcp.RegisterStakeHolder(holderAddress, newValidatorRegistration(tx))
+	if err != nil {
+		return common.Hash{}, result.Error("Failed to register validator, err: %v", err)
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+
+	holderAccount.Sequence++
+	view.SetAccount(holderAddress, holderAccount)
+
+	collectFee(view, tx.Fee)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *RegisterValidatorExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.RegisterValidatorTx)
+	return &core.TxInfo{
+		Address:           tx.Holder.Address,
+		Sequence:          tx.Holder.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+	}
+}
+
+func (exec *RegisterValidatorExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.RegisterValidatorTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasRegisterValidatorTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
+
+func newValidatorRegistration(tx *types.RegisterValidatorTx) *core.ValidatorRegistration {
+	return &core.ValidatorRegistration{
+		CommissionPercent: tx.CommissionPercent,
+		Moniker:           tx.Moniker,
+		Website:           tx.Website,
+		ConsensusPubKey:   tx.ConsensusPubKey,
+	}
+}
@@ -95,6 +95,8 @@ func (exec *ReleaseFundTxExecutor) process(chainID string, view *st.StoreView, t
 	sourceAccount.Sequence++
 	view.SetAccount(sourceAddress, sourceAccount)
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
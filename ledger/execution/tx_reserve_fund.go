@@ -111,6 +111,8 @@ func (exec *ReserveFundTxExecutor) process(chainID string, view *st.StoreView, t
 	sourceAccount.Sequence++
 	view.SetAccount(sourceAddress, sourceAccount)
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
@@ -96,6 +96,8 @@ func (exec *SendTxExecutor) process(chainID string, view *st.StoreView, transact
 	adjustByInputs(view, accounts, tx.Inputs)
 	adjustByOutputs(view, accounts, tx.Outputs)
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
@@ -145,6 +145,8 @@ func (exec *ServicePaymentTxExecutor) process(chainID string, view *st.StoreView
 		view.SetAccount(address, account)
 	}
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
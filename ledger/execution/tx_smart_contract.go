@@ -114,6 +114,8 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 	}
 	view.SetAccount(fromAddress, fromAccount)
 
+	collectFee(view, fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
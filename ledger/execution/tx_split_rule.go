@@ -138,6 +138,8 @@ func (exec *SplitRuleTxExecutor) process(chainID string, view *st.StoreView, tra
 	initiatorAccount.Sequence++
 	view.SetAccount(tx.Initiator.Address, initiatorAccount)
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
@@ -0,0 +1,131 @@
+package execution
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ TxExecutor = (*UnjailExecutor)(nil)
+
+// ------------------------------- Unjail Transaction -----------------------------------
+
+// UnjailExecutor implements the TxExecutor interface
+type UnjailExecutor struct {
+}
+
+// NewUnjailExecutor creates a new instance of UnjailExecutor
+func NewUnjailExecutor() *UnjailExecutor {
+	return &UnjailExecutor{}
+}
+
+func (exec *UnjailExecutor) sanityCheck(chainID string, view *st.StoreView, transaction types.Tx) result.Result {
+	tx := transaction.(*types.UnjailTx)
+
+	if view.Height() < common.HeightEnableValidatorLiveness {
+		return result.Error("Unjail transaction not supported until block height %v",
+			common.HeightEnableValidatorLiveness)
+	}
+
+	res := tx.Holder.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	holderAccount, success := getInput(view, tx.Holder)
+	if success.IsError() {
+		return result.Error("Failed to get the holder account: %v", tx.Holder.Address)
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(holderAccount, signBytes, tx.Holder)
+	if res.IsError() {
+		logger.Infof(fmt.Sprintf("validateSourceAdvanced failed on %v: %v", tx.Holder.Address.Hex(), res))
+		return res
+	}
+
+	if !sanityCheckForFee(tx.Fee) {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v TFuelWei",
+			types.MinimumTransactionFeeTFuelWei).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return result.Error("Validator candidate pool not found")
+	}
+	holder := vcp.GetStakeHolder(tx.Holder.Address)
+	if holder == nil || !holder.Jailed {
+		return result.Error("Stake holder %v is not jailed", tx.Holder.Address)
+	}
+
+	minimalBalance := tx.Fee
+	if !holderAccount.Balance.IsGTE(minimalBalance) {
+		logger.Infof(fmt.Sprintf("Unjail: Holder did not have enough balance %v", tx.Holder.Address.Hex()))
+		return result.Error("Unjail: Holder balance is %v, but required minimal balance is %v",
+			holderAccount.Balance, minimalBalance)
+	}
+
+	return result.OK
+}
+
+func (exec *UnjailExecutor) process(chainID string, view *st.StoreView, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.UnjailTx)
+
+	holderAccount, success := getInput(view, tx.Holder)
+	if success.IsError() {
+		return common.Hash{}, result.Error("Failed to get the holder account")
+	}
+
+	if !chargeFee(holderAccount, tx.Fee) {
+		return common.Hash{}, result.Error("Failed to charge transaction fee")
+	}
+
+	holderAddress := tx.Holder.Address
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return common.Hash{}, result.Error("Validator candidate pool not found")
+	}
+	err := vcp.UnjailStakeHolder(holderAddress)
+	if err != nil {
+		return common.Hash{}, result.Error("Failed to unjail, err: %v", err)
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+	view.DeleteValidatorLiveness(holderAddress) // start over with a fresh window
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+
+	holderAccount.Sequence++
+	view.SetAccount(holderAddress, holderAccount)
+
+	collectFee(view, tx.Fee)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *UnjailExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.UnjailTx)
+	return &core.TxInfo{
+		Address:           tx.Holder.Address,
+		Sequence:          tx.Holder.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+	}
+}
+
+func (exec *UnjailExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.UnjailTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(types.GasUnjailTx)
+	effectiveGasPrice := new(big.Int).Div(fee.TFuelWei, gas)
+	return effectiveGasPrice
+}
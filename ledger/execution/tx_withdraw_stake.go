@@ -88,11 +88,17 @@ func (exec *WithdrawStakeExecutor) process(chainID string, view *st.StoreView, t
 	if tx.Purpose == core.StakeForValidator {
 		vcp := view.GetValidatorCandidatePool()
 		currentHeight := exec.state.Height()
+		prevAmount := activeStakeAmount(vcp, holderAddress, sourceAddress)
 		err := vcp.WithdrawStake(sourceAddress, holderAddress, currentHeight)
 		if err != nil {
 			return common.Hash{}, result.Error("Failed to withdraw stake, err: %v", err)
 		}
 		view.UpdateValidatorCandidatePool(vcp)
+		if view.Height() >= common.HeightEnableValidatorRewards {
+			// The withdrawn stake stops earning, pay out the rewards earned so far
+			paid := payStakeReward(view, holderAddress, sourceAddress, prevAmount)
+			sourceAccount.Balance = sourceAccount.Balance.Plus(paid)
+		}
 	} else if tx.Purpose == core.StakeForGuardian {
 		return common.Hash{}, result.Error("Withdraw stake for guardian not supported yet")
 	} else {
@@ -109,6 +115,8 @@ func (exec *WithdrawStakeExecutor) process(chainID string, view *st.StoreView, t
 	sourceAccount.Sequence++
 	view.SetAccount(sourceAddress, sourceAccount)
 
+	collectFee(view, tx.Fee)
+
 	txHash := types.TxID(chainID, tx)
 	return txHash, result.OK
 }
@@ -153,9 +153,48 @@ func (ledger *Ledger) ScreenTx(rawTx common.Bytes) (txInfo *core.TxInfo, res res
 	return txInfo, res
 }
 
-// ProposeBlockTxs collects and executes a list of transactions, which will be used to assemble the next blockl
+// ScreenTxReplacement screens the given transaction which is meant to replace a pending
+// transaction with the same sequence number. precedingRawTxs are the pending transactions of
+// the same account with lower sequence numbers, sorted by sequence.
+func (ledger *Ledger) ScreenTxReplacement(rawTx common.Bytes, precedingRawTxs []common.Bytes) (txInfo *core.TxInfo, res result.Result) {
+	var tx types.Tx
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, result.Error("Error decoding tx: %v", err)
+	}
+
+	if ledger.shouldSkipCheckTx(tx) {
+		return nil, result.Error("Unauthorized transaction, should skip").
+			WithErrorCode(result.CodeUnauthorizedTx)
+	}
+
+	precedingTxs := []types.Tx{}
+	for _, precedingRawTx := range precedingRawTxs {
+		precedingTx, err := types.TxFromBytes(precedingRawTx)
+		if err != nil {
+			return nil, result.Error("Error decoding preceding tx: %v", err)
+		}
+		precedingTxs = append(precedingTxs, precedingTx)
+	}
+
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
+
+	return ledger.executor.ScreenTxReplacement(tx, precedingTxs)
+}
+
+// GetTxInfo decodes the raw transaction and returns its information, e.g. the effective gas price
+func (ledger *Ledger) GetTxInfo(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, result.Error("Error decoding tx: %v", err)
+	}
+	return ledger.executor.GetTxInfo(tx)
+}
+
+// ProposeBlockTxs collects and executes a list of transactions, which will be used to assemble the given block.
 // It also clears these transactions from the mempool.
-func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+func (ledger *Ledger) ProposeBlockTxs(block *core.Block) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
 	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
 	// Otherwise, could cause deadlock since mempool.InsertTransaction() also first acquires the mempool, and then the ledger lock
 	ledger.mempool.Lock()
@@ -168,7 +207,7 @@ func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs
 
 	// Add special transactions
 	rawTxCandidates := []common.Bytes{}
-	ledger.addSpecialTransactions(view, &rawTxCandidates)
+	ledger.addSpecialTransactions(view, block, &rawTxCandidates)
 
 	// Add regular transactions submitted by the clients
 	regularRawTxs := ledger.mempool.ReapUnsafe(core.MaxNumRegularTxsPerBlock)
@@ -182,7 +221,7 @@ func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs
 		if err != nil {
 			continue
 		}
-		_, res := ledger.executor.CheckTx(tx)
+		_, res := ledger.executor.CheckBlockTx(tx, block)
 		if res.IsError() {
 			logger.Errorf("Transaction check failed: errMsg = %v, tx = %v", res.Message, tx)
 			continue
@@ -190,6 +229,9 @@ func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs
 		blockRawTxs = append(blockRawTxs, rawTxCandidate)
 	}
 
+	ledger.updateValidatorLiveness(view, block)
+	ledger.activateValidatorRegistration(view)
+	ledger.updateValidatorSet(view, block)
 	ledger.handleDelayedStateUpdates(view)
 
 	stateRootHash = view.Hash()
@@ -197,15 +239,27 @@ func (ledger *Ledger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs
 	return stateRootHash, blockRawTxs, result.OK
 }
 
-// ApplyBlockTxs applies the given block transactions. If any of the transactions failed, it returns
+// ApplyBlockTxs applies the transactions of the given block. If any of the transactions failed, it returns
 // an error immediately. If all the transactions execute successfully, it then validates the state
 // root hash. If the states root hash matches the expected value, it clears the transactions from the mempool
-func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoot common.Hash) result.Result {
+func (ledger *Ledger) ApplyBlockTxs(block *core.Block) result.Result {
 	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
 	// Otherwise, could cause deadlock since mempool.InsertTransaction() also first acquires the mempool, and then the ledger lock
 	ledger.mempool.Lock()
 	defer ledger.mempool.Unlock()
 
+	res := ledger.applyBlockTxs(block)
+	if res.IsError() {
+		return res
+	}
+
+	ledger.mempool.UpdateUnsafe(block.Txs)         // clear txs from the mempool
+	ledger.mempool.RescreenUnsafe(ledger.height()) // the screened view has been rebuilt by the commit
+
+	return res
+}
+
+func (ledger *Ledger) applyBlockTxs(block *core.Block) result.Result {
 	ledger.mu.Lock()
 	defer ledger.mu.Unlock()
 
@@ -215,7 +269,7 @@ func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoo
 	currStateRoot := view.Hash()
 
 	hasValidatorUpdate := false
-	for _, rawTx := range blockRawTxs {
+	for _, rawTx := range block.Txs {
 		tx, err := types.TxFromBytes(rawTx)
 		if err != nil {
 			ledger.resetState(currHeight, currStateRoot)
@@ -225,16 +279,30 @@ func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoo
 			hasValidatorUpdate = true
 		} else if _, ok := tx.(*types.WithdrawStakeTx); ok {
 			hasValidatorUpdate = true
+		} else if _, ok := tx.(*types.UnjailTx); ok {
+			hasValidatorUpdate = true
+		} else if _, ok := tx.(*types.RegisterValidatorTx); ok {
+			hasValidatorUpdate = true
 		}
-		_, res := ledger.executor.ExecuteTx(tx)
+		_, res := ledger.executor.ExecuteBlockTx(tx, block)
 		if res.IsError() {
 			ledger.resetState(currHeight, currStateRoot)
 			return res
 		}
 	}
 
+	if ledger.updateValidatorLiveness(view, block) {
+		hasValidatorUpdate = true
+	}
+	if ledger.activateValidatorRegistration(view) {
+		hasValidatorUpdate = true
+	}
+	if ledger.updateValidatorSet(view, block) {
+		hasValidatorUpdate = true
+	}
 	ledger.handleDelayedStateUpdates(view)
 
+	expectedStateRoot := block.StateHash
 	newStateRoot := view.Hash()
 	if newStateRoot != expectedStateRoot {
 		ledger.resetState(currHeight, currStateRoot)
@@ -245,17 +313,32 @@ func (ledger *Ledger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoo
 
 	ledger.state.Commit() // commit to persistent storage
 
-	ledger.mempool.UpdateUnsafe(blockRawTxs) // clear txs from the mempool
-
 	return result.OKWith(result.Info{"hasValidatorUpdate": hasValidatorUpdate})
 }
 
-// ResetState sets the ledger state with the designated root
+// ResetState sets the ledger state with the designated root, and re-screens the
+// mempool transactions against the rebuilt screened view
 func (ledger *Ledger) ResetState(height uint64, rootHash common.Hash) result.Result {
+	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
+	ledger.mempool.Lock()
+	defer ledger.mempool.Unlock()
+
 	ledger.mu.Lock()
-	defer ledger.mu.Unlock()
+	res := ledger.resetState(height, rootHash)
+	ledger.mu.Unlock()
+
+	if res.IsOK() {
+		ledger.mempool.RescreenUnsafe(height)
+	}
+	return res
+}
+
+// height returns the block height corresponding to the ledger state
+func (ledger *Ledger) height() uint64 {
+	ledger.mu.RLock()
+	defer ledger.mu.RUnlock()
 
-	return ledger.resetState(height, rootHash)
+	return ledger.state.Height()
 }
 
 // FinalizeState sets the ledger state with the finalized root
@@ -330,19 +413,44 @@ func (ledger *Ledger) handleStakeReturn(view *st.StoreView) {
 	view.UpdateValidatorCandidatePool(vcp)
 }
 
+// activateValidatorRegistration applies the minimal self-stake to the validator candidate pool once it
+// reaches HeightEnableValidatorRegistration. It returns true if the validator candidate pool is updated.
+func (ledger *Ledger) activateValidatorRegistration(view *st.StoreView) bool {
+	if view.Height() < common.HeightEnableValidatorRegistration {
+		return false
+	}
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil || vcp.MinSelfStake != nil {
+		return false
+	}
+
+	vcp.ActivateRegistration(core.MinValidatorSelfStake)
+	view.UpdateValidatorCandidatePool(vcp)
+	logger.Infof("Activated the validator registration, minimal self-stake: %v ThetaWei", core.MinValidatorSelfStake)
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+
+	return true
+}
+
 // addSpecialTransactions adds special transactions (e.g. coinbase transaction, slash transaction) to the block
-func (ledger *Ledger) addSpecialTransactions(view *st.StoreView, rawTxs *[]common.Bytes) {
+func (ledger *Ledger) addSpecialTransactions(view *st.StoreView, block *core.Block, rawTxs *[]common.Bytes) {
 	extBlk := ledger.consensus.GetLastFinalizedBlock()
 	epoch := ledger.consensus.GetEpoch()
 	proposer := ledger.valMgr.GetProposer(extBlk.Hash(), epoch)
 	validators := ledger.valMgr.GetValidatorSet(extBlk.Hash()).Validators()
 
-	ledger.addCoinbaseTx(view, &proposer, &validators, rawTxs)
+	ledger.addCoinbaseTx(view, block, &proposer, &validators, rawTxs)
 	ledger.addSlashTxs(view, &proposer, &validators, rawTxs)
 }
 
 // addCoinbaseTx adds a Coinbase transaction
-func (ledger *Ledger) addCoinbaseTx(view *st.StoreView, proposer *core.Validator, validators *[]core.Validator, rawTxs *[]common.Bytes) {
+func (ledger *Ledger) addCoinbaseTx(view *st.StoreView, block *core.Block, proposer *core.Validator, validators *[]core.Validator, rawTxs *[]common.Bytes) {
 	proposerAddress := proposer.Address
 	proposerTxIn := types.TxInput{
 		Address: proposerAddress,
@@ -353,7 +461,7 @@ func (ledger *Ledger) addCoinbaseTx(view *st.StoreView, proposer *core.Validator
 		validatorAddress := validator.Address
 		validatorAddresses[idx] = validatorAddress
 	}
-	accountRewardMap := exec.CalculateReward(view, validatorAddresses)
+	accountRewardMap := exec.CalculateReward(view, proposerAddress, validatorAddresses, exec.HCCVoters(block))
 
 	coinbaseTxOutputs := []types.TxOutput{}
 	for accountAddressStr, accountReward := range accountRewardMap {
@@ -424,8 +532,11 @@ func (ledger *Ledger) addSlashTxs(view *st.StoreView, proposer *core.Validator,
 // signTransaction signs the given transaction
 func (ledger *Ledger) signTransaction(tx types.Tx) (*crypto.Signature, error) {
 	chainID := ledger.state.GetChainID()
-	signBytes := tx.SignBytes(chainID)
-	signature, err := ledger.consensus.PrivateKey().Sign(signBytes)
+	rawTx, err := types.TxToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := ledger.consensus.Signer().SignTx(chainID, rawTx)
 	if err != nil {
 		return nil, err
 	}
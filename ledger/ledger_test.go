@@ -7,12 +7,15 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/result"
 	"github.com/thetatoken/theta/core"
+	exec "github.com/thetatoken/theta/ledger/execution"
+	st "github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
 	"github.com/thetatoken/theta/store/database/backend"
 )
@@ -63,7 +66,7 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 	startTime := time.Now()
 
 	// Propose block transactions
-	_, blockTxs, res := ledger.ProposeBlockTxs()
+	_, blockTxs, res := ledger.ProposeBlockTxs(core.NewBlock())
 
 	endTime := time.Now()
 	elapsed := endTime.Sub(startTime)
@@ -86,7 +89,7 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 			assert.Equal(0, idx) // The first tx needs to be a coinbase transaction
 			coinbaseTx := tx.(*types.CoinbaseTx)
 			signBytes := coinbaseTx.SignBytes(chainID)
-			ledger.consensus.PrivateKey().PublicKey().VerifySignature(signBytes, coinbaseTx.Proposer.Signature)
+			ledger.consensus.Signer().PublicKey().VerifySignature(signBytes, coinbaseTx.Proposer.Signature)
 		case *types.SendTx:
 			assert.True(idx > 0)
 			currSendTx := tx.(*types.SendTx)
@@ -125,7 +128,10 @@ func TestLedgerApplyBlockTxs(t *testing.T) {
 	}
 	expectedStateRoot := common.HexToHash("0d7bff2377e3638b82b09c21b7d0636ed593d2225164cb9b67f7296432194c58")
 
-	res := ledger.ApplyBlockTxs(blockRawTxs, expectedStateRoot)
+	block := core.NewBlock()
+	block.Txs = blockRawTxs
+	block.StateHash = expectedStateRoot
+	res := ledger.ApplyBlockTxs(block)
 	require.True(res.IsOK(), res.Message)
 
 	//
@@ -326,8 +332,9 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	for h := uint64(0); h < heightDelta1; h++ {
 		es.state.Commit() // increment height
 	}
-	expectedStateHash, _, res := es.consensus.GetLedger().ProposeBlockTxs()
-	res = es.consensus.GetLedger().ApplyBlockTxs([]common.Bytes{}, expectedStateHash)
+	block := core.NewBlock()
+	block.StateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs(block)
+	res = es.consensus.GetLedger().ApplyBlockTxs(block)
 	assert.True(res.IsOK())
 
 	srcAcc = es.state.Delivered().GetAccount(withdrawSourcePrivAcc.Address)
@@ -340,8 +347,9 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	for h := uint64(0); h < heightDelta2; h++ {
 		es.state.Commit() // increment height
 	}
-	expectedStateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs()
-	res = es.consensus.GetLedger().ApplyBlockTxs([]common.Bytes{}, expectedStateHash)
+	block = core.NewBlock()
+	block.StateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs(block)
+	res = es.consensus.GetLedger().ApplyBlockTxs(block)
 	assert.True(res.IsOK())
 
 	srcAcc = es.state.Delivered().GetAccount(withdrawSourcePrivAcc.Address)
@@ -353,3 +361,199 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	assert.True(returnedCoins.TFuelWei.Cmp(core.Zero) == 0)
 	log.Infof("Returned coins: %v", returnedCoins)
 }
+
+func TestValidatorLivenessTracking(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	valAddrs := []common.Address{
+		common.HexToAddress("0x1"),
+		common.HexToAddress("0x2"),
+		common.HexToAddress("0x3"),
+		common.HexToAddress("0x4"), // never votes
+	}
+	vcp := &core.ValidatorCandidatePool{}
+	valSet := core.NewValidatorSet()
+	for _, addr := range valAddrs {
+		require.Nil(vcp.DepositStake(addr, addr, core.MinValidatorStakeDeposit))
+		valSet.AddValidator(core.NewValidator(addr.Hex(), core.MinValidatorStakeDeposit))
+	}
+
+	view := st.NewStoreView(1, common.Hash{}, backend.NewMemDatabase())
+	view.UpdateValidatorCandidatePool(vcp)
+	ledger := &Ledger{
+		valMgr: exec.NewTestValidatorManager(valSet.Validators()[0], valSet),
+	}
+
+	newCC := func(idx uint64) core.CommitCertificate {
+		blockHash := common.BytesToHash(new(big.Int).SetUint64(idx + 1).Bytes())
+		votes := core.NewVoteSet()
+		for _, addr := range valAddrs[:3] {
+			votes.AddVote(core.Vote{Block: blockHash, ID: addr})
+		}
+		return core.CommitCertificate{BlockHash: blockHash, Votes: votes}
+	}
+
+	for i := uint64(0); i < core.LivenessWindowSize-1; i++ {
+		cc := newCC(i)
+		assert.False(ledger.trackVotes(view, cc))
+		assert.False(ledger.trackVotes(view, cc)) // tracked only once
+	}
+	vl := view.GetValidatorLiveness(valAddrs[3])
+	require.NotNil(vl)
+	assert.Equal(core.LivenessWindowSize-1, vl.NumTracked)
+	assert.Equal(core.LivenessWindowSize-1, vl.NumMissedVotes)
+	assert.Equal(float64(100), view.GetValidatorLiveness(valAddrs[0]).Uptime())
+
+	// Certificates without the majority are not tracked
+	cc := newCC(core.LivenessWindowSize)
+	cc.Votes = core.NewVoteSet()
+	cc.Votes.AddVote(core.Vote{Block: cc.BlockHash, ID: valAddrs[0]})
+	assert.False(ledger.trackVotes(view, cc))
+	assert.Equal(core.LivenessWindowSize-1, view.GetValidatorLiveness(valAddrs[3]).NumTracked)
+
+	// The inactive validator is jailed once the window is full
+	assert.True(ledger.trackVotes(view, newCC(core.LivenessWindowSize+1)))
+	assert.Nil(view.GetValidatorLiveness(valAddrs[3]))
+	vcp = view.GetValidatorCandidatePool()
+	assert.True(vcp.GetStakeHolder(valAddrs[3]).Jailed)
+	assert.Equal(3, len(vcp.GetTopStakeHolders(10)))
+	assert.True(view.GetStakeTransactionHeightList().Contains(view.Height()))
+	assert.Equal(core.MinValidatorStakeDeposit, vcp.GetStakeHolder(valAddrs[3]).TotalStake()) // not slashed before the fork
+
+	// From HeightEnableValidatorRewards, the stakes of the jailed validator are slashed
+	view = st.NewStoreView(common.HeightEnableValidatorRewards, common.Hash{}, backend.NewMemDatabase())
+	vcp = &core.ValidatorCandidatePool{}
+	for _, addr := range valAddrs {
+		require.Nil(vcp.DepositStake(addr, addr, core.MinValidatorStakeDeposit))
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+	for i := uint64(0); i < core.LivenessWindowSize; i++ {
+		ledger.trackVotes(view, newCC(i))
+	}
+	stakeHolder := view.GetValidatorCandidatePool().GetStakeHolder(valAddrs[3])
+	assert.True(stakeHolder.Jailed)
+	slashed := new(big.Int).Mul(core.MinValidatorStakeDeposit, big.NewInt(int64(common.JailSlashPercent)))
+	slashed.Div(slashed, big.NewInt(100))
+	assert.Equal(new(big.Int).Sub(core.MinValidatorStakeDeposit, slashed), stakeHolder.TotalStake())
+	assert.Equal(slashed, view.GetStakeRewardAccount(valAddrs[3], valAddrs[3]).Slashed)
+}
+
+func TestValidatorSetHandover(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	delayBlocks := common.ValidatorSetDelayBlocks
+	common.ValidatorSetDelayBlocks = 10
+	defer func() { common.ValidatorSetDelayBlocks = delayBlocks }()
+
+	minDeposit := core.MinValidatorStakeDeposit
+	vcp := &core.ValidatorCandidatePool{}
+	for i := int64(1); i <= 4; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		require.Nil(vcp.DepositStake(addr, addr, minDeposit))
+	}
+	vcp.ActivateRegistration(minDeposit) // activated no later than the validator set delay
+
+	height := common.HeightEnableValidatorSetDelay
+	view := st.NewStoreView(height, common.Hash{}, backend.NewMemDatabase())
+	view.UpdateValidatorCandidatePool(vcp)
+	ledger := &Ledger{}
+	block := core.NewBlock()
+	update := func(h uint64) bool {
+		block.Height = h
+		block.Epoch = h
+		return ledger.updateValidatorSet(view, block)
+	}
+
+	// The active validator set starts from the top stake holders
+	assert.False(update(height))
+	vcp = view.GetValidatorCandidatePool()
+	require.True(vcp.HasActiveValidators())
+	assert.Equal(4, vcp.GetActiveValidatorSet().Size())
+
+	// A new stake holder joins after the delay, fading in with at most 30% of the voting power per transition
+	newcomer := common.BigToAddress(big.NewInt(5))
+	require.Nil(vcp.DepositStake(newcomer, newcomer, new(big.Int).Mul(minDeposit, big.NewInt(4))))
+	require.Nil(vcp.RegisterStakeHolder(newcomer, &core.ValidatorRegistration{Moniker: "newcomer"}))
+	view.UpdateValidatorCandidatePool(vcp)
+	assert.False(update(height + 1))
+	vcp = view.GetValidatorCandidatePool()
+	require.NotNil(vcp.GetPendingValidatorSet())
+	assert.Equal(height+11, vcp.PendingActivationHeight)
+	assert.Equal(4, vcp.GetActiveValidatorSet().Size())
+	assert.False(update(height + 10))
+
+	assert.True(update(height + 11))
+	vcp = view.GetValidatorCandidatePool()
+	active := vcp.GetActiveValidatorSet()
+	assert.Equal(5, active.Size())
+	v, err := active.GetValidator(newcomer)
+	require.Nil(err)
+	assert.Equal(0, v.Stake.Cmp(new(big.Int).Div(new(big.Int).Mul(minDeposit, big.NewInt(12)), big.NewInt(10))))
+	require.NotNil(vcp.GetPendingValidatorSet())
+	assert.Equal(height+21, vcp.PendingActivationHeight)
+	assert.True(view.GetStakeTransactionHeightList().Contains(view.Height()))
+
+	// The hand-over completes in the following transitions
+	assert.True(update(height + 21))
+	assert.True(update(height + 31))
+	vcp = view.GetValidatorCandidatePool()
+	assert.Nil(vcp.GetPendingValidatorSet())
+	assert.True(vcp.GetActiveValidatorSet().Equals(vcp.SelectTopValidators(10)))
+	assert.False(update(height + 41))
+}
+
+func TestValidatorSetHandoverSeed(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	maxNumValidators := viper.GetInt(common.CfgConsensusMaxNumValidators)
+	viper.Set(common.CfgConsensusMaxNumValidators, common.MaxNumValidatorsWithDelay+2)
+	defer viper.Set(common.CfgConsensusMaxNumValidators, maxNumValidators)
+
+	minDeposit := core.MinValidatorStakeDeposit
+	vcp := &core.ValidatorCandidatePool{}
+	numHolders := int64(common.MaxNumValidatorsWithDelay + 2)
+	for i := int64(1); i <= numHolders; i++ {
+		addr := common.BigToAddress(big.NewInt(i))
+		require.Nil(vcp.DepositStake(addr, addr, new(big.Int).Mul(minDeposit, big.NewInt(i))))
+	}
+	vcp.ActivateRegistration(minDeposit)
+
+	height := common.HeightEnableValidatorSetDelay
+	view := st.NewStoreView(height, common.Hash{}, backend.NewMemDatabase())
+	view.UpdateValidatorCandidatePool(vcp)
+	ledger := &Ledger{}
+	block := core.NewBlock()
+	update := func(h uint64) bool {
+		block.Height = h
+		block.Epoch = h
+		return ledger.updateValidatorSet(view, block)
+	}
+
+	// The active validator set starts from the one chosen before the fork, and the hand-over
+	// to the fewer top stake holders is scheduled
+	assert.True(update(height))
+	vcp = view.GetValidatorCandidatePool()
+	require.True(vcp.HasActiveValidators())
+	assert.Equal(int(numHolders), vcp.GetActiveValidatorSet().Size())
+	target := vcp.SelectTopValidators(common.MaxNumValidatorsWithDelay)
+	require.NotNil(vcp.GetPendingValidatorSet())
+	assert.True(vcp.GetPendingValidatorSet().Equals(target))
+	assert.Equal(height+common.ValidatorSetDelayBlocks, vcp.PendingActivationHeight)
+	assert.True(view.GetStakeTransactionHeightList().Contains(height))
+
+	// The active validator set shrinks towards the target, and never grows
+	h := height
+	for i := 0; i < 20 && vcp.GetPendingValidatorSet() != nil; i++ {
+		size := vcp.GetActiveValidatorSet().Size()
+		h = vcp.PendingActivationHeight
+		assert.True(update(h))
+		vcp = view.GetValidatorCandidatePool()
+		assert.True(vcp.GetActiveValidatorSet().Size() <= size)
+	}
+	assert.Nil(vcp.GetPendingValidatorSet())
+	assert.True(vcp.GetActiveValidatorSet().Equals(target))
+	assert.False(update(h + 1))
+}
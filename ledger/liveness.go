@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	exec "github.com/thetatoken/theta/ledger/execution"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/kvstore"
+)
+
+// maxTrackedSkippedEpochs caps the number of skipped epochs checked for missed proposals per block
+const maxTrackedSkippedEpochs uint64 = 100
+
+// updateValidatorLiveness tracks the votes in the commit certificate carried by the block and the
+// proposals of the validators, and jails the validators whose uptime drops below the threshold.
+// It returns true if the validator candidate pool is updated.
+func (ledger *Ledger) updateValidatorLiveness(view *st.StoreView, block *core.Block) bool {
+	if block == nil || block.Height < common.HeightEnableValidatorLiveness {
+		return false
+	}
+
+	ledger.trackProposals(view, block)
+	return ledger.trackVotes(view, block.HCC)
+}
+
+// trackProposals records the block proposed by the proposer, and the epochs skipped since the parent
+// block for the proposers expected in them. Since each node selects the proposer with its own last
+// finalized block, the expected proposer of a skipped epoch is computed with the parent block and the
+// count is an estimate. It is reported but not used for jailing.
+func (ledger *Ledger) trackProposals(view *st.StoreView, block *core.Block) {
+	vl := getOrCreateValidatorLiveness(view, block.Proposer)
+	vl.NumProposedBlocks++
+	view.SetValidatorLiveness(block.Proposer, vl)
+
+	parent, err := findBlock(kvstore.NewKVStore(ledger.state.DB()), block.Parent)
+	if err != nil || parent.Epoch+1 >= block.Epoch {
+		return
+	}
+	fromEpoch := parent.Epoch + 1
+	if block.Epoch-fromEpoch > maxTrackedSkippedEpochs {
+		fromEpoch = block.Epoch - maxTrackedSkippedEpochs
+	}
+	for epoch := fromEpoch; epoch < block.Epoch; epoch++ {
+		proposer := ledger.valMgr.GetProposer(block.Parent, epoch)
+		vl := getOrCreateValidatorLiveness(view, proposer.Address)
+		vl.NumMissedProposals++
+		view.SetValidatorLiveness(proposer.Address, vl)
+	}
+}
+
+// trackVotes records the votes of the validators in the given commit certificate. Each certificate
+// is tracked once even if it is carried by multiple blocks, and only the certificates proven by a
+// majority are tracked, so that the blocks without votes do not count against the validators.
+func (ledger *Ledger) trackVotes(view *st.StoreView, hcc core.CommitCertificate) bool {
+	if hcc.BlockHash.IsEmpty() || hcc.BlockHash == view.GetLivenessTrackedCC() {
+		return false
+	}
+	valSet := ledger.valMgr.GetValidatorSet(hcc.BlockHash)
+	if !hcc.IsProven(valSet) {
+		return false
+	}
+	view.SetLivenessTrackedCC(hcc.BlockHash)
+
+	voted := make(map[common.Address]bool)
+	for _, vote := range hcc.Votes.Votes() {
+		voted[vote.ID] = true
+	}
+
+	vcp := view.GetValidatorCandidatePool()
+	hasJailed := false
+	for _, validator := range valSet.Validators() {
+		vl := getOrCreateValidatorLiveness(view, validator.Address)
+		vl.RecordVote(voted[validator.Address])
+		if vl.ShouldBeJailed() && vcp != nil && canJail(vcp) {
+			if err := vcp.JailStakeHolder(validator.Address); err == nil {
+				logger.Infof("Jailed validator %v, liveness: %v", validator.Address.Hex(), vl)
+				if view.Height() >= common.HeightEnableValidatorRewards {
+					if slashed, err := exec.SlashStake(view, vcp, validator.Address, common.JailSlashPercent); err == nil {
+						logger.Infof("Slashed %v ThetaWei from the stakes of validator %v", slashed, validator.Address.Hex())
+					}
+				}
+				view.DeleteValidatorLiveness(validator.Address)
+				hasJailed = true
+				continue
+			}
+		}
+		view.SetValidatorLiveness(validator.Address, vl)
+	}
+
+	if !hasJailed {
+		return false
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+
+	return true
+}
+
+// canJail returns true if at least one stake holder would remain eligible as validator
+func canJail(vcp *core.ValidatorCandidatePool) bool {
+	eligible := 0
+	for _, stakeHolder := range vcp.GetTopStakeHolders(len(vcp.SortedCandidates)) {
+		if stakeHolder.TotalStake().Cmp(core.Zero) > 0 {
+			eligible++
+		}
+	}
+	return eligible > 1
+}
+
+func getOrCreateValidatorLiveness(view *st.StoreView, addr common.Address) *core.ValidatorLiveness {
+	vl := view.GetValidatorLiveness(addr)
+	if vl == nil {
+		vl = core.NewValidatorLiveness(addr)
+	}
+	return vl
+}
@@ -42,3 +42,40 @@ func ValidatorCandidatePoolKey() common.Bytes {
 func StakeTransactionHeightListKey() common.Bytes {
 	return common.Bytes("ls/sthl")
 }
+
+// ValidatorLivenessKeyPrefix returns the prefix for the validator liveness key
+func ValidatorLivenessKeyPrefix() common.Bytes {
+	return common.Bytes("ls/vl/")
+}
+
+// ValidatorLivenessKey constructs the state key for the liveness record of the given validator
+func ValidatorLivenessKey(addr common.Address) common.Bytes {
+	return append(ValidatorLivenessKeyPrefix(), addr[:]...)
+}
+
+// LivenessTrackedCCKey returns the state key for the hash of the last commit certificate
+// tracked for the validator liveness
+func LivenessTrackedCCKey() common.Bytes {
+	return common.Bytes("ls/vltcc")
+}
+
+// FeePoolKey returns the state key for the transaction fees collected but not distributed yet
+func FeePoolKey() common.Bytes {
+	return common.Bytes("ls/fp")
+}
+
+// StakeRewardIndexKey constructs the state key for the reward index of the given stake holder
+func StakeRewardIndexKey(holder common.Address) common.Bytes {
+	return append(common.Bytes("ls/sri/"), holder[:]...)
+}
+
+// StakeRewardAccountKeyPrefix returns the prefix for the stake reward account keys of the given stake holder
+func StakeRewardAccountKeyPrefix(holder common.Address) common.Bytes {
+	return append(common.Bytes("ls/sra/"), holder[:]...)
+}
+
+// StakeRewardAccountKey constructs the state key for the reward account of the stake deposited by the
+// source to the holder
+func StakeRewardAccountKey(holder common.Address, source common.Address) common.Bytes {
+	return append(StakeRewardAccountKeyPrefix(holder), source[:]...)
+}
@@ -303,6 +303,122 @@ func (sv *StoreView) UpdateStakeTransactionHeightList(hl *types.HeightList) {
 	sv.Set(StakeTransactionHeightListKey(), hlBytes)
 }
 
+// GetValidatorLiveness gets the liveness record of the given validator, nil if not tracked yet
+func (sv *StoreView) GetValidatorLiveness(addr common.Address) *core.ValidatorLiveness {
+	data := sv.Get(ValidatorLivenessKey(addr))
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	vl := &core.ValidatorLiveness{}
+	err := types.FromBytes(data, vl)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading validator liveness %X, error: %v",
+			data, err.Error()))
+	}
+	return vl
+}
+
+// SetValidatorLiveness sets the liveness record of the given validator
+func (sv *StoreView) SetValidatorLiveness(addr common.Address, vl *core.ValidatorLiveness) {
+	vlBytes, err := types.ToBytes(vl)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing validator liveness %v, error: %v",
+			vl, err.Error()))
+	}
+	sv.Set(ValidatorLivenessKey(addr), vlBytes)
+}
+
+// DeleteValidatorLiveness deletes the liveness record of the given validator
+func (sv *StoreView) DeleteValidatorLiveness(addr common.Address) {
+	sv.Delete(ValidatorLivenessKey(addr))
+}
+
+// GetLivenessTrackedCC gets the hash of the block of the last commit certificate tracked for the validator liveness
+func (sv *StoreView) GetLivenessTrackedCC() common.Hash {
+	data := sv.Get(LivenessTrackedCCKey())
+	return common.BytesToHash(data)
+}
+
+// SetLivenessTrackedCC sets the hash of the block of the last commit certificate tracked for the validator liveness
+func (sv *StoreView) SetLivenessTrackedCC(blockHash common.Hash) {
+	sv.Set(LivenessTrackedCCKey(), blockHash[:])
+}
+
+// GetFeePool gets the transaction fees collected but not distributed yet
+func (sv *StoreView) GetFeePool() types.Coins {
+	data := sv.Get(FeePoolKey())
+	if data == nil || len(data) == 0 {
+		return types.NewCoins(0, 0)
+	}
+	fees := types.Coins{}
+	err := types.FromBytes(data, &fees)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading fee pool %X, error: %v",
+			data, err.Error()))
+	}
+	return fees.NoNil()
+}
+
+// SetFeePool sets the transaction fees collected but not distributed yet
+func (sv *StoreView) SetFeePool(fees types.Coins) {
+	feesBytes, err := types.ToBytes(fees.NoNil())
+	if err != nil {
+		panic(fmt.Sprintf("Error writing fee pool %v, error: %v",
+			fees, err.Error()))
+	}
+	sv.Set(FeePoolKey(), feesBytes)
+}
+
+// GetStakeRewardIndex gets the reward index of the given stake holder
+func (sv *StoreView) GetStakeRewardIndex(holder common.Address) types.Coins {
+	data := sv.Get(StakeRewardIndexKey(holder))
+	if data == nil || len(data) == 0 {
+		return types.NewCoins(0, 0)
+	}
+	index := types.Coins{}
+	err := types.FromBytes(data, &index)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading stake reward index %X, error: %v",
+			data, err.Error()))
+	}
+	return index.NoNil()
+}
+
+// SetStakeRewardIndex sets the reward index of the given stake holder
+func (sv *StoreView) SetStakeRewardIndex(holder common.Address, index types.Coins) {
+	indexBytes, err := types.ToBytes(index.NoNil())
+	if err != nil {
+		panic(fmt.Sprintf("Error writing stake reward index %v, error: %v",
+			index, err.Error()))
+	}
+	sv.Set(StakeRewardIndexKey(holder), indexBytes)
+}
+
+// GetStakeRewardAccount gets the reward account of the stake deposited by the source to the holder, nil if not exists
+func (sv *StoreView) GetStakeRewardAccount(holder common.Address, source common.Address) *types.StakeRewardAccount {
+	data := sv.Get(StakeRewardAccountKey(holder, source))
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	sra := &types.StakeRewardAccount{}
+	err := types.FromBytes(data, sra)
+	if err != nil {
+		panic(fmt.Sprintf("Error reading stake reward account %X, error: %v",
+			data, err.Error()))
+	}
+	return sra
+}
+
+// SetStakeRewardAccount sets the reward account of the stake deposited by the source to the holder
+func (sv *StoreView) SetStakeRewardAccount(holder common.Address, source common.Address, sra *types.StakeRewardAccount) {
+	sraBytes, err := types.ToBytes(sra)
+	if err != nil {
+		panic(fmt.Sprintf("Error writing stake reward account %v, error: %v",
+			sra, err.Error()))
+	}
+	sv.Set(StakeRewardAccountKey(holder, source), sraBytes)
+}
+
 func (sv *StoreView) GetStore() *treestore.TreeStore {
 	return sv.store
 }
@@ -20,6 +20,7 @@ import (
 	mp "github.com/thetatoken/theta/mempool"
 	"github.com/thetatoken/theta/p2p"
 	p2psim "github.com/thetatoken/theta/p2p/simulation"
+	"github.com/thetatoken/theta/signer"
 	"github.com/thetatoken/theta/store/database"
 	"github.com/thetatoken/theta/store/database/backend"
 	"github.com/thetatoken/theta/store/kvstore"
@@ -53,7 +54,7 @@ func newExecSim(chainID string, db database.Database, snapshot mockSnapshot, val
 	dispatcher := dp.NewDispatcher(messenger)
 
 	valMgr := consensus.NewFixedValidatorManager()
-	consensus := consensus.NewConsensusEngine(valPrivAcc.PrivKey, store, chain, dispatcher, valMgr)
+	consensus := consensus.NewConsensusEngine(signer.NewLocalSigner(valPrivAcc.PrivKey, nil), store, chain, dispatcher, valMgr)
 	valMgr.SetConsensusEngine(consensus)
 
 	mempool := mp.CreateMempool(dispatcher)
@@ -211,7 +212,7 @@ func newTestLedger() (chainID string, ledger *Ledger, mempool *mp.Mempool) {
 }
 
 func newTesetValidatorManager(consensus core.ConsensusEngine) core.ValidatorManager {
-	proposerAddressStr := consensus.PrivateKey().PublicKey().Address().String()
+	proposerAddressStr := consensus.Signer().Address().String()
 	propser := core.NewValidator(proposerAddressStr, new(big.Int).SetUint64(999))
 
 	_, val2PubKey, err := crypto.TEST_GenerateKeyPairWithSeed("val2")
@@ -274,16 +275,19 @@ func newRawCoinbaseTx(chainID string, ledger *Ledger, sequence int) common.Bytes
 		outputs = append(outputs, output)
 	}
 
-	proposerSk := ledger.consensus.PrivateKey()
-	proposerPk := proposerSk.PublicKey()
+	proposerSigner := ledger.consensus.Signer()
+	proposerPk := proposerSigner.PublicKey()
 	coinbaseTx := &types.CoinbaseTx{
 		Proposer:    types.TxInput{Address: proposerPk.Address(), Sequence: uint64(sequence)},
 		Outputs:     outputs,
 		BlockHeight: 2,
 	}
 
-	signBytes := coinbaseTx.SignBytes(chainID)
-	sig, err := proposerSk.Sign(signBytes)
+	rawTx, err := types.TxToBytes(coinbaseTx)
+	if err != nil {
+		panic("Failed to encode the coinbase transaction")
+	}
+	sig, err := proposerSigner.SignTx(chainID, rawTx)
 	if err != nil {
 		panic("Failed to sign the coinbase transaction")
 	}
@@ -33,6 +33,9 @@ const (
 	TxSmartContract
 	TxDepositStake
 	TxWithdrawStake
+	TxUnjail
+	TxClaimStakeReward
+	TxRegisterValidator
 )
 
 func TxFromBytes(raw []byte) (Tx, error) {
@@ -82,6 +85,18 @@ func TxFromBytes(raw []byte) (Tx, error) {
 		data := &WithdrawStakeTx{}
 		err = rlp.Decode(buff, data)
 		return data, err
+	} else if txType == TxUnjail {
+		data := &UnjailTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxClaimStakeReward {
+		data := &ClaimStakeRewardTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
+	} else if txType == TxRegisterValidator {
+		data := &RegisterValidatorTx{}
+		err = rlp.Decode(buff, data)
+		return data, err
 	} else {
 		return nil, fmt.Errorf("Unknown TX type: %v", txType)
 	}
@@ -111,6 +126,12 @@ func TxToBytes(t Tx) ([]byte, error) {
 		txType = TxDepositStake
 	case *WithdrawStakeTx:
 		txType = TxWithdrawStake
+	case *UnjailTx:
+		txType = TxUnjail
+	case *ClaimStakeRewardTx:
+		txType = TxClaimStakeReward
+	case *RegisterValidatorTx:
+		txType = TxRegisterValidator
 	default:
 		return nil, errors.New("Unsupported message type")
 	}
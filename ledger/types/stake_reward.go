@@ -0,0 +1,72 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// StakeRewardPrecision scales the reward indices of the stake holders, which are the cumulative
+// rewards per staked ThetaWei. It needs to be well above the total stake of a holder in ThetaWei, so
+// that small rewards are not lost to rounding.
+var StakeRewardPrecision = new(big.Int).Exp(big.NewInt(10), big.NewInt(36), nil)
+
+//
+// StakeRewardAccount records the rewards earned by a stake source for its stake deposited to a holder.
+// The rewards accrue lazily: the account is settled against the reward index of the holder only when
+// the stake changes or the rewards are claimed.
+//
+type StakeRewardAccount struct {
+	Index   Coins    // reward index of the holder when the account was last settled
+	Accrued Coins    // rewards earned but not paid out yet
+	Claimed Coins    // total rewards paid out
+	Slashed *big.Int // total ThetaWei slashed from the stake
+}
+
+// NewStakeRewardAccount creates an account settled at the given index
+func NewStakeRewardAccount(index Coins) *StakeRewardAccount {
+	return &StakeRewardAccount{
+		Index:   index.NoNil(),
+		Accrued: NewCoins(0, 0),
+		Claimed: NewCoins(0, 0),
+		Slashed: big.NewInt(0),
+	}
+}
+
+// Pending returns the rewards earned by the stake amount up to the given index, in addition to the accrued rewards
+func (sra *StakeRewardAccount) Pending(index Coins, amount *big.Int) Coins {
+	delta := index.NoNil().Minus(sra.Index)
+	theta := new(big.Int).Mul(delta.ThetaWei, amount)
+	tfuel := new(big.Int).Mul(delta.TFuelWei, amount)
+	earned := Coins{
+		ThetaWei: theta.Div(theta, StakeRewardPrecision),
+		TFuelWei: tfuel.Div(tfuel, StakeRewardPrecision),
+	}
+	return sra.Accrued.Plus(earned)
+}
+
+// Settle moves the rewards earned by the stake amount up to the given index into the accrued rewards
+func (sra *StakeRewardAccount) Settle(index Coins, amount *big.Int) {
+	sra.Accrued = sra.Pending(index, amount)
+	sra.Index = index.NoNil()
+}
+
+// PayOut clears the accrued rewards and returns them
+func (sra *StakeRewardAccount) PayOut() Coins {
+	paid := sra.Accrued.NoNil()
+	sra.Claimed = sra.Claimed.Plus(paid)
+	sra.Accrued = NewCoins(0, 0)
+	return paid
+}
+
+// Slash records the amount slashed from the stake. The account needs to be settled before the stake shrinks.
+func (sra *StakeRewardAccount) Slash(amount *big.Int) {
+	slashed := new(big.Int)
+	if sra.Slashed != nil {
+		slashed.Set(sra.Slashed)
+	}
+	sra.Slashed = slashed.Add(slashed, amount)
+}
+
+func (sra *StakeRewardAccount) String() string {
+	return fmt.Sprintf("{Index: %v, Accrued: %v, Claimed: %v, Slashed: %v}", sra.Index, sra.Accrued, sra.Claimed, sra.Slashed)
+}
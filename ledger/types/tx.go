@@ -25,19 +25,25 @@ Transaction Types:
  - SplitRuleTx          Payment split rule
  - DepositStakeTx       Deposit stake to a target address (e.g. a validator)
  - WithdrawStakeTx      Withdraw stake from a target address (e.g. a validator)
+ - UnjailTx             Make a jailed validator eligible for the validator selection again
+ - ClaimStakeRewardTx   Claim the staking rewards earned by a stake deposited to a validator
+ - RegisterValidatorTx  Register the commission and the metadata of a validator candidate
  - SmartContractTx      Execute smart contract
 */
 
 // Gas of regular transactions
 const (
-	GasSendTxPerAccount   uint64 = 5000
-	GasReserveFundTx      uint64 = 10000
-	GasReleaseFundTx      uint64 = 10000
-	GasServicePaymentTx   uint64 = 10000
-	GasSplitRuleTx        uint64 = 10000
-	GasUpdateValidatorsTx uint64 = 10000
-	GasDepositStakeTx     uint64 = 10000
-	GasWidthdrawStakeTx   uint64 = 10000
+	GasSendTxPerAccount    uint64 = 5000
+	GasReserveFundTx       uint64 = 10000
+	GasReleaseFundTx       uint64 = 10000
+	GasServicePaymentTx    uint64 = 10000
+	GasSplitRuleTx         uint64 = 10000
+	GasUpdateValidatorsTx  uint64 = 10000
+	GasDepositStakeTx      uint64 = 10000
+	GasWidthdrawStakeTx    uint64 = 10000
+	GasUnjailTx            uint64 = 10000
+	GasClaimStakeRewardTx  uint64 = 10000
+	GasRegisterValidatorTx uint64 = 10000
 )
 
 type Tx interface {
@@ -81,34 +87,38 @@ func encodeToBytes(str string) []byte {
 //-----------------------------------------------------------------------------
 
 type TxInput struct {
-	Address   common.Address // Hash of the PubKey
-	Coins     Coins
-	Sequence  uint64            // Must be 1 greater than the last committed TxInput
-	Signature *crypto.Signature // Depends on the PubKey type and the whole Tx
+	Address      common.Address // Hash of the PubKey
+	Coins        Coins
+	Sequence     uint64            // Must be 1 greater than the last committed TxInput
+	Signature    *crypto.Signature // Depends on the PubKey type and the whole Tx
+	ExpiryHeight uint64            `rlp:"optional"` // The tx is invalid after this block height, 0 means no expiry
 }
 
 type TxInputJSON struct {
-	Address   common.Address    `json:"address"`   // Hash of the PubKey
-	Coins     Coins             `json:"coins"`     //
-	Sequence  common.JSONUint64 `json:"sequence"`  // Must be 1 greater than the last committed TxInput
-	Signature *crypto.Signature `json:"signature"` // Depends on the PubKey type and the whole Tx
+	Address      common.Address    `json:"address"`       // Hash of the PubKey
+	Coins        Coins             `json:"coins"`         //
+	Sequence     common.JSONUint64 `json:"sequence"`      // Must be 1 greater than the last committed TxInput
+	Signature    *crypto.Signature `json:"signature"`     // Depends on the PubKey type and the whole Tx
+	ExpiryHeight common.JSONUint64 `json:"expiry_height"` // The tx is invalid after this block height, 0 means no expiry
 }
 
 func NewTxInputJSON(a TxInput) TxInputJSON {
 	return TxInputJSON{
-		Address:   a.Address,
-		Coins:     a.Coins,
-		Sequence:  common.JSONUint64(a.Sequence),
-		Signature: a.Signature,
+		Address:      a.Address,
+		Coins:        a.Coins,
+		Sequence:     common.JSONUint64(a.Sequence),
+		Signature:    a.Signature,
+		ExpiryHeight: common.JSONUint64(a.ExpiryHeight),
 	}
 }
 
 func (a TxInputJSON) TxInput() TxInput {
 	return TxInput{
-		Address:   a.Address,
-		Coins:     a.Coins,
-		Sequence:  uint64(a.Sequence),
-		Signature: a.Signature,
+		Address:      a.Address,
+		Coins:        a.Coins,
+		Sequence:     uint64(a.Sequence),
+		Signature:    a.Signature,
+		ExpiryHeight: uint64(a.ExpiryHeight),
 	}
 }
 
@@ -147,7 +157,7 @@ func (txIn TxInput) ValidateBasic() result.Result {
 }
 
 func (txIn TxInput) String() string {
-	return fmt.Sprintf("TxInput{%v,%v,%v,%v}", txIn.Address.Hex(), txIn.Coins, txIn.Sequence, txIn.Signature)
+	return fmt.Sprintf("TxInput{%v,%v,%v,%v,%v}", txIn.Address.Hex(), txIn.Coins, txIn.Sequence, txIn.Signature, txIn.ExpiryHeight)
 }
 
 func NewTxInput(address common.Address, coins Coins, sequence int) TxInput {
@@ -866,6 +876,111 @@ func (tx *WithdrawStakeTx) String() string {
 		tx.Source.Address, tx.Holder.Address, tx.Source.Coins.ThetaWei, tx.Purpose)
 }
 
+//-----------------------------------------------------------------------------
+
+type UnjailTx struct {
+	Fee    Coins   `json:"fee"`    // Fee
+	Holder TxInput `json:"holder"` // jailed stake holder account
+}
+
+func (_ *UnjailTx) AssertIsTx() {}
+
+func (tx *UnjailTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Holder.Signature
+	tx.Holder.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Holder.Signature = sig
+	return signBytes
+}
+
+func (tx *UnjailTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Holder.Address == addr {
+		tx.Holder.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *UnjailTx) String() string {
+	return fmt.Sprintf("UnjailTx{holder: %v, fee: %v}", tx.Holder.Address, tx.Fee)
+}
+
+//-----------------------------------------------------------------------------
+
+type ClaimStakeRewardTx struct {
+	Fee    Coins    `json:"fee"`    // Fee
+	Source TxInput  `json:"source"` // source account of the stake, receives the rewards
+	Holder TxOutput `json:"holder"` // stake holder account
+}
+
+func (_ *ClaimStakeRewardTx) AssertIsTx() {}
+
+func (tx *ClaimStakeRewardTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Source.Signature
+	tx.Source.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Source.Signature = sig
+	return signBytes
+}
+
+func (tx *ClaimStakeRewardTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Source.Address == addr {
+		tx.Source.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *ClaimStakeRewardTx) String() string {
+	return fmt.Sprintf("ClaimStakeRewardTx{%v <- %v, fee: %v}", tx.Source.Address, tx.Holder.Address, tx.Fee)
+}
+
+//-----------------------------------------------------------------------------
+
+type RegisterValidatorTx struct {
+	Fee               Coins        `json:"fee"`                // Fee
+	Holder            TxInput      `json:"holder"`             // stake holder account of the validator candidate
+	CommissionPercent uint         `json:"commission_percent"` // share of the staking rewards kept by the holder
+	Moniker           string       `json:"moniker"`
+	Website           string       `json:"website"`
+	ConsensusPubKey   common.Bytes `json:"consensus_pub_key"`
+}
+
+func (_ *RegisterValidatorTx) AssertIsTx() {}
+
+func (tx *RegisterValidatorTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Holder.Signature
+	tx.Holder.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Holder.Signature = sig
+	return signBytes
+}
+
+func (tx *RegisterValidatorTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Holder.Address == addr {
+		tx.Holder.Signature = sig
+		return true
+	}
+	return false
+}
+
+func (tx *RegisterValidatorTx) String() string {
+	return fmt.Sprintf("RegisterValidatorTx{holder: %v, commission: %v%%, moniker: %v, website: %v, fee: %v}",
+		tx.Holder.Address, tx.CommissionPercent, tx.Moniker, tx.Website, tx.Fee)
+}
+
 // --------------- Utils --------------- //
 
 // Need to add the following prefix to the tx signbytes to be compatible with
@@ -0,0 +1,92 @@
+package ledger
+
+import (
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/consensus"
+	"github.com/thetatoken/theta/core"
+	st "github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+// updateValidatorSet hands the active validator set over to the top stake holders with a delay. A change
+// of the top stake holders is scheduled to take effect after common.ValidatorSetDelayBlocks and
+// common.ValidatorSetDelayEpochs.
+// The schedule is kept when the top stake holders change again in the meantime, so that frequent stake
+// changes cannot postpone the hand-over forever. Each transition changes at most
+// common.MaxValidatorSetChangePercent of the voting power, and the rest of the change is rescheduled. It returns
+// true if the active validator set is updated, or a change of it is scheduled right at the fork.
+func (ledger *Ledger) updateValidatorSet(view *st.StoreView, block *core.Block) bool {
+	if block == nil || block.Height < common.HeightEnableValidatorSetDelay {
+		return false
+	}
+	vcp := view.GetValidatorCandidatePool()
+	if vcp == nil {
+		return false
+	}
+
+	delayBlocks := common.ValidatorSetDelayBlocks
+	delayEpochs := common.ValidatorSetDelayEpochs
+	target := vcp.SelectTopValidators(common.MaxNumValidatorsWithDelay)
+	if !vcp.HasActiveValidators() {
+		// Start from the validator set chosen by the validator manager before the fork, and schedule the
+		// hand-over if the top stake holders differ
+		seed := consensus.SelectTopStakeHoldersAsValidators(vcp)
+		vcp.SetActiveValidatorSet(seed)
+		if seed.Equals(target) {
+			view.UpdateValidatorCandidatePool(vcp)
+			return false
+		}
+		vcp.SetPendingValidatorSet(target, block.Height+delayBlocks, block.Epoch+delayEpochs)
+		view.UpdateValidatorCandidatePool(vcp)
+		logger.Infof("Scheduled validator set change at height %v, epoch %v: %v",
+			vcp.PendingActivationHeight, vcp.PendingActivationEpoch, target)
+		appendStakeTransactionHeight(view)
+		return true
+	}
+
+	active := vcp.GetActiveValidatorSet()
+	pending := vcp.GetPendingValidatorSet()
+	if target.Equals(active) {
+		if pending != nil {
+			vcp.SetPendingValidatorSet(nil, 0, 0) // the change has been reverted
+			view.UpdateValidatorCandidatePool(vcp)
+		}
+		return false
+	}
+	if pending == nil {
+		vcp.SetPendingValidatorSet(target, block.Height+delayBlocks, block.Epoch+delayEpochs)
+		view.UpdateValidatorCandidatePool(vcp)
+		logger.Infof("Scheduled validator set change at height %v, epoch %v: %v",
+			vcp.PendingActivationHeight, vcp.PendingActivationEpoch, target)
+	} else if !target.Equals(pending) {
+		vcp.SetPendingValidatorSet(target, vcp.PendingActivationHeight, vcp.PendingActivationEpoch)
+		view.UpdateValidatorCandidatePool(vcp)
+	}
+
+	if block.Height < vcp.PendingActivationHeight || block.Epoch < vcp.PendingActivationEpoch {
+		return false
+	}
+
+	next := core.HandOverValidators(active, target, common.MaxValidatorSetChangePercent, common.MaxNumValidatorsWithDelay)
+	vcp.SetActiveValidatorSet(next)
+	if next.Equals(target) {
+		vcp.SetPendingValidatorSet(nil, 0, 0)
+	} else {
+		vcp.SetPendingValidatorSet(target, block.Height+delayBlocks, block.Epoch+delayEpochs)
+	}
+	view.UpdateValidatorCandidatePool(vcp)
+	logger.Infof("Validator set changed at height %v: %v", block.Height, next)
+	appendStakeTransactionHeight(view)
+
+	return true
+}
+
+// appendStakeTransactionHeight records the current height as one where the validator candidate pool changed
+func appendStakeTransactionHeight(view *st.StoreView) {
+	hl := view.GetStakeTransactionHeightList()
+	if hl == nil {
+		hl = &types.HeightList{}
+	}
+	hl.Append(view.Height())
+	view.UpdateStakeTransactionHeightList(hl)
+}
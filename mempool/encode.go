@@ -0,0 +1,79 @@
+package mempool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/rlp"
+)
+
+type MessageIDEnum uint8
+
+const (
+	MessageIDTxAnnouncement MessageIDEnum = iota // tx hashes announced by a peer, sent as an InventoryResponse
+	MessageIDTxRequest                           // tx hashes requested by a peer, sent as a DataRequest
+	MessageIDTxResponse                          // raw tx requested by a peer, sent as a DataResponse
+)
+
+// rlpListPrefix is the smallest first byte of an RLP encoded list. Nodes without announce-and-fetch
+// gossip send plain RLP encoded DataResponses, while the message IDs are encoded as single bytes.
+const rlpListPrefix = 0xC0
+
+func encodeMessage(message interface{}) (common.Bytes, error) {
+	var buf bytes.Buffer
+	var msgID MessageIDEnum
+	switch message.(type) {
+	case dispatcher.InventoryResponse:
+		msgID = MessageIDTxAnnouncement
+	case dispatcher.DataRequest:
+		msgID = MessageIDTxRequest
+	case dispatcher.DataResponse:
+		msgID = MessageIDTxResponse
+	default:
+		return nil, errors.New("Unsupported message type")
+	}
+	err := rlp.Encode(&buf, msgID)
+	if err != nil {
+		return nil, err
+	}
+	err = rlp.Encode(&buf, message)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMessage(raw common.Bytes) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("Empty message")
+	}
+	if raw[0] >= rlpListPrefix { // Legacy transaction gossip
+		data := dispatcher.DataResponse{}
+		err := rlp.DecodeBytes(raw, &data)
+		return data, err
+	}
+
+	var msgID MessageIDEnum
+	err := rlp.DecodeBytes(raw[:1], &msgID)
+	if err != nil {
+		return nil, err
+	}
+	if msgID == MessageIDTxAnnouncement {
+		data := dispatcher.InventoryResponse{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	} else if msgID == MessageIDTxRequest {
+		data := dispatcher.DataRequest{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	} else if msgID == MessageIDTxResponse {
+		data := dispatcher.DataResponse{}
+		err = rlp.DecodeBytes(raw[1:], &data)
+		return data, err
+	} else {
+		return nil, fmt.Errorf("Unknown message ID: %v", msgID)
+	}
+}
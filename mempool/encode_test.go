@@ -0,0 +1,47 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	dp "github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/rlp"
+)
+
+func TestMessageEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	announcement := dp.InventoryResponse{ChannelID: common.ChannelIDTransaction, Entries: []string{"A0", "B1"}}
+	b, err := encodeMessage(announcement)
+	assert.Nil(err)
+	raw, err := decodeMessage(b)
+	assert.Nil(err)
+	announcement2 := raw.(dp.InventoryResponse)
+	assert.Equal(common.ChannelIDTransaction, announcement2.ChannelID)
+	assert.Equal([]string{"A0", "B1"}, announcement2.Entries)
+
+	request := dp.DataRequest{ChannelID: common.ChannelIDTransaction, Entries: []string{"A0"}}
+	b, err = encodeMessage(request)
+	assert.Nil(err)
+	raw, err = decodeMessage(b)
+	assert.Nil(err)
+	assert.Equal([]string{"A0"}, raw.(dp.DataRequest).Entries)
+
+	response := dp.DataResponse{ChannelID: common.ChannelIDTransaction, Payload: createTestRawTx("tx1")}
+	b, err = encodeMessage(response)
+	assert.Nil(err)
+	raw, err = decodeMessage(b)
+	assert.Nil(err)
+	assert.Equal("tx1", string(raw.(dp.DataResponse).Payload))
+
+	// Transactions pushed by nodes without announce-and-fetch gossip
+	b, err = rlp.EncodeToBytes(response)
+	assert.Nil(err)
+	raw, err = decodeMessage(b)
+	assert.Nil(err)
+	assert.Equal("tx1", string(raw.(dp.DataResponse).Payload))
+
+	_, err = encodeMessage(dp.InventoryRequest{})
+	assert.NotNil(err)
+}
@@ -3,16 +3,19 @@ package mempool
 import (
 	"context"
 	"encoding/hex"
-	"errors"
 	"math/big"
+	"sort"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/common/clist"
 	"github.com/thetatoken/theta/common/math"
 	"github.com/thetatoken/theta/common/pqueue"
+	"github.com/thetatoken/theta/common/result"
 	"github.com/thetatoken/theta/core"
 	dp "github.com/thetatoken/theta/dispatcher"
 )
@@ -27,6 +30,20 @@ func (m MempoolError) Error() string {
 
 const DuplicateTxError = MempoolError("Transaction already seen")
 
+const ReplacementUnderpricedError = MempoolError("Replacement transaction underpriced")
+
+// TxScreeningError is returned when a transaction fails the ledger screening
+type TxScreeningError struct {
+	Result result.Result
+}
+
+func (e TxScreeningError) Error() string {
+	return e.Result.Message
+}
+
+// txSweepInterval is the interval between two consecutive sweeps of the expired transactions
+const txSweepInterval = 1 * time.Minute
+
 //
 // mempoolTransaction implements the pqueue.Element interface
 //
@@ -34,6 +51,9 @@ type mempoolTransaction struct {
 	index          int
 	rawTransaction common.Bytes
 	txInfo         *core.TxInfo
+	evicted        bool      // set when the transaction is replaced or dropped before it was gossiped
+	insertedAt     time.Time // wall-clock time when the transaction entered the mempool
+	insertedHeight uint64    // latest block height known to the mempool when the transaction was inserted
 }
 
 var _ pqueue.Element = (*mempoolTransaction)(nil)
@@ -51,10 +71,12 @@ func (mt *mempoolTransaction) GetIndex() int {
 	return mt.index
 }
 
-func createMempoolTransaction(rawTransaction common.Bytes, txInfo *core.TxInfo) *mempoolTransaction {
+func createMempoolTransaction(rawTransaction common.Bytes, txInfo *core.TxInfo, height uint64) *mempoolTransaction {
 	return &mempoolTransaction{
 		rawTransaction: rawTransaction,
 		txInfo:         txInfo,
+		insertedAt:     time.Now(),
+		insertedHeight: height,
 	}
 }
 
@@ -85,9 +107,8 @@ func (mtg *mempoolTransactionGroup) GetIndex() int {
 	return mtg.index
 }
 
-func (mtg *mempoolTransactionGroup) AddTx(rawTx common.Bytes, txInfo *core.TxInfo) {
-	mpx := createMempoolTransaction(rawTx, txInfo)
-	mtg.txs.Push(mpx)
+func (mtg *mempoolTransactionGroup) AddTx(mptx *mempoolTransaction) {
+	mtg.txs.Push(mptx)
 }
 
 func (mtg *mempoolTransactionGroup) PopTx() (common.Bytes, *core.TxInfo) {
@@ -99,6 +120,35 @@ func (mtg *mempoolTransactionGroup) IsEmpty() bool {
 	return mtg.txs.IsEmpty()
 }
 
+// FindTx returns the pending transaction with the given sequence, or nil if there is none.
+func (mtg *mempoolTransactionGroup) FindTx(sequence uint64) *mempoolTransaction {
+	for _, elem := range *mtg.txs.ElementList() {
+		mptx := elem.(*mempoolTransaction)
+		if mptx.txInfo.Sequence == sequence {
+			return mptx
+		}
+	}
+	return nil
+}
+
+// SortedTxs returns the transactions in the group sorted by sequence.
+func (mtg *mempoolTransactionGroup) SortedTxs() []*mempoolTransaction {
+	txs := []*mempoolTransaction{}
+	for _, elem := range *mtg.txs.ElementList() {
+		txs = append(txs, elem.(*mempoolTransaction))
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].txInfo.Sequence < txs[j].txInfo.Sequence
+	})
+	return txs
+}
+
+// ReplaceTx replaces the pending transaction oldTx with newTx.
+func (mtg *mempoolTransactionGroup) ReplaceTx(oldTx *mempoolTransaction, newTx *mempoolTransaction) {
+	mtg.txs.Remove(oldTx.GetIndex())
+	mtg.AddTx(newTx)
+}
+
 // RemoveTxs removes matching Txs from transaction group. Returns number of Txs removed.
 func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool) (numRemoved int) {
 	elementList := mtg.txs.ElementList()
@@ -118,12 +168,11 @@ func (mtg *mempoolTransactionGroup) RemoveTxs(committedRawTxMap map[string]bool)
 	return
 }
 
-func createMempoolTransactionGroup(rawTx common.Bytes, txInfo *core.TxInfo) *mempoolTransactionGroup {
+func createMempoolTransactionGroup(address common.Address) *mempoolTransactionGroup {
 	txGroup := &mempoolTransactionGroup{
-		address: txInfo.Address,
+		address: address,
 		txs:     pqueue.CreatePriorityQueue(),
 	}
-	txGroup.AddTx(rawTx, txInfo)
 	return txGroup
 }
 
@@ -137,12 +186,21 @@ type Mempool struct {
 	ledger     core.Ledger
 	dispatcher *dp.Dispatcher
 
-	newTxs           *clist.CList          // new transactions, to be gossiped to other nodes
+	newTxs           *clist.CList          // new transactions (*mempoolTransaction), to be gossiped to other nodes
+	gossiper         *txGossiper           // announces the new transactions and serves the transaction requests from peers
 	candidateTxs     *pqueue.PriorityQueue // candidate transactions for new block assembly, ordered by the transaction fee (high to low)
 	txBookeepper     transactionBookkeeper
 	addressToTxGroup map[common.Address]*mempoolTransactionGroup
 	size             int
 
+	replacementPriceBump int64 // minimal gas price increase (in percent) required to replace a pending tx
+
+	height      uint64        // height of the latest block the pending transactions were screened against
+	txTTLBlocks uint64        // number of blocks a tx can stay in the mempool, 0 means no limit
+	txTTL       time.Duration // duration a tx can stay in the mempool, 0 means no limit
+
+	journal *txJournal // persists the accepted transactions across restarts, nil if disabled
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	quit    chan struct{}
@@ -153,7 +211,7 @@ type Mempool struct {
 
 // CreateMempool creates an instance of Mempool
 func CreateMempool(dispatcher *dp.Dispatcher) *Mempool {
-	return &Mempool{
+	mempool := &Mempool{
 		mutex:            &sync.Mutex{},
 		dispatcher:       dispatcher,
 		newTxs:           clist.New(),
@@ -161,7 +219,13 @@ func CreateMempool(dispatcher *dp.Dispatcher) *Mempool {
 		addressToTxGroup: make(map[common.Address]*mempoolTransactionGroup),
 		txBookeepper:     createTransactionBookkeeper(defaultMaxNumTxs),
 		wg:               &sync.WaitGroup{},
+
+		replacementPriceBump: viper.GetInt64(common.CfgMempoolReplacementPriceBump),
+		txTTLBlocks:          viper.GetUint64(common.CfgMempoolTxTTLBlocks),
+		txTTL:                time.Duration(viper.GetInt64(common.CfgMempoolTxTTLSeconds)) * time.Second,
 	}
+	mempool.gossiper = createTxGossiper(mempool, dispatcher)
+	return mempool
 }
 
 // SetLedger sets the ledger for the mempool
@@ -169,20 +233,37 @@ func (mp *Mempool) SetLedger(ledger core.Ledger) {
 	mp.ledger = ledger
 }
 
+// SetJournalFilePath enables the transaction journal, which allows the pending transactions
+// to survive node restarts. Needs to be called before Mempool.Start()
+func (mp *Mempool) SetJournalFilePath(filePath string) {
+	mp.journal = createTxJournal(filePath)
+}
+
 // InsertTransaction inserts the incoming transaction to mempool (submitted by the clients or relayed from peers)
 func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	mp.mutex.Lock()
 	defer mp.mutex.Unlock()
 
+	return mp.insertTransaction(rawTx)
+}
+
+func (mp *Mempool) insertTransaction(rawTx common.Bytes) error {
 	if mp.txBookeepper.hasSeen(rawTx) {
 		logger.Infof("[mempool] Transaction already seen: %v", hex.EncodeToString(rawTx))
 		return DuplicateTxError
 	}
 
 	txInfo, checkTxRes := mp.ledger.ScreenTx(rawTx)
+	if checkTxRes.Code == result.CodeInvalidSequence {
+		// The transaction might be meant to replace a pending transaction with the same sequence
+		replaced, err := mp.replaceTransaction(rawTx)
+		if replaced || err != nil {
+			return err
+		}
+	}
 	if !checkTxRes.IsOK() {
 		logger.Infof("[mempool] Transaction screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), checkTxRes.Message)
-		return errors.New(checkTxRes.Message)
+		return TxScreeningError{Result: checkTxRes}
 	}
 
 	logger.Infof("[mempool] Insert tx: %v, txInfo: %v", hex.EncodeToString(rawTx), txInfo)
@@ -194,19 +275,96 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	// should not be rejected even though it has been submitted earlier.
 	mp.txBookeepper.record(rawTx)
 
-	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
+	mptx := createMempoolTransaction(rawTx, txInfo, mp.height)
+	mp.addMempoolTransaction(mptx)
+	mp.writeJournal(rawTx)
+
+	mp.newTxs.PushBack(mptx)
+	return nil
+}
+
+// addMempoolTransaction adds the screened transaction to its transaction group
+func (mp *Mempool) addMempoolTransaction(mptx *mempoolTransaction) {
+	address := mptx.txInfo.Address
+	txGroup, ok := mp.addressToTxGroup[address]
 	if ok {
-		txGroup.AddTx(rawTx, txInfo)
 		mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
 	} else {
-		txGroup = createMempoolTransactionGroup(rawTx, txInfo)
-		mp.addressToTxGroup[txInfo.Address] = txGroup
+		txGroup = createMempoolTransactionGroup(address)
+		mp.addressToTxGroup[address] = txGroup
 	}
+	txGroup.AddTx(mptx)
 	mp.candidateTxs.Push(txGroup)
-
-	mp.newTxs.PushBack(rawTx)
 	mp.size++
-	return nil
+}
+
+func (mp *Mempool) writeJournal(rawTx common.Bytes) {
+	if mp.journal == nil {
+		return
+	}
+	if err := mp.journal.insert(rawTx); err != nil {
+		logger.Warnf("[mempool] Failed to journal tx: %v, error: %v", hex.EncodeToString(rawTx), err)
+	}
+}
+
+// replaceTransaction tries to replace a pending transaction which has the same address and sequence
+// as the given transaction. The replacement is accepted only if its effective gas price is higher than
+// that of the pending transaction by at least replacementPriceBump percent. It returns false and no
+// error if there is no pending transaction to be replaced.
+func (mp *Mempool) replaceTransaction(rawTx common.Bytes) (bool, error) {
+	txInfo, res := mp.ledger.GetTxInfo(rawTx)
+	if res.IsError() {
+		return false, nil
+	}
+
+	txGroup, ok := mp.addressToTxGroup[txInfo.Address]
+	if !ok {
+		return false, nil
+	}
+	oldTx := txGroup.FindTx(txInfo.Sequence)
+	if oldTx == nil {
+		return false, nil
+	}
+
+	// The replacement is screened against the account state right before the pending
+	// transaction, so the account only needs to afford the replacement
+	precedingRawTxs := []common.Bytes{}
+	for _, mptx := range txGroup.SortedTxs() {
+		if mptx.txInfo.Sequence >= txInfo.Sequence {
+			break
+		}
+		precedingRawTxs = append(precedingRawTxs, mptx.rawTransaction)
+	}
+	txInfo, res = mp.ledger.ScreenTxReplacement(rawTx, precedingRawTxs)
+	if res.IsError() {
+		logger.Infof("[mempool] Replacement tx screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), res.Message)
+		return true, TxScreeningError{Result: res}
+	}
+
+	// Require newPrice * 100 >= oldPrice * (100 + bump), and newPrice > oldPrice
+	oldPrice := oldTx.txInfo.EffectiveGasPrice
+	newPrice := txInfo.EffectiveGasPrice
+	minPrice := new(big.Int).Mul(oldPrice, big.NewInt(100+mp.replacementPriceBump))
+	if newPrice.Cmp(oldPrice) <= 0 || new(big.Int).Mul(newPrice, big.NewInt(100)).Cmp(minPrice) < 0 {
+		logger.Infof("[mempool] Replacement tx underpriced, tx: %v, gas price: %v, pending gas price: %v",
+			hex.EncodeToString(rawTx), newPrice, oldPrice)
+		return true, ReplacementUnderpricedError
+	}
+
+	logger.Infof("[mempool] Replace tx: %v with tx: %v, txInfo: %v",
+		hex.EncodeToString(oldTx.rawTransaction), hex.EncodeToString(rawTx), txInfo)
+
+	mp.txBookeepper.record(rawTx)
+	mp.writeJournal(rawTx)
+
+	oldTx.evicted = true // no need to gossip the replaced tx any more
+	mp.candidateTxs.Remove(txGroup.index)
+	mptx := createMempoolTransaction(rawTx, txInfo, mp.height)
+	txGroup.ReplaceTx(oldTx, mptx)
+	mp.candidateTxs.Push(txGroup)
+
+	mp.newTxs.PushBack(mptx)
+	return true, nil
 }
 
 // Start needs to be called when the Mempool starts
@@ -215,15 +373,45 @@ func (mp *Mempool) Start(ctx context.Context) error {
 	mp.ctx = c
 	mp.cancel = cancel
 
+	if mp.journal != nil {
+		mp.loadJournal()
+	}
+
 	mp.wg.Add(1)
 	go mp.broadcastTransactionsRoutine()
 
+	mp.wg.Add(1)
+	go mp.sweepExpiredTransactionsRoutine()
+
 	return nil
 }
 
+// loadJournal re-inserts the journaled transactions into the mempool. Transactions
+// that no longer pass the screening are discarded.
+func (mp *Mempool) loadJournal() {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	numLoaded, err := mp.journal.load(mp.insertTransaction)
+	if err != nil {
+		logger.Warnf("[mempool] Failed to load tx journal: %v", err)
+	}
+	logger.Infof("[mempool] Loaded %v transactions from the journal", numLoaded)
+
+	if err := mp.journal.rotate(mp.pendingRawTxs()); err != nil {
+		logger.Warnf("[mempool] Failed to rotate tx journal: %v", err)
+	}
+}
+
 // Stop needs to be called when the Mempool stops
 func (mp *Mempool) Stop() {
 	mp.cancel()
+
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+	if mp.journal != nil {
+		mp.journal.close()
+	}
 }
 
 // Wait suspends the caller goroutine
@@ -246,6 +434,21 @@ func (mp *Mempool) Size() int {
 	return mp.size
 }
 
+// GetPendingGasPrices returns the effective gas prices of all the pending transactions
+func (mp *Mempool) GetPendingGasPrices() []*big.Int {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	gasPrices := make([]*big.Int, 0, mp.size)
+	for _, elem := range *mp.candidateTxs.ElementList() {
+		txGroup := elem.(*mempoolTransactionGroup)
+		for _, txElem := range *txGroup.txs.ElementList() {
+			gasPrices = append(gasPrices, txElem.(*mempoolTransaction).txInfo.EffectiveGasPrice)
+		}
+	}
+	return gasPrices
+}
+
 // Reap returns a list of valid raw transactions and remove these
 // transactions from the candidate pool. maxNumTxs == 0 means
 // none, maxNumTxs < 0 means uncapped. Note that Reap does NOT remove
@@ -332,6 +535,126 @@ func (mp *Mempool) UpdateUnsafe(committedRawTxs []common.Bytes) bool {
 	return true
 }
 
+// RescreenUnsafe re-screens all the pending transactions against the screened view of the ledger,
+// and drops the ones that are no longer valid or have expired. It needs to be called whenever the
+// screened view is rebuilt, e.g. after a new block is committed or the ledger state is reset to a
+// different block. The height is that of the latest block. Caller must call Mempool.Lock() before
+// calling this method.
+func (mp *Mempool) RescreenUnsafe(height uint64) {
+	mp.height = height
+	now := time.Now()
+
+	pendingTxs := []*mempoolTransaction{}
+	for _, elem := range *mp.candidateTxs.ElementList() {
+		txGroup := elem.(*mempoolTransactionGroup)
+		pendingTxs = append(pendingTxs, txGroup.SortedTxs()...)
+	}
+
+	mp.candidateTxs = pqueue.CreatePriorityQueue()
+	mp.addressToTxGroup = make(map[common.Address]*mempoolTransactionGroup)
+	mp.size = 0
+
+	numDropped := 0
+	for _, mptx := range pendingTxs {
+		if mp.isExpired(mptx, now) {
+			logger.Debugf("[mempool] Drop expired tx: %v", hex.EncodeToString(mptx.rawTransaction))
+			mp.dropTransaction(mptx)
+			numDropped++
+			continue
+		}
+		txInfo, res := mp.ledger.ScreenTx(mptx.rawTransaction)
+		if res.IsError() {
+			logger.Debugf("[mempool] Drop tx: %v, error: %v", hex.EncodeToString(mptx.rawTransaction), res.Message)
+			mp.dropTransaction(mptx)
+			numDropped++
+			continue
+		}
+		mptx.txInfo = txInfo
+		mp.addMempoolTransaction(mptx)
+	}
+
+	if numDropped > 0 {
+		logger.Infof("[mempool] Re-screened %v transactions, %v dropped", len(pendingTxs), numDropped)
+	}
+
+	if mp.journal != nil && mp.journal.loaded {
+		if err := mp.journal.rotate(mp.pendingRawTxs()); err != nil {
+			logger.Warnf("[mempool] Failed to rotate tx journal: %v", err)
+		}
+	}
+}
+
+// dropTransaction marks the transaction as evicted, and removes it from the transactionBookkeeper
+// since it could become valid again later on. The caller is responsible to remove it from its group.
+func (mp *Mempool) dropTransaction(mptx *mempoolTransaction) {
+	mptx.evicted = true
+	mp.txBookeepper.remove(mptx.rawTransaction)
+}
+
+// isExpired checks whether the transaction has stayed in the mempool for longer than the TTL,
+// either in terms of blocks or wall-clock time
+func (mp *Mempool) isExpired(mptx *mempoolTransaction, now time.Time) bool {
+	if mp.txTTLBlocks > 0 && mp.height > mptx.insertedHeight+mp.txTTLBlocks {
+		return true
+	}
+	if mp.txTTL > 0 && now.Sub(mptx.insertedAt) > mp.txTTL {
+		return true
+	}
+	return false
+}
+
+// RemoveExpiredTransactions removes the expired transactions from the transaction candidate
+// list and the transactionBookkeeper. It returns the number of transactions removed.
+func (mp *Mempool) RemoveExpiredTransactions() int {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	return mp.removeExpiredTransactions(time.Now())
+}
+
+func (mp *Mempool) removeExpiredTransactions(now time.Time) (numRemoved int) {
+	for address, txGroup := range mp.addressToTxGroup {
+		expiredTxs := []*mempoolTransaction{}
+		for _, elem := range *txGroup.txs.ElementList() {
+			mptx := elem.(*mempoolTransaction)
+			if mp.isExpired(mptx, now) {
+				expiredTxs = append(expiredTxs, mptx)
+			}
+		}
+		if len(expiredTxs) == 0 {
+			continue
+		}
+
+		mp.candidateTxs.Remove(txGroup.index) // Need to re-insert txGroup into queue since its priority could change.
+		for _, mptx := range expiredTxs {
+			logger.Debugf("[mempool] Remove expired tx: %v, txInfo: %v", hex.EncodeToString(mptx.rawTransaction), mptx.txInfo)
+			txGroup.txs.Remove(mptx.GetIndex())
+			mp.dropTransaction(mptx)
+		}
+		if txGroup.IsEmpty() {
+			delete(mp.addressToTxGroup, address)
+		} else {
+			mp.candidateTxs.Push(txGroup)
+		}
+
+		mp.size -= len(expiredTxs)
+		numRemoved += len(expiredTxs)
+	}
+	return numRemoved
+}
+
+// pendingRawTxs returns the pending transactions, ordered by sequence for each address
+func (mp *Mempool) pendingRawTxs() []common.Bytes {
+	rawTxs := []common.Bytes{}
+	for _, elem := range *mp.candidateTxs.ElementList() {
+		txGroup := elem.(*mempoolTransactionGroup)
+		for _, mptx := range txGroup.SortedTxs() {
+			rawTxs = append(rawTxs, mptx.rawTransaction)
+		}
+	}
+	return rawTxs
+}
+
 // Flush removes all transactions from the Mempool and the transactionBookkeeper
 func (mp *Mempool) Flush() {
 	mp.mutex.Lock()
@@ -345,7 +668,26 @@ func (mp *Mempool) Flush() {
 	mp.size = 0
 }
 
-// broadcastTransactionRoutine broadcasts transactions to neighoring peers
+// sweepExpiredTransactionsRoutine periodically removes the expired transactions
+func (mp *Mempool) sweepExpiredTransactionsRoutine() {
+	defer mp.wg.Done()
+
+	ticker := time.NewTicker(txSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mp.ctx.Done():
+			return
+		case <-ticker.C:
+			if numRemoved := mp.RemoveExpiredTransactions(); numRemoved > 0 {
+				logger.Infof("[mempool] Removed %v expired transactions", numRemoved)
+			}
+		}
+	}
+}
+
+// broadcastTransactionRoutine announces the new transactions to the neighboring peers in batches
 func (mp *Mempool) broadcastTransactionsRoutine() {
 	defer mp.wg.Done()
 
@@ -362,19 +704,22 @@ func (mp *Mempool) broadcastTransactionsRoutine() {
 			next = mp.newTxs.FrontWait() // Wait until a tx is available
 		}
 
-		rawTx := next.Value.(common.Bytes)
-
-		// Broadcast the transaction
-		data := dp.DataResponse{
-			ChannelID: common.ChannelIDTransaction,
-			Payload:   rawTx,
+		// Collect the transactions that are already available, unless they have been replaced in the meantime
+		rawTxs := []common.Bytes{}
+		mp.mutex.Lock()
+		for next != nil && len(rawTxs) < maxNumTxsPerAnnounce {
+			mptx := next.Value.(*mempoolTransaction)
+			if !mptx.evicted {
+				rawTxs = append(rawTxs, mptx.rawTransaction)
+			}
+			curr := next
+			next = curr.Next()
+			mp.newTxs.Remove(curr) // already broadcasted, should remove
 		}
+		mp.mutex.Unlock()
 
-		peerIDs := []string{} // empty peerID list means broadcasting to all neighboring peers
-		mp.dispatcher.SendData(peerIDs, data)
-
-		curr := next
-		next = curr.NextWait()
-		mp.newTxs.Remove(curr) // already broadcasted, should remove
+		if len(rawTxs) > 0 {
+			mp.gossiper.announce(rawTxs)
+		}
 	}
 }
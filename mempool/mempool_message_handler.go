@@ -4,9 +4,8 @@ import (
 	"encoding/hex"
 	"fmt"
 
-	"github.com/thetatoken/theta/rlp"
-
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
 	"github.com/thetatoken/theta/p2p/types"
 
 	dp "github.com/thetatoken/theta/dispatcher"
@@ -36,21 +35,18 @@ func (mmh *MempoolMessageHandler) GetChannelIDs() []common.ChannelIDEnum {
 
 // EncodeMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) EncodeMessage(message interface{}) (common.Bytes, error) {
-	return rlp.EncodeToBytes(message)
+	return encodeMessage(message)
 }
 
 // ParseMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
-	var dataResponse dp.DataResponse
-	rlp.DecodeBytes(rawMessageBytes, &dataResponse)
-
-	rawTx := dataResponse.Payload
 	message := types.Message{
 		PeerID:    peerID,
 		ChannelID: channelID,
-		Content:   rawTx,
 	}
-	return message, nil
+	data, err := decodeMessage(rawMessageBytes)
+	message.Content = data
+	return message, err
 }
 
 // HandleMessage implements the p2p.MessageHandler interface
@@ -58,12 +54,30 @@ func (mmh *MempoolMessageHandler) HandleMessage(message types.Message) error {
 	if message.ChannelID != common.ChannelIDTransaction {
 		return fmt.Errorf("Invalid channel for MempoolMessageHandler: %v", message.ChannelID)
 	}
-	rawTx := message.Content.(common.Bytes)
-	logger.Infof("Received gossiped transaction: %v", hex.EncodeToString(rawTx))
 
-	err := mmh.mempool.InsertTransaction(rawTx)
-	if err == DuplicateTxError {
-		return nil
+	gossiper := mmh.mempool.gossiper
+	switch content := message.Content.(type) {
+	case dp.InventoryResponse:
+		gossiper.handleAnnouncement(message.PeerID, content)
+	case dp.DataRequest:
+		gossiper.handleRequest(message.PeerID, content)
+	case dp.DataResponse:
+		rawTx := content.Payload
+		logger.Infof("Received gossiped transaction: %v", hex.EncodeToString(rawTx))
+
+		gossiper.handleResponse(message.PeerID, rawTx)
+		err := mmh.mempool.InsertTransaction(rawTx)
+		if err == DuplicateTxError {
+			return nil
+		}
+		if screeningErr, ok := err.(TxScreeningError); ok && screeningErr.Result.Code == result.CodeInvalidSignature {
+			// Honest peers only relay the transactions that passed the screening, and a bad
+			// signature, unlike a stale sequence or balance, never becomes valid later on
+			mmh.mempool.dispatcher.ReportPeer(message.PeerID, types.MisbehaviorInvalidTx)
+		}
+		return err
+	default:
+		return fmt.Errorf("Unknown message type for MempoolMessageHandler: %v", message.Content)
 	}
-	return err
+	return nil
 }
@@ -383,15 +383,261 @@ func TestMempoolTransactionGossip(t *testing.T) {
 	assert.Equal(3, mempool.Size())
 	log.Infof(">>> Client submitted tx1, tx2, tx3")
 
-	numGossippedTxs := 2 * 3 // 2 peers, each should receive 3 transactions
-	for i := 0; i < numGossippedTxs; i++ {
+	txHashes := map[string]string{
+		getTransactionHash(tx1): "tx1",
+		getTransactionHash(tx2): "tx2",
+		getTransactionHash(tx3): "tx3",
+	}
+	numAnnouncedTxs := 2 * 3 // 2 peers, each should receive the announcements of 3 transactions
+	for numAnnouncedTxs > 0 {
 		receivedMsg := <-netMsgIntercepter.ReceivedMessages
 		senderID := receivedMsg.PeerID
-		dataResponse := receivedMsg.Content.(dp.DataResponse)
-		rawTx := string(dataResponse.Payload[:])
-		log.Infof("received transaction, sender: %v, rawTx: %v", senderID, rawTx)
-		assert.True(rawTx == "tx1" || rawTx == "tx2" || rawTx == "tx3")
+		announcement := receivedMsg.Content.(dp.InventoryResponse)
+		assert.Equal(common.ChannelIDTransaction, announcement.ChannelID)
+		for _, txhash := range announcement.Entries {
+			rawTx, ok := txHashes[txhash]
+			log.Infof("received transaction announcement, sender: %v, rawTx: %v", senderID, rawTx)
+			assert.True(ok)
+			numAnnouncedTxs--
+		}
+	}
+
+	// Peers fetch the announced transaction
+	peer1.Send("peer0", p2ptypes.Message{
+		ChannelID: common.ChannelIDTransaction,
+		Content: dp.DataRequest{
+			ChannelID: common.ChannelIDTransaction,
+			Entries:   []string{getTransactionHash(tx2)},
+		},
+	})
+	receivedMsg := <-netMsgIntercepter.ReceivedMessages
+	for receivedMsg.PeerID != "peer0" {
+		receivedMsg = <-netMsgIntercepter.ReceivedMessages // skip the request itself
+	}
+	dataResponse := receivedMsg.Content.(dp.DataResponse)
+	assert.Equal("tx2", string(dataResponse.Payload[:]))
+}
+
+func TestMempoolAnnounceAndFetch(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool0, ctx := newTestMempool("peer0", p2psimnet)
+	mempool0.Start(ctx)
+	mempool1, _ := newTestMempool("peer1", p2psimnet)
+	mempool1.Start(ctx)
+	p2psimnet.Start(ctx)
+
+	tx1 := createTestRawTx("tx1")
+	tx2 := createTestRawTx("tx2")
+	tx3 := createTestRawTx("tx3")
+	assert.Nil(mempool0.InsertTransaction(tx1))
+	assert.Nil(mempool0.InsertTransaction(tx2))
+	assert.Nil(mempool0.InsertTransaction(tx3))
+
+	// peer1 fetches the announced transactions
+	size := func() int {
+		mempool1.Lock()
+		defer mempool1.Unlock()
+		return mempool1.Size()
+	}
+	for i := 0; i < 100 && size() < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(3, size())
+
+	// peer1 remembers that peer0 knows the transactions, so they are not announced back to peer0
+	mempool1.gossiper.mutex.Lock()
+	knownTxs := mempool1.gossiper.getPeerKnownTxs("peer0")
+	assert.True(knownTxs.has(getTransactionHash(tx1)))
+	assert.True(knownTxs.has(getTransactionHash(tx2)))
+	assert.True(knownTxs.has(getTransactionHash(tx3)))
+	mempool1.gossiper.mutex.Unlock()
+}
+
+func TestMempoolReplaceByFee(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.replacementPriceBump = 10
+	mempool.SetLedger(newTestReplacementLedger(map[string]*core.TxInfo{
+		"txA1":  newTestTxInfo("A", 1, 100),
+		"txA2":  newTestTxInfo("A", 2, 100),
+		"txA1a": newTestTxInfo("A", 1, 105), // underpriced replacement
+		"txA1b": newTestTxInfo("A", 1, 110),
+		"txA4":  newTestTxInfo("A", 4, 100), // invalid sequence, nothing to replace
+		"txB1":  newTestTxInfo("B", 1, 50),
+	}))
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Equal(3, mempool.Size())
+
+	assert.Equal(ReplacementUnderpricedError, mempool.InsertTransaction(createTestRawTx("txA1a")))
+	assert.NotNil(mempool.InsertTransaction(createTestRawTx("txA4")))
+	assert.Equal(3, mempool.Size())
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1b")))
+	assert.Equal(3, mempool.Size())
+	assert.Equal(DuplicateTxError, mempool.InsertTransaction(createTestRawTx("txA1b")))
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(3, len(reapedRawTxs))
+	assert.Equal("txA1b", string(reapedRawTxs[0][:]))
+	assert.Equal("txA2", string(reapedRawTxs[1][:]))
+	assert.Equal("txB1", string(reapedRawTxs[2][:]))
+
+	// The replaced transaction should not be gossiped
+	numEvicted := 0
+	for e := mempool.newTxs.Front(); e != nil; e = e.Next() {
+		mptx := e.Value.(*mempoolTransaction)
+		if mptx.evicted {
+			assert.Equal("txA1", string(mptx.rawTransaction))
+			numEvicted++
+		}
+	}
+	assert.Equal(1, numEvicted)
+}
+
+func TestMempoolReplaceByFeeBalance(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.replacementPriceBump = 10
+	ledger := newTestReplacementLedger(map[string]*core.TxInfo{
+		"txA1":  newTestTxInfo("A", 1, 100),
+		"txA2":  newTestTxInfo("A", 2, 100),
+		"txA2a": newTestTxInfo("A", 2, 200),
+		"txA2b": newTestTxInfo("A", 2, 140),
+	}).(*TestReplacementLedger)
+	ledger.balances = map[common.Address]int64{
+		common.HexToAddress("A"): 250,
+	}
+	ledger.costs = map[string]int64{
+		"txA1":  100,
+		"txA2":  100,
+		"txA2a": 200, // cannot be afforded even without txA2
+		"txA2b": 140, // can be afforded only without txA2
+	}
+	mempool.SetLedger(ledger)
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Equal(2, mempool.Size())
+
+	err := mempool.InsertTransaction(createTestRawTx("txA2a"))
+	assert.NotNil(err)
+	assert.IsType(TxScreeningError{}, err)
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2b")))
+	assert.Equal(2, mempool.Size())
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(2, len(reapedRawTxs))
+	assert.Equal("txA1", string(reapedRawTxs[0][:]))
+	assert.Equal("txA2b", string(reapedRawTxs[1][:]))
+}
+
+func TestMempoolRescreen(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	ledger := newTestReplacementLedger(map[string]*core.TxInfo{
+		"txA1": newTestTxInfo("A", 1, 100),
+		"txA2": newTestTxInfo("A", 2, 100),
+		"txA3": newTestTxInfo("A", 3, 100),
+		"txB1": newTestTxInfo("B", 1, 50),
+	}).(*TestReplacementLedger)
+	mempool.SetLedger(ledger)
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA2")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA3")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Equal(4, mempool.Size())
+
+	// Simulate a new block which includes txA1, and another transaction from B with sequence 1.
+	// The screened view is rebuilt from the new block.
+	ledger.sequences = map[common.Address]uint64{
+		common.HexToAddress("A"): 1,
+		common.HexToAddress("B"): 1,
 	}
+	mempool.Lock()
+	mempool.UpdateUnsafe([]common.Bytes{createTestRawTx("txA1")})
+	mempool.RescreenUnsafe(1)
+	mempool.Unlock()
+
+	assert.Equal(2, mempool.Size())
+	assert.Equal(uint64(3), ledger.sequences[common.HexToAddress("A")])
+	assert.False(mempool.txBookeepper.hasSeen(createTestRawTx("txB1")))
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(2, len(reapedRawTxs))
+	assert.Equal("txA2", string(reapedRawTxs[0][:]))
+	assert.Equal("txA3", string(reapedRawTxs[1][:]))
+}
+
+func TestMempoolExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	mempool.txTTLBlocks = 10
+	mempool.txTTL = 1 * time.Hour
+	ledger := newTestReplacementLedger(map[string]*core.TxInfo{
+		"txA1": newTestTxInfo("A", 1, 100),
+		"txA3": newTestTxInfo("A", 3, 100), // sequence gap, never becomes valid
+		"txB1": newTestTxInfo("B", 1, 50),
+		"txC1": newTestTxInfo("C", 1, 80),
+	}).(*TestReplacementLedger)
+	mempool.SetLedger(ledger)
+
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA1")))
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Equal(2, mempool.Size())
+
+	// txA3 is inserted with a sequence gap, which can only be removed by the TTL
+	ledger.sequences[common.HexToAddress("A")] = 2
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txA3")))
+	assert.Equal(3, mempool.Size())
+
+	// No transaction expires before the TTL
+	mempool.Lock()
+	assert.Equal(0, mempool.removeExpiredTransactions(time.Now()))
+	mempool.Unlock()
+	assert.Equal(3, mempool.Size())
+
+	// Wall-clock TTL
+	mempool.Lock()
+	assert.Equal(3, mempool.removeExpiredTransactions(time.Now().Add(2*time.Hour)))
+	mempool.Unlock()
+	assert.Equal(0, mempool.Size())
+	assert.Equal(0, len(mempool.addressToTxGroup))
+	assert.False(mempool.txBookeepper.hasSeen(createTestRawTx("txA3")))
+	assert.Equal(0, len(mempool.Reap(-1)))
+
+	// Block TTL
+	ledger.sequences = make(map[common.Address]uint64)
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txC1")))
+	mempool.Lock()
+	mempool.height = 5
+	mempool.Unlock()
+	assert.Nil(mempool.InsertTransaction(createTestRawTx("txB1")))
+	assert.Equal(2, mempool.Size())
+
+	mempool.Lock()
+	mempool.height = 11
+	assert.Equal(1, mempool.removeExpiredTransactions(time.Now()))
+	mempool.Unlock()
+	assert.Equal(1, mempool.Size())
+
+	reapedRawTxs := mempool.Reap(-1)
+	assert.Equal(1, len(reapedRawTxs))
+	assert.Equal("txB1", string(reapedRawTxs[0][:]))
 }
 
 // --------------- Test Utilities --------------- //
@@ -468,11 +714,19 @@ func (tl *TestLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result)
 	return txInfo, result.OK
 }
 
-func (tl *TestLedger) ProposeBlockTxs() (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
+func (tl *TestLedger) ScreenTxReplacement(rawTx common.Bytes, precedingRawTxs []common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.Error("Replacement not supported")
+}
+
+func (tl *TestLedger) GetTxInfo(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return nil, result.Error("Replacement not supported")
+}
+
+func (tl *TestLedger) ProposeBlockTxs(block *core.Block) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
 	return common.Hash{}, []common.Bytes{}, result.OK
 }
 
-func (tl *TestLedger) ApplyBlockTxs(blockRawTxs []common.Bytes, expectedStateRoot common.Hash) result.Result {
+func (tl *TestLedger) ApplyBlockTxs(block *core.Block) result.Result {
 	return result.OK
 }
 
@@ -488,6 +742,74 @@ func (tl *TestLedger) GetFinalizedValidatorCandidatePool(blockHash common.Hash,
 	return nil, nil
 }
 
+// TestReplacementLedger screens transactions according to a pre-defined TxInfo table. It
+// keeps track of the last screened sequence of each address like the screened view does.
+// If balances are set, it also makes sure each address can afford the costs of its
+// transactions.
+type TestReplacementLedger struct {
+	TestLedger
+
+	txInfos   map[string]*core.TxInfo
+	sequences map[common.Address]uint64
+
+	costs    map[string]int64
+	balances map[common.Address]int64 // balances before any pending tx
+	spent    map[common.Address]int64 // spent by the screened txs
+}
+
+func newTestReplacementLedger(txInfos map[string]*core.TxInfo) core.Ledger {
+	return &TestReplacementLedger{
+		txInfos:   txInfos,
+		sequences: make(map[common.Address]uint64),
+		spent:     make(map[common.Address]int64),
+	}
+}
+
+func newTestTxInfo(address string, sequence uint64, gasPrice int64) *core.TxInfo {
+	return &core.TxInfo{
+		EffectiveGasPrice: big.NewInt(gasPrice),
+		Address:           common.HexToAddress(address),
+		Sequence:          sequence,
+	}
+}
+
+func (tl *TestReplacementLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	txInfo := tl.txInfos[string(rawTx)]
+	if txInfo.Sequence != tl.sequences[txInfo.Address]+1 {
+		return nil, result.Error("Invalid sequence").WithErrorCode(result.CodeInvalidSequence)
+	}
+	if tl.balances != nil {
+		cost := tl.costs[string(rawTx)]
+		if tl.balances[txInfo.Address]-tl.spent[txInfo.Address] < cost {
+			return nil, result.Error("Insufficient fund").WithErrorCode(result.CodeInsufficientFund)
+		}
+		tl.spent[txInfo.Address] += cost
+	}
+	tl.sequences[txInfo.Address] = txInfo.Sequence
+	return txInfo, result.OK
+}
+
+func (tl *TestReplacementLedger) ScreenTxReplacement(rawTx common.Bytes, precedingRawTxs []common.Bytes) (*core.TxInfo, result.Result) {
+	txInfo := tl.txInfos[string(rawTx)]
+	if txInfo.Sequence > tl.sequences[txInfo.Address] {
+		return nil, result.Error("Invalid sequence").WithErrorCode(result.CodeInvalidSequence)
+	}
+	if tl.balances != nil {
+		balance := tl.balances[txInfo.Address]
+		for _, precedingRawTx := range precedingRawTxs {
+			balance -= tl.costs[string(precedingRawTx)]
+		}
+		if balance < tl.costs[string(rawTx)] {
+			return nil, result.Error("Insufficient fund").WithErrorCode(result.CodeInsufficientFund)
+		}
+	}
+	return txInfo, result.OK
+}
+
+func (tl *TestReplacementLedger) GetTxInfo(rawTx common.Bytes) (*core.TxInfo, result.Result) {
+	return tl.txInfos[string(rawTx)], result.OK
+}
+
 type TestNetworkMessageInterceptor struct {
 	lock             *sync.Mutex
 	ReceivedMessages chan p2ptypes.Message
@@ -46,6 +46,13 @@ func (tb *transactionBookkeeper) hasSeen(rawTx common.Bytes) bool {
 	return exists
 }
 
+func (tb *transactionBookkeeper) hasSeenHash(txhash string) bool {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	_, exists := tb.txMap[txhash]
+	return exists
+}
+
 func (tb *transactionBookkeeper) record(rawTx common.Bytes) bool {
 	tb.mutex.Lock()
 	defer tb.mutex.Unlock()
@@ -0,0 +1,215 @@
+package mempool
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	dp "github.com/thetatoken/theta/dispatcher"
+)
+
+const (
+	maxNumKnownTxsPerPeer = 32768               // max number of tx hashes remembered for each peer
+	maxNumGossipTxs       = 16384               // max number of announced raw txs kept to serve the tx requests
+	maxNumTxsPerAnnounce  = dp.MaxInventorySize // max number of tx hashes in one announcement
+	txRequestTimeout      = 5 * time.Second     // after the timeout, the tx can be requested from another peer
+)
+
+//
+// txHashCache is a FIFO cache of tx hashes, optionally with the corresponding raw transactions
+//
+type txHashCache struct {
+	entries map[string]common.Bytes // map: transaction hash -> raw transaction
+	order   list.List               // FIFO list of transaction hashes
+	maxSize int
+}
+
+func createTxHashCache(maxSize int) *txHashCache {
+	return &txHashCache{
+		entries: make(map[string]common.Bytes),
+		maxSize: maxSize,
+	}
+}
+
+func (tc *txHashCache) add(txhash string, rawTx common.Bytes) {
+	if _, exists := tc.entries[txhash]; exists {
+		return
+	}
+	if tc.order.Len() >= tc.maxSize { // remove the oldest entry
+		oldest := tc.order.Front()
+		delete(tc.entries, oldest.Value.(string))
+		tc.order.Remove(oldest)
+	}
+	tc.entries[txhash] = rawTx
+	tc.order.PushBack(txhash)
+}
+
+func (tc *txHashCache) has(txhash string) bool {
+	_, exists := tc.entries[txhash]
+	return exists
+}
+
+func (tc *txHashCache) get(txhash string) (common.Bytes, bool) {
+	rawTx, exists := tc.entries[txhash]
+	return rawTx, exists
+}
+
+//
+// txGossiper implements the announce-and-fetch transaction gossip. Instead of
+// pushing the raw transactions to all the neighbors, a node announces the hashes
+// of the new transactions in batches, and the neighbors only request the transactions
+// they have not seen yet. The gossiper remembers which transactions each peer already
+// knows, so that a transaction is never announced back to a peer that knows it.
+//
+type txGossiper struct {
+	mutex *sync.Mutex
+
+	mempool    *Mempool
+	dispatcher *dp.Dispatcher
+
+	peerKnownTxs map[string]*txHashCache // map: peerID -> hashes of the txs known by the peer
+	gossipTxs    *txHashCache            // announced txs, to serve the tx requests from the peers
+	requestedTxs map[string]time.Time    // map: transaction hash -> time when the tx was requested
+}
+
+func createTxGossiper(mempool *Mempool, dispatcher *dp.Dispatcher) *txGossiper {
+	return &txGossiper{
+		mutex:        &sync.Mutex{},
+		mempool:      mempool,
+		dispatcher:   dispatcher,
+		peerKnownTxs: make(map[string]*txHashCache),
+		gossipTxs:    createTxHashCache(maxNumGossipTxs),
+		requestedTxs: make(map[string]time.Time),
+	}
+}
+
+// announce announces the hashes of the given raw transactions to the peers that do not know them yet
+func (tg *txGossiper) announce(rawTxs []common.Bytes) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	txhashes := make([]string, 0, len(rawTxs))
+	for _, rawTx := range rawTxs {
+		txhash := getTransactionHash(rawTx)
+		tg.gossipTxs.add(txhash, rawTx)
+		txhashes = append(txhashes, txhash)
+	}
+
+	peerIDs := tg.dispatcher.Peers()
+	tg.prunePeers(peerIDs)
+	for _, peerID := range peerIDs {
+		knownTxs := tg.getPeerKnownTxs(peerID)
+		entries := []string{}
+		for _, txhash := range txhashes {
+			if knownTxs.has(txhash) {
+				continue
+			}
+			knownTxs.add(txhash, nil)
+			entries = append(entries, txhash)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		announcement := dp.InventoryResponse{
+			ChannelID: common.ChannelIDTransaction,
+			Entries:   entries,
+		}
+		tg.dispatcher.SendInventory([]string{peerID}, announcement)
+	}
+}
+
+// handleAnnouncement requests the announced transactions that have not been seen yet
+func (tg *txGossiper) handleAnnouncement(peerID string, announcement dp.InventoryResponse) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	now := time.Now()
+	knownTxs := tg.getPeerKnownTxs(peerID)
+	entries := []string{}
+	for _, txhash := range announcement.Entries {
+		knownTxs.add(txhash, nil)
+		if tg.mempool.txBookeepper.hasSeenHash(txhash) {
+			continue
+		}
+		if requestedAt, ok := tg.requestedTxs[txhash]; ok && now.Sub(requestedAt) < txRequestTimeout {
+			continue // already requested from another peer
+		}
+		tg.requestedTxs[txhash] = now
+		entries = append(entries, txhash)
+	}
+	tg.pruneRequests(now)
+	if len(entries) == 0 {
+		return
+	}
+
+	request := dp.DataRequest{
+		ChannelID: common.ChannelIDTransaction,
+		Entries:   entries,
+	}
+	tg.dispatcher.GetData([]string{peerID}, request)
+}
+
+// handleRequest sends the requested transactions to the peer
+func (tg *txGossiper) handleRequest(peerID string, request dp.DataRequest) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	knownTxs := tg.getPeerKnownTxs(peerID)
+	for _, txhash := range request.Entries {
+		rawTx, ok := tg.gossipTxs.get(txhash)
+		if !ok {
+			logger.Debugf("[mempool] Requested tx not available: %v, peer: %v", txhash, peerID)
+			continue
+		}
+		knownTxs.add(txhash, nil)
+
+		response := dp.DataResponse{
+			ChannelID: common.ChannelIDTransaction,
+			Payload:   rawTx,
+		}
+		tg.dispatcher.SendData([]string{peerID}, response)
+	}
+}
+
+// handleResponse records that the peer knows the received transaction
+func (tg *txGossiper) handleResponse(peerID string, rawTx common.Bytes) {
+	tg.mutex.Lock()
+	defer tg.mutex.Unlock()
+
+	txhash := getTransactionHash(rawTx)
+	tg.getPeerKnownTxs(peerID).add(txhash, nil)
+	delete(tg.requestedTxs, txhash)
+}
+
+func (tg *txGossiper) getPeerKnownTxs(peerID string) *txHashCache {
+	knownTxs, ok := tg.peerKnownTxs[peerID]
+	if !ok {
+		knownTxs = createTxHashCache(maxNumKnownTxsPerPeer)
+		tg.peerKnownTxs[peerID] = knownTxs
+	}
+	return knownTxs
+}
+
+// prunePeers forgets the peers that are no longer connected
+func (tg *txGossiper) prunePeers(peerIDs []string) {
+	connected := make(map[string]bool)
+	for _, peerID := range peerIDs {
+		connected[peerID] = true
+	}
+	for peerID := range tg.peerKnownTxs {
+		if !connected[peerID] {
+			delete(tg.peerKnownTxs, peerID)
+		}
+	}
+}
+
+// pruneRequests removes the timed out tx requests
+func (tg *txGossiper) pruneRequests(now time.Time) {
+	for txhash, requestedAt := range tg.requestedTxs {
+		if now.Sub(requestedAt) >= txRequestTimeout {
+			delete(tg.requestedTxs, txhash)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package mempool
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+//
+// txJournal persists the raw transactions accepted by the mempool, so that
+// they can be re-inserted into the mempool after the node restarts
+//
+type txJournal struct {
+	filePath string
+	writer   *os.File
+	loaded   bool // the journal file should not be rotated before it is loaded
+}
+
+func createTxJournal(filePath string) *txJournal {
+	return &txJournal{
+		filePath: filePath,
+	}
+}
+
+// load reads the raw transactions from the journal file, and passes them to the add
+// function one by one. It returns the number of transactions loaded.
+func (tj *txJournal) load(add func(rawTx common.Bytes) error) (int, error) {
+	tj.loaded = true
+
+	input, err := os.Open(tj.filePath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer input.Close()
+
+	numLoaded := 0
+	stream := rlp.NewStream(input, 0)
+	for {
+		var rawTx common.Bytes
+		if err = stream.Decode(&rawTx); err != nil {
+			if err == io.EOF {
+				return numLoaded, nil
+			}
+			return numLoaded, err // the tail of the journal could be corrupted if the node crashed while writing
+		}
+		if add(rawTx) == nil {
+			numLoaded++
+		}
+	}
+}
+
+// insert appends the given raw transaction to the journal file.
+func (tj *txJournal) insert(rawTx common.Bytes) error {
+	if tj.writer == nil {
+		return nil
+	}
+	return rlp.Encode(tj.writer, rawTx)
+}
+
+// rotate regenerates the journal file with the given raw transactions, which
+// are typically the transactions currently pending in the mempool.
+func (tj *txJournal) rotate(rawTxs []common.Bytes) error {
+	if tj.writer != nil {
+		if err := tj.writer.Close(); err != nil {
+			return err
+		}
+		tj.writer = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tj.filePath), 0700); err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	for _, rawTx := range rawTxs {
+		if err := rlp.Encode(buf, rawTx); err != nil {
+			return err
+		}
+	}
+	if err := common.WriteFileAtomic(tj.filePath, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	writer, err := os.OpenFile(tj.filePath, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	tj.writer = writer
+	return nil
+}
+
+// close flushes the journal file to disk and closes it.
+func (tj *txJournal) close() error {
+	if tj.writer == nil {
+		return nil
+	}
+	err := tj.writer.Close()
+	tj.writer = nil
+	return err
+}
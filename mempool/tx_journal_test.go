@@ -0,0 +1,56 @@
+package mempool
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func TestTxJournal(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "theta-mempool-journal-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	journalPath := path.Join(dir, "mempool", "journal")
+	tj := createTxJournal(journalPath)
+
+	loaded := []common.Bytes{}
+	add := func(rawTx common.Bytes) error {
+		loaded = append(loaded, rawTx)
+		return nil
+	}
+
+	// Non-existing journal file
+	numLoaded, err := tj.load(add)
+	assert.Nil(err)
+	assert.Equal(0, numLoaded)
+
+	assert.Nil(tj.rotate([]common.Bytes{createTestRawTx("tx1"), createTestRawTx("tx2")}))
+	assert.Nil(tj.insert(createTestRawTx("tx3")))
+	assert.Nil(tj.close())
+
+	tj = createTxJournal(journalPath)
+	numLoaded, err = tj.load(add)
+	assert.Nil(err)
+	assert.Equal(3, numLoaded)
+	assert.Equal("tx1", string(loaded[0]))
+	assert.Equal("tx2", string(loaded[1]))
+	assert.Equal("tx3", string(loaded[2]))
+
+	// Rotation should discard the transactions no longer pending
+	assert.Nil(tj.rotate([]common.Bytes{createTestRawTx("tx3")}))
+	assert.Nil(tj.close())
+
+	loaded = []common.Bytes{}
+	tj = createTxJournal(journalPath)
+	numLoaded, err = tj.load(add)
+	assert.Nil(err)
+	assert.Equal(1, numLoaded)
+	assert.Equal("tx3", string(loaded[0]))
+}
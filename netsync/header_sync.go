@@ -0,0 +1,610 @@
+package netsync
+
+import (
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/result"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/dispatcher"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxNumHeadersPerResponse is the max number of headers a node serves in one header response
+	MaxNumHeadersPerResponse = 512
+	// MaxNumPendingHeaders is the max number of verified headers waiting for their block bodies
+	MaxNumPendingHeaders = 16384
+	// BlockRangeSize is the number of block bodies requested from a peer in one batch
+	BlockRangeSize = 32
+	// MaxRangesPerPeer is the max number of block ranges in flight to a single peer
+	MaxRangesPerPeer = 2
+	// MinRangeTimeout and MaxRangeTimeout bound the adaptive timeout of a block range request
+	MinRangeTimeout = 5 * time.Second
+	MaxRangeTimeout = 30 * time.Second
+	// HeaderChainTimeout is the max time the header chain can go without progress before it is reset
+	HeaderChainTimeout = 2 * time.Minute
+)
+
+const (
+	throughputDecay    = 0.7 // weight of the history in the exponential moving average of the peer throughput
+	rangeTimeoutFactor = 3   // a range times out if it takes longer than this factor times the expected time
+)
+
+//
+// blockRange is a batch of consecutive block bodies requested from one peer
+//
+type blockRange struct {
+	headers     []*core.BlockHeader
+	pending     map[common.Hash]bool // hashes of the bodies not received yet
+	peerID      string
+	assignedAt  time.Time
+	timeout     time.Duration
+	status      RequestState
+	failedPeers map[string]bool // peers that failed to deliver the range in time
+}
+
+func newBlockRange(headers []*core.BlockHeader) *blockRange {
+	br := &blockRange{
+		headers:     headers,
+		pending:     make(map[common.Hash]bool),
+		status:      RequestToSendDataReq,
+		failedPeers: make(map[string]bool),
+	}
+	for _, header := range headers {
+		br.pending[header.Hash()] = true
+	}
+	return br
+}
+
+func (br *blockRange) lastHeight() uint64 {
+	return br.headers[len(br.headers)-1].Height
+}
+
+func (br *blockRange) pendingHashes() []string {
+	hashes := []string{}
+	for _, header := range br.headers {
+		if br.pending[header.Hash()] {
+			hashes = append(hashes, header.Hash().Hex())
+		}
+	}
+	return hashes
+}
+
+func (br *blockRange) hasTimedOut(now time.Time) bool {
+	return br.status == RequestWaitingDataResp && now.Sub(br.assignedAt) > br.timeout
+}
+
+//
+// peerSyncStats tracks the sync related states of a peer
+//
+type peerSyncStats struct {
+	height     uint64  // height of the highest finalized header served by the peer
+	throughput float64 // moving average of the block bodies delivered per second, 0 if unknown
+	inflight   int     // number of block ranges assigned to the peer
+}
+
+// rangeTimeout returns the timeout for the peer to deliver the given number of block bodies
+func (ps *peerSyncStats) rangeTimeout(numBlocks int) time.Duration {
+	if ps.throughput <= 0 {
+		return RequestTimeout
+	}
+	expected := time.Duration(float64(numBlocks) / ps.throughput * float64(time.Second))
+	timeout := rangeTimeoutFactor * expected
+	if timeout < MinRangeTimeout {
+		timeout = MinRangeTimeout
+	}
+	if timeout > MaxRangeTimeout {
+		timeout = MaxRangeTimeout
+	}
+	return timeout
+}
+
+func (ps *peerSyncStats) recordThroughput(numBlocks int, elapsed time.Duration) {
+	if elapsed <= 0 {
+		elapsed = time.Millisecond
+	}
+	sample := float64(numBlocks) / elapsed.Seconds()
+	if ps.throughput <= 0 {
+		ps.throughput = sample
+		return
+	}
+	ps.throughput = throughputDecay*ps.throughput + (1-throughputDecay)*sample
+}
+
+//
+// HeaderSyncer implements the header-first block sync. It first downloads the chain of the
+// finalized block headers, and verifies the linkage, the proposer signatures and the HCC of
+// each header. A header is only added to the header chain once the HCC of a following header
+// proves that a majority of the validators voted for it or for its descendant, so that a peer
+// cannot forge the header chain with its own signatures. The block bodies of the verified
+// headers are then fetched in batched ranges from multiple peers concurrently. The syncer
+// tracks the throughput of each peer, sizes the range timeouts accordingly, and re-assigns
+// the ranges that a peer fails to deliver in time. The header chain is reset if it makes no
+// progress for HeaderChainTimeout, and the blocks are then synced through the inventories.
+//
+// HeaderSyncer is not thread safe, it is protected by the mutex of the RequestManager.
+//
+type HeaderSyncer struct {
+	rm *RequestManager
+
+	headers      []*core.BlockHeader // verified headers waiting to be passed down, ordered by height
+	headerByHash map[common.Hash]*core.BlockHeader
+	bodies       map[common.Hash]*core.Block // received block bodies, keyed by block hash
+
+	ranges []*blockRange // ranges with bodies not received yet, ordered by height
+	peers  map[string]*peerSyncStats
+
+	lastHeaderRequest time.Time
+	lastProgress      time.Time // last time a header was added or a block body received
+}
+
+func NewHeaderSyncer(rm *RequestManager) *HeaderSyncer {
+	return &HeaderSyncer{
+		rm:           rm,
+		headers:      []*core.BlockHeader{},
+		headerByHash: make(map[common.Hash]*core.BlockHeader),
+		bodies:       make(map[common.Hash]*core.Block),
+		ranges:       []*blockRange{},
+		peers:        make(map[string]*peerSyncStats),
+
+		lastHeaderRequest: time.Now(),
+		lastProgress:      time.Now(),
+	}
+}
+
+// IsSyncing returns whether there are verified headers whose block bodies are not passed down yet.
+func (hs *HeaderSyncer) IsSyncing() bool {
+	return len(hs.headers) > 0
+}
+
+// HasHeader returns whether the header of the given block is being synced.
+func (hs *HeaderSyncer) HasHeader(hash common.Hash) bool {
+	_, ok := hs.headerByHash[hash]
+	return ok
+}
+
+// NumPendingHeaders returns the number of verified headers waiting for their block bodies.
+func (hs *HeaderSyncer) NumPendingHeaders() int {
+	return len(hs.headers)
+}
+
+// HeaderHeight returns the height of the highest verified header, or 0 if there is none.
+func (hs *HeaderSyncer) HeaderHeight() uint64 {
+	if len(hs.headers) == 0 {
+		return 0
+	}
+	return hs.headers[len(hs.headers)-1].Height
+}
+
+func (hs *HeaderSyncer) buildHeaderRequest() dispatcher.InventoryRequest {
+	starts := []string{}
+	if len(hs.headers) > 0 {
+		starts = append(starts, hs.headers[len(hs.headers)-1].Hash().Hex())
+	}
+	starts = append(starts, hs.rm.buildInventoryRequest().Starts...)
+	return dispatcher.InventoryRequest{
+		ChannelID: common.ChannelIDHeader,
+		Starts:    starts,
+	}
+}
+
+// requestHeaders asks the given peers (all peers if empty) for the headers following the local chain.
+func (hs *HeaderSyncer) requestHeaders(peerIDs []string) {
+	if len(hs.headers) >= MaxNumPendingHeaders {
+		return
+	}
+	hs.lastHeaderRequest = time.Now()
+	req := hs.buildHeaderRequest()
+
+	hs.rm.logger.WithFields(log.Fields{
+		"channelID": req.ChannelID,
+		"starts":    req.Starts,
+		"peers":     peerIDs,
+	}).Debug("Sending header request")
+
+	hs.rm.dispatcher.GetInventory(peerIDs, req)
+}
+
+// AddHeaders verifies the headers sent by the peer, and appends the headers certified by the HCC
+// of a following header to the header chain. The headers following the last certified one are
+// dropped, and requested again once the header chain is extended.
+func (hs *HeaderSyncer) AddHeaders(peerID string, headers []*core.BlockHeader) {
+	candidates := []*core.BlockHeader{}
+	candidateIndex := make(map[common.Hash]int)
+	numCertified := 0
+	var highest uint64
+	for _, header := range headers {
+		hash := header.Hash()
+		if len(candidates) == 0 {
+			if _, ok := hs.headerByHash[hash]; ok {
+				highest = header.Height
+				continue
+			}
+			if _, err := hs.rm.chain.FindBlock(hash); err == nil {
+				highest = header.Height
+				continue
+			}
+		}
+		if len(hs.headers)+len(candidates) >= MaxNumPendingHeaders {
+			break
+		}
+
+		// The headers could be a response to an outdated request, which is not a misbehavior.
+		parent, ok := hs.findParentHeader(header, candidates)
+		if !ok {
+			hs.rm.logger.WithFields(log.Fields{
+				"peer":   peerID,
+				"header": hash.Hex(),
+			}).Debug("Received header not extending the header chain")
+			break
+		}
+
+		res := hs.verifyHeader(header, parent)
+		certified := false
+		if res.IsOK() {
+			certified, res = hs.verifyHCC(header, candidateIndex)
+		}
+		if res.IsError() {
+			hs.rm.logger.WithFields(log.Fields{
+				"peer":   peerID,
+				"header": hash.Hex(),
+				"error":  res.String(),
+			}).Warn("Received invalid header")
+			hs.rm.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorInvalidHeader)
+			break
+		}
+
+		candidates = append(candidates, header)
+		candidateIndex[hash] = len(candidates) - 1
+		if idx, ok := candidateIndex[header.HCC.BlockHash]; certified && ok && idx+1 > numCertified {
+			numCertified = idx + 1
+		}
+	}
+
+	if numCertified > 0 {
+		highest = candidates[numCertified-1].Height
+	}
+	stats := hs.getPeerStats(peerID)
+	if highest > stats.height {
+		stats.height = highest
+	}
+	hs.rm.syncMgr.progress.updatePeerHeight(peerID, highest)
+	if numCertified == 0 {
+		return
+	}
+
+	added := candidates[:numCertified]
+	for _, header := range added {
+		hs.headers = append(hs.headers, header)
+		hs.headerByHash[header.Hash()] = header
+	}
+	hs.lastProgress = time.Now()
+
+	hs.rm.logger.WithFields(log.Fields{
+		"peer":         peerID,
+		"numHeaders":   len(added),
+		"headerHeight": hs.HeaderHeight(),
+	}).Info("Added block headers")
+
+	hs.createRanges(added)
+
+	// The peer may have more headers to offer
+	if len(headers) >= MaxNumHeadersPerResponse {
+		hs.requestHeaders([]string{peerID})
+	}
+	hs.scheduleRanges(time.Now())
+}
+
+// findParentHeader returns the parent of the header, which needs to be the last of the candidate
+// headers, the tip of the header chain, or a block in the local chain if the header chain is empty.
+func (hs *HeaderSyncer) findParentHeader(header *core.BlockHeader, candidates []*core.BlockHeader) (*core.BlockHeader, bool) {
+	var tip *core.BlockHeader
+	if len(candidates) > 0 {
+		tip = candidates[len(candidates)-1]
+	} else if len(hs.headers) > 0 {
+		tip = hs.headers[len(hs.headers)-1]
+	}
+	if tip != nil {
+		return tip, header.Parent == tip.Hash()
+	}
+	parent, err := hs.rm.chain.FindBlock(header.Parent)
+	if err != nil {
+		return nil, false
+	}
+	return parent.BlockHeader, true
+}
+
+// verifyHeader checks the header is a valid child of the given parent.
+func (hs *HeaderSyncer) verifyHeader(header *core.BlockHeader, parent *core.BlockHeader) result.Result {
+	if header.ChainID != hs.rm.chain.ChainID {
+		return result.Error("ChainID mismatch: %v", header.ChainID)
+	}
+	if header.Height != parent.Height+1 {
+		return result.Error("Header height %v does not follow parent height %v", header.Height, parent.Height)
+	}
+	if header.Epoch <= parent.Epoch {
+		return result.Error("Header epoch %v is not greater than parent epoch %v", header.Epoch, parent.Epoch)
+	}
+	return header.Validate()
+}
+
+// verifyHCC checks the HCC of the header certifies one of its ancestors, and that the votes in the
+// HCC, if any, are all signed by distinct voters on the certified block. It returns true if the votes
+// are proven by a majority of the validator set of the certified block. If the certified block is
+// not processed by the local chain yet, its validator set is not known, and the validator set
+// following the last finalized block is used instead. The HCC is then unproven rather than invalid
+// if the votes do not reach a majority, since the validator set could have changed.
+func (hs *HeaderSyncer) verifyHCC(header *core.BlockHeader, candidateIndex map[common.Hash]int) (bool, result.Result) {
+	hcc := header.HCC
+	_, isCandidate := candidateIndex[hcc.BlockHash]
+	if _, ok := hs.headerByHash[hcc.BlockHash]; !ok && !isCandidate {
+		if _, err := hs.rm.chain.FindBlock(hcc.BlockHash); err != nil {
+			return false, result.Error("HCC block is not an ancestor: %v", hcc.BlockHash.Hex())
+		}
+	}
+	if hcc.Votes == nil || hcc.Votes.IsEmpty() {
+		return false, result.OK
+	}
+	if res := validateHCCVotes(hcc); res.IsError() {
+		return false, res
+	}
+
+	validators, exact := hs.getValidatorSet(hcc.BlockHash)
+	if validators != nil && validators.HasMajority(hcc.Votes) {
+		return true, result.OK
+	}
+	if exact {
+		return false, result.Error("HCC votes do not reach a majority of the validators")
+	}
+	return false, result.OK
+}
+
+// validateHCCVotes checks that the votes in the HCC are properly signed, one per voter, and on
+// the HCC block.
+func validateHCCVotes(hcc core.CommitCertificate) result.Result {
+	if hcc.Votes.UniqueVoter().Size() != hcc.Votes.Size() {
+		return result.Error("HCC contains multiple votes from the same voter")
+	}
+	for _, vote := range hcc.Votes.Votes() {
+		if vote.Block != hcc.BlockHash {
+			return result.Error("HCC contains vote for another block: %v", vote.Block.Hex())
+		}
+	}
+	return hcc.Votes.Validate()
+}
+
+// getValidatorSet returns the validator set of the given block, and whether it is exact. For the
+// blocks not processed by the local chain, the validator set following the last finalized block
+// is returned.
+func (hs *HeaderSyncer) getValidatorSet(hash common.Hash) (*core.ValidatorSet, bool) {
+	valMgr := hs.rm.syncMgr.valMgr
+	if valMgr == nil {
+		return nil, false
+	}
+	if block, err := hs.rm.chain.FindBlock(hash); err == nil && block.Status.IsValid() {
+		return valMgr.GetValidatorSet(hash), true
+	}
+	lfb := hs.rm.syncMgr.consensus.GetLastFinalizedBlock()
+	return valMgr.GetNextValidatorSet(lfb.Hash()), false
+}
+
+func (hs *HeaderSyncer) createRanges(headers []*core.BlockHeader) {
+	for len(headers) > 0 {
+		n := BlockRangeSize
+		if n > len(headers) {
+			n = len(headers)
+		}
+		hs.ranges = append(hs.ranges, newBlockRange(headers[:n]))
+		headers = headers[n:]
+	}
+}
+
+// AddBlock stores the block body sent by the peer if its header is being synced, and returns the
+// blocks that are ready to be passed down in order. The returned flag is false if the block is not
+// tracked by the header syncer.
+func (hs *HeaderSyncer) AddBlock(peerID string, block *core.Block) ([]*core.Block, bool) {
+	hash := block.Hash()
+	if _, ok := hs.headerByHash[hash]; !ok {
+		return nil, false
+	}
+	if _, ok := hs.bodies[hash]; ok {
+		return nil, true
+	}
+	if res := block.ValidateTxs(); res.IsError() {
+		hs.rm.logger.WithFields(log.Fields{
+			"peer":  peerID,
+			"block": hash.Hex(),
+			"error": res.String(),
+		}).Warn("Received invalid block body")
+		hs.rm.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorInvalidBlock)
+		hs.RejectBlock(peerID, hash)
+		return nil, true
+	}
+	hs.bodies[hash] = block
+
+	now := time.Now()
+	hs.lastProgress = now
+	for i, br := range hs.ranges {
+		if !br.pending[hash] {
+			continue
+		}
+		delete(br.pending, hash)
+		if len(br.pending) == 0 {
+			hs.completeRange(br, now)
+			hs.ranges = append(hs.ranges[:i], hs.ranges[i+1:]...)
+		}
+		break
+	}
+
+	return hs.popReadyBlocks(), true
+}
+
+// RejectBlock handles an invalid block body sent by the peer. If the peer is assigned the range
+// of the block, the range is re-assigned to another peer right away instead of waiting for the
+// range to time out.
+func (hs *HeaderSyncer) RejectBlock(peerID string, hash common.Hash) {
+	for _, br := range hs.ranges {
+		if !br.pending[hash] {
+			continue
+		}
+		if br.status != RequestWaitingDataResp || br.peerID != peerID {
+			return
+		}
+		if stats, ok := hs.peers[peerID]; ok {
+			stats.inflight--
+		}
+		br.failedPeers[peerID] = true
+		br.status = RequestToSendDataReq
+		hs.scheduleRanges(time.Now())
+		return
+	}
+}
+
+func (hs *HeaderSyncer) completeRange(br *blockRange, now time.Time) {
+	if br.status != RequestWaitingDataResp {
+		return
+	}
+	if stats, ok := hs.peers[br.peerID]; ok {
+		stats.inflight--
+		stats.recordThroughput(len(br.headers), now.Sub(br.assignedAt))
+	}
+}
+
+// popReadyBlocks removes the consecutive received bodies from the front of the header chain.
+func (hs *HeaderSyncer) popReadyBlocks() []*core.Block {
+	ready := []*core.Block{}
+	for len(hs.headers) > 0 {
+		hash := hs.headers[0].Hash()
+		block, ok := hs.bodies[hash]
+		if !ok {
+			break
+		}
+		ready = append(ready, block)
+		delete(hs.bodies, hash)
+		delete(hs.headerByHash, hash)
+		hs.headers = hs.headers[1:]
+	}
+	return ready
+}
+
+// scheduleRanges re-assigns the timed out ranges, and assigns the pending ranges to the idle peers.
+// The header chain is reset if it made no progress for HeaderChainTimeout.
+func (hs *HeaderSyncer) scheduleRanges(now time.Time) {
+	hs.prunePeers()
+
+	if hs.IsSyncing() && now.Sub(hs.lastProgress) > HeaderChainTimeout {
+		hs.rm.logger.WithFields(log.Fields{
+			"numPendingHeaders": len(hs.headers),
+			"headerHeight":      hs.HeaderHeight(),
+			"lastProgress":      hs.lastProgress,
+		}).Warn("Header chain made no progress, resetting")
+		hs.reset()
+		return
+	}
+
+	for _, br := range hs.ranges {
+		if !br.hasTimedOut(now) {
+			continue
+		}
+		hs.rm.logger.WithFields(log.Fields{
+			"peer":       br.peerID,
+			"lastHeight": br.lastHeight(),
+			"pending":    len(br.pending),
+		}).Debug("Block range request timed out")
+
+		if stats, ok := hs.peers[br.peerID]; ok {
+			stats.inflight--
+			stats.throughput /= 2
+		}
+		br.failedPeers[br.peerID] = true
+		br.status = RequestToSendDataReq
+	}
+
+	for _, br := range hs.ranges {
+		if br.status != RequestToSendDataReq {
+			continue
+		}
+		peerID, stats := hs.selectPeer(br)
+		if stats == nil {
+			continue
+		}
+		br.peerID = peerID
+		br.assignedAt = now
+		br.timeout = stats.rangeTimeout(len(br.pending))
+		br.status = RequestWaitingDataResp
+		stats.inflight++
+
+		request := dispatcher.DataRequest{
+			ChannelID: common.ChannelIDBlock,
+			Entries:   br.pendingHashes(),
+		}
+		hs.rm.logger.WithFields(log.Fields{
+			"peer":       peerID,
+			"lastHeight": br.lastHeight(),
+			"numBlocks":  len(request.Entries),
+			"timeout":    br.timeout,
+		}).Debug("Sending block range request")
+		hs.rm.dispatcher.GetData([]string{peerID}, request)
+	}
+}
+
+// selectPeer returns the fastest idle peer that has the whole range, preferring the peers
+// which have not failed the range before.
+func (hs *HeaderSyncer) selectPeer(br *blockRange) (string, *peerSyncStats) {
+	var bestID string
+	var best *peerSyncStats
+	bestFailed := false
+	for peerID, stats := range hs.peers {
+		if stats.inflight >= MaxRangesPerPeer || stats.height < br.lastHeight() {
+			continue
+		}
+		failed := br.failedPeers[peerID]
+		if best == nil || (bestFailed && !failed) ||
+			(bestFailed == failed && stats.throughput > best.throughput) {
+			bestID, best, bestFailed = peerID, stats, failed
+		}
+	}
+	return bestID, best
+}
+
+// reset drops the header chain along with the received bodies and the pending ranges.
+func (hs *HeaderSyncer) reset() {
+	hs.headers = []*core.BlockHeader{}
+	hs.headerByHash = make(map[common.Hash]*core.BlockHeader)
+	hs.bodies = make(map[common.Hash]*core.Block)
+	hs.ranges = []*blockRange{}
+	for _, stats := range hs.peers {
+		stats.inflight = 0
+	}
+}
+
+func (hs *HeaderSyncer) getPeerStats(peerID string) *peerSyncStats {
+	stats, ok := hs.peers[peerID]
+	if !ok {
+		stats = &peerSyncStats{}
+		hs.peers[peerID] = stats
+	}
+	return stats
+}
+
+// prunePeers forgets the disconnected peers and their heights, and releases the ranges assigned to them.
+func (hs *HeaderSyncer) prunePeers() {
+	connected := make(map[string]bool)
+	for _, peerID := range hs.rm.dispatcher.Peers() {
+		connected[peerID] = true
+	}
+	for peerID := range hs.peers {
+		if !connected[peerID] {
+			delete(hs.peers, peerID)
+		}
+	}
+	hs.rm.syncMgr.progress.prunePeers(connected)
+	for _, br := range hs.ranges {
+		if br.status == RequestWaitingDataResp && !connected[br.peerID] {
+			br.status = RequestToSendDataReq
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package netsync
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/p2p/simulation"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func getTestHeaders(names ...string) []*core.BlockHeader {
+	headers := []*core.BlockHeader{}
+	for _, name := range names {
+		headers = append(headers, core.GetTestBlock(name).BlockHeader)
+	}
+	return headers
+}
+
+func receiveDataRequest(t *testing.T, c chan interface{}) dispatcher.DataRequest {
+	select {
+	case res := <-c:
+		req, ok := res.(dispatcher.DataRequest)
+		assert.True(t, ok)
+		return req
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for data request")
+	}
+	return dispatcher.DataRequest{}
+}
+
+// mockValidatorManager returns the default signer of the test blocks as the only validator
+type mockValidatorManager struct{}
+
+func (m mockValidatorManager) SetConsensusEngine(consensus core.ConsensusEngine) {}
+
+func (m mockValidatorManager) GetProposer(_ common.Hash, _ uint64) core.Validator {
+	return core.NewValidator(core.DefaultSigner.PublicKey().Address().Hex(), core.MinValidatorStakeDeposit)
+}
+
+func (m mockValidatorManager) GetNextProposer(blockHash common.Hash, epoch uint64) core.Validator {
+	return m.GetProposer(blockHash, epoch)
+}
+
+func (m mockValidatorManager) GetValidatorSet(blockHash common.Hash) *core.ValidatorSet {
+	valSet := core.NewValidatorSet()
+	valSet.AddValidator(m.GetProposer(blockHash, 0))
+	return valSet
+}
+
+func (m mockValidatorManager) GetNextValidatorSet(blockHash common.Hash) *core.ValidatorSet {
+	return m.GetValidatorSet(blockHash)
+}
+
+// createVotedTestBlock creates a test block proposed by the proposer, with the parent as the HCC
+// carrying the vote of the voter, if any.
+func createVotedTestBlock(name string, parentName string, proposer *crypto.PrivateKey, voter *crypto.PrivateKey) *core.Block {
+	parent := core.GetTestBlock(parentName)
+
+	block := core.NewBlock()
+	block.ChainID = "testchain"
+	block.StateHash = common.HexToHash(name)
+	block.Parent = parent.Hash()
+	block.Height = parent.Height + 1
+	block.Epoch = parent.Epoch + 1
+	block.HCC.BlockHash = parent.Hash()
+	if voter != nil {
+		vote := core.Vote{
+			Block:  parent.Hash(),
+			Height: parent.Height,
+			Epoch:  parent.Epoch,
+			ID:     voter.PublicKey().Address(),
+		}
+		sig, _ := voter.Sign(vote.SignBytes())
+		vote.SetSignature(sig)
+		block.HCC.Votes = core.NewVoteSet()
+		block.HCC.Votes.AddVote(vote)
+	}
+	block.Proposer = proposer.PublicKey().Address()
+	block.Timestamp = big.NewInt(time.Now().Unix())
+	block.Signature, _ = proposer.Sign(block.SignBytes())
+
+	core.TestBlocks[strings.ToLower(name)] = block
+	return block
+}
+
+// createVotedTestBlocks creates the test blocks from the (block, parent) pairs, with the votes of the
+// default signer of the test blocks in the HCCs
+func createVotedTestBlocks(pairs ...string) {
+	for i := 0; i < len(pairs); i += 2 {
+		createVotedTestBlock(pairs[i], pairs[i+1], core.DefaultSigner, core.DefaultSigner)
+	}
+}
+
+func TestHeaderSync(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	core.CreateTestBlock("A0", "")
+	createVotedTestBlocks(
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+		"A4", "A3",
+		"A5", "A4",
+		"B3", "A1",
+	)
+
+	// node1's chain initially contains only A0, A1
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	net2 := simnet.AddEndpoint("node2")
+	handler2 := &MockMsgHandler{C: make(chan interface{}, 128)}
+	net2.RegisterMessageHandler(handler2)
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a1, _ := initChain.FindBlock(core.GetTestBlock("A1").Hash())
+	consensus := NewMockConsensus(initChain, a1)
+	mockMsgConsumer := NewMockMessageConsumer()
+
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, mockMsgConsumer)
+	sm.SetValidatorManager(mockValidatorManager{})
+	rm := sm.requestMgr
+	hs := rm.headerSyncer
+
+	// Headers not extending the local chain are dropped, without penalizing the peer
+	rm.AddHeaders("node2", getTestHeaders("A3", "A4"))
+	assert.False(hs.IsSyncing())
+
+	// Headers are dropped from the first one with a broken link, and A2 is not certified by any header
+	rm.AddHeaders("node2", getTestHeaders("A2", "B3"))
+	assert.False(hs.IsSyncing())
+	assert.Equal(0, len(net1.Reports("node2")))
+
+	// A5 is not certified until a header with its votes is received
+	rm.AddHeaders("node2", getTestHeaders("A2", "A3", "A4", "A5"))
+	assert.Equal(3, hs.NumPendingHeaders())
+	assert.Equal(uint64(4), hs.HeaderHeight())
+	assert.False(hs.HasHeader(core.GetTestBlock("A5").Hash()))
+
+	// A range covering A2 to A4 is requested from node2
+	req := receiveDataRequest(t, handler2.C)
+	assert.Equal(common.ChannelIDBlock, req.ChannelID)
+	assert.Equal(3, len(req.Entries))
+	assert.Equal(core.GetTestBlock("A2").Hash().Hex(), req.Entries[0])
+	assert.Equal(core.GetTestBlock("A4").Hash().Hex(), req.Entries[2])
+
+	// Bodies received out of order are buffered until the preceding blocks arrive
+	rm.AddBlock("node2", core.GetTestBlock("A4"))
+	rm.AddBlock("node2", core.GetTestBlock("A3"))
+	assert.Equal(0, len(mockMsgConsumer.Received))
+
+	rm.AddBlock("node2", core.GetTestBlock("A2"))
+	expected := []string{"A2", "A3", "A4"}
+	assert.Equal(len(expected), len(mockMsgConsumer.Received))
+	for i, msg := range mockMsgConsumer.Received {
+		assert.Equal(core.GetTestBlock(expected[i]).Hash(), msg.(*core.Block).Hash())
+	}
+	assert.False(hs.IsSyncing())
+	assert.Equal(0, len(hs.ranges))
+
+	_, err := initChain.FindBlock(core.GetTestBlock("A4").Hash())
+	assert.Nil(err)
+
+	// Throughput of node2 is recorded once its ranges are completed
+	assert.True(hs.peers["node2"].throughput > 0)
+	assert.Equal(0, hs.peers["node2"].inflight)
+	assert.Equal(0, len(net1.Reports("node2")))
+}
+
+func TestHeaderSyncRejectsForgedHeaders(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	attacker, _, _ := crypto.GenerateKeyPair()
+	core.CreateTestBlock("A0", "")
+	createVotedTestBlocks(
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+	)
+	// Headers signed by the attacker, with HCCs without votes or with the votes of the attacker
+	createVotedTestBlock("E2", "A1", attacker, nil)
+	createVotedTestBlock("E3", "E2", attacker, nil)
+	createVotedTestBlock("F2", "A1", attacker, nil)
+	createVotedTestBlock("F3", "F2", attacker, attacker)
+	createVotedTestBlock("G2", "A1", attacker, attacker)
+
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a1, _ := initChain.FindBlock(core.GetTestBlock("A1").Hash())
+	consensus := NewMockConsensus(initChain, a1)
+
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, NewMockMessageConsumer())
+	sm.SetValidatorManager(mockValidatorManager{})
+	rm := sm.requestMgr
+	hs := rm.headerSyncer
+
+	// Well formed headers are not certified without the votes of the validators
+	rm.AddHeaders("node2", getTestHeaders("E2", "E3"))
+	assert.False(hs.IsSyncing())
+	rm.AddHeaders("node2", getTestHeaders("F2", "F3"))
+	assert.False(hs.IsSyncing())
+	assert.Equal(0, len(net1.Reports("node2")))
+
+	// The votes on a block in the local chain are checked against its validator set
+	rm.AddHeaders("node3", getTestHeaders("G2"))
+	assert.False(hs.IsSyncing())
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorInvalidHeader}, net1.Reports("node3"))
+
+	// The honest headers are accepted, and the header chain is reset if the bodies never arrive
+	rm.AddHeaders("node4", getTestHeaders("A2", "A3"))
+	assert.Equal(1, hs.NumPendingHeaders())
+
+	rm.mu.Lock()
+	hs.scheduleRanges(hs.lastProgress.Add(HeaderChainTimeout + time.Second))
+	rm.mu.Unlock()
+	assert.False(hs.IsSyncing())
+	assert.False(hs.HasHeader(core.GetTestBlock("A2").Hash()))
+	assert.Equal(0, len(hs.ranges))
+}
+
+func TestHeaderSyncReassignRange(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	core.CreateTestBlock("A0", "")
+	createVotedTestBlocks(
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+		"A4", "A3",
+	)
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	net2 := simnet.AddEndpoint("node2")
+	net3 := simnet.AddEndpoint("node3")
+	handler2 := &MockMsgHandler{C: make(chan interface{}, 128)}
+	handler3 := &MockMsgHandler{C: make(chan interface{}, 128)}
+	net2.RegisterMessageHandler(handler2)
+	net3.RegisterMessageHandler(handler3)
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a1, _ := initChain.FindBlock(core.GetTestBlock("A1").Hash())
+	consensus := NewMockConsensus(initChain, a1)
+	mockMsgConsumer := NewMockMessageConsumer()
+
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, mockMsgConsumer)
+	sm.SetValidatorManager(mockValidatorManager{})
+	rm := sm.requestMgr
+	hs := rm.headerSyncer
+
+	// Both peers have the headers, make node2 the faster one
+	hs.getPeerStats("node2").throughput = 100
+	hs.getPeerStats("node3").throughput = 10
+	rm.AddHeaders("node2", getTestHeaders("A2", "A3", "A4"))
+	rm.AddHeaders("node3", getTestHeaders("A2", "A3", "A4"))
+
+	req := receiveDataRequest(t, handler2.C)
+	assert.Equal(2, len(req.Entries))
+	br := hs.ranges[0]
+	assert.Equal("node2", br.peerID)
+	assert.Equal(MinRangeTimeout, br.timeout)
+
+	// node2 only delivers A2 before the range times out
+	rm.AddBlock("node2", core.GetTestBlock("A2"))
+	assert.Equal(1, len(mockMsgConsumer.Received))
+
+	rm.mu.Lock()
+	hs.scheduleRanges(br.assignedAt.Add(br.timeout + time.Second))
+	rm.mu.Unlock()
+
+	// The remaining block is requested from node3, and node2 is penalized
+	req = receiveDataRequest(t, handler3.C)
+	assert.Equal([]string{core.GetTestBlock("A3").Hash().Hex()}, req.Entries)
+	assert.Equal("node3", br.peerID)
+	assert.Equal(float64(50), hs.peers["node2"].throughput)
+	assert.Equal(0, hs.peers["node2"].inflight)
+
+	rm.AddBlock("node3", core.GetTestBlock("A3"))
+	assert.Equal(2, len(mockMsgConsumer.Received))
+	assert.False(hs.IsSyncing())
+	assert.Equal(0, hs.peers["node3"].inflight)
+}
+
+func TestHeaderSyncRejectInvalidBody(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	core.CreateTestBlock("A0", "")
+	createVotedTestBlocks(
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+		"A4", "A3",
+	)
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	net2 := simnet.AddEndpoint("node2")
+	net3 := simnet.AddEndpoint("node3")
+	handler2 := &MockMsgHandler{C: make(chan interface{}, 128)}
+	handler3 := &MockMsgHandler{C: make(chan interface{}, 128)}
+	net2.RegisterMessageHandler(handler2)
+	net3.RegisterMessageHandler(handler3)
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a1, _ := initChain.FindBlock(core.GetTestBlock("A1").Hash())
+	consensus := NewMockConsensus(initChain, a1)
+	mockMsgConsumer := NewMockMessageConsumer()
+
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, mockMsgConsumer)
+	sm.SetValidatorManager(mockValidatorManager{})
+	rm := sm.requestMgr
+	hs := rm.headerSyncer
+
+	hs.getPeerStats("node2").throughput = 100
+	hs.getPeerStats("node3").throughput = 10
+	rm.AddHeaders("node2", getTestHeaders("A2", "A3", "A4"))
+	rm.AddHeaders("node3", getTestHeaders("A2", "A3", "A4"))
+
+	req := receiveDataRequest(t, handler2.C)
+	assert.Equal(2, len(req.Entries))
+	br := hs.ranges[0]
+	assert.Equal("node2", br.peerID)
+
+	// node2 sends a body with transactions not matching the header
+	a2 := core.GetTestBlock("A2")
+	tampered := &core.Block{BlockHeader: a2.BlockHeader, Txs: []common.Bytes{common.Bytes("tx")}}
+	rm.AddBlock("node2", tampered)
+	assert.Equal(0, len(mockMsgConsumer.Received))
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorInvalidBlock}, net1.Reports("node2"))
+
+	// The range is re-assigned to node3 without waiting for the timeout
+	req = receiveDataRequest(t, handler3.C)
+	assert.Equal(2, len(req.Entries))
+	assert.Equal("node3", br.peerID)
+	assert.True(br.failedPeers["node2"])
+	assert.Equal(0, hs.peers["node2"].inflight)
+
+	// An invalid body relayed by a peer not assigned the range does not affect the range
+	rm.AddBlock("node2", tampered)
+	assert.Equal("node3", br.peerID)
+
+	rm.AddBlock("node3", a2)
+	rm.AddBlock("node3", core.GetTestBlock("A3"))
+	assert.Equal(2, len(mockMsgConsumer.Received))
+	assert.False(hs.IsSyncing())
+}
+
+func TestCollectHeaders(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+		"A4", "A3",
+		"C3", "A2",
+	})
+	initChain.FinalizePreviousBlocks(core.GetTestBlock("A3").Hash())
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a3, _ := initChain.FindBlock(core.GetTestBlock("A3").Hash())
+	consensus := NewMockConsensus(initChain, a3)
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, NewMockMessageConsumer())
+
+	// Only the headers of the finalized blocks are collected
+	headers := sm.collectHeaders(core.GetTestBlock("A1").Hash())
+	assert.Equal(2, len(headers))
+	assert.Equal(core.GetTestBlock("A2").Hash(), headers[0].Hash())
+	assert.Equal(core.GetTestBlock("A3").Hash(), headers[1].Hash())
+
+	headers = sm.collectHeaders(core.GetTestBlock("A3").Hash())
+	assert.Equal(0, len(headers))
+}
@@ -0,0 +1,109 @@
+package netsync
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+)
+
+const (
+	// MaxInvRequestBurst is the max number of inventory requests a peer can send in a burst
+	MaxInvRequestBurst = 20
+	// InvRequestRefillRate is the number of inventory requests per second a peer can send in the long run
+	InvRequestRefillRate = 5.0
+	// MaxNumBlockOrigins is the max number of received blocks whose origin peers are remembered
+	MaxNumBlockOrigins = 4096
+)
+
+//
+// requestLimiter limits the rate of the requests from each peer with a token bucket.
+//
+type requestLimiter struct {
+	mu      *sync.Mutex
+	burst   float64
+	rate    float64 // tokens per second
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newRequestLimiter(burst int, rate float64) *requestLimiter {
+	return &requestLimiter{
+		mu:      &sync.Mutex{},
+		burst:   float64(burst),
+		rate:    rate,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow consumes a token of the peer, returns false if the peer has run out of tokens.
+func (rl *requestLimiter) allow(peerID string, now time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	bucket, ok := rl.buckets[peerID]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.burst, lastRefill: now}
+		rl.buckets[peerID] = bucket
+	}
+	if elapsed := now.Sub(bucket.lastRefill).Seconds(); elapsed > 0 {
+		bucket.tokens += elapsed * rl.rate
+		if bucket.tokens > rl.burst {
+			bucket.tokens = rl.burst
+		}
+		bucket.lastRefill = now
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+//
+// blockOrigins remembers the peers the recent blocks are received from, so
+// that the peer can be held responsible once a block turns out to be invalid.
+//
+type blockOrigins struct {
+	mu      *sync.Mutex
+	peers   map[common.Hash]string
+	order   *list.List
+	maxSize int
+}
+
+func newBlockOrigins(maxSize int) *blockOrigins {
+	return &blockOrigins{
+		mu:      &sync.Mutex{},
+		peers:   make(map[common.Hash]string),
+		order:   list.New(),
+		maxSize: maxSize,
+	}
+}
+
+func (bo *blockOrigins) add(hash common.Hash, peerID string) {
+	bo.mu.Lock()
+	defer bo.mu.Unlock()
+
+	if _, ok := bo.peers[hash]; ok {
+		return
+	}
+	bo.peers[hash] = peerID
+	bo.order.PushBack(hash)
+	for bo.order.Len() > bo.maxSize {
+		oldest := bo.order.Remove(bo.order.Front()).(common.Hash)
+		delete(bo.peers, oldest)
+	}
+}
+
+func (bo *blockOrigins) get(hash common.Hash) (string, bool) {
+	bo.mu.Lock()
+	defer bo.mu.Unlock()
+
+	peerID, ok := bo.peers[hash]
+	return peerID, ok
+}
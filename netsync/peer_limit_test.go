@@ -0,0 +1,106 @@
+package netsync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/p2p/simulation"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	rl := newRequestLimiter(3, 1.0)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.True(rl.allow("peer1", now))
+	}
+	assert.False(rl.allow("peer1", now))
+	assert.True(rl.allow("peer2", now))
+
+	// One token is refilled per second
+	assert.True(rl.allow("peer1", now.Add(time.Second)))
+	assert.False(rl.allow("peer1", now.Add(time.Second)))
+}
+
+func TestBlockOrigins(t *testing.T) {
+	assert := assert.New(t)
+
+	bo := newBlockOrigins(2)
+	bo.add(common.HexToHash("a1"), "peer1")
+	bo.add(common.HexToHash("a2"), "peer2")
+	bo.add(common.HexToHash("a1"), "peer3")
+	peerID, ok := bo.get(common.HexToHash("a1"))
+	assert.True(ok)
+	assert.Equal("peer1", peerID)
+
+	// The oldest entry is evicted
+	bo.add(common.HexToHash("a3"), "peer3")
+	_, ok = bo.get(common.HexToHash("a1"))
+	assert.False(ok)
+	peerID, ok = bo.get(common.HexToHash("a3"))
+	assert.True(ok)
+	assert.Equal("peer3", peerID)
+}
+
+func TestReportMisbehavingPeers(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+	_ = blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+	})
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a1, _ := initChain.FindBlock(core.GetTestBlock("A1").Hash())
+	consensus := NewMockConsensus(initChain, a1)
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, NewMockMessageConsumer())
+
+	// Block with a tampered signature
+	a2 := core.GetTestBlock("A2")
+	forged := &core.Block{BlockHeader: &core.BlockHeader{}}
+	*forged.BlockHeader = *a2.BlockHeader
+	forged.Epoch = a2.Epoch + 100
+	forged.UpdateHash()
+	sm.handleBlock("node2", forged)
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorInvalidBlock}, net1.Reports("node2"))
+
+	// Unsigned vote
+	sm.handleVote("node3", core.Vote{Block: a2.Hash(), Height: a2.Height, Epoch: a2.Epoch, ID: common.HexToAddress("a1")})
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorInvalidVote}, net1.Reports("node3"))
+
+	// Unparsable payload
+	sm.handleDataResponse("node4", &dispatcher.DataResponse{ChannelID: common.ChannelIDBlock, Payload: common.Bytes{0xff}})
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorUnparsableMessage}, net1.Reports("node4"))
+
+	// A valid block is accepted, and its relayer is reported once the consensus engine finds it invalid
+	sm.handleBlock("node5", core.GetTestBlock("A3"))
+	assert.Equal(0, len(net1.Reports("node5")))
+	sm.HandleInvalidBlock(core.GetTestBlock("A3").Hash())
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorInvalidBlock}, net1.Reports("node5"))
+
+	// Inventory requests beyond the burst are dropped
+	req := &dispatcher.InventoryRequest{ChannelID: common.ChannelIDBlock, Starts: []string{a1.Hash().Hex()}}
+	for i := 0; i < MaxInvRequestBurst; i++ {
+		sm.handleInvRequest("node6", req)
+	}
+	assert.Equal(0, len(net1.Reports("node6")))
+	sm.handleInvRequest("node6", req)
+	assert.Equal([]p2ptypes.Misbehavior{p2ptypes.MisbehaviorRequestSpam}, net1.Reports("node6"))
+}
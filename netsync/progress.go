@@ -0,0 +1,132 @@
+package netsync
+
+import (
+	"sync"
+	"time"
+)
+
+const syncRateDecay = 0.9 // weight of the history in the exponential moving average of the sync rate
+
+// SyncStatus summarizes the progress of the block sync.
+type SyncStatus struct {
+	Syncing           bool          // whether the node falls behind the highest peer by more than the threshold
+	StartingHeight    uint64        // height of the last finalized block when the node started
+	CurrentHeight     uint64        // height of the last finalized block
+	HighestPeerHeight uint64        // highest validated block height of the connected peers
+	HeaderHeight      uint64        // height of the highest verified header waiting for its block body
+	NumPendingHeaders int           // number of verified headers waiting for their block bodies
+	BlocksPerSecond   float64       // moving average of the finalized blocks per second
+	ETA               time.Duration // estimated time to catch up with the highest peer, 0 if unknown
+}
+
+//
+// syncProgress tracks the heights of the connected peers, and the rate at which
+// the local finalized height grows. Only the heights backed by the validated
+// headers or blocks are counted, so that a peer cannot fake the sync target.
+//
+type syncProgress struct {
+	mu *sync.Mutex
+
+	startHeight uint64
+	peerHeights map[string]uint64 // highest validated height of each connected peer
+
+	rate       float64 // finalized blocks per second
+	lastHeight uint64
+	lastSample time.Time
+}
+
+func newSyncProgress(startHeight uint64) *syncProgress {
+	return &syncProgress{
+		mu:          &sync.Mutex{},
+		startHeight: startHeight,
+		peerHeights: make(map[string]uint64),
+		lastHeight:  startHeight,
+		lastSample:  time.Now(),
+	}
+}
+
+func (sp *syncProgress) updatePeerHeight(peerID string, height uint64) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if height > sp.peerHeights[peerID] {
+		sp.peerHeights[peerID] = height
+	}
+}
+
+func (sp *syncProgress) getPeerHeight(peerID string) uint64 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	return sp.peerHeights[peerID]
+}
+
+func (sp *syncProgress) getHighestPeerHeight() uint64 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	return sp.highestPeerHeightUnsafe()
+}
+
+func (sp *syncProgress) highestPeerHeightUnsafe() uint64 {
+	highest := uint64(0)
+	for _, height := range sp.peerHeights {
+		if height > highest {
+			highest = height
+		}
+	}
+	return highest
+}
+
+// prunePeers forgets the heights of the peers not in the connected set.
+func (sp *syncProgress) prunePeers(connected map[string]bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	for peerID := range sp.peerHeights {
+		if !connected[peerID] {
+			delete(sp.peerHeights, peerID)
+		}
+	}
+}
+
+// sample updates the sync rate with the current finalized height.
+func (sp *syncProgress) sample(height uint64, now time.Time) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	elapsed := now.Sub(sp.lastSample).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	var delta uint64
+	if height > sp.lastHeight {
+		delta = height - sp.lastHeight
+	}
+	sp.rate = syncRateDecay*sp.rate + (1-syncRateDecay)*float64(delta)/elapsed
+	sp.lastHeight = height
+	sp.lastSample = now
+}
+
+// status fills in the sync status given the current finalized height.
+func (sp *syncProgress) status(currentHeight uint64, behindThreshold uint64) SyncStatus {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	highestPeerHeight := sp.highestPeerHeightUnsafe()
+	status := SyncStatus{
+		StartingHeight:    sp.startHeight,
+		CurrentHeight:     currentHeight,
+		HighestPeerHeight: highestPeerHeight,
+		BlocksPerSecond:   sp.rate,
+	}
+	if highestPeerHeight <= currentHeight {
+		return status
+	}
+	behind := highestPeerHeight - currentHeight
+	status.Syncing = behind > behindThreshold
+	if sp.rate > 0 {
+		status.ETA = time.Duration(float64(behind) / sp.rate * float64(time.Second))
+	}
+	return status
+}
@@ -0,0 +1,112 @@
+package netsync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/blockchain"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/dispatcher"
+	"github.com/thetatoken/theta/p2p/simulation"
+)
+
+func TestSyncProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	sp := newSyncProgress(100)
+	status := sp.status(100, 10)
+	assert.False(status.Syncing)
+	assert.Equal(uint64(100), status.StartingHeight)
+	assert.Equal(time.Duration(0), status.ETA)
+
+	// Peers advertise heights within the threshold
+	sp.updatePeerHeight("peer1", 105)
+	assert.False(sp.status(100, 10).Syncing)
+
+	// Peers advertise heights far ahead, but the rate is unknown yet
+	sp.updatePeerHeight("peer2", 1100)
+	sp.updatePeerHeight("peer1", 300)
+	status = sp.status(100, 10)
+	assert.True(status.Syncing)
+	assert.Equal(uint64(1100), status.HighestPeerHeight)
+	assert.Equal(uint64(300), sp.getPeerHeight("peer1"))
+	assert.Equal(uint64(1100), sp.getPeerHeight("peer2"))
+	assert.Equal(uint64(0), sp.getPeerHeight("peer3"))
+	assert.Equal(time.Duration(0), status.ETA)
+
+	// The finalized height grows at 100 blocks per second
+	now := sp.lastSample
+	for i := 1; i <= 100; i++ {
+		sp.sample(100+uint64(i)*100, now.Add(time.Duration(i)*time.Second))
+	}
+	status = sp.status(600, 10)
+	assert.True(status.Syncing)
+	assert.InDelta(100, status.BlocksPerSecond, 0.1)
+	assert.InDelta(float64(5*time.Second), float64(status.ETA), float64(10*time.Millisecond))
+
+	// Caught up
+	status = sp.status(1095, 10)
+	assert.False(status.Syncing)
+	assert.Equal(uint64(1095), status.CurrentHeight)
+
+	// The target drops once the highest peer disconnects
+	sp.prunePeers(map[string]bool{"peer1": true})
+	status = sp.status(100, 10)
+	assert.Equal(uint64(300), status.HighestPeerHeight)
+	assert.Equal(uint64(0), sp.getPeerHeight("peer2"))
+}
+
+func TestPeerHeights(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	core.CreateTestBlock("A0", "")
+	createVotedTestBlocks(
+		"A1", "A0",
+		"A2", "A1",
+		"A3", "A2",
+	)
+	unvoted := createVotedTestBlock("B2", "A1", core.DefaultSigner, nil)
+	unvoted.Height = 1000
+	unvoted.Signature, _ = core.DefaultSigner.Sign(unvoted.SignBytes())
+	unvoted.UpdateHash()
+
+	initChain := blockchain.CreateTestChainByBlocks([]string{
+		"A1", "A0",
+	})
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	simnet.AddEndpoint("node2")
+	simnet.AddEndpoint("node3")
+	simnet.Start(context.Background())
+
+	dispatch := dispatcher.NewDispatcher(net1)
+	a1, _ := initChain.FindBlock(core.GetTestBlock("A1").Hash())
+	consensus := NewMockConsensus(initChain, a1)
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, NewMockMessageConsumer())
+	sm.SetValidatorManager(mockValidatorManager{})
+
+	// The height of a block is not counted without the votes of the validators
+	sm.handleBlock("node2", unvoted)
+	assert.Equal(uint64(0), sm.GetPeerHeight("node2"))
+
+	// The votes in a block certify the height of its parent
+	sm.handleBlock("node2", core.GetTestBlock("A2"))
+	assert.Equal(a1.Height, sm.GetPeerHeight("node2"))
+
+	// The height of the certified headers is counted
+	sm.requestMgr.AddHeaders("node3", getTestHeaders("A2", "A3"))
+	assert.Equal(core.GetTestBlock("A2").Height, sm.GetPeerHeight("node3"))
+	sm.requestMgr.AddHeaders("node4", getTestHeaders("A2", "A3"))
+	assert.Equal(core.GetTestBlock("A2").Height, sm.GetSyncStatus().HighestPeerHeight)
+
+	// The heights of the disconnected peers are dropped
+	sm.requestMgr.mu.Lock()
+	sm.requestMgr.headerSyncer.prunePeers()
+	sm.requestMgr.mu.Unlock()
+	assert.Equal(uint64(0), sm.GetPeerHeight("node4"))
+	assert.Equal(core.GetTestBlock("A2").Height, sm.GetPeerHeight("node3"))
+}
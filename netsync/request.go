@@ -20,6 +20,7 @@ import (
 const RequestTimeout = 10 * time.Second
 const MinInventoryRequestInterval = 3 * time.Second
 const RequestQuotaPerSecond = 1000
+const MaxNumOrphanBlocks = 4096
 
 type RequestState uint8
 
@@ -56,6 +57,8 @@ func (pb *PendingBlock) UpdateTimestamp() {
 type RequestManager struct {
 	logger *log.Entry
 
+	mu *sync.Mutex
+
 	ticker *time.Ticker
 	quota  int
 
@@ -73,6 +76,9 @@ type RequestManager struct {
 	pendingBlocks         *list.List
 	pendingBlocksByHash   map[string]*list.Element
 	pendingBlocksByParent map[string][]*core.Block
+	numOrphanBlocks       int
+
+	headerSyncer *HeaderSyncer
 
 	endHashCache      []common.Bytes
 	blockRequestCache []common.Bytes
@@ -83,6 +89,7 @@ func NewRequestManager(syncMgr *SyncManager) *RequestManager {
 		ticker: time.NewTicker(1 * time.Second),
 		quota:  RequestQuotaPerSecond,
 
+		mu: &sync.Mutex{},
 		wg: &sync.WaitGroup{},
 
 		lastInventoryRequest: time.Now(),
@@ -95,6 +102,7 @@ func NewRequestManager(syncMgr *SyncManager) *RequestManager {
 		pendingBlocksByHash:   make(map[string]*list.Element),
 		pendingBlocksByParent: make(map[string][]*core.Block),
 	}
+	rm.headerSyncer = NewHeaderSyncer(rm)
 
 	logger := util.GetLoggerForModule("request")
 	if viper.GetBool(common.CfgLogPrintSelfID) {
@@ -113,8 +121,9 @@ func (rm *RequestManager) mainLoop() {
 		case <-rm.ctx.Done():
 			rm.stopped = true
 			return
-		case <-rm.ticker.C:
+		case now := <-rm.ticker.C:
 			rm.quota = RequestQuotaPerSecond
+			rm.syncMgr.progress.sample(rm.syncMgr.consensus.GetLastFinalizedBlock().Height, now)
 			rm.tryToDownload()
 		}
 	}
@@ -174,12 +183,19 @@ func (rm *RequestManager) buildInventoryRequest() dispatcher.InventoryRequest {
 }
 
 func (rm *RequestManager) tryToDownload() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
 	hasUndownloadedBlocks := rm.pendingBlocks.Len() > 0 || len(rm.pendingBlocksByHash) > 0 || len(rm.pendingBlocksByParent) > 0
+	hs := rm.headerSyncer
 	inventoryRequestIntervalPassed := time.Since(rm.lastInventoryRequest) >= MinInventoryRequestInterval
 	if hasUndownloadedBlocks && inventoryRequestIntervalPassed {
 		rm.logger.WithFields(log.Fields{
 			"pendingBlocks":     rm.pendingBlocks.Len(),
-			"orphan blocks":     len(rm.pendingBlocksByParent),
+			"orphan blocks":     rm.numOrphanBlocks,
+			"pendingHeaders":    hs.NumPendingHeaders(),
+			"headerHeight":      hs.HeaderHeight(),
+			"highestPeerHeight": rm.syncMgr.progress.getHighestPeerHeight(),
 			"current chain tip": rm.syncMgr.consensus.GetTip(true).Hash().Hex(),
 		}).Info("Fast sync in progress")
 
@@ -195,6 +211,11 @@ func (rm *RequestManager) tryToDownload() {
 		rm.syncMgr.dispatcher.GetInventory([]string{}, req)
 	}
 
+	if (hasUndownloadedBlocks || hs.IsSyncing()) && time.Since(hs.lastHeaderRequest) >= MinInventoryRequestInterval {
+		hs.requestHeaders([]string{})
+	}
+	hs.scheduleRanges(time.Now())
+
 	for curr := rm.pendingBlocks.Front(); rm.quota != 0 && curr != nil; curr = curr.Next() {
 		pendingBlock := curr.Value.(*PendingBlock)
 		if pendingBlock.block != nil {
@@ -203,6 +224,9 @@ func (rm *RequestManager) tryToDownload() {
 		if len(pendingBlock.peers) == 0 {
 			continue
 		}
+		if hs.HasHeader(pendingBlock.hash) {
+			continue // the block body is downloaded by the header syncer
+		}
 		if pendingBlock.status == RequestToSendDataReq ||
 			(pendingBlock.status == RequestWaitingDataResp && pendingBlock.HasTimedOut()) {
 			randomPeerID := pendingBlock.peers[rand.Intn(len(pendingBlock.peers))]
@@ -225,6 +249,9 @@ func (rm *RequestManager) tryToDownload() {
 }
 
 func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
 	if _, err := rm.chain.FindBlock(x); err == nil {
 		return
 	}
@@ -256,7 +283,46 @@ func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string) {
 	}
 }
 
-func (rm *RequestManager) AddBlock(block *core.Block) {
+// AddHeaders adds the block headers received from the peer to the header chain.
+func (rm *RequestManager) AddHeaders(peerID string, headers []*core.BlockHeader) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.headerSyncer.AddHeaders(peerID, headers)
+}
+
+// RejectBlock handles an invalid block sent by the peer, so that the block can be requested from
+// other peers.
+func (rm *RequestManager) RejectBlock(peerID string, hash common.Hash) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.headerSyncer.RejectBlock(peerID, hash)
+}
+
+// HeaderProgress returns the height of the highest verified header and the number of headers
+// waiting for their block bodies.
+func (rm *RequestManager) HeaderProgress() (uint64, int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return rm.headerSyncer.HeaderHeight(), rm.headerSyncer.NumPendingHeaders()
+}
+
+func (rm *RequestManager) AddBlock(peerID string, block *core.Block) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if readyBlocks, ok := rm.headerSyncer.AddBlock(peerID, block); ok {
+		for _, readyBlock := range readyBlocks {
+			if _, err := rm.chain.FindBlock(readyBlock.Hash()); err == nil {
+				continue
+			}
+			rm.dumpReadyBlocks(readyBlock)
+		}
+		return
+	}
+
 	if pendingBlockEl, ok := rm.pendingBlocksByHash[block.Hash().String()]; ok {
 		pendingBlock := pendingBlockEl.Value.(*PendingBlock)
 		pendingBlock.block = block
@@ -277,10 +343,19 @@ func (rm *RequestManager) AddBlock(block *core.Block) {
 			break
 		}
 	}
-	if !found {
-		byParents = append(byParents, block)
+	if found {
+		return
+	}
+	if rm.numOrphanBlocks >= MaxNumOrphanBlocks {
+		// The orphan block will be downloaded again after its ancestors are synced
+		rm.logger.WithFields(log.Fields{
+			"block":  block.Hash().Hex(),
+			"parent": parent.Hex(),
+		}).Debug("Too many orphan blocks, dropping block")
+		return
 	}
-	rm.pendingBlocksByParent[parent.String()] = byParents
+	rm.pendingBlocksByParent[parent.String()] = append(byParents, block)
+	rm.numOrphanBlocks++
 }
 
 func (rm *RequestManager) dumpReadyBlocks(block *core.Block) {
@@ -293,6 +368,7 @@ func (rm *RequestManager) dumpReadyBlocks(block *core.Block) {
 		if children, ok := rm.pendingBlocksByParent[hash]; ok {
 			queue = append(queue, children...)
 			delete(rm.pendingBlocksByParent, hash)
+			rm.numOrphanBlocks -= len(children)
 		}
 
 		if pendingBlockEl, ok := rm.pendingBlocksByHash[hash]; ok {
@@ -3,6 +3,7 @@ package netsync
 import (
 	"context"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -29,9 +30,14 @@ var _ p2p.MessageHandler = (*SyncManager)(nil)
 type SyncManager struct {
 	chain      *blockchain.Chain
 	consensus  core.ConsensusEngine
+	valMgr     core.ValidatorManager // verifies the HCC votes of the synced headers, see HeaderSyncer
 	consumer   MessageConsumer
 	dispatcher *dispatcher.Dispatcher
 	requestMgr *RequestManager
+	progress   *syncProgress
+
+	invRequestLimiter *requestLimiter
+	blockOrigins      *blockOrigins
 
 	wg      *sync.WaitGroup
 	ctx     context.Context
@@ -50,10 +56,14 @@ func NewSyncManager(chain *blockchain.Chain, cons core.ConsensusEngine, network
 		consumer:   consumer,
 		dispatcher: disp,
 
+		invRequestLimiter: newRequestLimiter(MaxInvRequestBurst, InvRequestRefillRate),
+		blockOrigins:      newBlockOrigins(MaxNumBlockOrigins),
+
 		wg:       &sync.WaitGroup{},
 		incoming: make(chan p2ptypes.Message, viper.GetInt(common.CfgSyncMessageQueueSize)),
 	}
 	sm.requestMgr = NewRequestManager(sm)
+	sm.progress = newSyncProgress(cons.GetLastFinalizedBlock().Height)
 	network.RegisterMessageHandler(sm)
 
 	logger := util.GetLoggerForModule("sync")
@@ -65,6 +75,12 @@ func NewSyncManager(chain *blockchain.Chain, cons core.ConsensusEngine, network
 	return sm
 }
 
+// SetValidatorManager sets the validator manager used to verify the HCC votes of the synced
+// headers. Without it, no header is certified and the blocks are synced through the inventories.
+func (sm *SyncManager) SetValidatorManager(valMgr core.ValidatorManager) {
+	sm.valMgr = valMgr
+}
+
 func (sm *SyncManager) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
 	sm.ctx = c
@@ -150,20 +166,55 @@ func (sm *SyncManager) processMessage(message p2ptypes.Message) {
 	}
 }
 
+// GetSyncStatus returns the progress of the block sync.
+func (sm *SyncManager) GetSyncStatus() SyncStatus {
+	currentHeight := sm.consensus.GetLastFinalizedBlock().Height
+	behindThreshold := uint64(viper.GetInt(common.CfgSyncBehindHeightThreshold))
+	status := sm.progress.status(currentHeight, behindThreshold)
+	status.HeaderHeight, status.NumPendingHeaders = sm.requestMgr.HeaderProgress()
+	return status
+}
+
+// GetPeerHeight returns the last block height advertised by the peer, 0 if unknown.
+func (sm *SyncManager) GetPeerHeight(peerID string) uint64 {
+	return sm.progress.getPeerHeight(peerID)
+}
+
+// HandleInvalidBlock reports the peer the given block is received from, once
+// the block fails the validation of the consensus engine.
+func (sm *SyncManager) HandleInvalidBlock(hash common.Hash) {
+	peerID, ok := sm.blockOrigins.get(hash)
+	if !ok {
+		return
+	}
+	sm.logger.WithFields(log.Fields{
+		"block": hash.Hex(),
+		"peer":  peerID,
+	}).Warn("Received invalid block from peer")
+	sm.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorInvalidBlock)
+}
+
 // PassdownMessage passes message through to the consumer.
 func (sm *SyncManager) PassdownMessage(msg interface{}) {
 	sm.consumer.AddMessage(msg)
 }
 
-// locateStart finds first start hash that exists in local chain.
+// locateStart finds first start hash that exists in local chain. The blocks on the forks
+// abandoned below the last finalized block are skipped, since no blocks can be collected after them.
 func (m *SyncManager) locateStart(starts []string) common.Hash {
 	var start common.Hash
+	lfbHeight := m.consensus.GetLastFinalizedBlock().Height
 	for i := 0; i < len(starts); i++ {
 		curr := common.HexToHash(starts[i])
-		if _, err := m.chain.FindBlock(curr); err == nil {
-			start = curr
-			break
+		block, err := m.chain.FindBlock(curr)
+		if err != nil {
+			continue
+		}
+		if block.Height <= lfbHeight && !block.Status.IsFinalized() {
+			continue
 		}
+		start = curr
+		break
 	}
 	return start
 }
@@ -213,6 +264,44 @@ func (m *SyncManager) collectBlocks(start common.Hash, end common.Hash) []string
 	return ret
 }
 
+// collectHeaders dumps the headers of the finalized blocks following start, up to MaxNumHeadersPerResponse.
+func (m *SyncManager) collectHeaders(start common.Hash) []*core.BlockHeader {
+	ret := []*core.BlockHeader{}
+
+	curr := start
+	for len(ret) < MaxNumHeadersPerResponse {
+		block, err := m.chain.FindBlock(curr)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"hash": curr.Hex(),
+			}).Error("Failed to find block with given hash")
+			return ret
+		}
+
+		var next *core.ExtendedBlock
+		for _, child := range block.Children {
+			childBlock, err := m.chain.FindBlock(child)
+			if err != nil {
+				m.logger.WithFields(log.Fields{
+					"err":  err,
+					"hash": child.Hex(),
+				}).Error("Failed to load block")
+				return ret
+			}
+			if childBlock.Status.IsFinalized() {
+				next = childBlock
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		ret = append(ret, next.BlockHeader)
+		curr = next.Hash()
+	}
+	return ret
+}
+
 func (m *SyncManager) handleInvRequest(peerID string, req *dispatcher.InventoryRequest) {
 	m.logger.WithFields(log.Fields{
 		"channelID":   req.ChannelID,
@@ -220,6 +309,14 @@ func (m *SyncManager) handleInvRequest(peerID string, req *dispatcher.InventoryR
 		"endHash":     req.End,
 	}).Debug("Received inventory request")
 
+	if !m.invRequestLimiter.allow(peerID, time.Now()) {
+		m.logger.WithFields(log.Fields{
+			"peer": peerID,
+		}).Warn("Too many inventory requests from peer")
+		m.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorRequestSpam)
+		return
+	}
+
 	switch req.ChannelID {
 	case common.ChannelIDBlock:
 
@@ -241,6 +338,34 @@ func (m *SyncManager) handleInvRequest(peerID string, req *dispatcher.InventoryR
 			"len(resp.Entries)": len(resp.Entries),
 		}).Debug("Sending inventory response")
 		m.dispatcher.SendInventory([]string{peerID}, resp)
+	case common.ChannelIDHeader:
+		start := m.locateStart(req.Starts)
+		if start.IsEmpty() {
+			m.logger.WithFields(log.Fields{
+				"channelID": req.ChannelID,
+			}).Warn("No start hash can be found in local chain")
+			return
+		}
+
+		headers := m.collectHeaders(start)
+		if len(headers) == 0 {
+			return
+		}
+		payload, err := rlp.EncodeToBytes(headers)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"error": err,
+			}).Error("Failed to encode headers")
+			return
+		}
+
+		// Send response.
+		resp := dispatcher.DataResponse{ChannelID: common.ChannelIDHeader, Payload: payload}
+		m.logger.WithFields(log.Fields{
+			"channelID":    resp.ChannelID,
+			"len(headers)": len(headers),
+		}).Debug("Sending headers")
+		m.dispatcher.SendData([]string{peerID}, resp)
 	default:
 		m.logger.WithFields(log.Fields{"channelID": req.ChannelID}).Error("Unsupported channelID in received InvRequest")
 	}
@@ -280,6 +405,9 @@ func (m *SyncManager) handleDataRequest(peerID string, data *dispatcher.DataRequ
 				}).Error("Failed to find hash string locally")
 				return
 			}
+			if block.Status == core.BlockStatusInvalid {
+				continue
+			}
 
 			payload, err := rlp.EncodeToBytes(block.Block)
 			if err != nil {
@@ -307,6 +435,19 @@ func (m *SyncManager) handleDataRequest(peerID string, data *dispatcher.DataRequ
 
 func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataResponse) {
 	switch data.ChannelID {
+	case common.ChannelIDHeader:
+		headers := []*core.BlockHeader{}
+		err := rlp.DecodeBytes(data.Payload, &headers)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"channelID": data.ChannelID,
+				"payload":   data.Payload,
+				"error":     err,
+			}).Error("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorUnparsableMessage)
+			return
+		}
+		m.requestMgr.AddHeaders(peerID, headers)
 	case common.ChannelIDBlock:
 		block := core.NewBlock()
 		err := rlp.DecodeBytes(data.Payload, block)
@@ -316,9 +457,10 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"payload":   data.Payload,
 				"error":     err,
 			}).Error("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorUnparsableMessage)
 			return
 		}
-		m.handleBlock(block)
+		m.handleBlock(peerID, block)
 	case common.ChannelIDVote:
 		vote := core.Vote{}
 		err := rlp.DecodeBytes(data.Payload, &vote)
@@ -328,9 +470,10 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"payload":   data.Payload,
 				"error":     err,
 			}).Error("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorUnparsableMessage)
 			return
 		}
-		m.handleVote(vote)
+		m.handleVote(peerID, vote)
 	case common.ChannelIDProposal:
 		proposal := &core.Proposal{}
 		err := rlp.DecodeBytes(data.Payload, proposal)
@@ -340,9 +483,10 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"payload":   data.Payload,
 				"error":     err,
 			}).Error("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorUnparsableMessage)
 			return
 		}
-		m.handleProposal(proposal)
+		m.handleProposal(peerID, proposal)
 	default:
 		m.logger.WithFields(log.Fields{
 			"channelID": data.ChannelID,
@@ -350,20 +494,20 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 	}
 }
 
-func (sm *SyncManager) handleProposal(p *core.Proposal) {
+func (sm *SyncManager) handleProposal(peerID string, p *core.Proposal) {
 	sm.logger.WithFields(log.Fields{
 		"proposal": p,
 	}).Debug("Received proposal")
 
 	if p.Votes != nil {
 		for _, vote := range p.Votes.Votes() {
-			sm.handleVote(vote)
+			sm.handleVote(peerID, vote)
 		}
 	}
-	sm.handleBlock(p.Block)
+	sm.handleBlock(peerID, p.Block)
 }
 
-func (sm *SyncManager) handleBlock(block *core.Block) {
+func (sm *SyncManager) handleBlock(peerID string, block *core.Block) {
 	sm.logger.WithFields(log.Fields{
 		"block.Hash":   block.Hash().Hex(),
 		"block.Parent": block.Parent.Hex(),
@@ -373,7 +517,26 @@ func (sm *SyncManager) handleBlock(block *core.Block) {
 		return
 	}
 
-	sm.requestMgr.AddBlock(block)
+	res := block.Validate()
+	if res.IsOK() {
+		res = block.ValidateTxs()
+	}
+	if res.IsError() {
+		sm.logger.WithFields(log.Fields{
+			"block.Hash": block.Hash().Hex(),
+			"peer":       peerID,
+			"error":      res.String(),
+		}).Warn("Received invalid block")
+		sm.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorInvalidBlock)
+		sm.requestMgr.RejectBlock(peerID, block.Hash())
+		return
+	}
+	if height, ok := sm.getCertifiedHeight(block); ok {
+		sm.progress.updatePeerHeight(peerID, height)
+	}
+	sm.blockOrigins.add(block.Hash(), peerID)
+
+	sm.requestMgr.AddBlock(peerID, block)
 
 	sm.dispatcher.SendInventory([]string{}, dispatcher.InventoryResponse{
 		ChannelID: common.ChannelIDBlock,
@@ -381,7 +544,25 @@ func (sm *SyncManager) handleBlock(block *core.Block) {
 	})
 }
 
-func (sm *SyncManager) handleVote(vote core.Vote) {
+// getCertifiedHeight returns the height of the HCC block of the given block, if the HCC proves the
+// majority of the validators for a valid block in the local chain. The height of the block itself
+// is not certified until the votes on it are seen.
+func (sm *SyncManager) getCertifiedHeight(block *core.Block) (uint64, bool) {
+	hcc := block.HCC
+	if sm.valMgr == nil || hcc.Votes == nil || hcc.Votes.IsEmpty() {
+		return 0, false
+	}
+	hccBlock, err := sm.chain.FindBlock(hcc.BlockHash)
+	if err != nil || !hccBlock.Status.IsValid() {
+		return 0, false
+	}
+	if validateHCCVotes(hcc).IsError() || !sm.valMgr.GetValidatorSet(hcc.BlockHash).HasMajority(hcc.Votes) {
+		return 0, false
+	}
+	return hccBlock.Height, true
+}
+
+func (sm *SyncManager) handleVote(peerID string, vote core.Vote) {
 	sm.logger.WithFields(log.Fields{
 		"vote.Hash":  vote.Block.Hex(),
 		"vote.ID":    vote.ID.Hex(),
@@ -396,6 +577,16 @@ func (sm *SyncManager) handleVote(vote core.Vote) {
 		}
 	}
 
+	if res := vote.Validate(); res.IsError() {
+		sm.logger.WithFields(log.Fields{
+			"vote.Hash": vote.Block.Hex(),
+			"peer":      peerID,
+			"error":     res.String(),
+		}).Warn("Received invalid vote")
+		sm.dispatcher.ReportPeer(peerID, p2ptypes.MisbehaviorInvalidVote)
+		return
+	}
+
 	sm.PassdownMessage(vote)
 
 	payload, err := rlp.EncodeToBytes(vote)
@@ -7,7 +7,6 @@ import (
 
 	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
-	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/ledger"
 	"github.com/thetatoken/theta/rlp"
 	"github.com/thetatoken/theta/store/database/backend"
@@ -108,10 +107,13 @@ func TestSyncManager(t *testing.T) {
 	assert.Equal(common.ChannelIDBlock, msg1.ChannelID)
 	assert.Equal(core.GetTestBlock("A4").Hash().Hex(), msg1.Entries[0])
 
-	res = <-mockMsgHandler.C
-	msg2, ok := res.(dispatcher.InventoryRequest)
-	assert.True(ok)
-	assert.Equal(common.ChannelIDBlock, msg2.ChannelID)
+	// node1 should also broadcast InventoryRequest for blocks, possibly along with a header request
+	var msg2 dispatcher.InventoryRequest
+	for msg2.ChannelID != common.ChannelIDBlock {
+		res = <-mockMsgHandler.C
+		msg2, ok = res.(dispatcher.InventoryRequest)
+		assert.True(ok)
+	}
 	assert.Equal(3, len(msg2.Starts))
 	assert.Equal(core.GetTestBlock("B2").Hash().Hex(), msg2.Starts[0])
 	assert.Equal(core.GetTestBlock("A1").Hash().Hex(), msg2.Starts[1])
@@ -175,7 +177,7 @@ func NewMockConsensus(chain *blockchain.Chain, lfb *core.ExtendedBlock) *MockCon
 }
 
 // ID() string
-// PrivateKey() *crypto.PrivateKey
+// Signer() core.Signer
 // GetTip(includePendingBlockingLeaf bool) *ExtendedBlock
 // GetEpoch() uint64
 // GetLedger() Ledger
@@ -187,7 +189,7 @@ func (c *MockConsensus) ID() string {
 	return ""
 }
 
-func (c *MockConsensus) PrivateKey() *crypto.PrivateKey {
+func (c *MockConsensus) Signer() core.Signer {
 	return nil
 }
 
@@ -211,6 +213,9 @@ func (c *MockConsensus) FinalizedBlocks() chan *core.Block {
 func (c *MockConsensus) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return c.lfb
 }
+func (c *MockConsensus) FindBlock(blockHash common.Hash) (*core.ExtendedBlock, error) {
+	return c.chain.FindBlock(blockHash)
+}
 
 func TestCollectBlocks(t *testing.T) {
 	assert := assert.New(t)
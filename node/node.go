@@ -10,7 +10,6 @@ import (
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
-	"github.com/thetatoken/theta/crypto"
 	dp "github.com/thetatoken/theta/dispatcher"
 	ld "github.com/thetatoken/theta/ledger"
 	mp "github.com/thetatoken/theta/mempool"
@@ -43,12 +42,14 @@ type Node struct {
 }
 
 type Params struct {
-	ChainID      string
-	PrivateKey   *crypto.PrivateKey
-	Root         *core.Block
-	Network      p2p.Network
-	DB           database.Database
-	SnapshotPath string
+	ChainID            string
+	Signer             core.Signer
+	Root               *core.Block
+	Network            p2p.Network
+	DB                 database.Database
+	SnapshotPath       string
+	MempoolJournalPath string
+	ConsensusWALPath   string
 }
 
 func NewNode(params *Params) *Node {
@@ -56,7 +57,10 @@ func NewNode(params *Params) *Node {
 	chain := blockchain.NewChain(params.ChainID, store, params.Root)
 	validatorManager := consensus.NewRotatingValidatorManager()
 	dispatcher := dp.NewDispatcher(params.Network)
-	consensus := consensus.NewConsensusEngine(params.PrivateKey, store, chain, dispatcher, validatorManager)
+	consensus := consensus.NewConsensusEngine(params.Signer, store, chain, dispatcher, validatorManager)
+	if len(params.ConsensusWALPath) > 0 {
+		consensus.SetWALFilePath(params.ConsensusWALPath)
+	}
 
 	currentHeight := consensus.GetLastFinalizedBlock().Height
 	if currentHeight <= params.Root.Height {
@@ -67,10 +71,15 @@ func NewNode(params *Params) *Node {
 	}
 
 	syncMgr := netsync.NewSyncManager(chain, consensus, params.Network, dispatcher, consensus)
+	syncMgr.SetValidatorManager(validatorManager)
 	mempool := mp.CreateMempool(dispatcher)
+	if len(params.MempoolJournalPath) > 0 {
+		mempool.SetJournalFilePath(params.MempoolJournalPath)
+	}
 	ledger := ld.NewLedger(params.ChainID, params.DB, consensus, validatorManager, mempool)
 	validatorManager.SetConsensusEngine(consensus)
 	consensus.SetLedger(ledger)
+	consensus.SetInvalidBlockHandler(syncMgr.HandleInvalidBlock)
 	mempool.SetLedger(ledger)
 	txMsgHandler := mp.CreateMempoolMessageHandler(mempool)
 	params.Network.RegisterMessageHandler(txMsgHandler)
@@ -87,7 +96,7 @@ func NewNode(params *Params) *Node {
 	}
 
 	if viper.GetBool(common.CfgRPCEnabled) {
-		node.RPC = rpc.NewThetaRPCServer(mempool, ledger, chain, consensus)
+		node.RPC = rpc.NewThetaRPCServer(mempool, ledger, chain, consensus, syncMgr, params.Network)
 	}
 
 	return node
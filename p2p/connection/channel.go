@@ -2,8 +2,11 @@ package connection
 
 import (
 	"io"
+	"sync/atomic"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/common/metrics"
+	"github.com/thetatoken/theta/p2p/connection/flowrate"
 	"github.com/thetatoken/theta/rlp"
 )
 
@@ -16,6 +19,15 @@ type Channel struct {
 	sendBuf SendBuffer
 	recvBuf RecvBuffer
 
+	sendMonitor *flowrate.Monitor
+	recvMonitor *flowrate.Monitor
+
+	// Node-wide meters of the channel, aggregated over all the connections
+	sendMeter metrics.Meter
+	recvMeter metrics.Meter
+
+	recentlySent int64 // exponentially decayed number of bytes sent, accessed atomically
+
 	config ChannelConfig
 }
 
@@ -24,6 +36,7 @@ type Channel struct {
 //
 type ChannelConfig struct {
 	priority uint
+	minShare float64
 }
 
 // createDefaultChannel creates a channel with default configs
@@ -36,15 +49,32 @@ func createDefaultChannel(channelID common.ChannelIDEnum) Channel {
 	return channel
 }
 
+// createScheduledChannel creates a channel with the given schedule
+func createScheduledChannel(channelID common.ChannelIDEnum, schedule ChannelSchedule) Channel {
+	chCfg := ChannelConfig{
+		priority: schedule.Priority,
+		minShare: schedule.MinShare,
+	}
+	sbCfg := getDefaultSendBufferConfig()
+	rbCfg := getDefaultRecvBufferConfig()
+
+	channel := createChannel(channelID, chCfg, sbCfg, rbCfg)
+	return channel
+}
+
 // createChannel creates a channel for the given configs
 func createChannel(channelID common.ChannelIDEnum, channelConf ChannelConfig, sbConf SendBufferConfig, rbConf RecvBufferConfig) Channel {
 	sendBuf := createSendBuffer(sbConf)
 	recvBuf := createRecvBuffer(rbConf)
 	return Channel{
-		id:      channelID,
-		sendBuf: sendBuf,
-		recvBuf: recvBuf,
-		config:  channelConf,
+		id:          channelID,
+		sendBuf:     sendBuf,
+		recvBuf:     recvBuf,
+		sendMonitor: flowrate.New(0, 0),
+		recvMonitor: flowrate.New(0, 0),
+		sendMeter:   metrics.GetOrRegisterMeter("p2p/channel/"+ChannelName(channelID)+"/out", nil),
+		recvMeter:   metrics.GetOrRegisterMeter("p2p/channel/"+ChannelName(channelID)+"/in", nil),
+		config:      channelConf,
 	}
 }
 
@@ -52,6 +82,7 @@ func createChannel(channelID common.ChannelIDEnum, channelConf ChannelConfig, sb
 func getDefaultChannelConfig() ChannelConfig {
 	return ChannelConfig{
 		priority: 0,
+		minShare: 0,
 	}
 }
 
@@ -74,6 +105,8 @@ func (ch *Channel) attemptToEnqueueMessage(bytes []byte) bool {
 
 // receivePacket receives packet and return the converted bytes
 func (ch *Channel) receivePacket(packet *Packet) ([]byte, bool) {
+	ch.recvMonitor.Update(len(packet.Bytes))
+	ch.recvMeter.Mark(int64(len(packet.Bytes)))
 	bytes, success := ch.recvBuf.receivePacket(packet)
 	return bytes, success
 }
@@ -92,6 +125,9 @@ func (ch *Channel) sendPacketTo(writer io.Writer) (nonemptyPacket bool, numBytes
 	}
 
 	numBytes, err = writer.Write(packetBytes)
+	ch.sendMonitor.Update(numBytes)
+	ch.sendMeter.Mark(int64(numBytes))
+	atomic.AddInt64(&ch.recentlySent, int64(numBytes))
 	return true, numBytes, err
 }
 
@@ -105,3 +141,14 @@ func (ch *Channel) hasPacketToSend() bool {
 	hasPacket := !ch.sendBuf.isEmpty()
 	return hasPacket
 }
+
+// getRecentlySent returns the decayed number of bytes recently sent through the channel
+func (ch *Channel) getRecentlySent() int64 {
+	return atomic.LoadInt64(&ch.recentlySent)
+}
+
+// decayRecentlySent decays the number of bytes recently sent by the given factor
+func (ch *Channel) decayRecentlySent(factor float64) {
+	recentlySent := atomic.LoadInt64(&ch.recentlySent)
+	atomic.StoreInt64(&ch.recentlySent, int64(float64(recentlySent)*factor))
+}
@@ -2,12 +2,21 @@ package connection
 
 import (
 	"sync"
+	"time"
 
 	"github.com/thetatoken/theta/common"
 )
 
 const (
 	channelSelectionRoundRobinStrategy = 1
+	channelSelectionPriorityStrategy   = 2
+)
+
+const (
+	// recentlySentDecayInterval is the interval to decay the recently sent bytes of the channels
+	recentlySentDecayInterval = time.Second
+	// recentlySentDecayFactor is the factor to decay the recently sent bytes of the channels
+	recentlySentDecayFactor = 0.8
 )
 
 //
@@ -42,6 +51,8 @@ func createChannelGroup(cgConfig ChannelGroupConfig, channels []*Channel) (bool,
 	var channelSelector ChannelSelector
 	if cgConfig.selectionStrategy == channelSelectionRoundRobinStrategy {
 		channelSelector = createRoundRobinChannelSelector()
+	} else if cgConfig.selectionStrategy == channelSelectionPriorityStrategy {
+		channelSelector = createPriorityChannelSelector()
 	} else {
 		logger.Errorf("Invalid channel selection strategy")
 		return false, ChannelGroup{}
@@ -67,6 +78,12 @@ func getDefaultChannelGroupConfig() ChannelGroupConfig {
 	}
 }
 
+func getPriorityChannelGroupConfig() ChannelGroupConfig {
+	return ChannelGroupConfig{
+		selectionStrategy: channelSelectionPriorityStrategy,
+	}
+}
+
 func (cg *ChannelGroup) addChannel(channel *Channel) bool {
 	cg.mutex.Lock()
 	defer cg.mutex.Unlock()
@@ -179,3 +196,79 @@ func (rrcs *RoundRobinChannelSelector) nextSelectedChannelIndex(cg *ChannelGroup
 	}
 	return true, rrcs.lastUsedChannelIndex
 }
+
+//
+// PriorityChannelSelector implments the ChannelSelector interface. A channel
+// which has sent less than its minimum share of the recent traffic is served
+// first. Otherwise the channel with the highest priority is selected, and the
+// ties are broken by the least recently sent bytes.
+//
+type PriorityChannelSelector struct {
+	lastDecay time.Time
+}
+
+func createPriorityChannelSelector() ChannelSelector {
+	return &PriorityChannelSelector{
+		lastDecay: time.Now(),
+	}
+}
+
+func (pcs *PriorityChannelSelector) nextSelectedChannelIndex(cg *ChannelGroup) (success bool, index int) {
+	channels := *(cg.getAllChannels())
+	if len(channels) == 0 {
+		logger.Errorf("The channel group contains no channel")
+		return false, -1
+	}
+
+	if now := time.Now(); now.Sub(pcs.lastDecay) >= recentlySentDecayInterval {
+		for _, channel := range channels {
+			channel.decayRecentlySent(recentlySentDecayFactor)
+		}
+		pcs.lastDecay = now
+	}
+
+	totalRecentlySent := int64(0)
+	for _, channel := range channels {
+		totalRecentlySent += channel.getRecentlySent()
+	}
+
+	// Serve the channel furthest below its minimum share first
+	index = -1
+	maxDeficit := 0.0
+	for idx, channel := range channels {
+		if channel.config.minShare <= 0 || !channel.hasPacketToSend() {
+			continue
+		}
+		share := 0.0
+		if totalRecentlySent > 0 {
+			share = float64(channel.getRecentlySent()) / float64(totalRecentlySent)
+		}
+		if deficit := channel.config.minShare - share; deficit > maxDeficit {
+			maxDeficit = deficit
+			index = idx
+		}
+	}
+	if index >= 0 {
+		return true, index
+	}
+
+	// Otherwise serve the channel with the highest priority
+	for idx, channel := range channels {
+		if !channel.hasPacketToSend() {
+			continue
+		}
+		if index < 0 {
+			index = idx
+			continue
+		}
+		selected := channels[index]
+		if channel.config.priority > selected.config.priority ||
+			(channel.config.priority == selected.config.priority && channel.getRecentlySent() < selected.getRecentlySent()) {
+			index = idx
+		}
+	}
+	if index < 0 {
+		index = 0 // no channel has packet to send
+	}
+	return true, index
+}
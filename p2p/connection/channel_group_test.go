@@ -249,6 +249,66 @@ func TestRoundRobinChannelSelector2(t *testing.T) {
 	assert.Equal(&ch5, ch)
 }
 
+func TestPriorityChannelSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	cg := newTestEmptyPriorityChannelGroup()
+
+	chVote := createScheduledChannel(common.ChannelIDVote, ChannelSchedule{Priority: 9})
+	chTx := createScheduledChannel(common.ChannelIDTransaction, ChannelSchedule{Priority: 2, MinShare: 0.1})
+	chBlock := createScheduledChannel(common.ChannelIDBlock, ChannelSchedule{Priority: 1, MinShare: 0.2})
+
+	assert.True(cg.addChannel(&chBlock))
+	assert.True(cg.addChannel(&chTx))
+	assert.True(cg.addChannel(&chVote))
+
+	// Nothing to send
+	success, ch := cg.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Nil(ch)
+
+	assert.True(chBlock.enqueueMessage([]byte("block")))
+	assert.True(chTx.enqueueMessage([]byte("tx")))
+	assert.True(chVote.enqueueMessage([]byte("vote")))
+
+	// All the channels have received their minimum shares, the highest priority channel is served
+	chBlock.recentlySent = 800
+	chTx.recentlySent = 100
+	chVote.recentlySent = 100
+	success, ch = cg.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Equal(&chVote, ch)
+
+	// The channel furthest below its minimum share is served first
+	chVote.recentlySent = 9000
+	success, ch = cg.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Equal(&chBlock, ch)
+
+	chBlock.recentlySent = 5000
+	success, ch = cg.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Equal(&chTx, ch)
+
+	// Channels with the same priority are served by the least recently sent bytes
+	chTx.recentlySent = 2000
+	chVote2 := createScheduledChannel(common.ChannelIDProposal, ChannelSchedule{Priority: 9})
+	assert.True(cg.addChannel(&chVote2))
+	assert.True(chVote2.enqueueMessage([]byte("proposal")))
+	success, ch = cg.nextChannelToSendPacket()
+	assert.True(success)
+	assert.Equal(&chVote2, ch)
+
+	// Sending updates the recently sent bytes
+	strBuf := bytes.NewBufferString("")
+	nonempty, numBytes, err := chVote2.sendPacketTo(strBuf)
+	assert.True(nonempty)
+	assert.Nil(err)
+	assert.Equal(int64(numBytes), chVote2.getRecentlySent())
+	chVote2.decayRecentlySent(0.5)
+	assert.Equal(int64(numBytes/2), chVote2.getRecentlySent())
+}
+
 // --------------- Test Utilities --------------- //
 
 func newTestEmptyChannelGroup() ChannelGroup {
@@ -261,3 +321,14 @@ func newTestEmptyChannelGroup() ChannelGroup {
 
 	return dcg
 }
+
+func newTestEmptyPriorityChannelGroup() ChannelGroup {
+	cgCfg := getPriorityChannelGroupConfig()
+	channels := []*Channel{}
+	success, dcg := createChannelGroup(cgCfg, channels)
+	if !success {
+		panic("Failed to create channel group!")
+	}
+
+	return dcg
+}
@@ -0,0 +1,80 @@
+package connection
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+)
+
+//
+// ChannelSchedule specifies how a channel shares the send bandwidth of the connection
+//
+type ChannelSchedule struct {
+	Priority uint    // channels with higher priority are served first
+	MinShare float64 // fraction of the send bandwidth reserved for the channel, in [0, 1]
+}
+
+var channelNames = map[common.ChannelIDEnum]string{
+	common.ChannelIDCheckpoint:    "checkpoint",
+	common.ChannelIDHeader:        "header",
+	common.ChannelIDBlock:         "block",
+	common.ChannelIDProposal:      "proposal",
+	common.ChannelIDCC:            "cc",
+	common.ChannelIDVote:          "vote",
+	common.ChannelIDTransaction:   "transaction",
+	common.ChannelIDPeerDiscovery: "peerDiscovery",
+	common.ChannelIDPing:          "ping",
+	common.ChannelIDDHT:           "dht",
+}
+
+// ChannelName returns the name of the channel used in the config and the metrics
+func ChannelName(channelID common.ChannelIDEnum) string {
+	name, ok := channelNames[channelID]
+	if !ok {
+		return fmt.Sprintf("channel%v", byte(channelID))
+	}
+	return name
+}
+
+// ParseChannelID returns the ID of the channel with the given name
+func ParseChannelID(name string) (common.ChannelIDEnum, bool) {
+	for channelID, channelName := range channelNames {
+		if channelName == name {
+			return channelID, true
+		}
+	}
+	return common.ChannelIDInvalid, false
+}
+
+// GetDefaultChannelSchedules returns the default channel schedules. The consensus
+// channels are prioritized, while the bulk data channels are reserved minimum
+// bandwidth shares so that they are never starved.
+func GetDefaultChannelSchedules() map[common.ChannelIDEnum]ChannelSchedule {
+	return map[common.ChannelIDEnum]ChannelSchedule{
+		common.ChannelIDPing:          {Priority: 10, MinShare: 0},
+		common.ChannelIDProposal:      {Priority: 9, MinShare: 0},
+		common.ChannelIDVote:          {Priority: 9, MinShare: 0},
+		common.ChannelIDCC:            {Priority: 9, MinShare: 0},
+		common.ChannelIDCheckpoint:    {Priority: 6, MinShare: 0.05},
+		common.ChannelIDHeader:        {Priority: 6, MinShare: 0.05},
+		common.ChannelIDPeerDiscovery: {Priority: 4, MinShare: 0.05},
+		common.ChannelIDDHT:           {Priority: 4, MinShare: 0.05},
+		common.ChannelIDTransaction:   {Priority: 2, MinShare: 0.1},
+		common.ChannelIDBlock:         {Priority: 1, MinShare: 0.2},
+	}
+}
+
+// ValidateChannelSchedules checks the minimum shares of the channel schedules
+func ValidateChannelSchedules(schedules map[common.ChannelIDEnum]ChannelSchedule) error {
+	totalShare := 0.0
+	for channelID, schedule := range schedules {
+		if schedule.MinShare < 0 || schedule.MinShare > 1 {
+			return fmt.Errorf("Invalid minimum share of channel %v: %v", ChannelName(channelID), schedule.MinShare)
+		}
+		totalShare += schedule.MinShare
+	}
+	if totalShare > 1 {
+		return fmt.Errorf("Total minimum share of the channels exceeds 1: %v", totalShare)
+	}
+	return nil
+}
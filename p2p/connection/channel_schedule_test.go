@@ -0,0 +1,29 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+)
+
+func TestChannelSchedules(t *testing.T) {
+	assert := assert.New(t)
+
+	schedules := GetDefaultChannelSchedules()
+	assert.Nil(ValidateChannelSchedules(schedules))
+	assert.True(schedules[common.ChannelIDVote].Priority > schedules[common.ChannelIDBlock].Priority)
+	assert.True(schedules[common.ChannelIDProposal].Priority > schedules[common.ChannelIDTransaction].Priority)
+
+	schedules[common.ChannelIDBlock] = ChannelSchedule{Priority: 1, MinShare: 0.9}
+	assert.NotNil(ValidateChannelSchedules(schedules))
+	schedules[common.ChannelIDBlock] = ChannelSchedule{Priority: 1, MinShare: -0.1}
+	assert.NotNil(ValidateChannelSchedules(schedules))
+
+	channelID, ok := ParseChannelID("vote")
+	assert.True(ok)
+	assert.Equal(common.ChannelIDVote, channelID)
+	assert.Equal("vote", ChannelName(channelID))
+	_, ok = ParseChannelID("unknown")
+	assert.False(ok)
+}
@@ -3,6 +3,7 @@ package connection
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/thetatoken/theta/rlp"
 
@@ -39,6 +40,30 @@ func TestDefaultChannelEnqueueShortMsg(t *testing.T) {
 	assert.Equal(byte(0x01), decodedPacket.IsEOF)
 }
 
+func TestChannelTrafficStats(t *testing.T) {
+	assert := assert.New(t)
+	ch := createDefaultChannel(common.ChannelIDBlock)
+
+	msgBytes := []byte("hello world")
+	ch.enqueueMessage(msgBytes)
+	strBuf := bytes.NewBufferString("")
+	_, numBytes, err := ch.sendPacketTo(strBuf)
+	assert.Nil(err)
+
+	var decodedPacket Packet
+	rlp.Decode(strBuf, &decodedPacket)
+	receiver := createDefaultChannel(common.ChannelIDBlock)
+	aggregatedBytes, success := receiver.receivePacket(&decodedPacket)
+	assert.True(success)
+	assert.Equal(msgBytes, aggregatedBytes)
+
+	// The monitors account the transferred bytes once the current sample is done
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(int64(numBytes), ch.sendMonitor.Status().Bytes)
+	assert.Equal(int64(len(msgBytes)), receiver.recvMonitor.Status().Bytes)
+	assert.Equal(int64(0), receiver.sendMonitor.Status().Bytes)
+}
+
 func TestDefaultChannelEnqueueLongMsg(t *testing.T) {
 	assert := assert.New(t)
 	ch := createDefaultChannel(common.ChannelIDTransaction)
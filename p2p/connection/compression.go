@@ -0,0 +1,112 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// CompressionNone indicates the messages are sent uncompressed
+	CompressionNone = ""
+	// CompressionSnappy indicates the messages can be compressed with snappy
+	CompressionSnappy = "snappy"
+)
+
+// SupportedCompressions lists the supported compression algorithms in the order of preference
+var SupportedCompressions = []string{CompressionSnappy}
+
+const (
+	compressionFlagRaw    = byte(0x00)
+	compressionFlagSnappy = byte(0x01)
+
+	// maxDecompressedMessageSize limits the size of a decompressed message to
+	// guard against decompression bombs
+	maxDecompressedMessageSize = 64 * 1024 * 1024 // 64MB
+)
+
+// NegotiateCompression returns the most preferred local compression algorithm which
+// is also supported by the remote node, or CompressionNone if there is no such algorithm.
+// Once negotiated, every message is prefixed with a flag indicating whether the message
+// is compressed, so that small messages can still be sent uncompressed.
+func NegotiateCompression(localAlgos []string, remoteAlgos []string) string {
+	for _, local := range localAlgos {
+		for _, remote := range remoteAlgos {
+			if local == remote && isSupportedCompression(local) {
+				return local
+			}
+		}
+	}
+	return CompressionNone
+}
+
+func isSupportedCompression(algo string) bool {
+	for _, supported := range SupportedCompressions {
+		if algo == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCompression sets the compression algorithm negotiated with the peer. It
+// should be called before the connection starts.
+func (conn *Connection) SetCompression(algo string) {
+	conn.compression = algo
+}
+
+// GetCompression returns the compression algorithm negotiated with the peer
+func (conn *Connection) GetCompression() string {
+	return conn.compression
+}
+
+// GetCompressionSavings returns the number of bytes saved by compressing the sent messages
+func (conn *Connection) GetCompressionSavings() int64 {
+	return atomic.LoadInt64(&conn.bytesSavedByCompression)
+}
+
+// compressMessage compresses the message if the compression is negotiated and the
+// message is larger than the threshold
+func (conn *Connection) compressMessage(msgBytes []byte) []byte {
+	if conn.compression == CompressionNone {
+		return msgBytes
+	}
+
+	if len(msgBytes) >= conn.config.CompressionThreshold {
+		compressed := snappy.Encode(nil, msgBytes)
+		if len(compressed) < len(msgBytes) {
+			atomic.AddInt64(&conn.bytesSavedByCompression, int64(len(msgBytes)-len(compressed)))
+			return append([]byte{compressionFlagSnappy}, compressed...)
+		}
+	}
+	return append([]byte{compressionFlagRaw}, msgBytes...)
+}
+
+// decompressMessage restores the message sent by compressMessage
+func (conn *Connection) decompressMessage(msgBytes []byte) ([]byte, error) {
+	if conn.compression == CompressionNone {
+		return msgBytes, nil
+	}
+
+	if len(msgBytes) == 0 {
+		return nil, errors.New("Missing compression flag")
+	}
+	flag, payload := msgBytes[0], msgBytes[1:]
+	switch flag {
+	case compressionFlagRaw:
+		return payload, nil
+	case compressionFlagSnappy:
+		decodedLen, err := snappy.DecodedLen(payload)
+		if err != nil {
+			return nil, err
+		}
+		if decodedLen > maxDecompressedMessageSize {
+			return nil, fmt.Errorf("Decompressed message too large: %v bytes", decodedLen)
+		}
+		return snappy.Decode(nil, payload)
+	default:
+		return nil, fmt.Errorf("Invalid compression flag: %v", flag)
+	}
+}
@@ -0,0 +1,104 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func TestNegotiateCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(CompressionSnappy, NegotiateCompression(SupportedCompressions, SupportedCompressions))
+	assert.Equal(CompressionNone, NegotiateCompression(SupportedCompressions, []string{}))
+	assert.Equal(CompressionNone, NegotiateCompression([]string{}, SupportedCompressions))
+	assert.Equal(CompressionNone, NegotiateCompression([]string{"zstd"}, []string{"zstd"})) // not supported locally
+}
+
+func TestCompressMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{config: GetDefaultConnectionConfig()}
+	largeMsg := bytes.Repeat([]byte("theta"), 1000)
+	smallMsg := []byte("theta")
+
+	// No compression negotiated, messages are sent as is
+	assert.Equal(largeMsg, conn.compressMessage(largeMsg))
+	decompressed, err := conn.decompressMessage(largeMsg)
+	assert.Nil(err)
+	assert.Equal(largeMsg, decompressed)
+
+	conn.SetCompression(CompressionSnappy)
+
+	compressed := conn.compressMessage(largeMsg)
+	assert.Equal(compressionFlagSnappy, compressed[0])
+	assert.True(len(compressed) < len(largeMsg))
+	assert.Equal(int64(len(largeMsg)+1-len(compressed)), conn.GetCompressionSavings())
+	decompressed, err = conn.decompressMessage(compressed)
+	assert.Nil(err)
+	assert.Equal(largeMsg, decompressed)
+
+	// Messages below the threshold are not compressed
+	compressed = conn.compressMessage(smallMsg)
+	assert.Equal(compressionFlagRaw, compressed[0])
+	decompressed, err = conn.decompressMessage(compressed)
+	assert.Nil(err)
+	assert.Equal(smallMsg, decompressed)
+
+	_, err = conn.decompressMessage([]byte{})
+	assert.NotNil(err)
+	_, err = conn.decompressMessage([]byte{0x7f, 0x01})
+	assert.NotNil(err)
+	_, err = conn.decompressMessage([]byte{compressionFlagSnappy, 0xff, 0xff})
+	assert.NotNil(err)
+}
+
+func TestConnectionCompression(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+	port := 43261
+
+	largeMsg := common.Bytes(bytes.Repeat([]byte("theta network "), 2000))
+
+	received := make(chan common.Bytes)
+	go func() {
+		listener := p2ptypes.GetTestListener(port)
+		netconn, err := listener.Accept()
+		assert.Nil(err)
+
+		conn := CreateConnection(netconn, GetDefaultConnectionConfig())
+		conn.SetCompression(CompressionSnappy)
+		conn.SetMessageParser(func(channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+			return p2ptypes.Message{ChannelID: channelID, Content: rawMessageBytes}, nil
+		})
+		conn.SetReceiveHandler(func(message p2ptypes.Message) error {
+			received <- message.Content.(common.Bytes)
+			return nil
+		})
+		conn.Start(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	netconn := p2ptypes.GetTestNetconn(port)
+	conn := CreateConnection(netconn, GetDefaultConnectionConfig())
+	conn.SetCompression(CompressionSnappy)
+	conn.SetMessageEncoder(func(channelID common.ChannelIDEnum, message interface{}) (common.Bytes, error) {
+		return message.(common.Bytes), nil
+	})
+	conn.Start(ctx)
+	defer conn.Stop()
+
+	assert.True(conn.EnqueueMessage(common.ChannelIDBlock, largeMsg))
+	select {
+	case msg := <-received:
+		assert.Equal(largeMsg, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for the message")
+	}
+	assert.True(conn.GetCompressionSavings() > 0)
+}
@@ -49,6 +49,11 @@ type Connection struct {
 	pingTimer  *timer.RepeatTimer   // send pings periodically
 
 	pendingPings uint
+	pingSentAt   int64 // unix nano time when the last ping was sent, accessed atomically
+	pingLatency  int64 // round trip time of the last ping in nanoseconds, accessed atomically
+
+	compression             string // compression algorithm negotiated with the peer
+	bytesSavedByCompression int64  // accessed atomically
 
 	config ConnectionConfig
 
@@ -72,6 +77,10 @@ type ConnectionConfig struct {
 	FlushThrottle      time.Duration
 	PingTimeout        time.Duration
 	MaxPendingPings    uint
+	ChannelSchedules   map[common.ChannelIDEnum]ChannelSchedule
+
+	// Messages no smaller than the threshold are compressed if the compression is negotiated
+	CompressionThreshold int
 }
 
 // MessageParser parses the raw message bytes to type p2ptypes.Message
@@ -92,14 +101,19 @@ type ErrorHandler func(interface{})
 
 // CreateConnection creates a Connection instance
 func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
-	channelCheckpoint := createDefaultChannel(common.ChannelIDCheckpoint)
-	channelHeader := createDefaultChannel(common.ChannelIDHeader)
-	channelBlock := createDefaultChannel(common.ChannelIDBlock)
-	channelProposal := createDefaultChannel(common.ChannelIDProposal)
-	channelVote := createDefaultChannel(common.ChannelIDVote)
-	channelTransaction := createDefaultChannel(common.ChannelIDTransaction)
-	channelPeerDiscover := createDefaultChannel(common.ChannelIDPeerDiscovery)
-	channelPing := createDefaultChannel(common.ChannelIDPing)
+	schedules := config.ChannelSchedules
+	if schedules == nil {
+		schedules = GetDefaultChannelSchedules()
+	}
+	channelCheckpoint := createScheduledChannel(common.ChannelIDCheckpoint, schedules[common.ChannelIDCheckpoint])
+	channelHeader := createScheduledChannel(common.ChannelIDHeader, schedules[common.ChannelIDHeader])
+	channelBlock := createScheduledChannel(common.ChannelIDBlock, schedules[common.ChannelIDBlock])
+	channelProposal := createScheduledChannel(common.ChannelIDProposal, schedules[common.ChannelIDProposal])
+	channelVote := createScheduledChannel(common.ChannelIDVote, schedules[common.ChannelIDVote])
+	channelTransaction := createScheduledChannel(common.ChannelIDTransaction, schedules[common.ChannelIDTransaction])
+	channelPeerDiscover := createScheduledChannel(common.ChannelIDPeerDiscovery, schedules[common.ChannelIDPeerDiscovery])
+	channelPing := createScheduledChannel(common.ChannelIDPing, schedules[common.ChannelIDPing])
+	channelDHT := createScheduledChannel(common.ChannelIDDHT, schedules[common.ChannelIDDHT])
 	channels := []*Channel{
 		&channelCheckpoint,
 		&channelHeader,
@@ -109,9 +123,10 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 		&channelTransaction,
 		&channelPeerDiscover,
 		&channelPing,
+		&channelDHT,
 	}
 
-	success, channelGroup := createChannelGroup(getDefaultChannelGroupConfig(), channels)
+	success, channelGroup := createChannelGroup(getPriorityChannelGroupConfig(), channels)
 	if !success {
 		return nil
 	}
@@ -144,6 +159,9 @@ func GetDefaultConnectionConfig() ConnectionConfig {
 		FlushThrottle:   100 * time.Millisecond,
 		PingTimeout:     40 * time.Second,
 		MaxPendingPings: 3,
+
+		ChannelSchedules:     GetDefaultChannelSchedules(),
+		CompressionThreshold: 1024, // 1KB
 	}
 }
 
@@ -218,6 +236,7 @@ func (conn *Connection) EnqueueMessage(channelID common.ChannelIDEnum, message i
 		logger.Errorf("Failed to encode message to bytes: %v, err: %v", message, err)
 		return false
 	}
+	msgBytes = conn.compressMessage(msgBytes)
 	success := channel.enqueueMessage(msgBytes)
 	if success {
 		conn.scheduleSendPulse()
@@ -240,6 +259,7 @@ func (conn *Connection) AttemptToEnqueueMessage(channelID common.ChannelIDEnum,
 		logger.Errorf("Failed to encode message to bytes: %v, error: %v", message, err)
 		return false
 	}
+	msgBytes = conn.compressMessage(msgBytes)
 	success := channel.attemptToEnqueueMessage(msgBytes)
 	if success {
 		conn.scheduleSendPulse()
@@ -308,6 +328,7 @@ func (conn *Connection) sendPingSignal() error {
 	conn.sendMonitor.Update(int(1))
 	conn.flush()
 	conn.pendingPings++
+	atomic.StoreInt64(&conn.pingSentAt, time.Now().UnixNano())
 	return nil
 }
 
@@ -384,7 +405,9 @@ func (conn *Connection) handlePingPong(packet *Packet) (success bool) {
 	case p2ptypes.PingSignal:
 		conn.schedulePongPulse()
 	case p2ptypes.PongSignal:
-		// do nothing for now
+		if sentAt := atomic.LoadInt64(&conn.pingSentAt); sentAt > 0 {
+			atomic.StoreInt64(&conn.pingLatency, time.Now().UnixNano()-sentAt)
+		}
 	default:
 		logger.Errorf("Invalid Ping/Pong signal")
 		return false
@@ -409,6 +432,12 @@ func (conn *Connection) handleReceivedPacket(packet *Packet) (success bool) {
 		return true
 	}
 
+	aggregatedBytes, err := conn.decompressMessage(aggregatedBytes)
+	if err != nil {
+		logger.Errorf("Error decompressing message on channel %v, err: %v", packet.ChannelID, err)
+		return false
+	}
+
 	message, err := conn.onParse(packet.ChannelID, aggregatedBytes)
 	if err != nil {
 		logger.Errorf("Error parsing packet: %v, err: %v", packet, err)
@@ -482,6 +511,42 @@ func (conn *Connection) GetNetconn() net.Conn {
 	return conn.netconn
 }
 
+// GetPingLatency returns the round trip time of the last ping, 0 if no pong is received yet
+func (conn *Connection) GetPingLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&conn.pingLatency))
+}
+
+// GetChannelStats returns the traffic statistics of each channel
+func (conn *Connection) GetChannelStats() []p2ptypes.ChannelStats {
+	stats := []p2ptypes.ChannelStats{}
+	channels := *conn.channelGroup.getAllChannels()
+	totalRecentlySent := int64(0)
+	for _, channel := range channels {
+		totalRecentlySent += channel.getRecentlySent()
+	}
+	for _, channel := range channels {
+		sendStatus := channel.sendMonitor.Status()
+		recvStatus := channel.recvMonitor.Status()
+		sendShare := 0.0
+		if totalRecentlySent > 0 {
+			sendShare = float64(channel.getRecentlySent()) / float64(totalRecentlySent)
+		}
+		stats = append(stats, p2ptypes.ChannelStats{
+			ChannelID:      channel.getID(),
+			Name:           ChannelName(channel.getID()),
+			Priority:       channel.config.priority,
+			MinShare:       channel.config.minShare,
+			SendShare:      sendShare,
+			QueuedMessages: channel.sendBuf.getSize(),
+			BytesSent:      sendStatus.Bytes,
+			BytesReceived:  recvStatus.Bytes,
+			SendRate:       sendStatus.CurRate,
+			RecvRate:       recvStatus.CurRate,
+		})
+	}
+	return stats
+}
+
 func (conn *Connection) stopForError(r interface{}) {
 	logger.Errorf("Connection error: %v", r)
 	if atomic.CompareAndSwapUint32(&conn.errored, 0, 1) {
@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"context"
+	"time"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/p2p/types"
@@ -50,4 +51,51 @@ type Network interface {
 
 	// ID returns the ID of the network peer
 	ID() string
+
+	// Peers returns the IDs of the connected peers
+	Peers() []string
+
+	// ReportPeer reports a misbehavior of the peer, which lowers the score of the peer
+	ReportPeer(peerID string, misbehavior types.Misbehavior)
+
+	// BanPeer disconnects the peer and refuses its connections for the given duration
+	BanPeer(peerID string, duration time.Duration, reason string) error
+
+	// UnbanPeer lifts the ban of the peer, returns false if the peer is not banned
+	UnbanPeer(peerID string) bool
+
+	// PeerStates returns the states of the connected peers
+	PeerStates() []types.PeerState
+
+	// BannedPeers returns the currently banned peers
+	BannedPeers() []types.BannedPeer
+
+	// NetInfo returns the network state of the node
+	NetInfo() types.NetInfo
+}
+
+//
+// PeerManager is implemented by the networks which support managing the peers at runtime
+//
+type PeerManager interface {
+	// AddPeer dials the peer at the given address, a persistent peer is also dialed on restarts
+	AddPeer(address string, persistent bool) error
+
+	// DisconnectPeer disconnects the peer and stops reconnecting to it, returns false if the peer is not connected
+	DisconnectPeer(peerID string) bool
+
+	// SetPrivatePeer marks or unmarks the peer as private, private peers are never gossiped to other peers
+	SetPrivatePeer(peerID string, private bool)
+
+	// UpdateAllowlist adds the peer ID or IP to, or removes it from the allowlist
+	UpdateAllowlist(entry string, add bool)
+
+	// UpdateDenylist adds the peer ID or IP to, or removes it from the denylist
+	UpdateDenylist(entry string, add bool)
+
+	// PeerLists returns the peer lists managed at runtime
+	PeerLists() types.PeerLists
+
+	// LookupPeer finds the address of the node with the given ID, e.g. a validator
+	LookupPeer(peerID string) (string, error)
 }
@@ -11,10 +11,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/rand"
 	"net"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,6 +24,7 @@ import (
 	mm "github.com/thetatoken/theta/common/math"
 	"github.com/thetatoken/theta/crypto"
 	nu "github.com/thetatoken/theta/p2p/netutil"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
 )
 
 const (
@@ -92,6 +95,11 @@ type AddrBook struct {
 	addrLookup        map[string]*knownAddress // new & old
 	addrNew           []map[string]*knownAddress
 	addrOld           []map[string]*knownAddress
+	bans              map[string]*p2ptypes.BannedPeer // peer ID -> ban
+	persistentAddrs   map[string]bool                 // addresses to dial on start and to reconnect on errors
+	privatePeers      map[string]bool                 // IDs of the peers never gossiped to other peers
+	allowlist         map[string]bool                 // peer IDs or IPs, if not empty only these peers are accepted
+	denylist          map[string]bool                 // peer IDs or IPs always refused
 	wg                sync.WaitGroup
 	nOld              int
 	nNew              int
@@ -104,10 +112,16 @@ func NewAddrBook(filePath string, routabilityStrict bool) *AddrBook {
 		rand:              rand.New(rand.NewSource(time.Now().UnixNano())),
 		ourAddrs:          make(map[string]*nu.NetAddress),
 		addrLookup:        make(map[string]*knownAddress),
+		bans:              make(map[string]*p2ptypes.BannedPeer),
+		persistentAddrs:   make(map[string]bool),
+		privatePeers:      make(map[string]bool),
+		allowlist:         make(map[string]bool),
+		denylist:          make(map[string]bool),
 		filePath:          filePath,
 		routabilityStrict: routabilityStrict,
 	}
 	am.init()
+	am.loadPeerListsFromFile(filePath)
 	return am
 }
 
@@ -275,6 +289,185 @@ func (a *AddrBook) RemoveAddress(addr *nu.NetAddress) {
 	a.removeFromAllBuckets(ka)
 }
 
+/* Bans */
+
+// BanPeer bans the peer with the given ID and IP until the given time.
+func (a *AddrBook) BanPeer(id string, ip string, until time.Time, reason string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	logger.Infof("Ban peer, ID: %v, IP: %v, until: %v, reason: %v", id, ip, until, reason)
+	a.bans[id] = &p2ptypes.BannedPeer{
+		ID:     id,
+		IP:     ip,
+		Until:  until,
+		Reason: reason,
+	}
+}
+
+// UnbanPeer lifts the ban of the peer. Returns false if the peer is not banned.
+func (a *AddrBook) UnbanPeer(id string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.removeExpiredBans(time.Now())
+	if _, ok := a.bans[id]; !ok {
+		return false
+	}
+	logger.Infof("Unban peer, ID: %v", id)
+	delete(a.bans, id)
+	return true
+}
+
+// IsBanned checks whether the peer ID or the IP is currently banned. An empty
+// ID or IP is not checked.
+func (a *AddrBook) IsBanned(id string, ip string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.removeExpiredBans(time.Now())
+	if _, ok := a.bans[id]; ok && id != "" {
+		return true
+	}
+	if ip == "" {
+		return false
+	}
+	for _, ban := range a.bans {
+		if ban.IP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBans returns the currently banned peers.
+func (a *AddrBook) GetBans() []p2ptypes.BannedPeer {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.removeExpiredBans(time.Now())
+	bans := []p2ptypes.BannedPeer{}
+	for _, ban := range a.bans {
+		bans = append(bans, *ban)
+	}
+	return bans
+}
+
+func (a *AddrBook) removeExpiredBans(now time.Time) {
+	for id, ban := range a.bans {
+		if !now.Before(ban.Until) {
+			delete(a.bans, id)
+		}
+	}
+}
+
+/* Peer lists */
+
+// AddPersistentAddress adds an address to dial on start and to reconnect on errors.
+func (a *AddrBook) AddPersistentAddress(addr string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.persistentAddrs[addr] = true
+}
+
+// RemovePersistentAddress removes a persistent address. Returns false if the address is not persistent.
+func (a *AddrBook) RemovePersistentAddress(addr string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if !a.persistentAddrs[addr] {
+		return false
+	}
+	delete(a.persistentAddrs, addr)
+	return true
+}
+
+// GetPersistentAddresses returns the persistent addresses.
+func (a *AddrBook) GetPersistentAddresses() []string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return sortedKeys(a.persistentAddrs)
+}
+
+// SetPrivatePeer marks or unmarks the peer as private. Private peers are never gossiped to other peers.
+func (a *AddrBook) SetPrivatePeer(id string, private bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if private {
+		a.privatePeers[id] = true
+	} else {
+		delete(a.privatePeers, id)
+	}
+}
+
+// IsPrivatePeer checks whether the peer is private.
+func (a *AddrBook) IsPrivatePeer(id string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.privatePeers[id]
+}
+
+// GetPrivatePeers returns the IDs of the private peers.
+func (a *AddrBook) GetPrivatePeers() []string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return sortedKeys(a.privatePeers)
+}
+
+// UpdateAllowlist adds the peer ID or IP to, or removes it from the allowlist.
+func (a *AddrBook) UpdateAllowlist(entry string, add bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	updateList(a.allowlist, entry, add)
+}
+
+// UpdateDenylist adds the peer ID or IP to, or removes it from the denylist.
+func (a *AddrBook) UpdateDenylist(entry string, add bool) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	updateList(a.denylist, entry, add)
+}
+
+// GetAllowlist returns the entries of the allowlist.
+func (a *AddrBook) GetAllowlist() []string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return sortedKeys(a.allowlist)
+}
+
+// GetDenylist returns the entries of the denylist.
+func (a *AddrBook) GetDenylist() []string {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return sortedKeys(a.denylist)
+}
+
+// IsAllowed checks the peer ID and the IP against the denylist and the allowlist.
+// An empty allowlist allows all the peers not in the denylist.
+func (a *AddrBook) IsAllowed(id string, ip string) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.denylist[id] || a.denylist[ip] {
+		return false
+	}
+	if len(a.allowlist) == 0 {
+		return true
+	}
+	return a.allowlist[id] || a.allowlist[ip]
+}
+
+func updateList(list map[string]bool, entry string, add bool) {
+	if add {
+		list[entry] = true
+	} else {
+		delete(list, entry)
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 /* Peer exchange */
 
 // GetSelection randomly selects some addresses (old & new). Suitable for peer-exchange protocols.
@@ -315,6 +508,12 @@ func (a *AddrBook) GetSelection() []*nu.NetAddress {
 type addrBookJSON struct {
 	Key   string
 	Addrs []*knownAddress
+	Bans  []*p2ptypes.BannedPeer
+
+	PersistentAddrs []string
+	PrivatePeers    []string
+	Allowlist       []string
+	Denylist        []string
 }
 
 func (a *AddrBook) saveToFile(filePath string) {
@@ -328,9 +527,22 @@ func (a *AddrBook) saveToFile(filePath string) {
 		addrs = append(addrs, ka)
 	}
 
+	// Compile Bans
+	a.removeExpiredBans(time.Now())
+	bans := []*p2ptypes.BannedPeer{}
+	for _, ban := range a.bans {
+		bans = append(bans, ban)
+	}
+
 	aJSON := &addrBookJSON{
 		Key:   a.key,
 		Addrs: addrs,
+		Bans:  bans,
+
+		PersistentAddrs: sortedKeys(a.persistentAddrs),
+		PrivatePeers:    sortedKeys(a.privatePeers),
+		Allowlist:       sortedKeys(a.allowlist),
+		Denylist:        sortedKeys(a.denylist),
 	}
 
 	jsonBytes, err := json.MarshalIndent(aJSON, "", "\t")
@@ -382,9 +594,44 @@ func (a *AddrBook) loadFromFile(filePath string) bool {
 			a.nOld++
 		}
 	}
+	a.restorePeerLists(aJSON)
 	return true
 }
 
+// loadPeerListsFromFile restores the bans and the peer lists persisted in the
+// address book file, so that they survive restarts.
+func (a *AddrBook) loadPeerListsFromFile(filePath string) {
+	jsonBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	aJSON := &addrBookJSON{}
+	if err := json.Unmarshal(jsonBytes, aJSON); err != nil {
+		logger.Warnf("Failed to load peer lists from file %s: %v", filePath, err)
+		return
+	}
+	a.restorePeerLists(aJSON)
+}
+
+func (a *AddrBook) restorePeerLists(aJSON *addrBookJSON) {
+	for _, ban := range aJSON.Bans {
+		a.bans[ban.ID] = ban
+	}
+	a.removeExpiredBans(time.Now())
+	for _, addr := range aJSON.PersistentAddrs {
+		a.persistentAddrs[addr] = true
+	}
+	for _, id := range aJSON.PrivatePeers {
+		a.privatePeers[id] = true
+	}
+	for _, entry := range aJSON.Allowlist {
+		a.allowlist[entry] = true
+	}
+	for _, entry := range aJSON.Denylist {
+		a.denylist[entry] = true
+	}
+}
+
 // Save saves the book.
 func (a *AddrBook) Save() {
 	logger.Infof("Saving AddrBook to file, size: %v", a.Size())
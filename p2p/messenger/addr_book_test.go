@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thetatoken/theta/p2p/netutil"
@@ -167,3 +168,61 @@ func TestAddrBookRemoveAddress(t *testing.T) {
 	book.RemoveAddress(nonExistingAddr)
 	assert.Equal(t, 0, book.Size())
 }
+
+func TestAddrBookBans(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+
+	book := NewAddrBook(fname, true)
+	book.BanPeer("peer1", "10.0.0.1", time.Now().Add(time.Hour), "invalid block")
+	book.BanPeer("peer2", "10.0.0.2", time.Now().Add(-time.Second), "expired")
+
+	assert.True(book.IsBanned("peer1", ""))
+	assert.True(book.IsBanned("", "10.0.0.1"))
+	assert.True(book.IsBanned("peer3", "10.0.0.1"))
+	assert.False(book.IsBanned("peer2", "10.0.0.2"))
+	assert.Equal(1, len(book.GetBans()))
+
+	// Bans survive restarts
+	book.saveToFile(fname)
+	book = NewAddrBook(fname, true)
+	bans := book.GetBans()
+	assert.Equal(1, len(bans))
+	assert.Equal("peer1", bans[0].ID)
+	assert.Equal("invalid block", bans[0].Reason)
+
+	assert.True(book.UnbanPeer("peer1"))
+	assert.False(book.UnbanPeer("peer1"))
+	assert.False(book.IsBanned("peer1", "10.0.0.1"))
+}
+
+func TestAddrBookPeerLists(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+
+	book := NewAddrBook(fname, true)
+	book.AddPersistentAddress("10.0.0.1:50001")
+	book.SetPrivatePeer("peer1", true)
+	book.UpdateDenylist("10.0.0.2", true)
+
+	// An empty allowlist allows everyone except the denied peers
+	assert.True(book.IsAllowed("peer1", "10.0.0.1"))
+	assert.False(book.IsAllowed("peer2", "10.0.0.2"))
+
+	book.UpdateAllowlist("peer1", true)
+	assert.True(book.IsAllowed("peer1", "10.0.0.1"))
+	assert.False(book.IsAllowed("peer3", "10.0.0.3"))
+
+	// Peer lists survive restarts
+	book.saveToFile(fname)
+	book = NewAddrBook(fname, true)
+	assert.Equal([]string{"10.0.0.1:50001"}, book.GetPersistentAddresses())
+	assert.True(book.IsPrivatePeer("peer1"))
+	assert.Equal([]string{"peer1"}, book.GetAllowlist())
+	assert.Equal([]string{"10.0.0.2"}, book.GetDenylist())
+
+	assert.True(book.RemovePersistentAddress("10.0.0.1:50001"))
+	assert.False(book.RemovePersistentAddress("10.0.0.1:50001"))
+	book.SetPrivatePeer("peer1", false)
+	assert.False(book.IsPrivatePeer("peer1"))
+}
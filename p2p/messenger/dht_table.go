@@ -0,0 +1,270 @@
+package messenger
+
+import (
+	"math/bits"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+)
+
+const (
+	// dhtBucketSize is the max number of nodes in a bucket, i.e. the k in Kademlia
+	dhtBucketSize = 16
+	// dhtNumBuckets is the number of buckets, one for each bit of the node ID
+	dhtNumBuckets = common.AddressLength * 8
+	// dhtMaxReplacements is the max number of replacement candidates kept for a full bucket
+	dhtMaxReplacements = 8
+	// dhtBucketSubnetLimit is the max number of nodes in a bucket from the same subnet
+	dhtBucketSubnetLimit = 2
+	// dhtTableSubnetLimit is the max number of nodes in the table from the same subnet
+	dhtTableSubnetLimit = 10
+)
+
+//
+// dhtNode is an entry of the DHT routing table. The nodes are keyed by their
+// IDs, which are the addresses of their public keys.
+//
+type dhtNode struct {
+	ID       common.Address
+	Addr     *netutil.NetAddress
+	lastSeen time.Time
+}
+
+type dhtBucket struct {
+	entries      []*dhtNode // least recently seen first
+	replacements []*dhtNode
+}
+
+//
+// dhtTable is a Kademlia routing table. To resist eclipse attacks, only the
+// nodes whose IDs have been verified through the handshake are added, the long
+// lived entries are never evicted by the new ones, and the number of nodes from
+// the same subnet is limited.
+//
+type dhtTable struct {
+	mu      *sync.Mutex
+	self    common.Address
+	buckets [dhtNumBuckets]*dhtBucket
+	subnets map[string]int
+}
+
+func newDHTTable(self common.Address) *dhtTable {
+	table := &dhtTable{
+		mu:      &sync.Mutex{},
+		self:    self,
+		subnets: make(map[string]int),
+	}
+	for i := range table.buckets {
+		table.buckets[i] = &dhtBucket{}
+	}
+	return table
+}
+
+// dhtLogDistance returns the logarithmic XOR distance between the two IDs, -1 if they are equal
+func dhtLogDistance(a, b common.Address) int {
+	for i := 0; i < common.AddressLength; i++ {
+		if x := a[i] ^ b[i]; x != 0 {
+			return (common.AddressLength-i)*8 - bits.LeadingZeros8(x) - 1
+		}
+	}
+	return -1
+}
+
+// dhtCloser returns whether a is closer to the target than b
+func dhtCloser(target, a, b common.Address) bool {
+	for i := 0; i < common.AddressLength; i++ {
+		da := a[i] ^ target[i]
+		db := b[i] ^ target[i]
+		if da != db {
+			return da < db
+		}
+	}
+	return false
+}
+
+// dhtSubnet returns the subnet of the address for the subnet limits. The non-routable
+// addresses are exempted from the limits.
+func dhtSubnet(addr *netutil.NetAddress) string {
+	if addr == nil || !addr.Routable() {
+		return ""
+	}
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return addr.IP.Mask(net.CIDRMask(48, 128)).String()
+}
+
+func (t *dhtTable) bucket(id common.Address) *dhtBucket {
+	dist := dhtLogDistance(t.self, id)
+	if dist < 0 {
+		return nil
+	}
+	return t.buckets[dist]
+}
+
+// add adds the verified node to the table, or refreshes it if it exists. If the
+// bucket is full, the node becomes a replacement candidate.
+func (t *dhtTable) add(id common.Address, addr *netutil.NetAddress, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucket(id)
+	if b == nil || addr == nil {
+		return false
+	}
+
+	for i, n := range b.entries {
+		if n.ID == id {
+			n.lastSeen = now
+			if !n.Addr.Equals(addr) {
+				t.releaseSubnet(n.Addr)
+				n.Addr = addr
+				t.holdSubnet(addr)
+			}
+			b.entries = append(append(b.entries[:i], b.entries[i+1:]...), n) // move to the back
+			return true
+		}
+	}
+
+	if !t.subnetAllowed(b, addr) {
+		return false
+	}
+
+	node := &dhtNode{ID: id, Addr: addr, lastSeen: now}
+	if len(b.entries) < dhtBucketSize {
+		b.entries = append(b.entries, node)
+		t.holdSubnet(addr)
+		return true
+	}
+
+	for i, n := range b.replacements {
+		if n.ID == id {
+			b.replacements = append(b.replacements[:i], b.replacements[i+1:]...)
+			break
+		}
+	}
+	b.replacements = append(b.replacements, node)
+	if len(b.replacements) > dhtMaxReplacements {
+		b.replacements = b.replacements[1:]
+	}
+	return false
+}
+
+// remove removes the node which can no longer be reached, and promotes the most
+// recently seen replacement candidate
+func (t *dhtTable) remove(id common.Address) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucket(id)
+	if b == nil {
+		return
+	}
+	for i, n := range b.entries {
+		if n.ID != id {
+			continue
+		}
+		b.entries = append(b.entries[:i], b.entries[i+1:]...)
+		t.releaseSubnet(n.Addr)
+		for len(b.replacements) > 0 {
+			last := len(b.replacements) - 1
+			r := b.replacements[last]
+			b.replacements = b.replacements[:last]
+			if t.subnetAllowed(b, r.Addr) {
+				b.entries = append(b.entries, r)
+				t.holdSubnet(r.Addr)
+				break
+			}
+		}
+		return
+	}
+	for i, n := range b.replacements {
+		if n.ID == id {
+			b.replacements = append(b.replacements[:i], b.replacements[i+1:]...)
+			return
+		}
+	}
+}
+
+// find returns the node with the given ID, nil if not found
+func (t *dhtTable) find(id common.Address) *dhtNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucket(id)
+	if b == nil {
+		return nil
+	}
+	for _, n := range b.entries {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+// closest returns at most n nodes closest to the target
+func (t *dhtTable) closest(target common.Address, n int) []*dhtNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes := []*dhtNode{}
+	for _, b := range t.buckets {
+		nodes = append(nodes, b.entries...)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return dhtCloser(target, nodes[i].ID, nodes[j].ID)
+	})
+	if len(nodes) > n {
+		nodes = nodes[:n]
+	}
+	return nodes
+}
+
+// size returns the number of nodes in the table
+func (t *dhtTable) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	size := 0
+	for _, b := range t.buckets {
+		size += len(b.entries)
+	}
+	return size
+}
+
+func (t *dhtTable) subnetAllowed(b *dhtBucket, addr *netutil.NetAddress) bool {
+	subnet := dhtSubnet(addr)
+	if subnet == "" {
+		return true
+	}
+	if t.subnets[subnet] >= dhtTableSubnetLimit {
+		return false
+	}
+	count := 0
+	for _, n := range b.entries {
+		if dhtSubnet(n.Addr) == subnet {
+			count++
+		}
+	}
+	return count < dhtBucketSubnetLimit
+}
+
+func (t *dhtTable) holdSubnet(addr *netutil.NetAddress) {
+	if subnet := dhtSubnet(addr); subnet != "" {
+		t.subnets[subnet]++
+	}
+}
+
+func (t *dhtTable) releaseSubnet(addr *netutil.NetAddress) {
+	if subnet := dhtSubnet(addr); subnet != "" {
+		t.subnets[subnet]--
+		if t.subnets[subnet] <= 0 {
+			delete(t.subnets, subnet)
+		}
+	}
+}
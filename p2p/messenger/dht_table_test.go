@@ -0,0 +1,131 @@
+package messenger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+)
+
+func TestDHTLogDistance(t *testing.T) {
+	assert := assert.New(t)
+
+	a := common.HexToAddress("0x0000000000000000000000000000000000000000")
+	assert.Equal(-1, dhtLogDistance(a, a))
+	assert.Equal(0, dhtLogDistance(a, common.HexToAddress("0x0000000000000000000000000000000000000001")))
+	assert.Equal(7, dhtLogDistance(a, common.HexToAddress("0x0000000000000000000000000000000000000080")))
+	assert.Equal(8, dhtLogDistance(a, common.HexToAddress("0x0000000000000000000000000000000000000100")))
+	assert.Equal(159, dhtLogDistance(a, common.HexToAddress("0x8000000000000000000000000000000000000000")))
+
+	target := common.HexToAddress("0x0000000000000000000000000000000000000010")
+	assert.True(dhtCloser(target, common.HexToAddress("0x0000000000000000000000000000000000000011"), common.HexToAddress("0x0000000000000000000000000000000000000001")))
+	assert.False(dhtCloser(target, target, target))
+}
+
+func TestDHTTableClosest(t *testing.T) {
+	assert := assert.New(t)
+
+	table := newDHTTable(common.Address{})
+	now := time.Now()
+	for i := 1; i <= 20; i++ {
+		id := common.Address{}
+		id[common.AddressLength-1] = byte(i)
+		assert.True(table.add(id, newTestDHTAddress(i), now))
+	}
+	assert.Equal(20, table.size())
+	assert.False(table.add(common.Address{}, newTestDHTAddress(0), now)) // never adds self
+
+	target := common.Address{}
+	target[common.AddressLength-1] = 0x05
+	nodes := table.closest(target, 3)
+	assert.Equal(3, len(nodes))
+	assert.Equal(byte(0x05), nodes[0].ID[common.AddressLength-1])
+	assert.Equal(byte(0x04), nodes[1].ID[common.AddressLength-1])
+	assert.Equal(byte(0x07), nodes[2].ID[common.AddressLength-1])
+
+	assert.NotNil(table.find(target))
+	table.remove(target)
+	assert.Nil(table.find(target))
+	assert.Equal(19, table.size())
+}
+
+func TestDHTTableBucketReplacements(t *testing.T) {
+	assert := assert.New(t)
+
+	table := newDHTTable(common.Address{})
+	now := time.Now()
+	ids := []common.Address{}
+	for i := 0; i < dhtBucketSize+2; i++ {
+		id := common.Address{}
+		id[0] = 0x80 // all nodes fall into the farthest bucket
+		id[common.AddressLength-1] = byte(i)
+		ids = append(ids, id)
+		added := table.add(id, newTestDHTAddress(i), now)
+		assert.Equal(i < dhtBucketSize, added)
+	}
+	assert.Equal(dhtBucketSize, table.size())
+	assert.Nil(table.find(ids[dhtBucketSize]))
+
+	// Re-adding an existing node refreshes it instead of evicting it
+	assert.True(table.add(ids[0], newTestDHTAddress(0), now.Add(time.Minute)))
+	assert.Equal(dhtBucketSize, table.size())
+
+	// The most recently seen replacement is promoted when a node is removed
+	table.remove(ids[1])
+	assert.Equal(dhtBucketSize, table.size())
+	assert.Nil(table.find(ids[1]))
+	assert.NotNil(table.find(ids[dhtBucketSize+1]))
+}
+
+func TestDHTTableSubnetLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	table := newDHTTable(common.Address{})
+	now := time.Now()
+	addr := func(host int) *netutil.NetAddress {
+		netAddr, err := netutil.NewNetAddressString(fmt.Sprintf("52.10.20.%v:50001", host))
+		assert.Nil(err)
+		return netAddr
+	}
+
+	id := func(b0, b19 byte) common.Address {
+		id := common.Address{}
+		id[0] = b0
+		id[common.AddressLength-1] = b19
+		return id
+	}
+
+	// At most dhtBucketSubnetLimit nodes from the same subnet in a bucket
+	assert.True(table.add(id(0x80, 1), addr(1), now))
+	assert.True(table.add(id(0x80, 2), addr(2), now))
+	assert.False(table.add(id(0x80, 3), addr(3), now))
+
+	// At most dhtTableSubnetLimit nodes from the same subnet in the table
+	added := 2
+	for b0 := byte(0x40); b0 > 0 && added < dhtTableSubnetLimit+2; b0 >>= 1 {
+		if table.add(id(b0, 1), addr(int(b0)), now) {
+			added++
+		}
+		if table.add(id(b0, 2), addr(int(b0)+1), now) {
+			added++
+		}
+	}
+	assert.Equal(dhtTableSubnetLimit, added)
+	assert.Equal(dhtTableSubnetLimit, table.size())
+
+	// The non-routable addresses are exempted from the limits
+	assert.True(table.add(id(0x80, 4), newTestDHTAddress(4), now))
+
+	// Removing a node releases its subnet
+	table.remove(id(0x80, 1))
+	assert.True(table.add(id(0x80, 5), addr(5), now))
+}
+
+func newTestDHTAddress(i int) *netutil.NetAddress {
+	netAddr, _ := netutil.NewNetAddressString(fmt.Sprintf("127.0.0.1:%v", 50000+i))
+	return netAddr
+}
@@ -0,0 +1,421 @@
+package messenger
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+	pr "github.com/thetatoken/theta/p2p/peer"
+	"github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const (
+	// DiscoveryModeAddrBook discovers peers by exchanging random addresses with the connected peers
+	DiscoveryModeAddrBook = "addrbook"
+	// DiscoveryModeDHT discovers peers by the Kademlia style lookups
+	DiscoveryModeDHT = "dht"
+)
+
+// DHTMessageType defines the types of the DHT message
+type DHTMessageType byte
+
+const (
+	dhtFindNodeRequestType DHTMessageType = 0x01
+	dhtFindNodeReplyType   DHTMessageType = 0x02
+)
+
+const (
+	dhtRefreshInterval   = 30 * time.Second
+	dhtRequestTimeout    = 5 * time.Second
+	dhtLookupConcurrency = 3 // number of nodes queried in parallel in each round, i.e. the alpha in Kademlia
+	dhtMaxLookupRounds   = 8
+)
+
+// DHTMessage defines the structure of the DHT message
+type DHTMessage struct {
+	Type      DHTMessageType
+	RequestID uint64
+	Target    common.Address
+	Nodes     []pr.PeerIDAddress
+}
+
+//
+// DHTDiscovery implements the MessageHandler interface. It maintains a
+// Kademlia routing table of the verified peers, and answers the lookups of the
+// other nodes. In the DHT discovery mode, it also drives the outbound
+// connectivity by looking up the nodes close to the node itself and to random
+// targets.
+//
+type DHTDiscovery struct {
+	discMgr *PeerDiscoveryManager
+	self    common.Address
+	table   *dhtTable
+	enabled bool // whether the DHT lookups drive the outbound connectivity
+
+	mu            *sync.Mutex
+	nextRequestID uint64
+	pending       map[uint64]chan []pr.PeerIDAddress
+
+	// Life cycle
+	wg      *sync.WaitGroup
+	quit    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// createDHTDiscovery creates an instance of DHTDiscovery
+func createDHTDiscovery(discMgr *PeerDiscoveryManager, self common.Address) (*DHTDiscovery, error) {
+	mode := viper.GetString(common.CfgP2PDiscoveryMode)
+	if mode != DiscoveryModeAddrBook && mode != DiscoveryModeDHT {
+		return nil, fmt.Errorf("Invalid discovery mode: %v", mode)
+	}
+
+	dht := &DHTDiscovery{
+		discMgr: discMgr,
+		self:    self,
+		table:   newDHTTable(self),
+		enabled: mode == DiscoveryModeDHT,
+		mu:      &sync.Mutex{},
+		pending: make(map[uint64]chan []pr.PeerIDAddress),
+		wg:      &sync.WaitGroup{},
+	}
+	return dht, nil
+}
+
+// Start is called when the DHTDiscovery starts
+func (dht *DHTDiscovery) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	dht.ctx = c
+	dht.cancel = cancel
+
+	if dht.enabled && !dht.discMgr.topology.isValidatorMode() {
+		dht.wg.Add(1)
+		go dht.refreshRoutine()
+	}
+	return nil
+}
+
+// Stop is called when the DHTDiscovery stops
+func (dht *DHTDiscovery) Stop() {
+	dht.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (dht *DHTDiscovery) Wait() {
+	dht.wg.Wait()
+}
+
+// GetChannelIDs implements the p2p.MessageHandler interface
+func (dht *DHTDiscovery) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{
+		common.ChannelIDDHT,
+	}
+}
+
+// EncodeMessage implements the p2p.MessageHandler interface
+func (dht *DHTDiscovery) EncodeMessage(message interface{}) (common.Bytes, error) {
+	return rlp.EncodeToBytes(message)
+}
+
+// ParseMessage implements the p2p.MessageHandler interface
+func (dht *DHTDiscovery) ParseMessage(peerID string,
+	channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
+	var dhtMsg DHTMessage
+	err := rlp.DecodeBytes(rawMessageBytes, &dhtMsg)
+	message := types.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+		Content:   dhtMsg,
+	}
+	if err != nil {
+		logger.Errorf("Error decoding DHTMessage: %v", err)
+		return message, err
+	}
+	return message, nil
+}
+
+// HandleMessage implements the p2p.MessageHandler interface
+func (dht *DHTDiscovery) HandleMessage(msg types.Message) error {
+	if msg.ChannelID != common.ChannelIDDHT {
+		errMsg := fmt.Sprintf("Invalid channelID for the DHTDiscovery: %v", msg.ChannelID)
+		logger.Errorf(errMsg)
+		return errors.New(errMsg)
+	}
+
+	peer := dht.discMgr.peerTable.GetPeer(msg.PeerID)
+	if peer == nil {
+		errMsg := fmt.Sprintf("Cannot find peer %v in the peer table", msg.PeerID)
+		logger.Errorf(errMsg)
+		return errors.New(errMsg)
+	}
+
+	dhtMsg := (msg.Content).(DHTMessage)
+	switch dhtMsg.Type {
+	case dhtFindNodeRequestType:
+		dht.handleFindNodeRequest(peer, dhtMsg)
+	case dhtFindNodeReplyType:
+		dht.handleFindNodeReply(peer, dhtMsg)
+	default:
+		errMsg := "Invalid DHTMessageType"
+		logger.Errorf(errMsg)
+		return errors.New(errMsg)
+	}
+	return nil
+}
+
+func (dht *DHTDiscovery) handleFindNodeRequest(peer *pr.Peer, message DHTMessage) {
+	nodes := []pr.PeerIDAddress{}
+	if !dht.discMgr.topology.isValidatorMode() { // a validator never reveals the addresses of its sentry nodes
+		for _, node := range dht.table.closest(message.Target, dhtBucketSize+1) {
+			id := node.ID.Hex()
+			if id == peer.ID() || !dht.isPublic(id) {
+				continue
+			}
+			nodes = append(nodes, pr.PeerIDAddress{ID: id, Addr: node.Addr})
+		}
+		if len(nodes) > dhtBucketSize {
+			nodes = nodes[:dhtBucketSize]
+		}
+	}
+
+	reply := DHTMessage{
+		Type:      dhtFindNodeReplyType,
+		RequestID: message.RequestID,
+		Target:    message.Target,
+		Nodes:     nodes,
+	}
+	peer.Send(common.ChannelIDDHT, reply)
+}
+
+func (dht *DHTDiscovery) handleFindNodeReply(peer *pr.Peer, message DHTMessage) {
+	dht.mu.Lock()
+	replyChan, ok := dht.pending[message.RequestID]
+	if ok {
+		delete(dht.pending, message.RequestID)
+	}
+	dht.mu.Unlock()
+
+	if !ok {
+		logger.Debugf("Unsolicited DHT reply from peer %v", peer.ID())
+		return
+	}
+	if len(message.Nodes) > dhtBucketSize {
+		message.Nodes = message.Nodes[:dhtBucketSize]
+	}
+	replyChan <- message.Nodes
+}
+
+// isPublic returns whether the peer can be revealed to the other nodes
+func (dht *DHTDiscovery) isPublic(peerID string) bool {
+	return !dht.discMgr.addrBook.IsPrivatePeer(peerID) && !dht.discMgr.topology.isPrivateValidator(peerID)
+}
+
+// addPeer adds the peer to the routing table. The ID of the peer has been
+// verified by the handshake.
+func (dht *DHTDiscovery) addPeer(peer *pr.Peer) {
+	if peer.NetAddress() == nil || !dht.isPublic(peer.ID()) {
+		return
+	}
+	dht.table.add(common.HexToAddress(peer.ID()), peer.NetAddress(), time.Now())
+}
+
+// findNode asks the peer for the nodes closest to the target
+func (dht *DHTDiscovery) findNode(peer *pr.Peer, target common.Address) ([]pr.PeerIDAddress, error) {
+	replyChan := make(chan []pr.PeerIDAddress, 1)
+	dht.mu.Lock()
+	dht.nextRequestID++
+	requestID := dht.nextRequestID
+	dht.pending[requestID] = replyChan
+	dht.mu.Unlock()
+
+	defer func() {
+		dht.mu.Lock()
+		delete(dht.pending, requestID)
+		dht.mu.Unlock()
+	}()
+
+	request := DHTMessage{
+		Type:      dhtFindNodeRequestType,
+		RequestID: requestID,
+		Target:    target,
+	}
+	if !peer.Send(common.ChannelIDDHT, request) {
+		return nil, fmt.Errorf("Failed to send the DHT request to peer %v", peer.ID())
+	}
+
+	select {
+	case nodes := <-replyChan:
+		return nodes, nil
+	case <-time.After(dhtRequestTimeout):
+		return nil, fmt.Errorf("DHT request to peer %v timed out", peer.ID())
+	}
+}
+
+// queryNode asks the node for the nodes closest to the target. If the node is
+// not connected yet, it is dialed for the query only, and disconnected once it
+// replies.
+func (dht *DHTDiscovery) queryNode(node pr.PeerIDAddress, target common.Address) []pr.PeerIDAddress {
+	peer := dht.discMgr.peerTable.GetPeer(node.ID)
+	if peer == nil {
+		maxNumPeers := GetDefaultPeerDiscoveryManagerConfig().MaxNumPeers
+		if node.Addr == nil || dht.discMgr.peerTable.GetTotalNumPeers() >= maxNumPeers {
+			return nil
+		}
+		var err error
+		peer, err = dht.discMgr.connectToOutboundPeer(node.Addr, false)
+		if err != nil {
+			logger.Debugf("Failed to connect to DHT node %v: %v", node.Addr, err)
+			dht.table.remove(common.HexToAddress(node.ID))
+			return nil
+		}
+		defer dht.disconnectLookupPeer(peer)
+	}
+
+	nodes, err := dht.findNode(peer, target)
+	if err != nil {
+		logger.Debugf("DHT lookup failed: %v", err)
+		return nil
+	}
+	return nodes
+}
+
+// disconnectLookupPeer disconnects a peer dialed only to query it
+func (dht *DHTDiscovery) disconnectLookupPeer(peer *pr.Peer) {
+	logger.Debugf("Disconnect DHT lookup peer: %v", peer.ID())
+	dht.discMgr.removePeer(peer.ID())
+	peer.Stop()
+}
+
+// lookup iteratively queries the nodes closer and closer to the target, and
+// returns the closest nodes found
+func (dht *DHTDiscovery) lookup(target common.Address) []pr.PeerIDAddress {
+	seen := map[string]bool{dht.self.Hex(): true}
+	queried := make(map[string]bool)
+	candidates := []pr.PeerIDAddress{}
+	addCandidates := func(nodes []pr.PeerIDAddress) {
+		for _, node := range nodes {
+			if seen[node.ID] || node.Addr == nil || !node.Addr.Valid() {
+				continue
+			}
+			seen[node.ID] = true
+			candidates = append(candidates, node)
+		}
+	}
+
+	for _, node := range dht.table.closest(target, dhtBucketSize) {
+		addCandidates([]pr.PeerIDAddress{{ID: node.ID.Hex(), Addr: node.Addr}})
+	}
+	if len(candidates) == 0 {
+		addCandidates(dht.discMgr.peerTable.GetSelection())
+	}
+
+	for round := 0; round < dhtMaxLookupRounds; round++ {
+		sort.Slice(candidates, func(i, j int) bool {
+			return dhtCloser(target, common.HexToAddress(candidates[i].ID), common.HexToAddress(candidates[j].ID))
+		})
+		if len(candidates) > dhtBucketSize {
+			candidates = candidates[:dhtBucketSize]
+		}
+
+		toQuery := []pr.PeerIDAddress{}
+		for _, node := range candidates {
+			if len(toQuery) >= dhtLookupConcurrency {
+				break
+			}
+			if !queried[node.ID] {
+				queried[node.ID] = true
+				toQuery = append(toQuery, node)
+			}
+		}
+		if len(toQuery) == 0 {
+			break
+		}
+
+		results := make(chan []pr.PeerIDAddress, len(toQuery))
+		for _, node := range toQuery {
+			go func(node pr.PeerIDAddress) {
+				results <- dht.queryNode(node, target)
+			}(node)
+		}
+		for range toQuery {
+			addCandidates(<-results)
+		}
+	}
+
+	return candidates
+}
+
+// FindPeer looks up the address of the node with the given ID, e.g. the address of a validator
+func (dht *DHTDiscovery) FindPeer(peerID string) (*netutil.NetAddress, error) {
+	if peer := dht.discMgr.peerTable.GetPeer(peerID); peer != nil && peer.NetAddress() != nil {
+		return peer.NetAddress(), nil
+	}
+
+	target := common.HexToAddress(peerID)
+	if node := dht.table.find(target); node != nil {
+		return node.Addr, nil
+	}
+	for _, node := range dht.lookup(target) {
+		if common.HexToAddress(node.ID) == target {
+			return node.Addr, nil
+		}
+	}
+	return nil, fmt.Errorf("Peer %v not found", peerID)
+}
+
+func (dht *DHTDiscovery) refreshRoutine() {
+	defer dht.wg.Done()
+
+	dht.refresh()
+	ticker := time.NewTicker(dhtRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-dht.ctx.Done():
+			dht.stopped = true
+			return
+		case <-ticker.C:
+			dht.refresh()
+		}
+	}
+}
+
+// refresh looks up the node itself and a random target to fill the routing
+// table, and connects to the nodes found if the node needs more peers
+func (dht *DHTDiscovery) refresh() {
+	if dht.discMgr.peerTable.GetTotalNumPeers() == 0 {
+		return // wait for the seed peers and the persistent peers
+	}
+
+	var randomTarget common.Address
+	rand.Read(randomTarget[:])
+
+	for _, target := range []common.Address{dht.self, randomTarget} {
+		nodes := dht.lookup(target)
+		numNeeded := int(GetDefaultPeerDiscoveryManagerConfig().SufficientNumPeers) - int(dht.discMgr.peerTable.GetTotalNumPeers())
+		for _, node := range nodes {
+			if numNeeded <= 0 {
+				break
+			}
+			if dht.discMgr.peerTable.PeerExists(node.ID) {
+				continue
+			}
+			if _, err := dht.discMgr.connectToOutboundPeer(node.Addr, false); err != nil {
+				dht.table.remove(common.HexToAddress(node.ID))
+				continue
+			}
+			numNeeded--
+		}
+	}
+	logger.Debugf("DHT refreshed, routing table size: %v", dht.table.size())
+}
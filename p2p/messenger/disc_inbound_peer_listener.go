@@ -26,6 +26,7 @@ type InboundPeerListener struct {
 	netListener  net.Listener
 	internalAddr *netutil.NetAddress
 	externalAddr *netutil.NetAddress
+	upnpMapped   bool // whether the external address is mapped through UPnP
 
 	inboundCallback InboundCallback
 
@@ -58,13 +59,14 @@ func createInboundPeerListener(discMgr *PeerDiscoveryManager, protocol string, l
 	logger.Infof("Local network listener, ip: %v, port: %v", netListenerIP, netListenerPort)
 
 	internalNetAddr := getInternalNetAddress(localAddr)
-	externalNetAddr := getExternalNetAddress(localAddrIP, localAddrPort, netListenerPort, skipUPNP)
+	externalNetAddr, upnpMapped := getExternalNetAddress(localAddrIP, localAddrPort, netListenerPort, skipUPNP)
 
 	inboundPeerListener := InboundPeerListener{
 		discMgr:      discMgr,
 		netListener:  netListener,
 		internalAddr: internalNetAddr,
 		externalAddr: externalNetAddr,
+		upnpMapped:   upnpMapped,
 		config:       config,
 		wg:           &sync.WaitGroup{},
 	}
@@ -133,6 +135,11 @@ func (ipl *InboundPeerListener) ExternalAddress() *netutil.NetAddress {
 	return ipl.externalAddr
 }
 
+// UPNPMapped returns whether the external address is mapped through UPnP
+func (ipl *InboundPeerListener) UPNPMapped() bool {
+	return ipl.upnpMapped
+}
+
 // NetListener returns the attached network listener
 func (ipl *InboundPeerListener) NetListener() net.Listener {
 	return ipl.netListener
@@ -179,12 +186,12 @@ func getInternalNetAddress(localAddr string) *netutil.NetAddress {
 	return internalAddr
 }
 
-func getExternalNetAddress(localAddrIP string, localAddrPort int, listenerPort int, skipUPNP bool) *netutil.NetAddress {
-	var externalAddr *netutil.NetAddress
+func getExternalNetAddress(localAddrIP string, localAddrPort int, listenerPort int, skipUPNP bool) (externalAddr *netutil.NetAddress, upnpMapped bool) {
 	if !skipUPNP {
 		// If the lAddrIP is INADDR_ANY, try UPNP
 		if localAddrIP == "" || localAddrIP == "0.0.0.0" {
 			externalAddr = getUPNPExternalAddress(localAddrPort, listenerPort)
+			upnpMapped = externalAddr != nil
 		}
 	}
 	// Otherwise just use the local address
@@ -195,7 +202,7 @@ func getExternalNetAddress(localAddrIP string, localAddrPort int, listenerPort i
 		panic(fmt.Sprintf("Could not determine external address!"))
 	}
 
-	return externalAddr
+	return externalAddr, upnpMapped
 }
 
 func getUPNPExternalAddress(externalPort, internalPort int) *netutil.NetAddress {
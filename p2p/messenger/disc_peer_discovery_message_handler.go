@@ -162,11 +162,25 @@ func (pdmh *PeerDiscoveryMessageHandler) HandleMessage(msg types.Message) error
 }
 
 func (pdmh *PeerDiscoveryMessageHandler) handlePeerAddressRequest(peer *pr.Peer, message PeerDiscoveryMessage) {
-	peerIDAddrs := pdmh.discMgr.peerTable.GetSelection()
+	peerIDAddrs := []pr.PeerIDAddress{}
+	if pdmh.discMgr.topology.isValidatorMode() {
+		pdmh.sendAddresses(peer, peerIDAddrs) // a validator never reveals the addresses of its sentry nodes
+		return
+	}
+	for _, peerIDAddr := range pdmh.discMgr.peerTable.GetSelection() {
+		if pdmh.discMgr.addrBook.IsPrivatePeer(peerIDAddr.ID) || pdmh.discMgr.topology.isPrivateValidator(peerIDAddr.ID) {
+			continue // never gossip the private peers
+		}
+		peerIDAddrs = append(peerIDAddrs, peerIDAddr)
+	}
 	pdmh.sendAddresses(peer, peerIDAddrs)
 }
 
 func (pdmh *PeerDiscoveryMessageHandler) handlePeerAddressReply(peer *pr.Peer, message PeerDiscoveryMessage) {
+	if pdmh.discMgr.topology.isValidatorMode() {
+		return // a validator only connects to its sentry nodes
+	}
+
 	validAddressMap := make(map[*netutil.NetAddress]bool)
 	for _, idAddr := range message.Addresses {
 		isNotASeedPeer := !pdmh.discMgr.seedPeerConnector.isASeedPeer(idAddr.Addr)
@@ -241,8 +255,19 @@ func (pdmh *PeerDiscoveryMessageHandler) maintainSufficientConnectivityRoutine()
 // of connections by dialing peers when the number of connected peers are lower than the
 // required threshold
 func (pdmh *PeerDiscoveryMessageHandler) maintainSufficientConnectivity() {
+	if pdmh.discMgr.topology.isValidatorMode() {
+		pdmh.discMgr.connectToGuardedPeers()
+		return
+	}
+	if pdmh.discMgr.topology.isSentryMode() {
+		pdmh.discMgr.connectToGuardedPeers()
+	}
+
 	numPeers := pdmh.discMgr.peerTable.GetTotalNumPeers()
 	if numPeers > 0 {
+		if pdmh.discMgr.dht.enabled {
+			return // the DHT lookups drive the outbound connectivity instead
+		}
 		if numPeers < GetDefaultPeerDiscoveryManagerConfig().SufficientNumPeers {
 			peers := *(pdmh.discMgr.peerTable.GetAllPeers())
 			numPeersToSendRequest := numPeers * requestPeersAddressesPercent / 100
@@ -256,8 +281,9 @@ func (pdmh *PeerDiscoveryMessageHandler) maintainSufficientConnectivity() {
 				pdmh.requestAddresses(peer)
 			}
 		}
-	} else { // no peer left in the peer table, try to reconnect to seed peers
+	} else { // no peer left in the peer table, try to reconnect to seed peers and persistent peers
 		pdmh.discMgr.seedPeerConnector.connectToSeedPeers()
+		pdmh.discMgr.connectToPersistentPeers()
 	}
 }
 
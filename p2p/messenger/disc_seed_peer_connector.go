@@ -90,6 +90,9 @@ func (spc *SeedPeerConnector) isASeedPeer(netAddr *netutil.NetAddress) bool {
 }
 
 func (spc *SeedPeerConnector) connectToSeedPeers() {
+	if spc.discMgr.topology.isValidatorMode() {
+		return // a validator only connects to its sentry nodes
+	}
 	logger.Infof("Connecting to seed peers...")
 	perm := rand.Perm(len(spc.seedPeerNetAddresses))
 	for i := 0; i < len(perm); i++ { // create outbound peers in a random order
@@ -3,10 +3,14 @@ package messenger
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
 	cn "github.com/thetatoken/theta/p2p/connection"
 	"github.com/thetatoken/theta/p2p/netutil"
 	pr "github.com/thetatoken/theta/p2p/peer"
@@ -22,11 +26,13 @@ type PeerDiscoveryManager struct {
 	addrBook  *AddrBook
 	peerTable *pr.PeerTable
 	nodeInfo  *p2ptypes.NodeInfo
+	topology  *sentryTopology
 
-	// Three mechanisms for peer discovery
+	// Mechanisms for peer discovery
 	seedPeerConnector   SeedPeerConnector           // pro-actively connect to seed peers
 	peerDiscMsgHandler  PeerDiscoveryMessageHandler // pro-actively connect to peer candidates obtained from connected peers
 	inboundPeerListener InboundPeerListener         // listen to incoming peering requests
+	dht                 *DHTDiscovery               // pro-actively connect to peers found by the DHT lookups, if enabled
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -60,6 +66,11 @@ func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, ad
 	discMgr.addrBook = NewAddrBook(addrBookFilePath, routabilityRestrict)
 
 	var err error
+	discMgr.topology, err = createSentryTopologyFromConfig()
+	if err != nil {
+		return discMgr, err
+	}
+
 	discMgr.seedPeerConnector, err = createSeedPeerConnector(discMgr, localNetworkAddr, seedPeerNetAddresses)
 	if err != nil {
 		return discMgr, err
@@ -70,8 +81,14 @@ func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, ad
 		return discMgr, err
 	}
 
+	discMgr.dht, err = createDHTDiscovery(discMgr, nodeInfo.PubKey.Address())
+	if err != nil {
+		return discMgr, err
+	}
+
 	inlConfig := GetDefaultInboundPeerListenerConfig()
-	discMgr.inboundPeerListener, err = createInboundPeerListener(discMgr, networkProtocol, localNetworkAddr, skipUPNP, inlConfig)
+	discMgr.inboundPeerListener, err = createInboundPeerListener(discMgr, networkProtocol, localNetworkAddr,
+		skipUPNP || discMgr.topology.isValidatorMode(), inlConfig) // a validator never exposes itself through UPnP
 	if err != nil {
 		return discMgr, err
 	}
@@ -121,9 +138,49 @@ func (discMgr *PeerDiscoveryManager) Start(ctx context.Context) error {
 		return err
 	}
 
+	err = discMgr.dht.Start(c)
+	if err != nil {
+		return err
+	}
+
+	discMgr.connectToPersistentPeers()
+	discMgr.connectToGuardedPeers()
+
 	return nil
 }
 
+// connectToPersistentPeers dials the persistent addresses saved in the address book
+func (discMgr *PeerDiscoveryManager) connectToPersistentPeers() {
+	for _, addrStr := range discMgr.addrBook.GetPersistentAddresses() {
+		addr, err := netutil.NewNetAddressString(addrStr)
+		if err != nil {
+			logger.Warnf("Invalid persistent peer address %v: %v", addrStr, err)
+			continue
+		}
+		if discMgr.isConnectedTo(addr) {
+			continue
+		}
+		discMgr.wg.Add(1)
+		go func(addr *netutil.NetAddress) {
+			defer discMgr.wg.Done()
+			if _, err := discMgr.connectToOutboundPeer(addr, true); err != nil {
+				logger.Warnf("Failed to connect to persistent peer %v: %v", addr, err)
+			} else {
+				logger.Infof("Successfully connected to persistent peer %v", addr)
+			}
+		}(addr)
+	}
+}
+
+func (discMgr *PeerDiscoveryManager) isConnectedTo(addr *netutil.NetAddress) bool {
+	for _, peer := range *discMgr.peerTable.GetAllPeers() {
+		if peer.NetAddress() != nil && peer.NetAddress().Equals(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // Stop is called when the PeerDiscoveryManager stops
 func (discMgr *PeerDiscoveryManager) Stop() {
 	discMgr.cancel()
@@ -134,9 +191,17 @@ func (discMgr *PeerDiscoveryManager) Wait() {
 	discMgr.seedPeerConnector.wg.Wait()
 	discMgr.inboundPeerListener.wg.Wait()
 	discMgr.peerDiscMsgHandler.wg.Wait()
+	discMgr.dht.wg.Wait()
 	discMgr.wg.Wait()
 }
 
+// removePeer removes the peer from the peer table and the sentry topology. The caller
+// is responsible for stopping the peer.
+func (discMgr *PeerDiscoveryManager) removePeer(peerID string) {
+	discMgr.peerTable.DeletePeer(peerID)
+	discMgr.topology.removePeer(peerID)
+}
+
 // HandlePeerWithErrors handles peers that are in the error state.
 // If the peer is persistent, it will attempt to reconnect to the
 // peer. Otherwise, it disconnects from that peer
@@ -159,7 +224,7 @@ func (discMgr *PeerDiscoveryManager) HandlePeerWithErrors(peer *pr.Peer) {
 		// we should not proceed to reconnect
 	}
 
-	discMgr.peerTable.DeletePeer(peer.ID())
+	discMgr.removePeer(peer.ID())
 	peer.Stop() // TODO: may need to stop peer regardless of the remote address comparison
 
 	if peer.IsPersistent() {
@@ -182,10 +247,42 @@ func (discMgr *PeerDiscoveryManager) HandlePeerWithErrors(peer *pr.Peer) {
 	}
 }
 
+// getConnectionConfig returns the default connection config, with the channel
+// schedules overridden by the config file
+func getConnectionConfig() cn.ConnectionConfig {
+	connConfig := cn.GetDefaultConnectionConfig()
+	connConfig.CompressionThreshold = viper.GetInt(common.CfgP2PCompressionThreshold)
+
+	overrides := make(map[string]cn.ChannelSchedule)
+	if err := viper.UnmarshalKey(common.CfgP2PChannelSchedules, &overrides); err != nil {
+		logger.Warnf("Failed to parse %v: %v", common.CfgP2PChannelSchedules, err)
+		return connConfig
+	}
+	if len(overrides) == 0 {
+		return connConfig
+	}
+
+	schedules := cn.GetDefaultChannelSchedules()
+	for name, schedule := range overrides {
+		channelID, ok := cn.ParseChannelID(name)
+		if !ok {
+			logger.Warnf("Unknown channel in %v: %v", common.CfgP2PChannelSchedules, name)
+			continue
+		}
+		schedules[channelID] = schedule
+	}
+	if err := cn.ValidateChannelSchedules(schedules); err != nil {
+		logger.Warnf("Invalid %v, fall back to the default channel schedules: %v", common.CfgP2PChannelSchedules, err)
+		return connConfig
+	}
+	connConfig.ChannelSchedules = schedules
+	return connConfig
+}
+
 func (discMgr *PeerDiscoveryManager) connectToOutboundPeer(peerNetAddress *netutil.NetAddress, persistent bool) (*pr.Peer, error) {
 	logger.Infof("Connecting to outbound peer: %v...", peerNetAddress)
 	peerConfig := pr.GetDefaultPeerConfig()
-	connConfig := cn.GetDefaultConnectionConfig()
+	connConfig := getConnectionConfig()
 	peer, err := pr.CreateOutboundPeer(peerNetAddress, peerConfig, connConfig)
 	if err != nil {
 		logger.Warnf("Failed to create outbound peer: %v", peerNetAddress)
@@ -199,7 +296,7 @@ func (discMgr *PeerDiscoveryManager) connectToOutboundPeer(peerNetAddress *netut
 func (discMgr *PeerDiscoveryManager) connectWithInboundPeer(netconn net.Conn, persistent bool) (*pr.Peer, error) {
 	logger.Infof("Connecting with inbound peer: %v...", netconn.RemoteAddr())
 	peerConfig := pr.GetDefaultPeerConfig()
-	connConfig := cn.GetDefaultConnectionConfig()
+	connConfig := getConnectionConfig()
 	peer, err := pr.CreateInboundPeer(netconn, peerConfig, connConfig)
 	if err != nil {
 		logger.Errorf("Failed to create inbound peer: %v", netconn.RemoteAddr())
@@ -218,6 +315,28 @@ func (discMgr *PeerDiscoveryManager) handshakeAndAddPeer(peer *pr.Peer) error {
 		return err
 	}
 
+	ip := ""
+	if peer.NetAddress() != nil {
+		ip = peer.NetAddress().IP.String()
+	}
+	if discMgr.addrBook.IsBanned(peer.ID(), ip) {
+		peer.GetConnection().GetNetconn().Close()
+		errMsg := fmt.Sprintf("Peer %v (%v) is banned", peer.ID(), ip)
+		logger.Warnf(errMsg)
+		return errors.New(errMsg)
+	}
+	if !discMgr.addrBook.IsAllowed(peer.ID(), ip) {
+		peer.GetConnection().GetNetconn().Close()
+		errMsg := fmt.Sprintf("Peer %v (%v) is not allowed", peer.ID(), ip)
+		logger.Warnf(errMsg)
+		return errors.New(errMsg)
+	}
+	if err := discMgr.topology.admitPeer(peer); err != nil {
+		peer.GetConnection().GetNetconn().Close()
+		logger.Warnf(err.Error())
+		return err
+	}
+
 	if discMgr.messenger != nil {
 		discMgr.messenger.AttachMessageHandlersToPeer(peer)
 	} else {
@@ -235,9 +354,12 @@ func (discMgr *PeerDiscoveryManager) handshakeAndAddPeer(peer *pr.Peer) error {
 		logger.Errorf(errMsg)
 		return errors.New(errMsg)
 	}
+	discMgr.dht.addPeer(peer)
 
-	discMgr.addrBook.AddAddress(peer.NetAddress(), peer.NetAddress())
-	discMgr.addrBook.Save()
+	if !discMgr.topology.isPrivateValidator(peer.ID()) {
+		discMgr.addrBook.AddAddress(peer.NetAddress(), peer.NetAddress())
+		discMgr.addrBook.Save()
+	}
 
 	return nil
 }
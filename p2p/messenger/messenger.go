@@ -4,12 +4,15 @@ import (
 	"context"
 	"strconv"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/crypto"
 	"github.com/thetatoken/theta/p2p"
+	cn "github.com/thetatoken/theta/p2p/connection"
 	pr "github.com/thetatoken/theta/p2p/peer"
 	p2ptypes "github.com/thetatoken/theta/p2p/types"
 )
@@ -27,6 +30,7 @@ type Messenger struct {
 
 	peerTable pr.PeerTable
 	nodeInfo  p2ptypes.NodeInfo // information of our blockchain node
+	scores    *peerScoreBook
 
 	config MessengerConfig
 
@@ -56,9 +60,13 @@ func CreateMessenger(pubKey *crypto.PublicKey, seedPeerNetAddresses []string,
 		msgHandlerMap: make(map[common.ChannelIDEnum](p2p.MessageHandler)),
 		peerTable:     pr.CreatePeerTable(),
 		nodeInfo:      p2ptypes.CreateNodeInfo(pubKey, uint16(port)),
+		scores:        newPeerScoreBook(),
 		config:        msgrConfig,
 		wg:            &sync.WaitGroup{},
 	}
+	if viper.GetBool(common.CfgP2PCompressionEnabled) {
+		messenger.nodeInfo.Compression = cn.SupportedCompressions
+	}
 
 	localNetAddress := "0.0.0.0:" + strconv.Itoa(port)
 	discMgrConfig := GetDefaultPeerDiscoveryManagerConfig()
@@ -74,6 +82,7 @@ func CreateMessenger(pubKey *crypto.PublicKey, seedPeerNetAddresses []string,
 	discMgr.SetMessenger(messenger)
 	messenger.SetPeerDiscoveryManager(discMgr)
 	messenger.RegisterMessageHandler(&discMgr.peerDiscMsgHandler)
+	messenger.RegisterMessageHandler(discMgr.dht)
 
 	return messenger, nil
 }
@@ -119,7 +128,11 @@ func (msgr *Messenger) Broadcast(message p2ptypes.Message) (successes chan bool)
 	logger.Debugf("Broadcasting messages...")
 	allPeers := msgr.peerTable.GetAllPeers()
 	successes = make(chan bool, len(*allPeers))
+	prioritized := msgr.relayToPrivateValidators(message, *allPeers, successes)
 	for _, peer := range *allPeers {
+		if prioritized[peer.ID()] {
+			continue
+		}
 		logger.Debugf("Broadcasting \"%v\" to %v", message.Content, peer.ID())
 		go func(peer *pr.Peer) {
 			success := msgr.Send(peer.ID(), message)
@@ -158,6 +171,75 @@ func (msgr *Messenger) ID() string {
 	return msgr.nodeInfo.PubKey.Address().Hex()
 }
 
+// Peers returns the IDs of the connected peers
+func (msgr *Messenger) Peers() []string {
+	allPeers := msgr.peerTable.GetAllPeers()
+	peerIDs := make([]string, 0, len(*allPeers))
+	for _, peer := range *allPeers {
+		peerIDs = append(peerIDs, peer.ID())
+	}
+	return peerIDs
+}
+
+// PeerStates returns the states of the connected peers
+func (msgr *Messenger) PeerStates() []p2ptypes.PeerState {
+	now := time.Now()
+	allPeers := msgr.peerTable.GetAllPeers()
+	states := make([]p2ptypes.PeerState, 0, len(*allPeers))
+	for _, peer := range *allPeers {
+		state := p2ptypes.PeerState{
+			ID:           peer.ID(),
+			Score:        msgr.scores.get(peer.ID(), now),
+			IsOutbound:   peer.IsOutbound(),
+			IsPersistent: peer.IsPersistent(),
+			ConnectedAt:  peer.ConnectedAt(),
+			Uptime:       uint64(now.Sub(peer.ConnectedAt()) / time.Second),
+		}
+		if peer.NetAddress() != nil {
+			state.Address = peer.NetAddress().String()
+		}
+		conn := peer.GetConnection()
+		state.PingLatency = uint64(conn.GetPingLatency() / time.Millisecond)
+		state.Compression = conn.GetCompression()
+		state.BytesSaved = conn.GetCompressionSavings()
+		state.Channels = conn.GetChannelStats()
+		for _, stats := range state.Channels {
+			state.BytesSent += stats.BytesSent
+			state.BytesReceived += stats.BytesReceived
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// NetInfo returns the network state of the node
+func (msgr *Messenger) NetInfo() p2ptypes.NetInfo {
+	listener := &msgr.discMgr.inboundPeerListener
+	netInfo := p2ptypes.NetInfo{
+		ID:             msgr.ID(),
+		ListenerAddrs:  []string{},
+		UPNPEnabled:    listener.UPNPMapped(),
+		AddrBookSize:   msgr.discMgr.addrBook.Size(),
+		NumBannedPeers: len(msgr.discMgr.addrBook.GetBans()),
+	}
+	if listener.NetListener() != nil {
+		netInfo.ListenerAddrs = append(netInfo.ListenerAddrs, listener.NetListener().Addr().String())
+	}
+	if listener.InternalAddress() != nil {
+		netInfo.ListenerAddrs = append(netInfo.ListenerAddrs, listener.InternalAddress().String())
+	}
+	if listener.ExternalAddress() != nil {
+		netInfo.ExternalAddr = listener.ExternalAddress().String()
+	}
+	for _, peer := range *msgr.peerTable.GetAllPeers() {
+		netInfo.NumPeers++
+		if peer.IsOutbound() {
+			netInfo.NumOutboundPeers++
+		}
+	}
+	return netInfo
+}
+
 // AttachMessageHandlersToPeer attaches the registerred message handlers to the given peer
 func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 	messageParser := func(channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
@@ -167,6 +249,9 @@ func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 			logger.Errorf("Failed to setup message parser for channelID %v", channelID)
 		}
 		message, err := msgHandler.ParseMessage(peerID, channelID, rawMessageBytes)
+		if err != nil {
+			msgr.ReportPeer(peerID, p2ptypes.MisbehaviorUnparsableMessage)
+		}
 		return message, err
 	}
 	peer.GetConnection().SetMessageParser(messageParser)
@@ -0,0 +1,119 @@
+package messenger
+
+import (
+	"github.com/thetatoken/theta/p2p"
+	"github.com/thetatoken/theta/p2p/netutil"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+//
+// Messenger implements the PeerManager interface
+//
+var _ p2p.PeerManager = (*Messenger)(nil)
+
+// AddPeer dials the peer at the given address. A persistent peer is saved to the
+// address book, and is dialed again on restarts.
+func (msgr *Messenger) AddPeer(address string, persistent bool) error {
+	netAddr, err := netutil.NewNetAddressString(address)
+	if err != nil {
+		return err
+	}
+
+	addrBook := msgr.discMgr.addrBook
+	if persistent {
+		addrBook.AddPersistentAddress(netAddr.String())
+		addrBook.Save()
+	}
+
+	if msgr.discMgr.isConnectedTo(netAddr) {
+		return nil
+	}
+	_, err = msgr.discMgr.connectToOutboundPeer(netAddr, true)
+	return err
+}
+
+// DisconnectPeer disconnects the peer, and removes its address from the persistent
+// addresses so that the node does not reconnect to it.
+func (msgr *Messenger) DisconnectPeer(peerID string) bool {
+	peer := msgr.peerTable.GetPeer(peerID)
+	if peer == nil {
+		return false
+	}
+
+	if peer.NetAddress() != nil {
+		addrBook := msgr.discMgr.addrBook
+		if addrBook.RemovePersistentAddress(peer.NetAddress().String()) {
+			addrBook.Save()
+		}
+	}
+
+	logger.Infof("Disconnect peer: %v", peerID)
+	peer.SetPersistency(false)
+	msgr.discMgr.removePeer(peerID)
+	peer.Stop()
+	return true
+}
+
+// SetPrivatePeer marks or unmarks the peer as private. Private peers are never
+// gossiped to other peers.
+func (msgr *Messenger) SetPrivatePeer(peerID string, private bool) {
+	addrBook := msgr.discMgr.addrBook
+	addrBook.SetPrivatePeer(peerID, private)
+	addrBook.Save()
+}
+
+// UpdateAllowlist adds the peer ID or IP to, or removes it from the allowlist.
+// The connected peers which are no longer allowed are disconnected.
+func (msgr *Messenger) UpdateAllowlist(entry string, add bool) {
+	addrBook := msgr.discMgr.addrBook
+	addrBook.UpdateAllowlist(entry, add)
+	addrBook.Save()
+	msgr.disconnectDisallowedPeers()
+}
+
+// UpdateDenylist adds the peer ID or IP to, or removes it from the denylist.
+// The connected peers which are no longer allowed are disconnected.
+func (msgr *Messenger) UpdateDenylist(entry string, add bool) {
+	addrBook := msgr.discMgr.addrBook
+	addrBook.UpdateDenylist(entry, add)
+	addrBook.Save()
+	msgr.disconnectDisallowedPeers()
+}
+
+// PeerLists returns the peer lists managed at runtime
+func (msgr *Messenger) PeerLists() p2ptypes.PeerLists {
+	addrBook := msgr.discMgr.addrBook
+	return p2ptypes.PeerLists{
+		PersistentAddrs: addrBook.GetPersistentAddresses(),
+		PrivatePeers:    addrBook.GetPrivatePeers(),
+		Allowlist:       addrBook.GetAllowlist(),
+		Denylist:        addrBook.GetDenylist(),
+	}
+}
+
+// LookupPeer finds the address of the node with the given ID through the DHT
+func (msgr *Messenger) LookupPeer(peerID string) (string, error) {
+	addr, err := msgr.discMgr.dht.FindPeer(peerID)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+func (msgr *Messenger) disconnectDisallowedPeers() {
+	addrBook := msgr.discMgr.addrBook
+	allPeers := *msgr.peerTable.GetAllPeers()
+	peers := make([]string, 0, len(allPeers))
+	for _, peer := range allPeers {
+		ip := ""
+		if peer.NetAddress() != nil {
+			ip = peer.NetAddress().IP.String()
+		}
+		if !addrBook.IsAllowed(peer.ID(), ip) {
+			peers = append(peers, peer.ID())
+		}
+	}
+	for _, peerID := range peers {
+		msgr.DisconnectPeer(peerID)
+	}
+}
@@ -0,0 +1,147 @@
+package messenger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+const (
+	// MaxPeerScore is the initial, and also the highest score of a peer
+	MaxPeerScore = 100
+
+	// PeerScoreRecoveryInterval is the time it takes for a peer to recover one point of score
+	PeerScoreRecoveryInterval = time.Minute
+)
+
+//
+// peerScore tracks the reputation of a peer. The score is lowered by the
+// misbehaviors of the peer, and slowly recovers over time.
+//
+type peerScore struct {
+	score      int
+	lastUpdate time.Time
+}
+
+// current returns the score after the recovery since the last update
+func (ps *peerScore) current(now time.Time) int {
+	recovered := int(now.Sub(ps.lastUpdate) / PeerScoreRecoveryInterval)
+	score := ps.score + recovered
+	if score > MaxPeerScore {
+		score = MaxPeerScore
+	}
+	return score
+}
+
+func (ps *peerScore) deduct(penalty int, now time.Time) int {
+	recovered := now.Sub(ps.lastUpdate) / PeerScoreRecoveryInterval
+	ps.score = ps.current(now) - penalty
+	ps.lastUpdate = ps.lastUpdate.Add(recovered * PeerScoreRecoveryInterval)
+	return ps.score
+}
+
+//
+// peerScoreBook keeps the scores of the peers
+//
+type peerScoreBook struct {
+	mu     *sync.Mutex
+	scores map[string]*peerScore
+}
+
+func newPeerScoreBook() *peerScoreBook {
+	return &peerScoreBook{
+		mu:     &sync.Mutex{},
+		scores: make(map[string]*peerScore),
+	}
+}
+
+// deduct lowers the score of the peer by the penalty, and returns the new score
+func (psb *peerScoreBook) deduct(peerID string, penalty int, now time.Time) int {
+	psb.mu.Lock()
+	defer psb.mu.Unlock()
+
+	ps, ok := psb.scores[peerID]
+	if !ok {
+		ps = &peerScore{score: MaxPeerScore, lastUpdate: now}
+		psb.scores[peerID] = ps
+	}
+	return ps.deduct(penalty, now)
+}
+
+func (psb *peerScoreBook) get(peerID string, now time.Time) int {
+	psb.mu.Lock()
+	defer psb.mu.Unlock()
+
+	ps, ok := psb.scores[peerID]
+	if !ok {
+		return MaxPeerScore
+	}
+	return ps.current(now)
+}
+
+func (psb *peerScoreBook) reset(peerID string) {
+	psb.mu.Lock()
+	defer psb.mu.Unlock()
+
+	delete(psb.scores, peerID)
+}
+
+// ReportPeer reports a misbehavior of the peer, which lowers the score of the
+// peer. The peer is banned once its score drops to zero.
+func (msgr *Messenger) ReportPeer(peerID string, misbehavior p2ptypes.Misbehavior) {
+	score := msgr.scores.deduct(peerID, misbehavior.Penalty(), time.Now())
+	logger.Warnf("Peer %v reported for %v, score: %v", peerID, misbehavior, score)
+	if score > 0 {
+		return
+	}
+
+	duration := time.Duration(viper.GetInt(common.CfgP2PBanDuration)) * time.Second
+	reason := fmt.Sprintf("score dropped to %v, last misbehavior: %v", score, misbehavior)
+	if err := msgr.BanPeer(peerID, duration, reason); err != nil {
+		logger.Warnf("Failed to ban peer %v: %v", peerID, err)
+	}
+}
+
+// BanPeer disconnects the peer and refuses its connections for the given duration
+func (msgr *Messenger) BanPeer(peerID string, duration time.Duration, reason string) error {
+	if duration <= 0 {
+		return fmt.Errorf("Invalid ban duration: %v", duration)
+	}
+
+	ip := ""
+	peer := msgr.peerTable.GetPeer(peerID)
+	if peer != nil && peer.NetAddress() != nil {
+		ip = peer.NetAddress().IP.String()
+	}
+
+	addrBook := msgr.discMgr.addrBook
+	addrBook.BanPeer(peerID, ip, time.Now().Add(duration), reason)
+	addrBook.Save()
+	msgr.scores.reset(peerID)
+
+	if peer != nil {
+		msgr.discMgr.removePeer(peerID)
+		peer.Stop()
+	}
+	return nil
+}
+
+// UnbanPeer lifts the ban of the peer, returns false if the peer is not banned
+func (msgr *Messenger) UnbanPeer(peerID string) bool {
+	addrBook := msgr.discMgr.addrBook
+	if !addrBook.UnbanPeer(peerID) {
+		return false
+	}
+	addrBook.Save()
+	return true
+}
+
+// BannedPeers returns the currently banned peers
+func (msgr *Messenger) BannedPeers() []p2ptypes.BannedPeer {
+	return msgr.discMgr.addrBook.GetBans()
+}
@@ -0,0 +1,35 @@
+package messenger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+func TestPeerScore(t *testing.T) {
+	assert := assert.New(t)
+
+	psb := newPeerScoreBook()
+	now := time.Now()
+	assert.Equal(MaxPeerScore, psb.get("peer1", now))
+
+	score := psb.deduct("peer1", p2ptypes.MisbehaviorInvalidVote.Penalty(), now)
+	assert.Equal(75, score)
+	score = psb.deduct("peer1", p2ptypes.MisbehaviorInvalidBlock.Penalty(), now)
+	assert.Equal(25, score)
+	assert.Equal(MaxPeerScore, psb.get("peer2", now))
+
+	// The score recovers over time, but never exceeds the max score
+	assert.Equal(35, psb.get("peer1", now.Add(10*PeerScoreRecoveryInterval+time.Second)))
+	assert.Equal(MaxPeerScore, psb.get("peer1", now.Add(1000*PeerScoreRecoveryInterval)))
+
+	// Partial recovery is kept across deductions
+	later := now.Add(PeerScoreRecoveryInterval / 2)
+	assert.Equal(15, psb.deduct("peer1", p2ptypes.MisbehaviorRequestSpam.Penalty(), later))
+	assert.Equal(16, psb.get("peer1", now.Add(PeerScoreRecoveryInterval)))
+
+	psb.reset("peer1")
+	assert.Equal(MaxPeerScore, psb.get("peer1", now))
+}
@@ -0,0 +1,195 @@
+package messenger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+	pr "github.com/thetatoken/theta/p2p/peer"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+const (
+	// NodeModeNormal is the default mode, in which the node peers with anyone
+	NodeModeNormal = "normal"
+
+	// NodeModeValidator is the mode in which the node only peers with its sentry
+	// nodes, and never advertises itself
+	NodeModeValidator = "validator"
+
+	// NodeModeSentry is the mode in which the node shields its private validators,
+	// i.e. it hides them from peer discovery and relays the consensus messages to them first
+	NodeModeSentry = "sentry"
+)
+
+//
+// sentryTopology enforces the role of the node in the sentry node topology.
+// In the validator mode, the guarded addresses are the sentry nodes. In the
+// sentry mode, the guarded addresses are the private validators.
+//
+type sentryTopology struct {
+	mode         string
+	guardedAddrs []netutil.NetAddress
+
+	mu           *sync.Mutex
+	guardedPeers map[string]bool // IDs of the connected peers at the guarded addresses
+}
+
+func createSentryTopology(mode string, guardedAddrStrs []string) (*sentryTopology, error) {
+	st := &sentryTopology{
+		mode:         mode,
+		mu:           &sync.Mutex{},
+		guardedPeers: make(map[string]bool),
+	}
+
+	switch mode {
+	case NodeModeNormal:
+		return st, nil
+	case NodeModeValidator, NodeModeSentry:
+	default:
+		return nil, fmt.Errorf("Invalid node mode: %v", mode)
+	}
+
+	for _, addrStr := range guardedAddrStrs {
+		addr, err := netutil.NewNetAddressString(addrStr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse address %v: %v", addrStr, err)
+		}
+		st.guardedAddrs = append(st.guardedAddrs, *addr)
+	}
+	if mode == NodeModeValidator && len(st.guardedAddrs) == 0 {
+		return nil, fmt.Errorf("No sentry node is specified for the validator mode, please set %v", common.CfgP2PSentryNodes)
+	}
+	return st, nil
+}
+
+// createSentryTopologyFromConfig creates the sentryTopology based on the node mode in the config
+func createSentryTopologyFromConfig() (*sentryTopology, error) {
+	f := func(c rune) bool {
+		return c == ','
+	}
+	mode := viper.GetString(common.CfgP2PNodeMode)
+	var guardedAddrStrs []string
+	switch mode {
+	case NodeModeValidator:
+		guardedAddrStrs = strings.FieldsFunc(viper.GetString(common.CfgP2PSentryNodes), f)
+	case NodeModeSentry:
+		guardedAddrStrs = strings.FieldsFunc(viper.GetString(common.CfgP2PPrivateValidators), f)
+	}
+	return createSentryTopology(mode, guardedAddrStrs)
+}
+
+func (st *sentryTopology) isValidatorMode() bool {
+	return st.mode == NodeModeValidator
+}
+
+func (st *sentryTopology) isSentryMode() bool {
+	return st.mode == NodeModeSentry
+}
+
+// isGuardedAddress checks whether the address belongs to a guarded node. For
+// inbound peers only the IP is compared, since the port is ephemeral.
+func (st *sentryTopology) isGuardedAddress(addr *netutil.NetAddress, outbound bool) bool {
+	if addr == nil {
+		return false
+	}
+	for i := range st.guardedAddrs {
+		guardedAddr := &st.guardedAddrs[i]
+		if outbound && addr.Equals(guardedAddr) {
+			return true
+		}
+		if !outbound && addr.IP.Equal(guardedAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// admitPeer decides whether the peer can be connected. In the validator mode,
+// only the sentry nodes are admitted.
+func (st *sentryTopology) admitPeer(peer *pr.Peer) error {
+	if st.mode == NodeModeNormal {
+		return nil
+	}
+
+	guarded := st.isGuardedAddress(peer.NetAddress(), peer.IsOutbound())
+	if st.isValidatorMode() && !guarded {
+		return fmt.Errorf("Peer %v (%v) is not a sentry node", peer.ID(), peer.NetAddress())
+	}
+	if guarded {
+		st.mu.Lock()
+		st.guardedPeers[peer.ID()] = true
+		st.mu.Unlock()
+	}
+	return nil
+}
+
+// removePeer forgets the disconnected peer
+func (st *sentryTopology) removePeer(peerID string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.guardedPeers, peerID)
+}
+
+// isPrivateValidator returns whether the peer is a validator shielded by this sentry node
+func (st *sentryTopology) isPrivateValidator(peerID string) bool {
+	if !st.isSentryMode() {
+		return false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.guardedPeers[peerID]
+}
+
+// isConsensusChannel returns whether the channel carries the consensus messages,
+// which are relayed to the private validators with priority
+func isConsensusChannel(channelID common.ChannelIDEnum) bool {
+	switch channelID {
+	case common.ChannelIDProposal, common.ChannelIDVote, common.ChannelIDBlock, common.ChannelIDCC:
+		return true
+	default:
+		return false
+	}
+}
+
+// connectToGuardedPeers dials the sentry nodes in the validator mode, or the
+// private validators in the sentry mode
+func (discMgr *PeerDiscoveryManager) connectToGuardedPeers() {
+	for i := range discMgr.topology.guardedAddrs {
+		addr := &discMgr.topology.guardedAddrs[i]
+		if discMgr.isConnectedTo(addr) {
+			continue
+		}
+		discMgr.wg.Add(1)
+		go func(addr *netutil.NetAddress) {
+			defer discMgr.wg.Done()
+			if _, err := discMgr.connectToOutboundPeer(addr, true); err != nil {
+				logger.Warnf("Failed to connect to guarded peer %v: %v", addr, err)
+			} else {
+				logger.Infof("Successfully connected to guarded peer %v", addr)
+			}
+		}(addr)
+	}
+}
+
+// relayToPrivateValidators enqueues the consensus messages to the private validators
+// before the other peers in the sentry mode. Returns the IDs of the peers the message
+// has been enqueued to.
+func (msgr *Messenger) relayToPrivateValidators(message p2ptypes.Message, peers []*pr.Peer, successes chan bool) map[string]bool {
+	prioritized := make(map[string]bool)
+	topology := msgr.discMgr.topology
+	if !topology.isSentryMode() || !isConsensusChannel(message.ChannelID) {
+		return prioritized
+	}
+	for _, peer := range peers {
+		if topology.isPrivateValidator(peer.ID()) && peer.AttemptToSend(message.ChannelID, message.Content) {
+			prioritized[peer.ID()] = true
+			successes <- true
+		}
+	}
+	return prioritized
+}
@@ -0,0 +1,53 @@
+package messenger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p/netutil"
+)
+
+func TestSentryTopology(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := createSentryTopology("unknown", []string{})
+	assert.NotNil(err)
+	_, err = createSentryTopology(NodeModeValidator, []string{})
+	assert.NotNil(err) // the validator mode requires sentry nodes
+	_, err = createSentryTopology(NodeModeValidator, []string{"not an address"})
+	assert.NotNil(err)
+
+	st, err := createSentryTopology(NodeModeNormal, []string{})
+	assert.Nil(err)
+	assert.False(st.isValidatorMode())
+	assert.False(st.isSentryMode())
+
+	st, err = createSentryTopology(NodeModeValidator, []string{"10.0.0.1:50001", "10.0.0.2:50001"})
+	assert.Nil(err)
+	assert.True(st.isValidatorMode())
+
+	sentryAddr, _ := netutil.NewNetAddressString("10.0.0.1:50001")
+	inboundAddr, _ := netutil.NewNetAddressString("10.0.0.2:61234")
+	otherAddr, _ := netutil.NewNetAddressString("10.0.0.3:50001")
+	assert.True(st.isGuardedAddress(sentryAddr, true))
+	assert.False(st.isGuardedAddress(inboundAddr, true)) // outbound peers must match the port
+	assert.True(st.isGuardedAddress(inboundAddr, false))
+	assert.False(st.isGuardedAddress(otherAddr, false))
+	assert.False(st.isGuardedAddress(nil, false))
+
+	st, err = createSentryTopology(NodeModeSentry, []string{"10.0.0.1:50001"})
+	assert.Nil(err)
+	assert.True(st.isSentryMode())
+	assert.False(st.isPrivateValidator("validator1"))
+	st.guardedPeers["validator1"] = true
+	assert.True(st.isPrivateValidator("validator1"))
+	st.removePeer("validator1")
+	assert.False(st.isPrivateValidator("validator1"))
+	assert.Equal(0, len(st.guardedPeers))
+
+	assert.True(isConsensusChannel(common.ChannelIDVote))
+	assert.True(isConsensusChannel(common.ChannelIDProposal))
+	assert.False(isConsensusChannel(common.ChannelIDTransaction))
+}
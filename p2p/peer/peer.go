@@ -28,6 +28,7 @@ type Peer struct {
 	isPersistent bool
 	isOutbound   bool
 	netAddress   *nu.NetAddress
+	connectedAt  time.Time
 
 	nodeInfo p2ptypes.NodeInfo // information of the blockchain node of the peer
 
@@ -139,6 +140,9 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 	targetPeerNodeInfo.PubKey = targetNodePubKey
 	peer.nodeInfo = targetPeerNodeInfo
 
+	compression := cn.NegotiateCompression(sourceNodeInfo.Compression, targetPeerNodeInfo.Compression)
+	peer.connection.SetCompression(compression)
+
 	if !peer.isOutbound {
 		peer.SetNetAddress(nu.NewNetAddressWithEnforcedPort(netconn.RemoteAddr(), int(peer.nodeInfo.Port)))
 	}
@@ -202,6 +206,11 @@ func (peer *Peer) NetAddress() *nu.NetAddress {
 	return peer.netAddress
 }
 
+// ConnectedAt returns the time when the connection with the peer was established
+func (peer *Peer) ConnectedAt() time.Time {
+	return peer.connectedAt
+}
+
 // ID returns the unique idenitifier of the peer in the P2P network
 func (peer *Peer) ID() string {
 	peerID := peer.nodeInfo.PubKey.Address() // use the blockchain address as the peer ID
@@ -229,11 +238,12 @@ func createPeer(netconn net.Conn, isOutbound bool,
 		netAddress = nu.NewNetAddress(netconn.RemoteAddr())
 	}
 	peer := &Peer{
-		connection: connection,
-		isOutbound: isOutbound,
-		netAddress: netAddress,
-		config:     peerConfig,
-		wg:         &sync.WaitGroup{},
+		connection:  connection,
+		isOutbound:  isOutbound,
+		netAddress:  netAddress,
+		connectedAt: time.Now(),
+		config:      peerConfig,
+		wg:          &sync.WaitGroup{},
 	}
 	return peer
 }
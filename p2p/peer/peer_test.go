@@ -147,8 +147,50 @@ func TestPeerHandshakeAndCommunication(t *testing.T) {
 	}
 }
 
+func TestPeerHandshakeNegotiatesCompression(t *testing.T) {
+	assert := assert.New(t)
+
+	// Both peers offer compression
+	outboundCompression, inboundCompression := handshakeWithCompression(38858, cn.SupportedCompressions, cn.SupportedCompressions)
+	assert.Equal(cn.CompressionSnappy, outboundCompression)
+	assert.Equal(cn.CompressionSnappy, inboundCompression)
+
+	// Only one peer offers compression
+	outboundCompression, inboundCompression = handshakeWithCompression(38859, cn.SupportedCompressions, nil)
+	assert.Equal(cn.CompressionNone, outboundCompression)
+	assert.Equal(cn.CompressionNone, inboundCompression)
+}
+
 // --------------- Test Utilities --------------- //
 
+func handshakeWithCompression(port int, outboundAlgos []string, inboundAlgos []string) (string, string) {
+	outboundCompression := make(chan string)
+	go func() {
+		outboundPeer := newOutboundPeer("127.0.0.1:" + strconv.Itoa(port))
+		nodeInfo := p2ptypes.CreateNodeInfo(p2ptypes.GetTestRandPubKey(), uint16(port))
+		nodeInfo.Compression = outboundAlgos
+		if err := outboundPeer.Handshake(&nodeInfo); err != nil {
+			panic(fmt.Sprintf("Failed to handshake: %v", err))
+		}
+		outboundCompression <- outboundPeer.GetConnection().GetCompression()
+	}()
+
+	listener := p2ptypes.GetTestListener(port)
+	netconn, err := listener.Accept()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to listen to the netconn: %v", err))
+	}
+	defer netconn.Close()
+
+	inboundPeer := newInboundPeer(netconn)
+	nodeInfo := p2ptypes.CreateNodeInfo(p2ptypes.GetTestRandPubKey(), uint16(port))
+	nodeInfo.Compression = inboundAlgos
+	if err := inboundPeer.Handshake(&nodeInfo); err != nil {
+		panic(fmt.Sprintf("Failed to handshake: %v", err))
+	}
+	return <-outboundCompression, inboundPeer.GetConnection().GetCompression()
+}
+
 func newOutboundPeer(ipAddr string) *Peer {
 	netaddr, err := nu.NewNetAddressString(ipAddr)
 	if err != nil {
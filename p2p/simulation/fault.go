@@ -0,0 +1,326 @@
+package simulation
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/rlp"
+)
+
+//
+// LinkConfig specifies the conditions of the one way link between two endpoints.
+// The zero value is a perfect link, which delivers every message instantly.
+//
+type LinkConfig struct {
+	Latency     time.Duration // base delay of each message
+	Jitter      time.Duration // random extra delay in [0, Jitter)
+	DropRate    float64       // probability that a message is lost, in [0, 1]
+	ReorderRate float64       // probability that a message skips the queue of the link and may overtake earlier messages, in [0, 1]
+	Bandwidth   int           // bytes per second, 0 for unlimited
+}
+
+func (lc LinkConfig) isPerfect() bool {
+	return lc == LinkConfig{}
+}
+
+//
+// SimnetStats summarizes the messages handled by the Simnet
+//
+type SimnetStats struct {
+	Sent        uint64 // messages to be delivered, counted once for each recipient
+	Delivered   uint64
+	Dropped     uint64 // messages lost according to the drop rate of the link
+	Partitioned uint64 // messages lost because the endpoints were partitioned
+}
+
+type scheduledEnvelope struct {
+	envelope  Envelope
+	deliverAt time.Time
+}
+
+//
+// simLink delivers the messages from one endpoint to another in order. The messages
+// are queued without bound, so that a slow receiver never blocks the Simnet.
+//
+type simLink struct {
+	mu           *sync.Mutex
+	pending      []scheduledEnvelope
+	signal       chan struct{}
+	lastDelivery time.Time
+	busyUntil    time.Time // when the link finishes transmitting the queued bytes
+}
+
+func newSimLink() *simLink {
+	return &simLink{
+		mu:     &sync.Mutex{},
+		signal: make(chan struct{}, 1),
+	}
+}
+
+func (sl *simLink) push(se scheduledEnvelope) {
+	sl.mu.Lock()
+	sl.pending = append(sl.pending, se)
+	sl.mu.Unlock()
+
+	select {
+	case sl.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (sl *simLink) pop() (scheduledEnvelope, bool) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+
+	if len(sl.pending) == 0 {
+		return scheduledEnvelope{}, false
+	}
+	se := sl.pending[0]
+	sl.pending = sl.pending[1:]
+	return se, true
+}
+
+func linkKey(from, to string) string {
+	return from + "->" + to
+}
+
+// SetSeed sets the seed of the random source used for the fault injection. Together
+// with the same link configs and schedules, the same seed reproduces the same
+// drops, delays and reorderings for the same sequence of messages.
+func (sn *Simnet) SetSeed(seed int64) {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	sn.seed = seed
+	sn.rand = rand.New(rand.NewSource(seed))
+}
+
+// Seed returns the seed of the random source, so that a failed run can be reproduced.
+func (sn *Simnet) Seed() int64 {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	return sn.seed
+}
+
+// SetDefaultLinkConfig sets the conditions of all the links without a specific config.
+func (sn *Simnet) SetDefaultLinkConfig(config LinkConfig) {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	sn.defaultLink = config
+}
+
+// SetLinkConfig sets the conditions of the link from one endpoint to another.
+func (sn *Simnet) SetLinkConfig(from, to string, config LinkConfig) {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	sn.linkConfigs[linkKey(from, to)] = config
+}
+
+// SetBidirectionalLinkConfig sets the conditions of the links in both directions between two endpoints.
+func (sn *Simnet) SetBidirectionalLinkConfig(a, b string, config LinkConfig) {
+	sn.SetLinkConfig(a, b, config)
+	sn.SetLinkConfig(b, a, config)
+}
+
+// Partition splits the endpoints into the given groups. Messages are only delivered
+// within a group, and each endpoint not in any group is isolated. Messages already
+// in flight are still delivered.
+func (sn *Simnet) Partition(groups ...[]string) {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	sn.partition = make(map[string]int)
+	for i, group := range groups {
+		for _, id := range group {
+			sn.partition[id] = i + 1
+		}
+	}
+}
+
+// Heal removes the partition.
+func (sn *Simnet) Heal() {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	sn.partition = nil
+}
+
+// SchedulePartition partitions the endpoints after the given duration since the
+// Simnet starts, or since now if it has already started.
+func (sn *Simnet) SchedulePartition(after time.Duration, groups ...[]string) {
+	sn.schedule(after, func() { sn.Partition(groups...) })
+}
+
+// ScheduleHeal heals the partition after the given duration since the Simnet
+// starts, or since now if it has already started.
+func (sn *Simnet) ScheduleHeal(after time.Duration) {
+	sn.schedule(after, sn.Heal)
+}
+
+// Stats returns the statistics of the messages handled so far.
+func (sn *Simnet) Stats() SimnetStats {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	return sn.stats
+}
+
+type simEvent struct {
+	after time.Duration
+	fn    func()
+}
+
+func (sn *Simnet) schedule(after time.Duration, fn func()) {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	event := simEvent{after: after, fn: fn}
+	if sn.ctx == nil {
+		sn.events = append(sn.events, event)
+		return
+	}
+	sn.runEvent(event)
+}
+
+func (sn *Simnet) runEvent(event simEvent) {
+	go func() {
+		select {
+		case <-sn.ctx.Done():
+		case <-time.After(event.after):
+			event.fn()
+		}
+	}()
+}
+
+func (sn *Simnet) isPartitioned(from, to string) bool {
+	if sn.partition == nil {
+		return false
+	}
+	groupFrom, ok1 := sn.partition[from]
+	groupTo, ok2 := sn.partition[to]
+	return !ok1 || !ok2 || groupFrom != groupTo
+}
+
+func (sn *Simnet) linkConfig(from, to string) LinkConfig {
+	if config, ok := sn.linkConfigs[linkKey(from, to)]; ok {
+		return config
+	}
+	return sn.defaultLink
+}
+
+// deliver delivers the envelope to the endpoint according to the link conditions
+func (sn *Simnet) deliver(endpoint *SimnetEndpoint, envelope Envelope) {
+	sn.faultMu.Lock()
+	defer sn.faultMu.Unlock()
+
+	sn.stats.Sent++
+	if sn.isPartitioned(envelope.From, endpoint.ID()) {
+		sn.stats.Partitioned++
+		return
+	}
+
+	config := sn.linkConfig(envelope.From, endpoint.ID())
+	if config.isPerfect() {
+		sn.stats.Delivered++
+		go func() {
+			endpoint.incoming <- envelope
+		}()
+		return
+	}
+
+	if config.DropRate > 0 && sn.rand.Float64() < config.DropRate {
+		sn.stats.Dropped++
+		return
+	}
+
+	key := linkKey(envelope.From, endpoint.ID())
+	link, ok := sn.links[key]
+	if !ok {
+		link = newSimLink()
+		sn.links[key] = link
+		go sn.linkLoop(link, endpoint)
+	}
+
+	sent := time.Now()
+	if config.Bandwidth > 0 {
+		if link.busyUntil.After(sent) {
+			sent = link.busyUntil
+		}
+		sent = sent.Add(time.Duration(int64(messageSize(envelope.Content)) * int64(time.Second) / int64(config.Bandwidth)))
+		link.busyUntil = sent
+	}
+	delay := config.Latency
+	if config.Jitter > 0 {
+		delay += time.Duration(sn.rand.Int63n(int64(config.Jitter)))
+	}
+	deliverAt := sent.Add(delay)
+
+	if config.ReorderRate > 0 && sn.rand.Float64() < config.ReorderRate {
+		go func() {
+			time.Sleep(time.Until(deliverAt))
+			sn.handOver(endpoint, envelope)
+		}()
+		return
+	}
+
+	if deliverAt.Before(link.lastDelivery) {
+		deliverAt = link.lastDelivery
+	}
+	link.lastDelivery = deliverAt
+	link.push(scheduledEnvelope{envelope: envelope, deliverAt: deliverAt})
+}
+
+func (sn *Simnet) linkLoop(link *simLink, endpoint *SimnetEndpoint) {
+	for {
+		se, ok := link.pop()
+		if !ok {
+			select {
+			case <-sn.ctx.Done():
+				return
+			case <-link.signal:
+				continue
+			}
+		}
+
+		select {
+		case <-sn.ctx.Done():
+			return
+		case <-time.After(time.Until(se.deliverAt)):
+			sn.handOver(endpoint, se.envelope)
+		}
+	}
+}
+
+func (sn *Simnet) handOver(endpoint *SimnetEndpoint, envelope Envelope) {
+	select {
+	case <-sn.ctx.Done():
+		return
+	case endpoint.incoming <- envelope:
+	}
+
+	sn.faultMu.Lock()
+	sn.stats.Delivered++
+	sn.faultMu.Unlock()
+}
+
+// messageSize estimates the size of the message on the wire for the bandwidth caps
+func messageSize(content interface{}) int {
+	switch c := content.(type) {
+	case common.Bytes:
+		return len(c)
+	case []byte:
+		return len(c)
+	case string:
+		return len(c)
+	}
+	raw, err := rlp.EncodeToBytes(content)
+	if err != nil {
+		return 0
+	}
+	return len(raw)
+}
@@ -2,6 +2,8 @@ package simulation
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -13,9 +15,10 @@ import (
 
 // Envelope wraps a message with network information for delivery.
 type Envelope struct {
-	From    string
-	To      string
-	Content interface{}
+	From      string
+	To        string
+	ChannelID common.ChannelIDEnum
+	Content   interface{}
 }
 
 // Simnet represents an instance of simluated network.
@@ -25,6 +28,17 @@ type Simnet struct {
 	messages   chan Envelope
 	MsgLogs    []Envelope
 
+	// Fault injection.
+	faultMu     *sync.Mutex
+	seed        int64
+	rand        *rand.Rand
+	defaultLink LinkConfig
+	linkConfigs map[string]LinkConfig
+	links       map[string]*simLink
+	partition   map[string]int // endpoint ID -> partition group, nil if not partitioned
+	events      []simEvent     // scheduled before the Simnet starts
+	stats       SimnetStats
+
 	// Life cycle.
 	wg      *sync.WaitGroup
 	mu      *sync.Mutex
@@ -35,21 +49,23 @@ type Simnet struct {
 
 // NewSimnet creates a new instance of Simnet.
 func NewSimnet() *Simnet {
-	return &Simnet{
-		messages: make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
-		MsgLogs:  []Envelope{},
-		wg:       &sync.WaitGroup{},
-		mu:       &sync.Mutex{},
-	}
+	return NewSimnetWithHandler(nil)
 }
 
 // NewSimnetWithHandler creates a new instance of Simnet with given MessageHandler as the default handler.
 func NewSimnetWithHandler(msgHandler p2p.MessageHandler) *Simnet {
+	seed := time.Now().UnixNano()
 	return &Simnet{
-		msgHandler: msgHandler,
-		messages:   make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
-		wg:         &sync.WaitGroup{},
-		mu:         &sync.Mutex{},
+		msgHandler:  msgHandler,
+		messages:    make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
+		MsgLogs:     []Envelope{},
+		faultMu:     &sync.Mutex{},
+		seed:        seed,
+		rand:        rand.New(rand.NewSource(seed)),
+		linkConfigs: make(map[string]LinkConfig),
+		links:       make(map[string]*simLink),
+		wg:          &sync.WaitGroup{},
+		mu:          &sync.Mutex{},
 	}
 }
 
@@ -60,6 +76,9 @@ func (sn *Simnet) AddEndpoint(id string) *SimnetEndpoint {
 		network:  sn,
 		incoming: make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
 		outgoing: make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
+		mu:       &sync.Mutex{},
+		reports:  make(map[string][]p2ptypes.Misbehavior),
+		bans:     make(map[string]p2ptypes.BannedPeer),
 	}
 	sn.Endpoints = append(sn.Endpoints, endpoint)
 	return endpoint
@@ -68,8 +87,14 @@ func (sn *Simnet) AddEndpoint(id string) *SimnetEndpoint {
 // Start is the main entry point for Simnet. It starts all endpoints and start a goroutine to handle message dlivery.
 func (sn *Simnet) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
+	sn.faultMu.Lock()
 	sn.ctx = c
 	sn.cancel = cancel
+	for _, event := range sn.events {
+		sn.runEvent(event)
+	}
+	sn.events = nil
+	sn.faultMu.Unlock()
 
 	for _, endpoint := range sn.Endpoints {
 		endpoint.Start(ctx)
@@ -102,15 +127,13 @@ func (sn *Simnet) mainLoop() {
 		case envelope := <-sn.messages:
 			time.Sleep(1 * time.Microsecond)
 			for _, endpoint := range sn.Endpoints {
-				if (envelope.To == "" && envelope.From != endpoint.ID()) || envelope.To == endpoint.ID() {
+				if envelope.To == endpoint.ID() && envelope.From == endpoint.ID() {
+					// Messages to self are not subject to the link conditions.
 					go func(endpoint *SimnetEndpoint, envelope Envelope) {
-						// Simulate network delay except for messages to self.
-						if envelope.From != endpoint.ID() {
-							// time.Sleep(100 * time.Millisecond)
-						}
 						endpoint.incoming <- envelope
-
 					}(endpoint, envelope)
+				} else if (envelope.To == "" && envelope.From != endpoint.ID()) || envelope.To == endpoint.ID() {
+					sn.deliver(endpoint, envelope)
 				}
 			}
 		}
@@ -137,6 +160,10 @@ type SimnetEndpoint struct {
 	handlers []p2p.MessageHandler
 	incoming chan Envelope
 	outgoing chan Envelope
+
+	mu      *sync.Mutex
+	reports map[string][]p2ptypes.Misbehavior // misbehaviors reported for each peer
+	bans    map[string]p2ptypes.BannedPeer
 }
 
 var _ p2p.Network = &SimnetEndpoint{}
@@ -148,8 +175,9 @@ func (se *SimnetEndpoint) Start(ctx context.Context) error {
 			select {
 			case envelope := <-se.incoming:
 				message := p2ptypes.Message{
-					PeerID:  envelope.From,
-					Content: envelope.Content,
+					PeerID:    envelope.From,
+					ChannelID: envelope.ChannelID,
+					Content:   envelope.Content,
 				}
 				se.HandleMessage(message)
 			}
@@ -180,7 +208,7 @@ func (se *SimnetEndpoint) Wait() {
 func (se *SimnetEndpoint) Broadcast(message p2ptypes.Message) (successes chan bool) {
 	successes = make(chan bool, 10)
 	go func() {
-		se.network.AddMessage(Envelope{From: se.ID(), Content: message.Content})
+		se.network.AddMessage(Envelope{From: se.ID(), ChannelID: message.ChannelID, Content: message.Content})
 		successes <- true
 	}()
 	return successes
@@ -189,7 +217,7 @@ func (se *SimnetEndpoint) Broadcast(message p2ptypes.Message) (successes chan bo
 // Send implements the Network interface.
 func (se *SimnetEndpoint) Send(id string, message p2ptypes.Message) bool {
 	go func() {
-		se.network.AddMessage(Envelope{From: se.ID(), To: id, Content: message.Content})
+		se.network.AddMessage(Envelope{From: se.ID(), To: id, ChannelID: message.ChannelID, Content: message.Content})
 	}()
 	return true
 }
@@ -204,6 +232,107 @@ func (se *SimnetEndpoint) ID() string {
 	return se.id
 }
 
+// Peers implements the Network interface. All the other endpoints of the Simnet are considered connected.
+func (se *SimnetEndpoint) Peers() []string {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	peerIDs := []string{}
+	for _, endpoint := range se.network.Endpoints {
+		if _, banned := se.bans[endpoint.ID()]; banned {
+			continue
+		}
+		if endpoint.ID() != se.ID() {
+			peerIDs = append(peerIDs, endpoint.ID())
+		}
+	}
+	return peerIDs
+}
+
+// ReportPeer implements the Network interface. The misbehaviors are recorded for inspection.
+func (se *SimnetEndpoint) ReportPeer(peerID string, misbehavior p2ptypes.Misbehavior) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	se.reports[peerID] = append(se.reports[peerID], misbehavior)
+}
+
+// Reports returns the misbehaviors reported for the given peer.
+func (se *SimnetEndpoint) Reports(peerID string) []p2ptypes.Misbehavior {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	return append([]p2ptypes.Misbehavior{}, se.reports[peerID]...)
+}
+
+// BanPeer implements the Network interface. Banned endpoints are excluded from Peers().
+func (se *SimnetEndpoint) BanPeer(peerID string, duration time.Duration, reason string) error {
+	if duration <= 0 {
+		return fmt.Errorf("Invalid ban duration: %v", duration)
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	se.bans[peerID] = p2ptypes.BannedPeer{
+		ID:     peerID,
+		Until:  time.Now().Add(duration),
+		Reason: reason,
+	}
+	return nil
+}
+
+// UnbanPeer implements the Network interface.
+func (se *SimnetEndpoint) UnbanPeer(peerID string) bool {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if _, ok := se.bans[peerID]; !ok {
+		return false
+	}
+	delete(se.bans, peerID)
+	return true
+}
+
+// PeerStates implements the Network interface.
+func (se *SimnetEndpoint) PeerStates() []p2ptypes.PeerState {
+	states := []p2ptypes.PeerState{}
+	for _, peerID := range se.Peers() {
+		score := 100
+		se.mu.Lock()
+		for _, misbehavior := range se.reports[peerID] {
+			score -= misbehavior.Penalty()
+		}
+		se.mu.Unlock()
+		states = append(states, p2ptypes.PeerState{ID: peerID, Score: score, Channels: []p2ptypes.ChannelStats{}})
+	}
+	return states
+}
+
+// BannedPeers implements the Network interface.
+func (se *SimnetEndpoint) BannedPeers() []p2ptypes.BannedPeer {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	bans := []p2ptypes.BannedPeer{}
+	for _, ban := range se.bans {
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
+// NetInfo implements the Network interface.
+func (se *SimnetEndpoint) NetInfo() p2ptypes.NetInfo {
+	peers := se.Peers()
+	return p2ptypes.NetInfo{
+		ID:             se.ID(),
+		ListenerAddrs:  []string{},
+		NumPeers:       len(peers),
+		AddrBookSize:   len(peers),
+		NumBannedPeers: len(se.BannedPeers()),
+	}
+}
+
 // HandleMessage implements the MessageHandler interface.
 func (se *SimnetEndpoint) HandleMessage(message p2ptypes.Message) error {
 	for _, handler := range se.handlers {
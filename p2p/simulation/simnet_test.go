@@ -106,3 +106,141 @@ func TestSimnetSend(t *testing.T) {
 	msgHandler.lock.Unlock()
 	assert.EqualValues([]string{"e1 -> world!"}, msgHandler.ReceivedMessages)
 }
+
+func (sm *SimMessageHandler) received() []string {
+	sm.lock.Lock()
+	defer sm.lock.Unlock()
+	res := append([]string{}, sm.ReceivedMessages...)
+	sort.Strings(res)
+	return res
+}
+
+func TestSimnetLatencyAndDrops(t *testing.T) {
+	assert := assert.New(t)
+	msgHandler := &SimMessageHandler{lock: &sync.Mutex{}}
+	simnet := NewSimnetWithHandler(msgHandler)
+	e1 := simnet.AddEndpoint("e1")
+	simnet.AddEndpoint("e2")
+	simnet.AddEndpoint("e3")
+	simnet.SetLinkConfig("e1", "e2", LinkConfig{Latency: 300 * time.Millisecond})
+	simnet.SetLinkConfig("e1", "e3", LinkConfig{DropRate: 1})
+	simnet.Start(context.Background())
+	defer simnet.Stop()
+
+	e1.Broadcast(createBlockMessage("hello!"))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(0, len(msgHandler.received()))
+
+	time.Sleep(500 * time.Millisecond)
+	assert.EqualValues([]string{"e1 -> hello!"}, msgHandler.received())
+
+	stats := simnet.Stats()
+	assert.Equal(uint64(2), stats.Sent)
+	assert.Equal(uint64(1), stats.Delivered)
+	assert.Equal(uint64(1), stats.Dropped)
+}
+
+func TestSimnetBandwidth(t *testing.T) {
+	assert := assert.New(t)
+	msgHandler := &SimMessageHandler{lock: &sync.Mutex{}}
+	simnet := NewSimnetWithHandler(msgHandler)
+	simnet.AddEndpoint("e1")
+	simnet.AddEndpoint("e2")
+	simnet.SetDefaultLinkConfig(LinkConfig{Bandwidth: 1000})
+	simnet.Start(context.Background())
+	defer simnet.Stop()
+
+	// Each message takes 200ms to transmit
+	for i := 0; i < 3; i++ {
+		simnet.AddMessage(Envelope{From: "e1", To: "e2", ChannelID: common.ChannelIDBlock, Content: string(make([]byte, 200))})
+	}
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(1, len(msgHandler.received()))
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(3, len(msgHandler.received()))
+}
+
+func TestSimnetInOrderDelivery(t *testing.T) {
+	assert := assert.New(t)
+	simnet := NewSimnet()
+	simnet.AddEndpoint("e1")
+	e2 := simnet.AddEndpoint("e2")
+	received := make(chan interface{}, 100)
+	e2.RegisterMessageHandler(&orderedMessageHandler{received: received})
+	simnet.SetDefaultLinkConfig(LinkConfig{Latency: 10 * time.Millisecond, Jitter: 50 * time.Millisecond})
+	simnet.Start(context.Background())
+	defer simnet.Stop()
+
+	for i := 0; i < 20; i++ {
+		simnet.AddMessage(Envelope{From: "e1", To: "e2", ChannelID: common.ChannelIDBlock, Content: i})
+	}
+	// The jitter does not reorder the messages on the same link without a reorder rate
+	for i := 0; i < 20; i++ {
+		select {
+		case content := <-received:
+			assert.Equal(i, content)
+		case <-time.After(2 * time.Second):
+			assert.FailNow("Message not received")
+		}
+	}
+}
+
+type orderedMessageHandler struct {
+	SimMessageHandler
+	received chan interface{}
+}
+
+func (om *orderedMessageHandler) HandleMessage(msg p2ptypes.Message) error {
+	om.received <- msg.Content
+	return nil
+}
+
+func TestSimnetPartition(t *testing.T) {
+	assert := assert.New(t)
+	msgHandler := &SimMessageHandler{lock: &sync.Mutex{}}
+	simnet := NewSimnetWithHandler(msgHandler)
+	e1 := simnet.AddEndpoint("e1")
+	simnet.AddEndpoint("e2")
+	e3 := simnet.AddEndpoint("e3")
+	simnet.Partition([]string{"e1", "e2"}, []string{"e3"})
+	simnet.ScheduleHeal(500 * time.Millisecond)
+	simnet.Start(context.Background())
+	defer simnet.Stop()
+
+	e1.Broadcast(createBlockMessage("hello!"))
+	e3.Broadcast(createBlockMessage("world!"))
+	time.Sleep(200 * time.Millisecond)
+	assert.EqualValues([]string{"e1 -> hello!"}, msgHandler.received())
+	assert.Equal(uint64(3), simnet.Stats().Partitioned)
+
+	time.Sleep(500 * time.Millisecond)
+	e3.Broadcast(createBlockMessage("again!"))
+	time.Sleep(200 * time.Millisecond)
+	assert.EqualValues([]string{"e1 -> hello!", "e3 -> again!", "e3 -> again!"}, msgHandler.received())
+}
+
+func TestSimnetDeterministicSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	run := func(seed int64) []string {
+		msgHandler := &SimMessageHandler{lock: &sync.Mutex{}}
+		simnet := NewSimnetWithHandler(msgHandler)
+		simnet.AddEndpoint("e1")
+		simnet.AddEndpoint("e2")
+		simnet.SetSeed(seed)
+		simnet.SetDefaultLinkConfig(LinkConfig{DropRate: 0.5, Jitter: 10 * time.Millisecond})
+		simnet.Start(context.Background())
+		defer simnet.Stop()
+
+		for i := 0; i < 50; i++ {
+			simnet.AddMessage(Envelope{From: "e1", To: "e2", ChannelID: common.ChannelIDBlock, Content: fmt.Sprintf("%02d", i)})
+		}
+		time.Sleep(300 * time.Millisecond)
+		return msgHandler.received()
+	}
+
+	r1 := run(42)
+	r2 := run(42)
+	assert.True(len(r1) > 0 && len(r1) < 50)
+	assert.EqualValues(r1, r2)
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/crypto"
@@ -23,6 +24,7 @@ type NodeInfo struct {
 	PubKey      *crypto.PublicKey `rlp:"-"`
 	PubKeyBytes common.Bytes      // needed for RLP serialization
 	Port        uint16
+	Compression []string `rlp:"optional"` // supported compression algorithms, in the order of preference
 }
 
 // CreateNodeInfo creates an instance of NodeInfo
@@ -43,6 +45,120 @@ const (
 	PongSignal = byte(0x1)
 )
 
+//
+// Misbehavior categorizes the misbehaviors of a peer, each of which lowers the score of the peer
+//
+type Misbehavior uint8
+
+const (
+	MisbehaviorUnparsableMessage Misbehavior = iota
+	MisbehaviorInvalidBlock
+	MisbehaviorInvalidHeader
+	MisbehaviorInvalidVote
+	MisbehaviorInvalidTx
+	MisbehaviorRequestSpam
+)
+
+func (m Misbehavior) String() string {
+	switch m {
+	case MisbehaviorUnparsableMessage:
+		return "unparsable message"
+	case MisbehaviorInvalidBlock:
+		return "invalid block"
+	case MisbehaviorInvalidHeader:
+		return "invalid header"
+	case MisbehaviorInvalidVote:
+		return "invalid vote"
+	case MisbehaviorInvalidTx:
+		return "invalid transaction"
+	case MisbehaviorRequestSpam:
+		return "request spam"
+	default:
+		return fmt.Sprintf("misbehavior %d", m)
+	}
+}
+
+// Penalty returns the score deducted from the peer for the misbehavior
+func (m Misbehavior) Penalty() int {
+	switch m {
+	case MisbehaviorInvalidBlock, MisbehaviorInvalidHeader:
+		return 50
+	case MisbehaviorUnparsableMessage, MisbehaviorInvalidVote:
+		return 25
+	default:
+		return 10
+	}
+}
+
+//
+// PeerState summarizes the state of a connected peer
+//
+type PeerState struct {
+	ID            string         `json:"id"`
+	Score         int            `json:"score"`
+	Address       string         `json:"address"`
+	IsOutbound    bool           `json:"is_outbound"`
+	IsPersistent  bool           `json:"is_persistent"`
+	Compression   string         `json:"compression"` // compression algorithm negotiated with the peer
+	ConnectedAt   time.Time      `json:"connected_at"`
+	Uptime        uint64         `json:"uptime"`       // in seconds
+	PingLatency   uint64         `json:"ping_latency"` // round trip time of the last ping in milliseconds, 0 if unknown
+	BytesSent     int64          `json:"bytes_sent"`
+	BytesReceived int64          `json:"bytes_received"`
+	BytesSaved    int64          `json:"bytes_saved"` // bytes saved by compressing the sent messages
+	Channels      []ChannelStats `json:"channels"`
+}
+
+//
+// ChannelStats summarizes the traffic of a connection on one channel
+//
+type ChannelStats struct {
+	ChannelID      common.ChannelIDEnum `json:"channel_id"`
+	Name           string               `json:"name"`
+	Priority       uint                 `json:"priority"`
+	MinShare       float64              `json:"min_share"`  // minimum fraction of the send bandwidth reserved for the channel
+	SendShare      float64              `json:"send_share"` // fraction of the recent send traffic of the connection
+	QueuedMessages int                  `json:"queued_messages"`
+	BytesSent      int64                `json:"bytes_sent"`
+	BytesReceived  int64                `json:"bytes_received"`
+	SendRate       int64                `json:"send_rate"` // in bytes per second
+	RecvRate       int64                `json:"recv_rate"` // in bytes per second
+}
+
+//
+// PeerLists holds the peer lists managed at runtime
+//
+type PeerLists struct {
+	PersistentAddrs []string `json:"persistent_addrs"` // addresses dialed on start and reconnected on errors
+	PrivatePeers    []string `json:"private_peers"`    // IDs of the peers never gossiped to other peers
+	Allowlist       []string `json:"allowlist"`        // peer IDs or IPs, if not empty only these peers are accepted
+	Denylist        []string `json:"denylist"`         // peer IDs or IPs always refused
+}
+
+//
+// NetInfo summarizes the network state of the node
+//
+type NetInfo struct {
+	ID               string   `json:"id"`
+	ListenerAddrs    []string `json:"listener_addrs"`
+	ExternalAddr     string   `json:"external_addr"`
+	UPNPEnabled      bool     `json:"upnp_enabled"` // whether the external address is mapped through UPnP
+	NumPeers         int      `json:"num_peers"`
+	NumOutboundPeers int      `json:"num_outbound_peers"`
+	AddrBookSize     int      `json:"addr_book_size"`
+	NumBannedPeers   int      `json:"num_banned_peers"`
+}
+
+//
+// BannedPeer records a banned peer
+//
+type BannedPeer struct {
+	ID     string    `json:"id"`
+	IP     string    `json:"ip"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
 type StackError struct {
 	Err   interface{}
 	Stack []byte
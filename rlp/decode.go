@@ -81,12 +81,18 @@ type Decoder interface {
 // error if there are too few or too many elements.
 //
 // The decoding of struct fields honours certain struct tags, "tail",
-// "nil" and "-".
+// "nil", "optional" and "-".
 //
 // The "-" tag ignores fields.
 //
 // For an explanation of "tail", see the example.
 //
+// The "optional" tag allows fields to be omitted from the end of the
+// input list. Omitted fields are set to their zero value. When encoding,
+// trailing optional fields with zero value are not written. Once a field
+// is optional, all subsequent fields must also be optional. This tag is
+// useful for adding new fields to a struct in a backward compatible way.
+//
 // The "nil" tag applies to pointer-typed fields and changes the decoding
 // rules for the field such that input values of size zero decode as a nil
 // pointer. This tag can be useful when decoding recursive types.
@@ -438,9 +444,16 @@ func makeStructDecoder(typ reflect.Type) (decoder, error) {
 		if _, err := s.List(); err != nil {
 			return wrapStreamError(err, typ)
 		}
-		for _, f := range fields {
+		for i, f := range fields {
 			err := f.info.decoder(s, val.Field(f.index))
-			if err == EOL {
+			if err == EOL && f.optional {
+				// The remaining optional fields were omitted, set them to zero
+				for _, rf := range fields[i:] {
+					fv := val.Field(rf.index)
+					fv.Set(reflect.Zero(fv.Type()))
+				}
+				break
+			} else if err == EOL {
 				return &decodeError{msg: "too few elements", typ: typ}
 			} else if err != nil {
 				return addErrorContext(err, "."+typ.Field(f.index).Name)
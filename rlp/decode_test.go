@@ -354,6 +354,22 @@ var (
 	)
 )
 
+type optionalFields struct {
+	A uint
+	B uint `rlp:"optional"`
+	C uint `rlp:"optional"`
+}
+
+type optionalBytes struct {
+	A uint
+	B []byte `rlp:"optional"`
+}
+
+type invalidOptional struct {
+	A uint `rlp:"optional"`
+	B uint
+}
+
 type hasIgnoredField struct {
 	A uint
 	B uint `rlp:"-"`
@@ -511,6 +527,38 @@ var decodeTests = []decodeTest{
 		value: tailRaw{A: 1, Tail: []RawValue{}},
 	},
 
+	// struct tag "optional"
+	{
+		input: "C101",
+		ptr:   new(optionalFields),
+		value: optionalFields{A: 1},
+	},
+	{
+		input: "C20102",
+		ptr:   new(optionalFields),
+		value: optionalFields{A: 1, B: 2},
+	},
+	{
+		input: "C3010203",
+		ptr:   new(optionalFields),
+		value: optionalFields{A: 1, B: 2, C: 3},
+	},
+	{
+		input: "C0",
+		ptr:   new(optionalFields),
+		error: "rlp: too few elements for rlp.optionalFields",
+	},
+	{
+		input: "C401020304",
+		ptr:   new(optionalFields),
+		error: "rlp: input list has too many elements for rlp.optionalFields",
+	},
+	{
+		input: "C20102",
+		ptr:   new(invalidOptional),
+		error: "rlp: struct field rlp.invalidOptional.B needs \"optional\" tag because preceding field \"A\" is optional",
+	},
+
 	// struct tag "-"
 	{
 		input: "C20102",
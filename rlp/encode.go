@@ -529,9 +529,17 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 	if err != nil {
 		return nil, err
 	}
+	firstOptional := firstOptionalField(fields)
 	writer := func(val reflect.Value, w *encbuf) error {
+		// Trailing optional fields with zero value are omitted
+		lastField := len(fields) - 1
+		for ; lastField >= firstOptional; lastField-- {
+			if !isZeroValue(val.Field(fields[lastField].index)) {
+				break
+			}
+		}
 		lh := w.list()
-		for _, f := range fields {
+		for _, f := range fields[:lastField+1] {
 			if err := f.info.writer(val.Field(f.index), w); err != nil {
 				return err
 			}
@@ -542,6 +550,15 @@ func makeStructWriter(typ reflect.Type) (writer, error) {
 	return writer, nil
 }
 
+// isZeroValue returns whether the optional field can be omitted. Empty slices are treated
+// as zero, since they encode the same as nil slices.
+func isZeroValue(val reflect.Value) bool {
+	if val.Kind() == reflect.Slice {
+		return val.Len() == 0
+	}
+	return reflect.DeepEqual(val.Interface(), reflect.Zero(val.Type()).Interface())
+}
+
 func makePtrWriter(typ reflect.Type) (writer, error) {
 	etypeinfo, err := cachedTypeInfo1(typ.Elem(), tags{})
 	if err != nil {
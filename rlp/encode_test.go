@@ -219,6 +219,13 @@ var encTests = []encTest{
 	{val: &tailRaw{A: 1, Tail: []RawValue{}}, output: "C101"},
 	{val: &tailRaw{A: 1, Tail: nil}, output: "C101"},
 	{val: &hasIgnoredField{A: 1, B: 2, C: 3}, output: "C20103"},
+	{val: &optionalFields{A: 1}, output: "C101"},
+	{val: &optionalFields{A: 1, B: 2}, output: "C20102"},
+	{val: &optionalFields{A: 1, C: 3}, output: "C3018003"},
+	{val: &optionalFields{A: 1, B: 2, C: 3}, output: "C3010203"},
+	{val: &optionalBytes{A: 1}, output: "C101"},
+	{val: &optionalBytes{A: 1, B: []byte{}}, output: "C101"},
+	{val: &optionalBytes{A: 1, B: []byte{2}}, output: "C20102"},
 
 	// nil
 	{val: (*uint)(nil), output: "80"},
@@ -0,0 +1,296 @@
+package rpc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/rpc"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/p2p"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
+)
+
+//
+// ThetaAdminRPCService provides the node administration RPCs. It is served on
+// a separate listener, which is bound to localhost unless protected by a token.
+//
+type ThetaAdminRPCService struct {
+	network p2p.Network
+}
+
+func (t *ThetaAdminRPCService) peerManager() (p2p.PeerManager, error) {
+	pm, ok := t.network.(p2p.PeerManager)
+	if !ok {
+		return nil, errors.New("Peer management is not supported by the network")
+	}
+	return pm, nil
+}
+
+// newAdminServer creates the http server of the admin RPC service
+func newAdminServer(network p2p.Network) *http.Server {
+	s := rpc.NewServer()
+	s.RegisterName("admin", &ThetaAdminRPCService{network: network})
+
+	handler := jsonrpc2.HTTPHandler(s)
+	token := viper.GetString(common.CfgRPCAdminToken)
+	return &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/rpc" {
+				http.NotFound(w, r)
+				return
+			}
+			if token != "" && !isAuthorized(r, token) {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}),
+	}
+}
+
+func isAuthorized(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	provided := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// checkAdminAddress refuses to expose the admin RPC service beyond localhost
+// without authentication.
+func checkAdminAddress(address string, token string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		return nil
+	}
+	if host == "localhost" {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("Admin RPC address %v is not a loopback address, %v must be set", address, common.CfgRPCAdminToken)
+	}
+	return nil
+}
+
+func (t *ThetaRPCServer) serveAdmin() {
+	address := viper.GetString(common.CfgRPCAdminAddress)
+	token := viper.GetString(common.CfgRPCAdminToken)
+	if err := checkAdminAddress(address, token); err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("Failed to start admin RPC server")
+		return
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("Failed to create admin listener")
+		return
+	}
+	defer l.Close()
+	logger.WithFields(log.Fields{"address": address}).Info("Admin RPC server started")
+
+	if err := t.adminServer.Serve(l); err != nil && err != http.ErrServerClosed {
+		logger.WithFields(log.Fields{"error": err}).Error("Admin RPC server stopped")
+	}
+}
+
+// ------------------------------- AddPeer -----------------------------------
+
+type AddPeerArgs struct {
+	Address    string `json:"address"`    // ip:port of the peer
+	Persistent bool   `json:"persistent"` // reconnect on disconnection, and save the address to the address book
+}
+
+type AddPeerResult struct {
+	Success bool `json:"success"`
+}
+
+// AddPeer dials a new peer.
+func (t *ThetaAdminRPCService) AddPeer(args *AddPeerArgs, result *AddPeerResult) (err error) {
+	if args.Address == "" {
+		return errors.New("Peer address must be specified")
+	}
+	pm, err := t.peerManager()
+	if err != nil {
+		return err
+	}
+	if err = pm.AddPeer(args.Address, args.Persistent); err != nil {
+		return err
+	}
+	result.Success = true
+	return nil
+}
+
+// ------------------------------- RemovePeer -----------------------------------
+
+type RemovePeerArgs struct {
+	PeerID string `json:"peer_id"`
+}
+
+type RemovePeerResult struct {
+	Success bool `json:"success"`
+}
+
+// RemovePeer disconnects a peer, and removes it from the persistent peers.
+func (t *ThetaAdminRPCService) RemovePeer(args *RemovePeerArgs, result *RemovePeerResult) (err error) {
+	if args.PeerID == "" {
+		return errors.New("Peer ID must be specified")
+	}
+	pm, err := t.peerManager()
+	if err != nil {
+		return err
+	}
+	result.Success = pm.DisconnectPeer(args.PeerID)
+	return nil
+}
+
+// ------------------------------- SetPrivatePeer -----------------------------------
+
+type SetPrivatePeerArgs struct {
+	PeerID  string `json:"peer_id"`
+	Private bool   `json:"private"`
+}
+
+type SetPrivatePeerResult struct {
+	Success bool `json:"success"`
+}
+
+// SetPrivatePeer marks a peer as private, so that its address is never gossiped to other peers.
+func (t *ThetaAdminRPCService) SetPrivatePeer(args *SetPrivatePeerArgs, result *SetPrivatePeerResult) (err error) {
+	if args.PeerID == "" {
+		return errors.New("Peer ID must be specified")
+	}
+	pm, err := t.peerManager()
+	if err != nil {
+		return err
+	}
+	pm.SetPrivatePeer(args.PeerID, args.Private)
+	result.Success = true
+	return nil
+}
+
+// ------------------------------- UpdateAccessList -----------------------------------
+
+type UpdateAccessListArgs struct {
+	List   string `json:"list"`   // "allow" or "deny"
+	Entry  string `json:"entry"`  // peer ID or IP address
+	Remove bool   `json:"remove"` // remove the entry from the list instead
+}
+
+type UpdateAccessListResult struct {
+	Success bool `json:"success"`
+}
+
+// UpdateAccessList adds an entry to, or removes it from the allowlist or the denylist.
+func (t *ThetaAdminRPCService) UpdateAccessList(args *UpdateAccessListArgs, result *UpdateAccessListResult) (err error) {
+	if args.Entry == "" {
+		return errors.New("Entry must be specified")
+	}
+	pm, err := t.peerManager()
+	if err != nil {
+		return err
+	}
+	switch args.List {
+	case "allow":
+		pm.UpdateAllowlist(args.Entry, !args.Remove)
+	case "deny":
+		pm.UpdateDenylist(args.Entry, !args.Remove)
+	default:
+		return fmt.Errorf("Unknown list: %v, should be either allow or deny", args.List)
+	}
+	result.Success = true
+	return nil
+}
+
+// ------------------------------- LookupPeer -----------------------------------
+
+type LookupPeerArgs struct {
+	PeerID string `json:"peer_id"` // the address of the node, e.g. a validator address
+}
+
+type LookupPeerResult struct {
+	Address string `json:"address"`
+}
+
+// LookupPeer finds the network address of a node by its ID.
+func (t *ThetaAdminRPCService) LookupPeer(args *LookupPeerArgs, result *LookupPeerResult) (err error) {
+	if args.PeerID == "" {
+		return errors.New("Peer ID must be specified")
+	}
+	pm, err := t.peerManager()
+	if err != nil {
+		return err
+	}
+	result.Address, err = pm.LookupPeer(args.PeerID)
+	return err
+}
+
+// ------------------------------- GetPeerLists -----------------------------------
+
+type GetPeerListsArgs struct{}
+
+type GetPeerListsResult struct {
+	p2ptypes.PeerLists
+}
+
+// GetPeerLists returns the persistent peers, the private peers, the allowlist and the denylist.
+func (t *ThetaAdminRPCService) GetPeerLists(args *GetPeerListsArgs, result *GetPeerListsResult) (err error) {
+	pm, err := t.peerManager()
+	if err != nil {
+		return err
+	}
+	result.PeerLists = pm.PeerLists()
+	return nil
+}
+
+// ------------------------------- BanPeer -----------------------------------
+
+type BanPeerArgs struct {
+	PeerID   string            `json:"peer_id"`
+	Duration common.JSONUint64 `json:"duration"` // in seconds, 0 means the configured ban duration
+	Reason   string            `json:"reason"`
+	Unban    bool              `json:"unban"` // lift the ban of the peer instead
+}
+
+type BanPeerResult struct {
+	Success bool `json:"success"`
+}
+
+// BanPeer manually bans a peer, or lifts the ban of a peer.
+func (t *ThetaAdminRPCService) BanPeer(args *BanPeerArgs, result *BanPeerResult) (err error) {
+	if args.PeerID == "" {
+		return errors.New("Peer ID must be specified")
+	}
+
+	if args.Unban {
+		result.Success = t.network.UnbanPeer(args.PeerID)
+		return nil
+	}
+
+	duration := time.Duration(args.Duration) * time.Second
+	if duration == 0 {
+		duration = time.Duration(viper.GetInt(common.CfgP2PBanDuration)) * time.Second
+	}
+	reason := args.Reason
+	if reason == "" {
+		reason = "banned through RPC"
+	}
+	if err = t.network.BanPeer(args.PeerID, duration, reason); err != nil {
+		return err
+	}
+	result.Success = true
+	return nil
+}
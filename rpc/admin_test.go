@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/p2p/simulation"
+)
+
+func TestCheckAdminAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(checkAdminAddress("127.0.0.1:16889", ""))
+	assert.Nil(checkAdminAddress("localhost:16889", ""))
+	assert.Nil(checkAdminAddress("[::1]:16889", ""))
+	assert.NotNil(checkAdminAddress("0.0.0.0:16889", ""))
+	assert.NotNil(checkAdminAddress(":16889", ""))
+	assert.Nil(checkAdminAddress("0.0.0.0:16889", "secret"))
+	assert.NotNil(checkAdminAddress("127.0.0.1", ""))
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	assert := assert.New(t)
+
+	r, _ := http.NewRequest("POST", "http://127.0.0.1:16889/rpc", nil)
+	assert.False(isAuthorized(r, "secret"))
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	assert.False(isAuthorized(r, "secret"))
+
+	r.Header.Set("Authorization", "secret")
+	assert.False(isAuthorized(r, "secret"))
+
+	r.Header.Set("Authorization", "Bearer secret")
+	assert.True(isAuthorized(r, "secret"))
+}
+
+func TestAdminBanPeer(t *testing.T) {
+	assert := assert.New(t)
+
+	simnet := simulation.NewSimnet()
+	net1 := simnet.AddEndpoint("node1")
+	simnet.AddEndpoint("node2")
+	service := &ThetaAdminRPCService{network: net1}
+
+	result := &BanPeerResult{}
+	assert.NotNil(service.BanPeer(&BanPeerArgs{}, result))
+
+	assert.Nil(service.BanPeer(&BanPeerArgs{PeerID: "node2", Duration: 60}, result))
+	assert.True(result.Success)
+	assert.Equal(0, len(net1.Peers()))
+	assert.Equal("banned through RPC", net1.BannedPeers()[0].Reason)
+
+	result = &BanPeerResult{}
+	assert.Nil(service.BanPeer(&BanPeerArgs{PeerID: "node2", Unban: true}, result))
+	assert.True(result.Success)
+	assert.Equal([]string{"node2"}, net1.Peers())
+}
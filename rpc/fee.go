@@ -0,0 +1,239 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/ledger/vm"
+)
+
+const (
+	// defaultNumFeeEstimationBlocks is the default number of recent finalized blocks sampled for fee estimation
+	defaultNumFeeEstimationBlocks uint64 = 20
+
+	// maxNumFeeEstimationBlocks is the max number of recent finalized blocks sampled for fee estimation
+	maxNumFeeEstimationBlocks uint64 = 200
+
+	// maxEstimateGasLimit is the upper bound of the gas limit searched by EstimateGas
+	maxEstimateGasLimit uint64 = 10000000
+)
+
+// ------------------------------- EstimateFee -----------------------------------
+
+type EstimateFeeArgs struct {
+	NumBlocks common.JSONUint64 `json:"num_blocks"` // number of recent finalized blocks to sample, 0 means the default
+}
+
+type FeeTier struct {
+	GasPrice  *common.JSONBig `json:"gas_price"`   // suggested gas price for smart contract transactions, in TFuelWei
+	SendTxFee *common.JSONBig `json:"send_tx_fee"` // suggested fee for a SendTx with one input and one output, in TFuelWei
+}
+
+type EstimateFeeResult struct {
+	Low             FeeTier           `json:"low"`
+	Medium          FeeTier           `json:"medium"`
+	High            FeeTier           `json:"high"`
+	NumBlocks       common.JSONUint64 `json:"num_blocks"`        // number of finalized blocks sampled
+	NumSampledTxs   common.JSONUint64 `json:"num_sampled_txs"`   // number of transactions sampled, including the pending ones
+	NumPendingTxs   common.JSONUint64 `json:"num_pending_txs"`   // number of transactions in the mempool
+	MinimumGasPrice *common.JSONBig   `json:"minimum_gas_price"` // minimum gas price accepted by the ledger
+}
+
+// EstimateFee suggests the gas price and fee tiers based on the effective gas prices of the transactions
+// in the recent finalized blocks and in the mempool. If the mempool holds more transactions than a block
+// can include, the medium and high tiers are raised to the price needed to be included in the next block.
+func (t *ThetaRPCService) EstimateFee(args *EstimateFeeArgs, result *EstimateFeeResult) (err error) {
+	numBlocks := uint64(args.NumBlocks)
+	if numBlocks == 0 {
+		numBlocks = defaultNumFeeEstimationBlocks
+	}
+	if numBlocks > maxNumFeeEstimationBlocks {
+		numBlocks = maxNumFeeEstimationBlocks
+	}
+
+	gasPrices := []*big.Int{}
+	numSampledBlocks := uint64(0)
+	blockHash := t.consensus.GetSummary().LastFinalizedBlock
+	for numSampledBlocks < numBlocks && !blockHash.IsEmpty() {
+		block, err := t.chain.FindBlock(blockHash)
+		if err != nil {
+			break
+		}
+		for _, rawTx := range block.Txs {
+			txInfo, res := t.ledger.GetTxInfo(rawTx)
+			if res.IsError() || txInfo.EffectiveGasPrice == nil || txInfo.EffectiveGasPrice.Sign() <= 0 {
+				continue // skip the special transactions (e.g. coinbase) which carry no fee
+			}
+			gasPrices = append(gasPrices, txInfo.EffectiveGasPrice)
+		}
+		numSampledBlocks++
+		if block.Height == 0 {
+			break
+		}
+		blockHash = block.Parent
+	}
+
+	pendingGasPrices := t.mempool.GetPendingGasPrices()
+	gasPrices = append(gasPrices, pendingGasPrices...)
+
+	minGasPrice := new(big.Int).SetUint64(types.MinimumGasPrice)
+	low, medium, high := estimateGasPrices(gasPrices, pendingGasPrices, minGasPrice, core.MaxNumRegularTxsPerBlock)
+
+	result.Low = newFeeTier(low)
+	result.Medium = newFeeTier(medium)
+	result.High = newFeeTier(high)
+	result.NumBlocks = common.JSONUint64(numSampledBlocks)
+	result.NumSampledTxs = common.JSONUint64(len(gasPrices))
+	result.NumPendingTxs = common.JSONUint64(len(pendingGasPrices))
+	result.MinimumGasPrice = (*common.JSONBig)(minGasPrice)
+
+	return nil
+}
+
+// estimateGasPrices returns the low, medium and high gas prices, i.e. the 25th, 50th and 90th percentiles
+// of the sampled gas prices, which include the pending ones. If there are more pending transactions than
+// a block can include, the medium and high prices are raised to the clearing price of the next block.
+func estimateGasPrices(gasPrices []*big.Int, pendingGasPrices []*big.Int, minGasPrice *big.Int, maxNumTxsPerBlock int) (low, medium, high *big.Int) {
+	sorted := append([]*big.Int{}, gasPrices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+
+	low = maxBigInt(percentile(sorted, 25), minGasPrice)
+	medium = maxBigInt(percentile(sorted, 50), minGasPrice)
+	high = maxBigInt(percentile(sorted, 90), minGasPrice)
+
+	// When the blocks are congested, only the transactions paying at least the clearing price
+	// can be included in the next block
+	if len(pendingGasPrices) > maxNumTxsPerBlock {
+		pending := append([]*big.Int{}, pendingGasPrices...)
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].Cmp(pending[j]) > 0
+		})
+		clearingPrice := new(big.Int).Add(pending[maxNumTxsPerBlock-1], big.NewInt(1))
+		medium = maxBigInt(medium, clearingPrice)
+		high = maxBigInt(high, medium)
+	}
+	return low, medium, high
+}
+
+func newFeeTier(gasPrice *big.Int) FeeTier {
+	sendTxGas := new(big.Int).SetUint64(2 * types.GasSendTxPerAccount) // one input and one output
+	return FeeTier{
+		GasPrice:  (*common.JSONBig)(gasPrice),
+		SendTxFee: (*common.JSONBig)(new(big.Int).Mul(gasPrice, sendTxGas)),
+	}
+}
+
+// percentile returns the p-th percentile of the sorted values, or nil if there is no value
+func percentile(sortedValues []*big.Int, p int) *big.Int {
+	if len(sortedValues) == 0 {
+		return nil
+	}
+	idx := (len(sortedValues) - 1) * p / 100
+	return sortedValues[idx]
+}
+
+func maxBigInt(a, b *big.Int) *big.Int {
+	if a == nil || a.Cmp(b) < 0 {
+		return new(big.Int).Set(b)
+	}
+	return new(big.Int).Set(a)
+}
+
+// ------------------------------- EstimateGas -----------------------------------
+
+type EstimateGasArgs struct {
+	SctxBytes string `json:"sctx_bytes"` // the gas limit of the SmartContractTx, if non-zero, caps the search
+}
+
+type EstimateGasResult struct {
+	GasLimit common.JSONUint64 `json:"gas_limit"` // minimal gas limit for the tx to execute successfully
+	GasUsed  common.JSONUint64 `json:"gas_used"`  // gas used when executed with the estimated gas limit
+}
+
+// EstimateGas runs the smart contract transaction against the latest ledger state, and binary searches
+// for the minimal gas limit with which the transaction executes without error. Similar to
+// CallSmartContract, it does NOT modify the global consensus state.
+func (t *ThetaRPCService) EstimateGas(args *EstimateGasArgs, result *EstimateGasResult) (err error) {
+	sctxBytes, err := hex.DecodeString(args.SctxBytes)
+	if err != nil {
+		return err
+	}
+
+	tx, err := types.TxFromBytes(sctxBytes)
+	if err != nil {
+		return err
+	}
+	sctx, ok := tx.(*types.SmartContractTx)
+	if !ok {
+		return fmt.Errorf("Failed to parse SmartContractTx: %v", args.SctxBytes)
+	}
+
+	hi := maxEstimateGasLimit
+	if sctx.GasLimit != 0 && sctx.GasLimit < hi {
+		hi = sctx.GasLimit
+	}
+
+	gasLimit, gasUsed, err := searchGasLimit(hi, func(gasLimit uint64) (uint64, error, error) {
+		return t.tryGasLimit(sctx, gasLimit)
+	})
+	if err != nil {
+		return err
+	}
+
+	result.GasLimit = common.JSONUint64(gasLimit)
+	result.GasUsed = common.JSONUint64(gasUsed)
+	return nil
+}
+
+// searchGasLimit binary searches for the minimal gas limit up to hi with which the trial executes
+// without VM error, and returns it along with the gas used by the trial with it
+func searchGasLimit(hi uint64, try func(gasLimit uint64) (gasUsed uint64, vmErr error, err error)) (uint64, uint64, error) {
+	gasUsed, vmErr, err := try(hi)
+	if err != nil {
+		return 0, 0, err
+	}
+	if vmErr != nil {
+		return 0, 0, fmt.Errorf("Transaction fails with gas limit %v: %v", hi, vmErr)
+	}
+
+	// The gas used is a lower bound of the gas limit. Binary search in (lo, hi]
+	lo := uint64(0)
+	if gasUsed > 0 {
+		lo = gasUsed - 1
+	}
+	hiGasUsed := gasUsed
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		gasUsed, vmErr, err := try(mid)
+		if err != nil {
+			return 0, 0, err
+		}
+		if vmErr != nil {
+			lo = mid
+		} else {
+			hi = mid
+			hiGasUsed = gasUsed
+		}
+	}
+	return hi, hiGasUsed, nil
+}
+
+// tryGasLimit executes the tx with the given gas limit on a snapshot of the latest ledger state
+func (t *ThetaRPCService) tryGasLimit(sctx *types.SmartContractTx, gasLimit uint64) (gasUsed uint64, vmErr error, err error) {
+	ledgerState, err := t.ledger.GetDeliveredSnapshot()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	trial := *sctx
+	trial.GasLimit = gasLimit
+	_, _, gasUsed, vmErr = vm.Execute(&trial, ledgerState)
+	return gasUsed, vmErr, nil
+}
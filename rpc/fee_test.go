@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func toBigInts(values ...int64) []*big.Int {
+	ret := []*big.Int{}
+	for _, v := range values {
+		ret = append(ret, big.NewInt(v))
+	}
+	return ret
+}
+
+func TestEstimateGasPrices(t *testing.T) {
+	assert := assert.New(t)
+
+	minGasPrice := big.NewInt(4)
+
+	// No samples
+	low, medium, high := estimateGasPrices(nil, nil, minGasPrice, 10)
+	assert.Equal(int64(4), low.Int64())
+	assert.Equal(int64(4), medium.Int64())
+	assert.Equal(int64(4), high.Int64())
+
+	// Percentiles of the samples, not lower than the minimum gas price
+	gasPrices := []*big.Int{}
+	for i := int64(100); i > 0; i-- {
+		gasPrices = append(gasPrices, big.NewInt(i))
+	}
+	low, medium, high = estimateGasPrices(gasPrices, nil, minGasPrice, 10)
+	assert.Equal(int64(25), low.Int64())
+	assert.Equal(int64(50), medium.Int64())
+	assert.Equal(int64(90), high.Int64())
+	assert.Equal(int64(100), gasPrices[0].Int64()) // the samples are not reordered
+
+	low, medium, high = estimateGasPrices(gasPrices, nil, big.NewInt(30), 10)
+	assert.Equal(int64(30), low.Int64())
+	assert.Equal(int64(50), medium.Int64())
+	assert.Equal(int64(90), high.Int64())
+
+	// Congested, the 3 pending txs paying the most fill the next block
+	pending := toBigInts(10, 50, 40, 20)
+	gasPrices = append(toBigInts(1, 1, 1, 1), pending...)
+	low, medium, high = estimateGasPrices(gasPrices, pending, big.NewInt(1), 3)
+	assert.Equal(int64(1), low.Int64())
+	assert.Equal(int64(21), medium.Int64())
+	assert.Equal(int64(40), high.Int64())
+
+	// Not congested
+	low, medium, high = estimateGasPrices(gasPrices, pending, big.NewInt(1), 4)
+	assert.Equal(int64(1), low.Int64())
+	assert.Equal(int64(1), medium.Int64())
+	assert.Equal(int64(40), high.Int64())
+}
+
+func TestSearchGasLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	// The trial needs a gas limit of 53000, but only uses 50000 of it
+	errOutOfGas := errors.New("out of gas")
+	numTrials := 0
+	try := func(gasLimit uint64) (uint64, error, error) {
+		numTrials++
+		if gasLimit < 53000 {
+			return gasLimit, errOutOfGas, nil
+		}
+		return 50000, nil, nil
+	}
+
+	gasLimit, gasUsed, err := searchGasLimit(10000000, try)
+	assert.Nil(err)
+	assert.Equal(uint64(53000), gasLimit)
+	assert.Equal(uint64(50000), gasUsed)
+	assert.True(numTrials < 30)
+
+	gasLimit, gasUsed, err = searchGasLimit(53000, try)
+	assert.Nil(err)
+	assert.Equal(uint64(53000), gasLimit)
+	assert.Equal(uint64(50000), gasUsed)
+
+	// Fails with the highest gas limit
+	_, _, err = searchGasLimit(52999, try)
+	assert.NotNil(err)
+
+	// Errors other than the VM errors abort the search
+	_, _, err = searchGasLimit(10000000, func(gasLimit uint64) (uint64, error, error) {
+		return 0, nil, errors.New("state not available")
+	})
+	assert.NotNil(err)
+}
+
+func TestEstimateGasInvalidTx(t *testing.T) {
+	assert := assert.New(t)
+
+	service := &ThetaRPCService{}
+	result := &EstimateGasResult{}
+
+	assert.NotNil(service.EstimateGas(&EstimateGasArgs{SctxBytes: "zz"}, result))
+
+	// The tx type of a SmartContractTx without the tx body
+	assert.NotNil(service.EstimateGas(&EstimateGasArgs{SctxBytes: "07"}, result))
+}
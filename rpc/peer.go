@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"github.com/thetatoken/theta/common"
+	p2ptypes "github.com/thetatoken/theta/p2p/types"
+)
+
+// ------------------------------- GetPeers -----------------------------------
+
+type GetPeersArgs struct{}
+
+type PeerInfo struct {
+	p2ptypes.PeerState
+	LastHeight common.JSONUint64 `json:"last_height"` // last block height advertised by the peer, 0 if unknown
+}
+
+type GetPeersResult struct {
+	Peers       []PeerInfo            `json:"peers"`
+	BannedPeers []p2ptypes.BannedPeer `json:"banned_peers"`
+}
+
+// GetPeers returns the connected peers with their connection details and scores, and the currently banned peers.
+func (t *ThetaRPCService) GetPeers(args *GetPeersArgs, result *GetPeersResult) (err error) {
+	result.Peers = []PeerInfo{}
+	for _, state := range t.network.PeerStates() {
+		result.Peers = append(result.Peers, PeerInfo{
+			PeerState:  state,
+			LastHeight: common.JSONUint64(t.syncMgr.GetPeerHeight(state.ID)),
+		})
+	}
+	result.BannedPeers = t.network.BannedPeers()
+	return nil
+}
+
+// ------------------------------- GetNetInfo -----------------------------------
+
+type GetNetInfoArgs struct{}
+
+type GetNetInfoResult struct {
+	p2ptypes.NetInfo
+}
+
+// GetNetInfo returns the listener addresses, the external address and the peer counts of the node.
+func (t *ThetaRPCService) GetNetInfo(args *GetNetInfoArgs, result *GetNetInfoResult) (err error) {
+	result.NetInfo = t.network.NetInfo()
+	return nil
+}
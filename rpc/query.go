@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/execution"
 	"github.com/thetatoken/theta/ledger/state"
 	"github.com/thetatoken/theta/ledger/types"
 )
@@ -172,6 +174,9 @@ const (
 	TxTypeSmartContract
 	TxTypeDepositStake
 	TxTypeWithdrawStake
+	TxTypeUnjail
+	TxTypeClaimStakeReward
+	TxTypeRegisterValidator
 )
 
 func (t *ThetaRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult) (err error) {
@@ -288,6 +293,10 @@ type GetStatusResult struct {
 	LatestFinalizedBlockEpoch  common.JSONUint64 `json:"latest_finalized_block_epoch"`
 	CurrentEpoch               common.JSONUint64 `json:"current_epoch"`
 	CurrentTime                *common.JSONBig   `json:"current_time"`
+	Syncing                    bool              `json:"syncing"`
+	EpochTimeout               common.JSONUint64 `json:"epoch_timeout"` // in milliseconds
+	ProposalWait               common.JSONUint64 `json:"proposal_wait"` // in milliseconds
+	NumFailedEpochs            common.JSONUint64 `json:"num_failed_epochs"`
 }
 
 func (t *ThetaRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult) (err error) {
@@ -305,6 +314,38 @@ func (t *ThetaRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult
 	}
 	result.CurrentEpoch = common.JSONUint64(s.Epoch)
 	result.CurrentTime = (*common.JSONBig)(big.NewInt(time.Now().Unix()))
+	result.Syncing = t.syncMgr.GetSyncStatus().Syncing
+	result.EpochTimeout = common.JSONUint64(s.Timeouts.EpochTimeout / time.Millisecond)
+	result.ProposalWait = common.JSONUint64(s.Timeouts.ProposalWait / time.Millisecond)
+	result.NumFailedEpochs = common.JSONUint64(s.Timeouts.NumFailedEpochs)
+	return
+}
+
+// ------------------------------ GetSyncStatus -----------------------------------
+
+type GetSyncStatusArgs struct{}
+
+type GetSyncStatusResult struct {
+	Syncing           bool              `json:"syncing"`
+	StartingHeight    common.JSONUint64 `json:"starting_height"`     // latest finalized block height when the node started
+	CurrentHeight     common.JSONUint64 `json:"current_height"`      // latest finalized block height
+	HighestPeerHeight common.JSONUint64 `json:"highest_peer_height"` // highest block height advertised by the peers
+	HeaderHeight      common.JSONUint64 `json:"header_height"`       // height of the highest downloaded header waiting for its block
+	NumPendingHeaders common.JSONUint64 `json:"num_pending_headers"`
+	BlocksPerSecond   string            `json:"blocks_per_second"`
+	ETASeconds        common.JSONUint64 `json:"eta_seconds"` // estimated seconds to catch up, 0 if unknown
+}
+
+func (t *ThetaRPCService) GetSyncStatus(args *GetSyncStatusArgs, result *GetSyncStatusResult) (err error) {
+	s := t.syncMgr.GetSyncStatus()
+	result.Syncing = s.Syncing
+	result.StartingHeight = common.JSONUint64(s.StartingHeight)
+	result.CurrentHeight = common.JSONUint64(s.CurrentHeight)
+	result.HighestPeerHeight = common.JSONUint64(s.HighestPeerHeight)
+	result.HeaderHeight = common.JSONUint64(s.HeaderHeight)
+	result.NumPendingHeaders = common.JSONUint64(s.NumPendingHeaders)
+	result.BlocksPerSecond = fmt.Sprintf("%.2f", s.BlocksPerSecond)
+	result.ETASeconds = common.JSONUint64(s.ETA / time.Second)
 	return
 }
 
@@ -351,6 +392,168 @@ func (t *ThetaRPCService) GetVcpByHeight(args *GetVcpByHeightArgs, result *GetVc
 	return nil
 }
 
+// ------------------------------ GetPendingValidatorSet -----------------------------------
+
+type GetPendingValidatorSetArgs struct{}
+
+type GetPendingValidatorSetResult struct {
+	Height            common.JSONUint64 `json:"height"`
+	ActiveValidators  []core.Validator  `json:"active_validators"`
+	PendingValidators []core.Validator  `json:"pending_validators"` // empty if no change is scheduled
+	ActivationHeight  common.JSONUint64 `json:"activation_height"`
+	ActivationEpoch   common.JSONUint64 `json:"activation_epoch"`
+}
+
+func (t *ThetaRPCService) GetPendingValidatorSet(args *GetPendingValidatorSetArgs, result *GetPendingValidatorSetResult) (err error) {
+	finalizedView, err := t.ledger.GetFinalizedSnapshot()
+	if err != nil {
+		return err
+	}
+	vcp := finalizedView.GetValidatorCandidatePool()
+	if vcp == nil {
+		return errors.New("Validator candidate pool not found")
+	}
+
+	result.Height = common.JSONUint64(finalizedView.Height())
+	result.ActiveValidators = consensus.SelectTopStakeHoldersAsValidators(vcp).Validators()
+	result.PendingValidators = []core.Validator{}
+	if pending := vcp.GetPendingValidatorSet(); pending != nil {
+		result.PendingValidators = pending.Validators()
+		result.ActivationHeight = common.JSONUint64(vcp.PendingActivationHeight)
+		result.ActivationEpoch = common.JSONUint64(vcp.PendingActivationEpoch)
+	}
+
+	return nil
+}
+
+// ------------------------------ GetValidatorLiveness -----------------------------------
+
+type GetValidatorLivenessArgs struct {
+	Address string `json:"address"` // optional, all the stake holders if empty
+}
+
+type ValidatorLivenessResult struct {
+	Address            common.Address    `json:"address"`
+	Jailed             bool              `json:"jailed"`
+	Uptime             string            `json:"uptime"` // percentage of the commit certificates in the window voted in
+	WindowSize         common.JSONUint64 `json:"window_size"`
+	NumMissedVotes     common.JSONUint64 `json:"num_missed_votes"`
+	NumProposedBlocks  common.JSONUint64 `json:"num_proposed_blocks"`
+	NumMissedProposals common.JSONUint64 `json:"num_missed_proposals"`
+}
+
+type GetValidatorLivenessResult struct {
+	Height     common.JSONUint64         `json:"height"`
+	Validators []ValidatorLivenessResult `json:"validators"`
+}
+
+func (t *ThetaRPCService) GetValidatorLiveness(args *GetValidatorLivenessArgs, result *GetValidatorLivenessResult) (err error) {
+	finalizedView, err := t.ledger.GetFinalizedSnapshot()
+	if err != nil {
+		return err
+	}
+	vcp := finalizedView.GetValidatorCandidatePool()
+	if vcp == nil {
+		return errors.New("Validator candidate pool not found")
+	}
+
+	result.Height = common.JSONUint64(finalizedView.Height())
+	result.Validators = []ValidatorLivenessResult{}
+	for _, stakeHolder := range vcp.SortedCandidates {
+		if args.Address != "" && stakeHolder.Holder != common.HexToAddress(args.Address) {
+			continue
+		}
+		vl := finalizedView.GetValidatorLiveness(stakeHolder.Holder)
+		if vl == nil {
+			vl = core.NewValidatorLiveness(stakeHolder.Holder)
+		}
+		result.Validators = append(result.Validators, ValidatorLivenessResult{
+			Address:            stakeHolder.Holder,
+			Jailed:             stakeHolder.Jailed,
+			Uptime:             fmt.Sprintf("%.2f", vl.Uptime()),
+			WindowSize:         common.JSONUint64(vl.WindowSize()),
+			NumMissedVotes:     common.JSONUint64(vl.NumMissedVotes),
+			NumProposedBlocks:  common.JSONUint64(vl.NumProposedBlocks),
+			NumMissedProposals: common.JSONUint64(vl.NumMissedProposals),
+		})
+	}
+	if args.Address != "" && len(result.Validators) == 0 {
+		return fmt.Errorf("Stake holder %v not found", args.Address)
+	}
+
+	return nil
+}
+
+// ------------------------------ GetStakeRewards -----------------------------------
+
+type GetStakeRewardsArgs struct {
+	Source string `json:"source"`
+	Holder string `json:"holder"` // optional, all the stake holders of the source if empty
+}
+
+type StakeRewardResult struct {
+	Holder    common.Address `json:"holder"`
+	Amount    *big.Int       `json:"amount"`
+	Withdrawn bool           `json:"withdrawn"`
+	Pending   types.Coins    `json:"pending"` // rewards earned but not claimed yet
+	Claimed   types.Coins    `json:"claimed"`
+	Slashed   *big.Int       `json:"slashed"` // ThetaWei slashed from the stake
+}
+
+type GetStakeRewardsResult struct {
+	Height  common.JSONUint64   `json:"height"`
+	Source  common.Address      `json:"source"`
+	Rewards []StakeRewardResult `json:"rewards"`
+}
+
+func (t *ThetaRPCService) GetStakeRewards(args *GetStakeRewardsArgs, result *GetStakeRewardsResult) (err error) {
+	if args.Source == "" {
+		return errors.New("Source address must be specified")
+	}
+	source := common.HexToAddress(args.Source)
+
+	finalizedView, err := t.ledger.GetFinalizedSnapshot()
+	if err != nil {
+		return err
+	}
+	vcp := finalizedView.GetValidatorCandidatePool()
+	if vcp == nil {
+		return errors.New("Validator candidate pool not found")
+	}
+
+	result.Height = common.JSONUint64(finalizedView.Height())
+	result.Source = source
+	result.Rewards = getStakeRewards(finalizedView, vcp, source, args.Holder)
+
+	return nil
+}
+
+// getStakeRewards returns the rewards of the stakes deposited by the source, to the given holder only
+// if it is not empty
+func getStakeRewards(view *state.StoreView, vcp *core.ValidatorCandidatePool, source common.Address, holder string) []StakeRewardResult {
+	rewards := []StakeRewardResult{}
+	for _, stakeHolder := range vcp.SortedCandidates {
+		if holder != "" && stakeHolder.Holder != common.HexToAddress(holder) {
+			continue
+		}
+		for _, stake := range stakeHolder.Stakes {
+			if stake.Source != source {
+				continue
+			}
+			pending, claimed, slashed := execution.PendingStakeReward(view, stakeHolder.Holder, stake)
+			rewards = append(rewards, StakeRewardResult{
+				Holder:    stakeHolder.Holder,
+				Amount:    stake.Amount,
+				Withdrawn: stake.Withdrawn,
+				Pending:   pending,
+				Claimed:   claimed,
+				Slashed:   slashed,
+			})
+		}
+	}
+	return rewards
+}
+
 // ------------------------------ Utils ------------------------------
 
 func getTxType(tx types.Tx) byte {
@@ -376,6 +579,12 @@ func getTxType(tx types.Tx) byte {
 		t = TxTypeDepositStake
 	case *types.WithdrawStakeTx:
 		t = TxTypeWithdrawStake
+	case *types.UnjailTx:
+		t = TxTypeUnjail
+	case *types.ClaimStakeRewardTx:
+		t = TxTypeClaimStakeReward
+	case *types.RegisterValidatorTx:
+		t = TxTypeRegisterValidator
 	}
 
 	return t
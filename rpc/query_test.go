@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/ledger/execution"
+	"github.com/thetatoken/theta/ledger/state"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/store/database/backend"
+)
+
+func TestGetStakeRewards(t *testing.T) {
+	assert := assert.New(t)
+
+	holderA := common.HexToAddress("0x1")
+	holderB := common.HexToAddress("0x2")
+	source := common.HexToAddress("0x3")
+	minDeposit := core.MinValidatorStakeDeposit
+	vcp := &core.ValidatorCandidatePool{}
+	assert.Nil(vcp.DepositStake(holderA, holderA, minDeposit))
+	assert.Nil(vcp.DepositStake(source, holderA, minDeposit))
+	assert.Nil(vcp.DepositStake(source, holderB, minDeposit))
+
+	view := state.NewStoreView(common.HeightEnableValidatorRewards, common.Hash{}, backend.NewMemDatabase())
+	view.SetStakeRewardIndex(holderA, types.Coins{
+		ThetaWei: big.NewInt(0),
+		TFuelWei: new(big.Int).Div(types.StakeRewardPrecision, big.NewInt(1e6)),
+	})
+	slashed, err := execution.SlashStake(view, vcp, holderA, 10)
+	assert.Nil(err)
+	assert.Equal(new(big.Int).Div(minDeposit, big.NewInt(5)), slashed)
+	assert.Nil(vcp.WithdrawStake(source, holderB, view.Height()))
+	view.UpdateValidatorCandidatePool(vcp)
+
+	rewards := getStakeRewards(view, vcp, source, "")
+	assert.Equal(2, len(rewards))
+	for _, reward := range rewards {
+		switch reward.Holder {
+		case holderA:
+			// Earned before the slashing, on the full stake
+			assert.Equal(new(big.Int).Sub(minDeposit, new(big.Int).Div(minDeposit, big.NewInt(10))), reward.Amount)
+			assert.False(reward.Withdrawn)
+			expected := types.Coins{ThetaWei: big.NewInt(0), TFuelWei: new(big.Int).Div(minDeposit, big.NewInt(1e6))}
+			assert.True(expected.IsEqual(reward.Pending), reward.Pending.String())
+			assert.True(reward.Claimed.IsZero())
+			assert.Equal(new(big.Int).Div(minDeposit, big.NewInt(10)), reward.Slashed)
+		case holderB:
+			assert.Equal(minDeposit, reward.Amount)
+			assert.True(reward.Withdrawn)
+			assert.True(reward.Pending.IsZero())
+			assert.Equal(0, reward.Slashed.Sign())
+		default:
+			assert.Fail("Unexpected holder", reward.Holder.Hex())
+		}
+	}
+
+	// Filtered by the holder
+	rewards = getStakeRewards(view, vcp, source, holderB.Hex())
+	assert.Equal(1, len(rewards))
+	assert.Equal(holderB, rewards[0].Holder)
+
+	// No rewards for a source without stakes
+	assert.Equal(0, len(getStakeRewards(view, vcp, common.HexToAddress("0x4"), "")))
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/thetatoken/theta/consensus"
 	"github.com/thetatoken/theta/ledger"
 	"github.com/thetatoken/theta/mempool"
+	"github.com/thetatoken/theta/netsync"
+	"github.com/thetatoken/theta/p2p"
 	"github.com/thetatoken/theta/rpc/lib/rpc-codec/jsonrpc2"
 	"golang.org/x/net/netutil"
 	"golang.org/x/net/websocket"
@@ -30,6 +32,8 @@ type ThetaRPCService struct {
 	ledger    *ledger.Ledger
 	chain     *blockchain.Chain
 	consensus *consensus.ConsensusEngine
+	syncMgr   *netsync.SyncManager
+	network   p2p.Network
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -46,10 +50,12 @@ type ThetaRPCServer struct {
 	handler  *rpc.Server
 	router   *mux.Router
 	listener net.Listener
+
+	adminServer *http.Server
 }
 
 // NewThetaRPCServer creates a new instance of ThetaRPCServer.
-func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *blockchain.Chain, consensus *consensus.ConsensusEngine) *ThetaRPCServer {
+func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *blockchain.Chain, consensus *consensus.ConsensusEngine, syncMgr *netsync.SyncManager, network p2p.Network) *ThetaRPCServer {
 	t := &ThetaRPCServer{
 		ThetaRPCService: &ThetaRPCService{
 			wg: &sync.WaitGroup{},
@@ -60,6 +66,8 @@ func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *b
 	t.ledger = ledger
 	t.chain = chain
 	t.consensus = consensus
+	t.syncMgr = syncMgr
+	t.network = network
 
 	s := rpc.NewServer()
 	s.RegisterName("theta", t.ThetaRPCService)
@@ -76,6 +84,10 @@ func NewThetaRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, chain *b
 		Handler: t.router,
 	}
 
+	if viper.GetBool(common.CfgRPCAdminEnabled) {
+		t.adminServer = newAdminServer(network)
+	}
+
 	logger = util.GetLoggerForModule("rpc")
 
 	return t
@@ -98,10 +110,16 @@ func (t *ThetaRPCServer) mainLoop() {
 	defer t.wg.Done()
 
 	go t.serve()
+	if t.adminServer != nil {
+		go t.serveAdmin()
+	}
 
 	<-t.ctx.Done()
 	t.stopped = true
 	t.server.Shutdown(t.ctx)
+	if t.adminServer != nil {
+		t.adminServer.Shutdown(t.ctx)
+	}
 }
 
 func (t *ThetaRPCServer) serve() {
@@ -3,9 +3,11 @@ package rpc
 import (
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/thetatoken/theta/common"
 	"github.com/thetatoken/theta/core"
 	"github.com/thetatoken/theta/crypto"
@@ -110,6 +112,10 @@ type BroadcastRawTransactionResult struct {
 
 func (t *ThetaRPCService) BroadcastRawTransaction(
 	args *BroadcastRawTransactionArgs, result *BroadcastRawTransactionResult) (err error) {
+	if err := t.checkSyncing(); err != nil {
+		return err
+	}
+
 	txBytes, err := hex.DecodeString(args.TxBytes)
 	if err != nil {
 		return err
@@ -154,6 +160,10 @@ type BroadcastRawTransactionAsyncResult struct {
 
 func (t *ThetaRPCService) BroadcastRawTransactionAsync(
 	args *BroadcastRawTransactionAsyncArgs, result *BroadcastRawTransactionAsyncResult) (err error) {
+	if err := t.checkSyncing(); err != nil {
+		return err
+	}
+
 	txBytes, err := hex.DecodeString(args.TxBytes)
 	if err != nil {
 		return err
@@ -166,3 +176,16 @@ func (t *ThetaRPCService) BroadcastRawTransactionAsync(
 
 	return t.mempool.InsertTransaction(txBytes)
 }
+
+// checkSyncing refuses the transaction broadcasts while the node is syncing, if configured so,
+// since the transactions would be screened against a stale ledger state.
+func (t *ThetaRPCService) checkSyncing() error {
+	if !viper.GetBool(common.CfgRPCRefuseTxsWhileSyncing) {
+		return nil
+	}
+	s := t.syncMgr.GetSyncStatus()
+	if s.Syncing {
+		return fmt.Errorf("Node is syncing, current height: %v, highest peer height: %v", s.CurrentHeight, s.HighestPeerHeight)
+	}
+	return nil
+}
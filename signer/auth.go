@@ -0,0 +1,60 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	authChallengeLength = 32
+	authTimeout         = 5 * time.Second
+)
+
+// authResponse proves the knowledge of the shared secret for the given challenge
+func authResponse(secret string, challenge []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("theta-signer"))
+	mac.Write(challenge)
+	return mac.Sum(nil)
+}
+
+// authenticateClient sends a random challenge to the client, and verifies that the client
+// responds with the HMAC of the challenge keyed by the shared secret.
+func authenticateClient(conn net.Conn, secret string) error {
+	conn.SetDeadline(time.Now().Add(authTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	challenge := make([]byte, authChallengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return err
+	}
+	if _, err := conn.Write(challenge); err != nil {
+		return err
+	}
+	response := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return err
+	}
+	if !hmac.Equal(response, authResponse(secret, challenge)) {
+		return fmt.Errorf("Invalid signer secret")
+	}
+	return nil
+}
+
+// authenticateToServer answers the challenge of the signer server with the shared secret
+func authenticateToServer(conn net.Conn, secret string) error {
+	conn.SetDeadline(time.Now().Add(authTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	challenge := make([]byte, authChallengeLength)
+	if _, err := io.ReadFull(conn, challenge); err != nil {
+		return err
+	}
+	_, err := conn.Write(authResponse(secret, challenge))
+	return err
+}
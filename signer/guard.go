@@ -0,0 +1,179 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+)
+
+//
+// SignState records the latest block and vote signed with the validator key
+//
+type SignState struct {
+	HasSignedBlock bool        `json:"has_signed_block"`
+	BlockEpoch     uint64      `json:"block_epoch"`
+	BlockHeight    uint64      `json:"block_height"`
+	BlockSignHash  common.Hash `json:"block_sign_hash"` // Keccak256 of the sign bytes of the block
+	HasSignedVote  bool        `json:"has_signed_vote"`
+	VoteHeight     uint64      `json:"vote_height"`
+	VoteEpoch      uint64      `json:"vote_epoch"` // highest epoch of the votes at the vote height
+	VoteBlock      common.Hash `json:"vote_block"`
+}
+
+//
+// SignGuard refuses to sign a block or a vote that conflicts with the ones signed
+// before, so that a validator never signs two different blocks in the same epoch, or
+// votes for two different blocks at the same height. Signing the same block or vote
+// again is allowed, e.g. for repeating a vote in a later epoch. If the file path is
+// not empty, the state is persisted before each signature is released.
+//
+type SignGuard struct {
+	mu       *sync.Mutex
+	filePath string
+	state    SignState
+}
+
+// NewSignGuard creates a guard with the state loaded from the given file. An empty
+// file path creates a guard that only keeps the state in memory.
+func NewSignGuard(filePath string) (*SignGuard, error) {
+	sg := &SignGuard{
+		mu:       &sync.Mutex{},
+		filePath: filePath,
+	}
+	if len(filePath) == 0 {
+		return sg, nil
+	}
+
+	raw, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return sg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &sg.state); err != nil {
+		return nil, fmt.Errorf("Failed to parse the sign state %v: %v", filePath, err)
+	}
+	return sg, nil
+}
+
+// IsFresh returns true if nothing has been signed under the guard, e.g. when the state
+// file is missing
+func (sg *SignGuard) IsFresh() bool {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	return !sg.state.HasSignedBlock && !sg.state.HasSignedVote
+}
+
+// State returns the latest signed block and vote
+func (sg *SignGuard) State() SignState {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	return sg.state
+}
+
+// CheckAndRecordBlock checks the block header against the sign state, and records
+// it if it does not conflict
+func (sg *SignGuard) CheckAndRecordBlock(header *core.BlockHeader) error {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	signHash := crypto.Keccak256Hash(header.SignBytes())
+	state := sg.state
+	if state.HasSignedBlock {
+		if header.Epoch < state.BlockEpoch {
+			return fmt.Errorf("Refused to sign block for epoch %v, already signed a block for epoch %v", header.Epoch, state.BlockEpoch)
+		}
+		if header.Epoch == state.BlockEpoch {
+			if signHash == state.BlockSignHash {
+				return nil
+			}
+			return fmt.Errorf("Refused to sign a different block for epoch %v", header.Epoch)
+		}
+	}
+
+	state.HasSignedBlock = true
+	state.BlockEpoch = header.Epoch
+	state.BlockHeight = header.Height
+	state.BlockSignHash = signHash
+	return sg.update(state)
+}
+
+// CheckAndRecordVote checks the vote against the sign state, and records it if it
+// does not conflict
+func (sg *SignGuard) CheckAndRecordVote(vote core.Vote) error {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	state := sg.state
+	if state.HasSignedVote {
+		if vote.Height < state.VoteHeight {
+			return fmt.Errorf("Refused to vote at height %v, already voted at height %v", vote.Height, state.VoteHeight)
+		}
+		if vote.Height == state.VoteHeight {
+			if vote.Block != state.VoteBlock {
+				return fmt.Errorf("Refused to vote for a different block at height %v", vote.Height)
+			}
+			if vote.Epoch <= state.VoteEpoch {
+				return nil
+			}
+			// Repeating the vote in a later epoch
+			state.VoteEpoch = vote.Epoch
+			return sg.update(state)
+		}
+	}
+
+	state.HasSignedVote = true
+	state.VoteHeight = vote.Height
+	state.VoteEpoch = vote.Epoch
+	state.VoteBlock = vote.Block
+	return sg.update(state)
+}
+
+// Seed records the latest block and vote signed before the guard was in place, e.g. as kept
+// in the consensus state of a node upgraded from a version without the guard. It has no effect
+// if the guard has already recorded signatures. Either of the vote and the header can be nil.
+func (sg *SignGuard) Seed(vote *core.Vote, header *core.BlockHeader) error {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	state := sg.state
+	if state.HasSignedBlock || state.HasSignedVote {
+		return nil
+	}
+	if header != nil {
+		state.HasSignedBlock = true
+		state.BlockEpoch = header.Epoch
+		state.BlockHeight = header.Height
+		state.BlockSignHash = crypto.Keccak256Hash(header.SignBytes())
+	}
+	if vote != nil {
+		state.HasSignedVote = true
+		state.VoteHeight = vote.Height
+		state.VoteEpoch = vote.Epoch
+		state.VoteBlock = vote.Block
+	}
+	return sg.update(state)
+}
+
+func (sg *SignGuard) update(state SignState) error {
+	if len(sg.filePath) > 0 {
+		raw, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := common.WriteFileAtomic(sg.filePath, raw, 0600); err != nil {
+			return fmt.Errorf("Failed to persist the sign state: %v", err)
+		}
+	}
+	sg.state = state
+	return nil
+}
@@ -0,0 +1,99 @@
+package signer
+
+import (
+	"fmt"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+var _ core.Signer = (*LocalSigner)(nil)
+
+//
+// LocalSigner signs with a private key held in the process
+//
+type LocalSigner struct {
+	privKey *crypto.PrivateKey
+	guard   *SignGuard
+}
+
+// NewLocalSigner creates a signer with the given private key. The guard protects against
+// signing conflicting blocks and votes, nil to sign without protection.
+func NewLocalSigner(privKey *crypto.PrivateKey, guard *SignGuard) *LocalSigner {
+	return &LocalSigner{
+		privKey: privKey,
+		guard:   guard,
+	}
+}
+
+// IsSignStateFresh returns true if the guard has no record of previous signatures, which
+// happens when the guard file is lost or the key was never used.
+func (ls *LocalSigner) IsSignStateFresh() (bool, error) {
+	return ls.guard != nil && ls.guard.IsFresh(), nil
+}
+
+// SeedSignState seeds a fresh guard with the latest vote and block signed before the guard
+// was in place
+func (ls *LocalSigner) SeedSignState(vote *core.Vote, header *core.BlockHeader) error {
+	if ls.guard == nil {
+		return nil
+	}
+	return ls.guard.Seed(vote, header)
+}
+
+// Address implements the core.Signer interface
+func (ls *LocalSigner) Address() common.Address {
+	return ls.privKey.PublicKey().Address()
+}
+
+// PublicKey implements the core.Signer interface
+func (ls *LocalSigner) PublicKey() *crypto.PublicKey {
+	return ls.privKey.PublicKey()
+}
+
+// SignBlock implements the core.Signer interface
+func (ls *LocalSigner) SignBlock(header *core.BlockHeader) (*crypto.Signature, error) {
+	if header.Proposer != ls.Address() {
+		return nil, fmt.Errorf("Block proposer %v does not match the signer %v", header.Proposer.Hex(), ls.Address().Hex())
+	}
+	if ls.guard != nil {
+		if err := ls.guard.CheckAndRecordBlock(header); err != nil {
+			return nil, err
+		}
+	}
+	return ls.privKey.Sign(header.SignBytes())
+}
+
+// SignVote implements the core.Signer interface
+func (ls *LocalSigner) SignVote(vote core.Vote) (*crypto.Signature, error) {
+	if vote.ID != ls.Address() {
+		return nil, fmt.Errorf("Voter %v does not match the signer %v", vote.ID.Hex(), ls.Address().Hex())
+	}
+	if ls.guard != nil {
+		if err := ls.guard.CheckAndRecordVote(vote); err != nil {
+			return nil, err
+		}
+	}
+	return ls.privKey.Sign(vote.SignBytes())
+}
+
+// SignTx implements the core.Signer interface
+func (ls *LocalSigner) SignTx(chainID string, rawTx common.Bytes) (*crypto.Signature, error) {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	switch tx.(type) {
+	case *types.CoinbaseTx, *types.SlashTx:
+	default:
+		return nil, fmt.Errorf("Refused to sign transaction of type %T", tx)
+	}
+	return ls.privKey.Sign(tx.SignBytes(chainID))
+}
+
+// VRFProve implements the core.Signer interface
+func (ls *LocalSigner) VRFProve(alpha common.Bytes) (common.Bytes, error) {
+	return ls.privKey.VRFProve(alpha)
+}
@@ -0,0 +1,220 @@
+package signer
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"time"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+	"github.com/thetatoken/theta/rlp"
+)
+
+const remoteSignerTimeout = 5 * time.Second
+
+var _ core.Signer = (*RemoteSigner)(nil)
+
+//
+// RemoteSigner forwards the signing requests to a signer server, so that the
+// validator key can be kept in a separate process or on a separate machine. The
+// connection is re-established on demand if it breaks.
+//
+type RemoteSigner struct {
+	network string
+	address string
+	secret  string
+
+	mu     *sync.Mutex
+	client *rpc.Client
+
+	pubKey *crypto.PublicKey
+}
+
+// NewRemoteSigner connects to the signer server at the given address, authenticates with the
+// shared secret if it is not empty, and retrieves the public key of the validator.
+func NewRemoteSigner(addr string, secret string) (*RemoteSigner, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "tcp" && len(secret) == 0 {
+		return nil, fmt.Errorf("The signer secret is required to connect on tcp")
+	}
+	rs := &RemoteSigner{
+		network: network,
+		address: address,
+		secret:  secret,
+		mu:      &sync.Mutex{},
+	}
+
+	result := &PublicKeyResult{}
+	if err := rs.call("Signer.PublicKey", &PublicKeyArgs{}, result); err != nil {
+		return nil, err
+	}
+	pubKey, err := crypto.PublicKeyFromBytes(result.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	rs.pubKey = pubKey
+	return rs, nil
+}
+
+// Address implements the core.Signer interface
+func (rs *RemoteSigner) Address() common.Address {
+	return rs.pubKey.Address()
+}
+
+// PublicKey implements the core.Signer interface
+func (rs *RemoteSigner) PublicKey() *crypto.PublicKey {
+	return rs.pubKey
+}
+
+// IsSignStateFresh returns true if the remote signer has no record of previous signatures
+func (rs *RemoteSigner) IsSignStateFresh() (bool, error) {
+	result := &SignStateResult{}
+	if err := rs.call("Signer.SignState", &SignStateArgs{}, result); err != nil {
+		return false, err
+	}
+	return result.Fresh, nil
+}
+
+// SeedSignState seeds the fresh sign state of the remote signer with the latest vote and block
+// signed before the sign state was in place
+func (rs *RemoteSigner) SeedSignState(vote *core.Vote, header *core.BlockHeader) error {
+	args := &SeedSignStateArgs{}
+	if vote != nil {
+		raw, err := rlp.EncodeToBytes(vote)
+		if err != nil {
+			return err
+		}
+		args.Vote = raw
+	}
+	if header != nil {
+		raw, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return err
+		}
+		args.Header = raw
+	}
+	return rs.call("Signer.SeedSignState", args, &SeedSignStateResult{})
+}
+
+// SignBlock implements the core.Signer interface
+func (rs *RemoteSigner) SignBlock(header *core.BlockHeader) (*crypto.Signature, error) {
+	raw, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	result := &SignResult{}
+	if err := rs.call("Signer.SignBlock", &SignBlockArgs{Header: raw}, result); err != nil {
+		return nil, err
+	}
+	return rs.verified(header.SignBytes(), result.Signature)
+}
+
+// SignVote implements the core.Signer interface
+func (rs *RemoteSigner) SignVote(vote core.Vote) (*crypto.Signature, error) {
+	raw, err := rlp.EncodeToBytes(vote)
+	if err != nil {
+		return nil, err
+	}
+	result := &SignResult{}
+	if err := rs.call("Signer.SignVote", &SignVoteArgs{Vote: raw}, result); err != nil {
+		return nil, err
+	}
+	return rs.verified(vote.SignBytes(), result.Signature)
+}
+
+// SignTx implements the core.Signer interface
+func (rs *RemoteSigner) SignTx(chainID string, rawTx common.Bytes) (*crypto.Signature, error) {
+	tx, err := types.TxFromBytes(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	result := &SignResult{}
+	if err := rs.call("Signer.SignTx", &SignTxArgs{ChainID: chainID, Tx: rawTx}, result); err != nil {
+		return nil, err
+	}
+	return rs.verified(tx.SignBytes(chainID), result.Signature)
+}
+
+// VRFProve implements the core.Signer interface
+func (rs *RemoteSigner) VRFProve(alpha common.Bytes) (common.Bytes, error) {
+	result := &VRFProveResult{}
+	if err := rs.call("Signer.VRFProve", &VRFProveArgs{Alpha: alpha}, result); err != nil {
+		return nil, err
+	}
+	if !rs.pubKey.VRFVerify(alpha, result.Proof) {
+		return nil, fmt.Errorf("Remote signer returned an invalid VRF proof")
+	}
+	return result.Proof, nil
+}
+
+// verified makes sure the signature returned by the remote signer is from the validator key
+func (rs *RemoteSigner) verified(msg common.Bytes, sigBytes common.Bytes) (*crypto.Signature, error) {
+	sig, err := crypto.SignatureFromBytes(sigBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !rs.pubKey.VerifySignature(msg, sig) {
+		return nil, fmt.Errorf("Remote signer returned an invalid signature")
+	}
+	return sig, nil
+}
+
+func (rs *RemoteSigner) call(method string, args interface{}, result interface{}) error {
+	client, err := rs.getClient()
+	if err != nil {
+		return err
+	}
+
+	call := client.Go(method, args, result, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		err = call.Error
+	case <-time.After(remoteSignerTimeout):
+		err = fmt.Errorf("Remote signer timed out on %v", method)
+		rs.resetClient(client)
+		return err
+	}
+	if _, refused := err.(rpc.ServerError); err != nil && !refused {
+		rs.resetClient(client) // the connection is broken, reconnect on the next call
+	}
+	return err
+}
+
+func (rs *RemoteSigner) getClient() (*rpc.Client, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.client != nil {
+		return rs.client, nil
+	}
+	conn, err := net.DialTimeout(rs.network, rs.address, remoteSignerTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to the remote signer: %v", err)
+	}
+	if len(rs.secret) != 0 {
+		if err := authenticateToServer(conn, rs.secret); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Failed to authenticate to the remote signer: %v", err)
+		}
+	}
+	rs.client = jsonrpc.NewClient(conn)
+	return rs.client, nil
+}
+
+func (rs *RemoteSigner) resetClient(client *rpc.Client) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.client == client {
+		rs.client.Close()
+		rs.client = nil
+	}
+}
@@ -0,0 +1,280 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/rlp"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "signer"})
+
+// ParseAddress splits the signer address, e.g. "unix:///var/theta/signer.sock" or
+// "tcp://127.0.0.1:16890", into the network and the address to dial or listen on.
+func ParseAddress(addr string) (network string, address string, err error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("Invalid signer address: %v", addr)
+	}
+	switch parts[0] {
+	case "unix", "tcp":
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("Unsupported signer network: %v", parts[0])
+	}
+}
+
+//
+// Server serves the signing requests from the remote nodes with a local signer
+//
+type Server struct {
+	signer   *LocalSigner
+	listener net.Listener
+	secret   string
+
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewServer creates a server that listens on the given address and signs with the given signer.
+// The clients need to prove the knowledge of the shared secret if it is not empty. The secret is
+// required on tcp, where the access is not restricted by the file permissions.
+func NewServer(signer *LocalSigner, addr string, secret string) (*Server, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "tcp" && len(secret) == 0 {
+		return nil, fmt.Errorf("The signer secret is required to listen on tcp")
+	}
+	if network == "unix" {
+		os.Remove(address) // stale socket of a previous run
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if err := os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return &Server{
+		signer:   signer,
+		listener: listener,
+		secret:   secret,
+		wg:       &sync.WaitGroup{},
+	}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Start starts accepting the connections
+func (s *Server) Start(ctx context.Context) error {
+	c, cancel := context.WithCancel(ctx)
+	s.ctx = c
+	s.cancel = cancel
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Signer", &SignerService{signer: s.signer}); err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.acceptLoop(srv)
+
+	go func() {
+		<-s.ctx.Done()
+		s.listener.Close()
+	}()
+	return nil
+}
+
+// Stop notifies the server to stop without blocking
+func (s *Server) Stop() {
+	s.cancel()
+}
+
+// Wait blocks until the server stops
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) acceptLoop(srv *rpc.Server) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.ctx.Done():
+				return
+			default:
+			}
+			logger.Warnf("Failed to accept connection: %v", err)
+			continue
+		}
+		go s.serveConn(srv, conn)
+	}
+}
+
+func (s *Server) serveConn(srv *rpc.Server, conn net.Conn) {
+	if len(s.secret) != 0 {
+		if err := authenticateClient(conn, s.secret); err != nil {
+			logger.Warnf("Rejected connection from %v: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return
+		}
+	}
+	logger.Infof("Accepted connection from %v", conn.RemoteAddr())
+	srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+
+// ------------------------------- Signer Service ----------------------------------- //
+
+// SignerService exposes the signer through RPC
+type SignerService struct {
+	signer *LocalSigner
+}
+
+type PublicKeyArgs struct{}
+
+type PublicKeyResult struct {
+	PublicKey common.Bytes `json:"public_key"`
+}
+
+func (ss *SignerService) PublicKey(args *PublicKeyArgs, result *PublicKeyResult) error {
+	result.PublicKey = ss.signer.PublicKey().ToBytes()
+	return nil
+}
+
+type SignStateArgs struct{}
+
+type SignStateResult struct {
+	Fresh bool `json:"fresh"`
+}
+
+func (ss *SignerService) SignState(args *SignStateArgs, result *SignStateResult) error {
+	fresh, err := ss.signer.IsSignStateFresh()
+	if err != nil {
+		return err
+	}
+	result.Fresh = fresh
+	return nil
+}
+
+type SeedSignStateArgs struct {
+	Vote   common.Bytes `json:"vote"`   // RLP encoded vote, empty if none
+	Header common.Bytes `json:"header"` // RLP encoded block header, empty if none
+}
+
+type SeedSignStateResult struct{}
+
+func (ss *SignerService) SeedSignState(args *SeedSignStateArgs, result *SeedSignStateResult) error {
+	var vote *core.Vote
+	if len(args.Vote) != 0 {
+		vote = &core.Vote{}
+		if err := rlp.DecodeBytes(args.Vote, vote); err != nil {
+			return err
+		}
+	}
+	var header *core.BlockHeader
+	if len(args.Header) != 0 {
+		header = &core.BlockHeader{}
+		if err := rlp.DecodeBytes(args.Header, header); err != nil {
+			return err
+		}
+	}
+	return ss.signer.SeedSignState(vote, header)
+}
+
+type SignBlockArgs struct {
+	Header common.Bytes `json:"header"` // RLP encoded block header
+}
+
+type SignResult struct {
+	Signature common.Bytes `json:"signature"`
+}
+
+func (ss *SignerService) SignBlock(args *SignBlockArgs, result *SignResult) error {
+	header := &core.BlockHeader{}
+	if err := rlp.DecodeBytes(args.Header, header); err != nil {
+		return err
+	}
+	sig, err := ss.signer.SignBlock(header)
+	if err != nil {
+		logger.Warnf("Refused to sign block %v: %v", header, err)
+		return err
+	}
+	result.Signature = sig.ToBytes()
+	return nil
+}
+
+type SignVoteArgs struct {
+	Vote common.Bytes `json:"vote"` // RLP encoded vote
+}
+
+func (ss *SignerService) SignVote(args *SignVoteArgs, result *SignResult) error {
+	vote := core.Vote{}
+	if err := rlp.DecodeBytes(args.Vote, &vote); err != nil {
+		return err
+	}
+	sig, err := ss.signer.SignVote(vote)
+	if err != nil {
+		logger.Warnf("Refused to sign vote %v: %v", vote, err)
+		return err
+	}
+	result.Signature = sig.ToBytes()
+	return nil
+}
+
+type SignTxArgs struct {
+	ChainID string       `json:"chain_id"`
+	Tx      common.Bytes `json:"tx"` // RLP encoded transaction
+}
+
+func (ss *SignerService) SignTx(args *SignTxArgs, result *SignResult) error {
+	sig, err := ss.signer.SignTx(args.ChainID, args.Tx)
+	if err != nil {
+		logger.Warnf("Refused to sign transaction: %v", err)
+		return err
+	}
+	result.Signature = sig.ToBytes()
+	return nil
+}
+
+type VRFProveArgs struct {
+	Alpha common.Bytes `json:"alpha"`
+}
+
+type VRFProveResult struct {
+	Proof common.Bytes `json:"proof"`
+}
+
+func (ss *SignerService) VRFProve(args *VRFProveArgs, result *VRFProveResult) error {
+	proof, err := ss.signer.VRFProve(args.Alpha)
+	if err != nil {
+		return err
+	}
+	if len(proof) != crypto.VRFProofLength {
+		return fmt.Errorf("Invalid VRF proof length: %v", len(proof))
+	}
+	result.Proof = proof
+	return nil
+}
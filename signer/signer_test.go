@@ -0,0 +1,216 @@
+package signer
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thetatoken/theta/common"
+	"github.com/thetatoken/theta/core"
+	"github.com/thetatoken/theta/crypto"
+	"github.com/thetatoken/theta/ledger/types"
+)
+
+func newTestHeader(proposer common.Address, epoch, height uint64, parent string) *core.BlockHeader {
+	return &core.BlockHeader{
+		ChainID:   "testchain",
+		Epoch:     epoch,
+		Height:    height,
+		Parent:    common.BytesToHash([]byte(parent)),
+		Timestamp: big.NewInt(1),
+		Proposer:  proposer,
+	}
+}
+
+func TestSignGuard(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "signguard")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "sign_state.json")
+
+	guard, err := NewSignGuard(filePath)
+	require.Nil(err)
+	assert.True(guard.IsFresh())
+	proposer := common.HexToAddress("0x1")
+
+	// Blocks: one per epoch
+	assert.Nil(guard.CheckAndRecordBlock(newTestHeader(proposer, 10, 5, "a")))
+	assert.Nil(guard.CheckAndRecordBlock(newTestHeader(proposer, 10, 5, "a")))
+	assert.NotNil(guard.CheckAndRecordBlock(newTestHeader(proposer, 10, 5, "b")))
+	assert.NotNil(guard.CheckAndRecordBlock(newTestHeader(proposer, 9, 4, "c")))
+	assert.Nil(guard.CheckAndRecordBlock(newTestHeader(proposer, 11, 5, "b")))
+
+	// Votes: one block per height, repeating the same vote is allowed
+	blockA := common.BytesToHash([]byte("a"))
+	blockB := common.BytesToHash([]byte("b"))
+	assert.Nil(guard.CheckAndRecordVote(core.Vote{Block: blockA, Height: 5, Epoch: 10}))
+	assert.Nil(guard.CheckAndRecordVote(core.Vote{Block: blockA, Height: 5, Epoch: 12}))
+	assert.NotNil(guard.CheckAndRecordVote(core.Vote{Block: blockB, Height: 5, Epoch: 12}))
+	assert.NotNil(guard.CheckAndRecordVote(core.Vote{Block: blockB, Height: 4, Epoch: 12}))
+	assert.Nil(guard.CheckAndRecordVote(core.Vote{Block: blockB, Height: 6, Epoch: 13}))
+
+	// The state survives restarts
+	reloaded, err := NewSignGuard(filePath)
+	require.Nil(err)
+	assert.Equal(guard.State(), reloaded.State())
+	assert.Equal(uint64(11), reloaded.State().BlockEpoch)
+	assert.Equal(uint64(6), reloaded.State().VoteHeight)
+	assert.Equal(uint64(13), reloaded.State().VoteEpoch)
+	assert.False(reloaded.IsFresh())
+	assert.NotNil(reloaded.CheckAndRecordBlock(newTestHeader(proposer, 11, 5, "c")))
+	assert.NotNil(reloaded.CheckAndRecordVote(core.Vote{Block: blockA, Height: 6, Epoch: 13}))
+
+	// Seeding has no effect on the recorded signatures
+	assert.Nil(reloaded.Seed(&core.Vote{Block: blockA, Height: 3, Epoch: 3}, nil))
+	assert.Equal(guard.State(), reloaded.State())
+}
+
+func TestSignGuardSeed(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	guard, err := NewSignGuard("")
+	require.Nil(err)
+	proposer := common.HexToAddress("0x1")
+	blockA := common.BytesToHash([]byte("a"))
+
+	assert.Nil(guard.Seed(&core.Vote{Block: blockA, Height: 5, Epoch: 10}, newTestHeader(proposer, 10, 5, "a")))
+	assert.False(guard.IsFresh())
+	assert.NotNil(guard.CheckAndRecordBlock(newTestHeader(proposer, 10, 5, "b")))
+	assert.NotNil(guard.CheckAndRecordVote(core.Vote{Block: common.BytesToHash([]byte("b")), Height: 5, Epoch: 11}))
+	assert.Nil(guard.CheckAndRecordVote(core.Vote{Block: blockA, Height: 5, Epoch: 11}))
+}
+
+func TestRemoteSigner(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(err)
+	guard, err := NewSignGuard(path.Join(dir, "sign_state.json"))
+	require.Nil(err)
+	server, err := NewServer(NewLocalSigner(privKey, guard), "unix://"+path.Join(dir, "signer.sock"), "")
+	require.Nil(err)
+	require.Nil(server.Start(context.Background()))
+	defer server.Stop()
+
+	rs, err := NewRemoteSigner("unix://"+path.Join(dir, "signer.sock"), "")
+	require.Nil(err)
+	address := privKey.PublicKey().Address()
+	assert.Equal(address, rs.Address())
+	fresh, err := rs.IsSignStateFresh()
+	require.Nil(err)
+	assert.True(fresh)
+	require.Nil(rs.SeedSignState(nil, nil))
+	fresh, err = rs.IsSignStateFresh()
+	require.Nil(err)
+	assert.True(fresh)
+
+	// Block
+	header := newTestHeader(address, 10, 5, "a")
+	sig, err := rs.SignBlock(header)
+	require.Nil(err)
+	assert.True(sig.Verify(header.SignBytes(), address))
+	fresh, err = rs.IsSignStateFresh()
+	require.Nil(err)
+	assert.False(fresh)
+	_, err = rs.SignBlock(newTestHeader(address, 10, 5, "b"))
+	assert.NotNil(err)
+	_, err = rs.SignBlock(newTestHeader(common.HexToAddress("0x1"), 11, 5, "a"))
+	assert.NotNil(err)
+
+	// Vote
+	vote := core.Vote{Block: header.Hash(), Height: 5, Epoch: 10, ID: address}
+	sig, err = rs.SignVote(vote)
+	require.Nil(err)
+	assert.True(sig.Verify(vote.SignBytes(), address))
+	_, err = rs.SignVote(core.Vote{Block: common.BytesToHash([]byte("b")), Height: 5, Epoch: 11, ID: address})
+	assert.NotNil(err)
+
+	// Transactions
+	coinbaseTx := &types.CoinbaseTx{
+		Proposer:    types.TxInput{Address: address},
+		BlockHeight: 5,
+	}
+	rawTx, err := types.TxToBytes(coinbaseTx)
+	require.Nil(err)
+	sig, err = rs.SignTx("testchain", rawTx)
+	require.Nil(err)
+	assert.True(sig.Verify(coinbaseTx.SignBytes("testchain"), address))
+
+	sendTx := &types.SendTx{Fee: types.NewCoins(0, 1)}
+	rawTx, err = types.TxToBytes(sendTx)
+	require.Nil(err)
+	_, err = rs.SignTx("testchain", rawTx)
+	assert.NotNil(err)
+
+	// VRF
+	proof, err := rs.VRFProve(common.Bytes("alpha"))
+	require.Nil(err)
+	assert.True(privKey.PublicKey().VRFVerify(common.Bytes("alpha"), proof))
+
+	// The sign state is unknown if the signer is unreachable
+	unreachable := &RemoteSigner{network: "unix", address: path.Join(dir, "missing.sock"), mu: &sync.Mutex{}}
+	_, err = unreachable.IsSignStateFresh()
+	assert.NotNil(err)
+}
+
+func TestRemoteSignerSecret(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(err)
+
+	// The secret is required on tcp
+	_, err = NewServer(NewLocalSigner(privKey, nil), "tcp://127.0.0.1:0", "")
+	assert.NotNil(err)
+	_, err = NewRemoteSigner("tcp://127.0.0.1:16890", "")
+	assert.NotNil(err)
+
+	server, err := NewServer(NewLocalSigner(privKey, nil), "tcp://127.0.0.1:0", "secret")
+	require.Nil(err)
+	require.Nil(server.Start(context.Background()))
+	defer server.Stop()
+	addr := "tcp://" + server.Addr().String()
+
+	_, err = NewRemoteSigner(addr, "wrong secret")
+	assert.NotNil(err)
+
+	rs, err := NewRemoteSigner(addr, "secret")
+	require.Nil(err)
+	assert.Equal(privKey.PublicKey().Address(), rs.Address())
+}
+
+func TestParseAddress(t *testing.T) {
+	assert := assert.New(t)
+
+	network, address, err := ParseAddress("unix:///tmp/signer.sock")
+	assert.Nil(err)
+	assert.Equal("unix", network)
+	assert.Equal("/tmp/signer.sock", address)
+
+	network, address, err = ParseAddress("tcp://127.0.0.1:16890")
+	assert.Nil(err)
+	assert.Equal("tcp", network)
+	assert.Equal("127.0.0.1:16890", address)
+
+	_, _, err = ParseAddress("127.0.0.1:16890")
+	assert.NotNil(err)
+	_, _, err = ParseAddress("udp://127.0.0.1:16890")
+	assert.NotNil(err)
+}